@@ -0,0 +1,45 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+
+	"ticket_app/internal/config"
+)
+
+const configUsage = `usage: ticket_app config print
+
+Prints every configuration key with its resolved value (environment, then
+.env, then CONFIG_FILE, then the default), with secrets redacted.`
+
+// loadConfig loads the configuration and logs every invalid key
+func loadConfig() (*config.Config, bool) {
+	cfg, err := config.Load()
+	if err != nil {
+		log.Printf("%v", err)
+		return nil, false
+	}
+	return cfg, true
+}
+
+// runConfig handles `ticket_app config ...` and returns the exit code
+func runConfig(args []string) int {
+	if len(args) != 1 || args[0] != "print" {
+		fmt.Fprintln(os.Stderr, configUsage)
+		return 2
+	}
+	cfg, err := config.Load()
+	var invalid *config.ValidationError
+	if err != nil && !errors.As(err, &invalid) {
+		log.Printf("%v", err)
+		return 1
+	}
+	fmt.Print(cfg)
+	if invalid != nil {
+		fmt.Fprintln(os.Stderr, invalid)
+		return 1
+	}
+	return 0
+}
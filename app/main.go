@@ -1,130 +1,129 @@
 package main
 
 import (
+	"context"
+	"fmt"
 	"log"
 	"os"
-	"strconv"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
-	"github.com/gofiber/fiber/v2/middleware/cors"
-	"gorm.io/driver/postgres"
-	"gorm.io/gorm"
-
-	"fmt"
-	"ticket_app/auth"
-	"ticket_app/booking"
-	"ticket_app/domain"
-	"ticket_app/event"
-	"ticket_app/health"
-	queueService "ticket_app/internal/queue"
-	"ticket_app/internal/redis"
-	bookingRepo "ticket_app/internal/repository/booking"
-	eventRepo "ticket_app/internal/repository/event"
-	paymentRepo "ticket_app/internal/repository/payment"
-	userRepo "ticket_app/internal/repository/user"
-	"ticket_app/internal/rest"
-	"ticket_app/internal/rest/middleware"
 
-	"github.com/joho/godotenv"
+	"ticket_app/internal/bootstrap"
+	"ticket_app/internal/queue"
 )
 
-const (
-	defaultTimeout = 30
-	defaultAddress = ":9090"
-)
+const idempotencyTTL = 24 * time.Hour
 
-func init() {
-	err := godotenv.Load()
-	if err != nil {
-		log.Fatal("Error loading .env file")
-	}
-}
+const usage = `usage: ticket_app <command> [arguments]
 
-func main() {
-	// Prepare database
-	dbHost := os.Getenv("POSTGRES_HOST")
-	dbPort := os.Getenv("POSTGRES_PORT")
-	dbUser := os.Getenv("POSTGRES_USER")
-	dbPass := os.Getenv("POSTGRES_PASSWORD")
-	dbName := os.Getenv("POSTGRES_DB")
+commands:
+  serve     run the HTTP API only
+  worker    run the payment worker, the payment timeout scheduler, the hold expirer and the waitlist offerer only
+  all       run the HTTP API and the workers in one process (default)
+  migrate   manage the database schema, see ` + "`ticket_app migrate`" + `
+  seed      insert demo users and events into the database
+  config    print the resolved configuration, see ` + "`ticket_app config`"
 
-	dsn := fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%s sslmode=disable",
-		dbHost, dbUser, dbPass, dbName, dbPort)
-	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
-	if err != nil {
-		log.Fatalf("Failed to connect to database: %v. DSN: %s", err, dsn)
+func main() {
+	command := "all"
+	var args []string
+	if len(os.Args) > 1 {
+		command, args = os.Args[1], os.Args[2:]
 	}
-	// Auto migrate to create all tables
 
-	if err := db.AutoMigrate(
-		&domain.User{},
-		&domain.Booking{},
-		&domain.Event{},
-		&domain.Payment{},
-	); err != nil {
-		log.Fatalf("Failed to migrate database: %v", err)
-	} else {
-		log.Println("Database migration completed successfully")
+	switch command {
+	case "serve":
+		os.Exit(run(true, false))
+	case "worker":
+		os.Exit(run(false, true))
+	case "all":
+		os.Exit(run(true, true))
+	case "migrate":
+		os.Exit(runMigrate(args))
+	case "seed":
+		os.Exit(runSeed(args))
+	case "config":
+		os.Exit(runConfig(args))
+	case "help", "-h", "--help":
+		fmt.Println(usage)
+	default:
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
 	}
-	
-	// Initialize Redis
-	redisClient, err := redis.NewRedis()
-	if err != nil {
-		log.Fatalf("Failed to initialize Redis: %v", err)
+}
+
+// run starts the HTTP API and/or the background workers and blocks until
+// SIGINT/SIGTERM, then stops taking requests, drains the workers and closes
+// connections, all within SHUTDOWN_TIMEOUT seconds. It returns the exit code.
+func run(serveHTTP, runWorkers bool) int {
+	cfg, ok := loadConfig()
+	if !ok {
+		return 1
 	}
-	if redisClient == nil {
-		log.Fatalf("Redis client initialization returned nil")
+	c := bootstrap.New(cfg)
+	shutdown := func(app *fiber.App) int {
+		ctx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+		defer cancel()
+		exitCode := 0
+		if app != nil {
+			if err := app.ShutdownWithContext(ctx); err != nil {
+				log.Printf("HTTP server shutdown: %v", err)
+				exitCode = 1
+			}
+		}
+		if err := c.Shutdown(ctx); err != nil {
+			log.Printf("Shutdown: %v", err)
+			exitCode = 1
+		}
+		log.Println("Shutdown complete")
+		return exitCode
 	}
-	log.Printf("Redis client initialized: %p, GetClient: %p", redisClient, redisClient.GetClient())
-	defer redisClient.Close()
 
-	// Register health service
-	healthService := health.NewHealthService(db, redisClient.GetClient())
-
-	// Initialize Fiber app
-	app := fiber.New()
-
-	// Middleware CORS
-	app.Use(cors.New())
-
-	authService := auth.NewAuthService(userRepo.NewGormUserRepository(db))
-	eventService := event.NewEventService(eventRepo.NewGormEventRepository(db))
-	bookingService := booking.NewBookingService(bookingRepo.NewGormBookingRepository(db), userRepo.NewGormUserRepository(db), eventRepo.NewGormEventRepository(db))
-	// paymentService := payment.NewPaymentService(paymentRepo.NewGormPaymentRepository(db))
-	log.Printf("Creating QueueService with redisClient: %p", redisClient)
-	queueService := queueService.NewQueueService(redisClient, paymentRepo.NewGormPaymentRepository(db), bookingRepo.NewGormBookingRepository(db), eventRepo.NewGormEventRepository(db))
-	rest.NewHealthHandlerFiber(app, healthService)
-	rest.NewEventHandler(app, eventService)
-	rest.NewAuthHandlerFiber(app, authService)
-
-
-	app.Get("/auth/profile", middleware.JWTMiddleware(), func(c *fiber.Ctx) error {
-		return c.Next() 
-	})
-
-	app.Use(middleware.JWTMiddleware())
-	rest.NewBookingHandler(app, bookingService, authService)
+	// Schema changes are applied with `ticket_app migrate up`, never on startup
+	if err := c.CheckMigrations(context.Background()); err != nil {
+		log.Printf("%v", err)
+		return max(shutdown(nil), 1)
+	}
 
-	// Custom timeout middleware
-	timeoutStr := os.Getenv("CONTEXT_TIMEOUT")
-	timeout, err := strconv.Atoi(timeoutStr)
-	if err != nil {
-		log.Println("failed to parse timeout, using default timeout")
-		timeout = defaultTimeout
+	var app *fiber.App
+	var err error
+	serverErr := make(chan error, 1)
+	if serveHTTP {
+		if app, err = newHTTPServer(c); err != nil {
+			log.Printf("Failed to build HTTP server: %v", err)
+			return max(shutdown(nil), 1)
+		}
+		if !runWorkers && cfg.Queue.Backend == queue.BackendMemory {
+			log.Println("Memory queue backend: jobs enqueued by `serve` are only processed by a worker in the same process, use `all`")
+		}
+		go func() {
+			serverErr <- app.Listen(cfg.Server.Address)
+		}()
 	}
-	timeoutContext := time.Duration(timeout) * time.Second
-	app.Use(func(c *fiber.Ctx) error {
-		c.Locals("timeout", timeoutContext)
-		return c.Next()
-	})
-	// Start queue worker and timeout checker in goroutines
-	go queueService.StartTimeoutChecker()
-	go queueService.StartWorker()
-	// Start Server
-	address := os.Getenv("SERVER_ADDRESS")
-	if address == "" {
-		address = defaultAddress
+	if runWorkers {
+		queueService, err := c.QueueService()
+		if err != nil {
+			log.Printf("Failed to build queue: %v", err)
+			return max(shutdown(app), 1)
+		}
+		c.Lifecycle.Go("Timeout checker", queueService.StartTimeoutChecker)
+		c.Lifecycle.Go("Hold expirer", queueService.StartHoldExpirer)
+		c.Lifecycle.Go("Waitlist offerer", queueService.StartWaitlistOfferer)
+		c.Lifecycle.Go("Payment worker", queueService.StartWorker)
 	}
-	log.Fatal(app.Listen(address))
-}
\ No newline at end of file
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	exitCode := 0
+	select {
+	case <-ctx.Done():
+		log.Println("Shutdown signal received")
+	case err := <-serverErr:
+		log.Printf("Server stopped: %v", err)
+		exitCode = 1
+	}
+	return max(shutdown(app), exitCode)
+}
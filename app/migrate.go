@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+
+	"ticket_app/internal/bootstrap"
+	"ticket_app/internal/migration"
+	"ticket_app/migrations"
+)
+
+const migrateUsage = `usage: ticket_app migrate <command>
+
+commands:
+  up             apply all pending migrations
+  down [N|all]   revert the last N applied migrations (default 1)
+  status         list migrations and when they were applied
+  create NAME    add an empty up/down pair to the migrations directory`
+
+// migrationsDir is where `migrate create` writes new files; the binary itself
+// runs the copies embedded from the migrations package
+const migrationsDir = "migrations"
+
+// runMigrate handles `ticket_app migrate ...` and returns the exit code
+func runMigrate(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		return 2
+	}
+
+	if args[0] == "create" {
+		if len(args) != 2 {
+			fmt.Fprintln(os.Stderr, migrateUsage)
+			return 2
+		}
+		up, down, err := migration.Create(migrationsDir, args[1])
+		if err != nil {
+			log.Printf("Failed to create migration: %v", err)
+			return 1
+		}
+		fmt.Printf("Created %s\nCreated %s\n", up, down)
+		return 0
+	}
+
+	cfg, ok := loadConfig()
+	if !ok {
+		return 1
+	}
+	c := bootstrap.New(cfg)
+	defer c.Shutdown(context.Background())
+	db, err := c.DB()
+	if err != nil {
+		log.Printf("%v", err)
+		return 1
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		log.Printf("Failed to get database pool: %v", err)
+		return 1
+	}
+	migrator, err := migration.NewMigrator(sqlDB, migrations.FS)
+	if err != nil {
+		log.Printf("Failed to load migrations: %v", err)
+		return 1
+	}
+
+	ctx := context.Background()
+	switch args[0] {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		for _, m := range applied {
+			fmt.Printf("Applied %06d_%s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			log.Printf("Migration failed: %v", err)
+			return 1
+		}
+		if len(applied) == 0 {
+			fmt.Println("Database is up to date")
+		}
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			if args[1] == "all" {
+				steps = int(^uint(0) >> 1)
+			} else if steps, err = strconv.Atoi(args[1]); err != nil || steps < 1 {
+				fmt.Fprintln(os.Stderr, migrateUsage)
+				return 2
+			}
+		}
+		reverted, err := migrator.Down(ctx, steps)
+		for _, m := range reverted {
+			fmt.Printf("Reverted %06d_%s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			log.Printf("Migration failed: %v", err)
+			return 1
+		}
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			log.Printf("Failed to read migration status: %v", err)
+			return 1
+		}
+		for _, st := range statuses {
+			applied := "pending"
+			if st.AppliedAt != nil {
+				applied = "applied " + st.AppliedAt.Format("2006-01-02 15:04:05 MST")
+			}
+			fmt.Printf("%06d_%-40s %s\n", st.Version, st.Name, applied)
+		}
+	default:
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		return 2
+	}
+	return 0
+}
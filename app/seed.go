@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"ticket_app/auth"
+	"ticket_app/domain"
+	"ticket_app/internal/bootstrap"
+	eventRepo "ticket_app/internal/repository/event"
+	userRepo "ticket_app/internal/repository/user"
+)
+
+// seedPassword is the password of every seeded user, for local testing only
+const seedPassword = "password123"
+
+// seedUsers has one user per role; the organizer owns the seeded events
+var seedUsers = []struct {
+	Email string
+	Role  domain.Role
+}{
+	{"alice@example.com", domain.RoleAdmin},
+	{"bob@example.com", domain.RoleCustomer},
+	{"carol@example.com", domain.RoleOrganizer},
+}
+
+const seedOrganizer = "carol@example.com"
+
+var seedEvents = []domain.Event{
+	{Name: "Saigon Jazz Night", Description: "An evening of live jazz by the river", TotalTickets: 200, TicketPrice: 350000},
+	{Name: "Hanoi Rock Festival", Description: "Two stages, ten bands, one night", TotalTickets: 1000, TicketPrice: 600000},
+	{Name: "Da Nang Tech Conference", Description: "Talks and workshops on cloud and AI", TotalTickets: 500, TicketPrice: 1200000},
+}
+
+// runSeed handles `ticket_app seed`: it inserts demo users and events, skipping
+// the ones that already exist, and returns the exit code
+func runSeed(args []string) int {
+	if len(args) != 0 {
+		fmt.Fprintln(os.Stderr, "usage: ticket_app seed")
+		return 2
+	}
+	cfg, ok := loadConfig()
+	if !ok {
+		return 1
+	}
+	c := bootstrap.New(cfg)
+	defer c.Shutdown(context.Background())
+	ctx := context.Background()
+	if err := c.CheckMigrations(ctx); err != nil {
+		log.Printf("%v", err)
+		return 1
+	}
+	db, err := c.DB()
+	if err != nil {
+		log.Printf("%v", err)
+		return 1
+	}
+
+	users := userRepo.NewGormUserRepository(db)
+	password, err := auth.HashPassword(seedPassword)
+	if err != nil {
+		log.Printf("Failed to hash password: %v", err)
+		return 1
+	}
+	var organizerID *uint
+	for _, u := range seedUsers {
+		email := u.Email
+		existing, err := users.FindByEmail(ctx, email)
+		if err != nil {
+			log.Printf("Failed to look up user %s: %v", email, err)
+			return 1
+		}
+		if existing != nil {
+			fmt.Printf("User %s already exists\n", email)
+		} else {
+			verifiedAt := time.Now()
+			existing = &domain.User{Email: email, Password: password, Role: u.Role, EmailVerifiedAt: &verifiedAt}
+			if err := users.Create(ctx, existing); err != nil {
+				log.Printf("Failed to create user %s: %v", email, err)
+				return 1
+			}
+			fmt.Printf("Created %s %s (password %q)\n", u.Role, email, seedPassword)
+		}
+		if email == seedOrganizer {
+			organizerID = &existing.ID
+		}
+	}
+
+	events := eventRepo.NewGormEventRepository(db)
+	all, err := events.FindAll(ctx)
+	if err != nil {
+		log.Printf("Failed to list events: %v", err)
+		return 1
+	}
+	exists := map[string]bool{}
+	for _, e := range all {
+		exists[e.Name] = true
+	}
+	start := time.Now().Truncate(24*time.Hour).AddDate(0, 0, 30).Add(19 * time.Hour)
+	for i, e := range seedEvents {
+		if exists[e.Name] {
+			fmt.Printf("Event %q already exists\n", e.Name)
+			continue
+		}
+		e.StartDate = start.AddDate(0, 0, 7*i)
+		e.EndDate = e.StartDate.Add(4 * time.Hour)
+		e.Status = domain.EventStatusActive
+		e.OrganizerID = organizerID
+		if err := events.Create(ctx, &e); err != nil {
+			log.Printf("Failed to create event %q: %v", e.Name, err)
+			return 1
+		}
+		fmt.Printf("Created event %q (id %d)\n", e.Name, e.ID)
+	}
+	return 0
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/cors"
+
+	"ticket_app/domain"
+	"ticket_app/internal/bootstrap"
+	"ticket_app/internal/config"
+	"ticket_app/internal/rest"
+	"ticket_app/internal/rest/middleware"
+)
+
+// newHTTPServer registers the middleware and the REST handlers on a new Fiber app
+func newHTTPServer(c *bootstrap.Container) (*fiber.App, error) {
+	services, err := c.Services()
+	if err != nil {
+		return nil, err
+	}
+
+	tokens, err := c.Tokens()
+	if err != nil {
+		return nil, err
+	}
+
+	// Initialize Fiber app
+	app := fiber.New(fiberConfig(c.Config.Server))
+
+	// Middleware CORS
+	app.Use(cors.New())
+
+	// Request timeout, propagated to services through c.UserContext()
+	app.Use(middleware.Timeout(c.Config.Server.RequestTimeout))
+
+	requireAuth := middleware.JWTMiddleware(tokens, services.Revocations)
+	rest.NewHealthHandlerFiber(app, services.Health)
+	rest.NewEventHandler(app, services.Event, requireAuth)
+	rest.NewVenueHandler(app, services.Venue, requireAuth)
+	rest.NewAuthHandlerFiber(app, services.Auth, requireAuth)
+	rest.NewJWKSHandler(app, tokens)
+
+	// Mọi route đăng ký sau đây cần access token; /admin cần thêm role admin
+	app.Use(requireAuth, middleware.ResolveUser(services.Auth))
+	app.Use("/admin", middleware.RequireRole(domain.RoleAdmin))
+
+	// Idempotency-Key support for endpoints that clients retry
+	idempotency := middleware.Idempotency(services.Idempotency, idempotencyTTL)
+	app.Post("/bookings", idempotency)
+	app.Post("/holds", idempotency)
+	app.Post("/orders", idempotency)
+	app.Post("/payments", idempotency)
+
+	rest.NewBookingHandler(app, services.Booking)
+	rest.NewOrderHandler(app, services.Order)
+	rest.NewWaitlistHandler(app, services.Waitlist)
+	rest.NewPaymentHandler(app, services.Payment)
+	rest.NewDeadLetterHandler(app, services.Queue)
+	rest.NewUserAdminHandler(app, services.Auth)
+	return app, nil
+}
+
+// fiberConfig makes c.IP() return the client IP from X-Forwarded-For, but only
+// for requests that come from one of the trusted proxies
+func fiberConfig(server config.ServerConfig) fiber.Config {
+	if len(server.TrustedProxies) == 0 {
+		return fiber.Config{}
+	}
+	return fiber.Config{
+		ProxyHeader:             fiber.HeaderXForwardedFor,
+		EnableTrustedProxyCheck: true,
+		TrustedProxies:          server.TrustedProxies,
+	}
+}
@@ -2,91 +2,928 @@ package auth
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/hex"
 	"errors"
+	"fmt"
 	"log"
+	"net/url"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
+	"unicode/utf8"
 
 	"ticket_app/domain"
+	"ticket_app/internal/mailer"
+	"ticket_app/internal/random"
+	"ticket_app/internal/repository"
+	"ticket_app/internal/repository/audit"
+	"ticket_app/internal/repository/recoverycode"
+	"ticket_app/internal/repository/refreshtoken"
 	"ticket_app/internal/repository/user"
-
-	"os"
+	"ticket_app/internal/repository/usertoken"
+	"ticket_app/internal/token"
+	"ticket_app/internal/totp"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 	"golang.org/x/crypto/bcrypt"
 )
 
 type AuthService interface {
 	Register(ctx context.Context, email, password string) (*domain.User, error)
-	Login(ctx context.Context, email, password string) (string, error) // Chỉ trả về AccessToken
-	FindByEmail(email string) (*domain.User, error)
+	// Login trả cùng một lỗi domain.ErrInvalidCredentials khi sai email hoặc sai password;
+	// khi bị giới hạn thì trả *ThrottledError. User đã bật 2FA nhận MFA token thay cho cặp token.
+	Login(ctx context.Context, email, password string, client Client) (*LoginResult, error)
+	// VerifyMFA hoàn tất đăng nhập bằng MFA token của Login và mã TOTP hoặc recovery code
+	VerifyMFA(ctx context.Context, mfaToken, code string, client Client) (*TokenPair, error)
+	// EnrollMFA tạo secret TOTP mới; 2FA chỉ bật sau khi ConfirmMFA
+	EnrollMFA(ctx context.Context, userID uint) (*MFAEnrollment, error)
+	// ConfirmMFA bật 2FA bằng mã đầu tiên từ authenticator và trả về các recovery code
+	ConfirmMFA(ctx context.Context, userID uint, code string) ([]string, error)
+	// DisableMFA tắt 2FA; cần mã TOTP hoặc recovery code
+	DisableMFA(ctx context.Context, userID uint, code string, client Client) error
+	// RegenerateRecoveryCodes thay toàn bộ recovery code; cần mã TOTP hoặc recovery code
+	RegenerateRecoveryCodes(ctx context.Context, userID uint, code string, client Client) ([]string, error)
+	// Refresh đổi refresh token lấy cặp token mới; refresh token cũ hết hiệu lực
+	Refresh(ctx context.Context, refreshToken string) (*TokenPair, error)
+	// Logout thu hồi access token hiện tại và phiên (family) của nó
+	Logout(ctx context.Context, claims jwt.MapClaims) error
+	// LogoutAll thu hồi mọi phiên và mọi access token của user
+	LogoutAll(ctx context.Context, claims jwt.MapClaims) error
+	FindByEmail(ctx context.Context, email string) (*domain.User, error)
+	// ChangeRole đổi role của user; access token đang dùng bị thu hồi để role mới có hiệu lực ngay
+	ChangeRole(ctx context.Context, userID uint, role domain.Role) (*domain.User, error)
+	// VerifyEmail xác nhận email bằng token trong mail đăng ký
+	VerifyEmail(ctx context.Context, token string) error
+	// ResendVerification gửi lại mail xác nhận; không báo lỗi nếu email không tồn tại hoặc đã xác nhận
+	ResendVerification(ctx context.Context, email string) error
+	// RequestPasswordReset gửi mail đặt lại password; không báo lỗi nếu email không tồn tại
+	RequestPasswordReset(ctx context.Context, email string) error
+	// ResetPassword đặt password mới bằng token trong mail và đăng xuất mọi phiên
+	ResetPassword(ctx context.Context, token, password string) error
+	// UnlockAccount mở khoá email bị khoá vì đăng nhập sai, bằng token trong mail báo khoá
+	UnlockAccount(ctx context.Context, token string, client Client) error
+}
+
+// LoginResult là kết quả của Login: cặp token, hoặc MFA token khi user đã bật 2FA
+type LoginResult struct {
+	*TokenPair
+	MFARequired  bool   `json:"mfa_required,omitempty"`
+	MFAToken     string `json:"mfa_token,omitempty"`
+	MFAExpiresIn int64  `json:"mfa_expires_in,omitempty"` // số giây trước khi MFA token hết hạn
+}
+
+// MFAEnrollment là secret TOTP mới và URI otpauth:// để authenticator quét (QR code)
+type MFAEnrollment struct {
+	Secret string `json:"secret"`
+	URI    string `json:"otpauth_uri"`
+}
+
+// recoveryCodeCount là số recovery code được tạo mỗi lần
+const recoveryCodeCount = 10
+
+// LoginThrottle đếm số lần đăng nhập sai theo email và theo IP
+type LoginThrottle interface {
+	// Wait trả về thời gian phải chờ trước lần đăng nhập tiếp theo; 0 là được thử ngay
+	Wait(ctx context.Context, email, ip string) (time.Duration, error)
+	// Failed ghi nhận một lần sai; trả về true nếu lần sai này làm email bị khoá
+	Failed(ctx context.Context, email, ip string) (bool, error)
+	// Succeeded xoá các lần sai của email
+	Succeeded(ctx context.Context, email string) error
+	// Unlock mở khoá email
+	Unlock(ctx context.Context, email string) error
+}
+
+// Client là thông tin người gọi, được ghi vào audit log
+type Client struct {
+	IP        string
+	UserAgent string
+}
+
+// ThrottledError là lỗi của Login khi email hoặc IP đang phải chờ hoặc bị khoá
+type ThrottledError struct {
+	RetryAfter time.Duration
+}
+
+func (e *ThrottledError) Error() string {
+	return domain.ErrTooManyLoginAttempts.Error()
+}
+
+func (e *ThrottledError) Unwrap() error {
+	return domain.ErrTooManyLoginAttempts
+}
+
+// TokenPair là kết quả của Login và Refresh
+type TokenPair struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"` // số giây trước khi access token hết hạn
+}
+
+// Config là thời hạn của các loại token và địa chỉ frontend dùng trong link gửi qua mail
+type Config struct {
+	TokenTTL        time.Duration // access token
+	RefreshTTL      time.Duration // refresh token
+	VerificationTTL time.Duration // token xác nhận email
+	ResetTTL        time.Duration // token đặt lại password
+	UnlockTTL       time.Duration // token mở khoá tài khoản
+	MFAChallengeTTL time.Duration // MFA token giữa bước password và bước mã 2FA
+	MFAIssuer       string        // tên hiển thị trong authenticator
+	// MFARequiredRoles chỉ có role của mình trong access token khi đăng nhập bằng 2FA;
+	// nếu không, access token chỉ mang role customer
+	MFARequiredRoles []domain.Role
+	BaseURL          string // link trong mail là BaseURL/verify-email, /reset-password và /unlock-account
 }
 
 type authService struct {
-	userRepo user.UserRepository
-	jwtKey   []byte
+	userRepo      user.UserRepository
+	refreshTokens refreshtoken.RefreshTokenRepository
+	userTokens    usertoken.UserTokenRepository
+	recoveryCodes recoverycode.RecoveryCodeRepository
+	auditLogs     audit.AuditRepository
+	txManager     repository.TxManager
+	tokens        token.Issuer
+	revocations   token.RevocationStore
+	throttle      LoginThrottle
+	mailer        mailer.Mailer
+	tokenTTL      time.Duration
+	refreshTTL    time.Duration
+	cfg           Config
 }
 
-func NewAuthService(userRepo user.UserRepository) AuthService {
+// NewAuthService ký access token bằng tokens, giới hạn đăng nhập sai bằng
+// throttle và gửi mail xác nhận, đặt lại password, mở khoá qua mailer
+func NewAuthService(userRepo user.UserRepository, refreshTokens refreshtoken.RefreshTokenRepository, userTokens usertoken.UserTokenRepository, recoveryCodes recoverycode.RecoveryCodeRepository, auditLogs audit.AuditRepository, txManager repository.TxManager, tokens token.Issuer, revocations token.RevocationStore, throttle LoginThrottle, mailer mailer.Mailer, cfg Config) AuthService {
 	return &authService{
-		userRepo: userRepo,
-		jwtKey:   []byte("your-secret-key"), // Thay bằng key bảo mật trong production
+		userRepo:      userRepo,
+		refreshTokens: refreshTokens,
+		userTokens:    userTokens,
+		recoveryCodes: recoveryCodes,
+		auditLogs:     auditLogs,
+		txManager:     txManager,
+		tokens:        tokens,
+		revocations:   revocations,
+		throttle:      throttle,
+		mailer:        mailer,
+		tokenTTL:      cfg.TokenTTL,
+		refreshTTL:    cfg.RefreshTTL,
+		cfg:           cfg,
 	}
 }
 
+// HashPassword hash password bằng bcrypt trước khi lưu
+func HashPassword(password string) (string, error) {
+	hashed, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	return string(hashed), err
+}
+
 func (s *authService) Register(ctx context.Context, email, password string) (*domain.User, error) {
 	log.Println("Registering user with email:", email)
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	hashedPassword, err := HashPassword(password)
 	if err != nil {
 		return nil, err
 	}
 
 	user := &domain.User{
 		Email:    email,
-		Password: string(hashedPassword),
+		Password: hashedPassword,
+		Role:     domain.RoleCustomer,
 	}
 
-	if err := s.userRepo.Create(user); err != nil {
+	var verification string
+	err = s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.userRepo.Create(ctx, user); err != nil {
+			return err
+		}
+		verification, err = s.newUserToken(ctx, user.ID, domain.TokenEmailVerification, s.cfg.VerificationTTL, time.Now())
+		return err
+	})
+	if err != nil {
 		log.Println("Error creating user:", err)
 		return nil, err
 	}
 
+	// User đã được tạo: gửi mail lỗi thì user dùng resend-verification
+	if err := s.mailer.Send(ctx, verificationMail(user.Email, s.link("/verify-email", verification), s.cfg.VerificationTTL)); err != nil {
+		log.Printf("Failed to send verification mail to %s: %v", user.Email, err)
+	}
+
 	// Không trả về password
 	user.Password = ""
 	return user, nil
 }
 
-func (s *authService) Login(ctx context.Context, email, password string) (string, error) {
-	user, err := s.userRepo.FindByEmail(email)
+// dummyHash được so sánh khi email không tồn tại, để thời gian trả lời giống
+// như khi sai password
+var dummyHash = sync.OnceValue(func() []byte {
+	hash, _ := bcrypt.GenerateFromPassword([]byte("not a real password"), bcrypt.DefaultCost)
+	return hash
+})
+
+func (s *authService) Login(ctx context.Context, email, password string, client Client) (*LoginResult, error) {
+	wait, err := s.throttle.Wait(ctx, email, client.IP)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	if user == nil {
-		return "", errors.New("user not found")
+	user, err := s.userRepo.FindByEmail(ctx, email)
+	if err != nil {
+		return nil, err
+	}
+	// Đang phải chờ thì không kiểm tra password, kể cả khi nó đúng
+	if wait > 0 {
+		s.audit(ctx, domain.AuditLoginThrottled, user, email, client)
+		return nil, &ThrottledError{RetryAfter: wait}
 	}
 
+	if user == nil {
+		bcrypt.CompareHashAndPassword(dummyHash(), []byte(password))
+		return nil, s.loginFailed(ctx, nil, email, client)
+	}
 	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
-		return "", errors.New("invalid password")
+		return nil, s.loginFailed(ctx, user, email, client)
+	}
+
+	// Các lần sai chỉ được xoá khi đăng nhập xong, kể cả bước 2FA: biết password
+	// không cho phép đoán mã 2FA mãi
+	if user.MFAEnabled() {
+		raw, err := s.newUserToken(ctx, user.ID, domain.TokenMFAChallenge, s.cfg.MFAChallengeTTL, time.Now())
+		if err != nil {
+			return nil, err
+		}
+		return &LoginResult{MFARequired: true, MFAToken: raw, MFAExpiresIn: int64(s.cfg.MFAChallengeTTL / time.Second)}, nil
 	}
-	exp, err := strconv.Atoi(os.Getenv("JWT_EXPIRATION_TIME"))
+	pair, err := s.loginSucceeded(ctx, user, false, client)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	// Tạo Access Token (hết hạn sau 15 phút)
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"email": user.Email,
-		"exp":   time.Now().Add(time.Minute * time.Duration(exp)).Unix(),
+	return &LoginResult{TokenPair: pair}, nil
+}
+
+// loginSucceeded mở một phiên mới (family của refresh token)
+func (s *authService) loginSucceeded(ctx context.Context, user *domain.User, mfa bool, client Client) (*TokenPair, error) {
+	if err := s.throttle.Succeeded(ctx, user.Email); err != nil {
+		log.Printf("Failed to reset login failures of %s: %v", user.Email, err)
+	}
+	s.audit(ctx, domain.AuditLoginSucceeded, user, user.Email, client)
+	pair, _, err := s.issue(ctx, user, uuid.NewString(), mfa, time.Now())
+	return pair, err
+}
+
+func (s *authService) VerifyMFA(ctx context.Context, mfaToken, code string, client Client) (*TokenPair, error) {
+	now := time.Now()
+	var user *domain.User
+	err := s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		t, err := s.userTokens.FindByHashForUpdate(ctx, hashToken(mfaToken), domain.TokenMFAChallenge)
+		if errors.Is(err, domain.ErrNotFound) {
+			return domain.ErrInvalidUserToken
+		}
+		if err != nil {
+			return err
+		}
+		if t.UsedAt != nil || !now.Before(t.ExpiresAt) {
+			return domain.ErrInvalidUserToken
+		}
+		if user, err = s.userRepo.FindById(ctx, t.UserID); err != nil {
+			return err
+		}
+		if err := s.checkMFACodeThrottled(ctx, user, code, now, client); err != nil {
+			return err
+		}
+		// Mã sai thì MFA token vẫn dùng được cho tới khi hết hạn hoặc email bị khoá
+		return s.userTokens.MarkUsed(ctx, t.ID, now)
 	})
+	var throttled *ThrottledError
+	switch {
+	case errors.As(err, &throttled):
+		s.audit(ctx, domain.AuditLoginThrottled, user, user.Email, client)
+		return nil, err
+	case errors.Is(err, domain.ErrInvalidMFACode):
+		return nil, s.mfaFailed(ctx, user, client)
+	case err != nil:
+		return nil, err
+	}
+	return s.loginSucceeded(ctx, user, true, client)
+}
 
-	tokenString, err := token.SignedString(s.jwtKey)
+func (s *authService) EnrollMFA(ctx context.Context, userID uint) (*MFAEnrollment, error) {
+	user, err := s.userRepo.FindById(ctx, userID)
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+	if user.MFAEnabled() {
+		return nil, domain.ErrMFAAlreadyEnabled
+	}
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.userRepo.SetTOTPSecret(ctx, userID, secret); err != nil {
+		return nil, err
+	}
+	return &MFAEnrollment{Secret: secret, URI: totp.URI(s.cfg.MFAIssuer, user.Email, secret)}, nil
+}
+
+func (s *authService) ConfirmMFA(ctx context.Context, userID uint, code string) ([]string, error) {
+	var codes []string
+	var user *domain.User
+	err := s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		var err error
+		if user, err = s.userRepo.FindById(ctx, userID); err != nil {
+			return err
+		}
+		if user.MFAEnabled() {
+			return domain.ErrMFAAlreadyEnabled
+		}
+		if user.TOTPSecret == "" {
+			return domain.ErrMFANotEnabled
+		}
+		step, ok := totp.Validate(user.TOTPSecret, code, time.Now())
+		if !ok {
+			return domain.ErrInvalidMFACode
+		}
+		if err := s.userRepo.EnableTOTP(ctx, userID, time.Now(), step); err != nil {
+			return err
+		}
+		codes, err = s.replaceRecoveryCodes(ctx, userID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.audit(ctx, domain.AuditMFAEnabled, user, user.Email, Client{})
+	return codes, nil
+}
+
+func (s *authService) DisableMFA(ctx context.Context, userID uint, code string, client Client) error {
+	now := time.Now()
+	var user *domain.User
+	err := s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		var err error
+		if user, err = s.mfaUser(ctx, userID); err != nil {
+			return err
+		}
+		if err := s.checkMFACodeThrottled(ctx, user, code, now, client); err != nil {
+			return err
+		}
+		if err := s.userRepo.DisableTOTP(ctx, userID); err != nil {
+			return err
+		}
+		if err := s.recoveryCodes.DeleteUser(ctx, userID); err != nil {
+			return err
+		}
+		// Các phiên đã qua 2FA không còn được tin như trước
+		return s.refreshTokens.RevokeUser(ctx, userID, now)
+	})
+	if errors.Is(err, domain.ErrInvalidMFACode) {
+		return s.mfaFailed(ctx, user, client)
+	}
+	if err != nil {
+		return err
+	}
+	s.audit(ctx, domain.AuditMFADisabled, user, user.Email, Client{})
+	return s.revocations.RevokeSubject(ctx, strconv.FormatUint(uint64(userID), 10), now, s.tokenTTL)
+}
+
+func (s *authService) RegenerateRecoveryCodes(ctx context.Context, userID uint, code string, client Client) ([]string, error) {
+	var codes []string
+	var user *domain.User
+	err := s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		var err error
+		if user, err = s.mfaUser(ctx, userID); err != nil {
+			return err
+		}
+		if err := s.checkMFACodeThrottled(ctx, user, code, time.Now(), client); err != nil {
+			return err
+		}
+		codes, err = s.replaceRecoveryCodes(ctx, userID)
+		return err
+	})
+	if errors.Is(err, domain.ErrInvalidMFACode) {
+		return nil, s.mfaFailed(ctx, user, client)
+	}
+	return codes, err
+}
+
+// checkMFACodeThrottled là checkMFACode khi email hoặc IP không bị giới hạn;
+// mã sai được đếm như đăng nhập sai, để không đoán được mã 2FA
+func (s *authService) checkMFACodeThrottled(ctx context.Context, user *domain.User, code string, now time.Time, client Client) error {
+	wait, err := s.throttle.Wait(ctx, user.Email, client.IP)
+	if err != nil {
+		return err
+	}
+	if wait > 0 {
+		return &ThrottledError{RetryAfter: wait}
+	}
+	return s.checkMFACode(ctx, user, code, now)
+}
+
+// mfaFailed ghi nhận một mã 2FA sai như một lần đăng nhập sai
+func (s *authService) mfaFailed(ctx context.Context, user *domain.User, client Client) error {
+	s.audit(ctx, domain.AuditMFAFailed, user, user.Email, client)
+	locked, err := s.throttle.Failed(ctx, user.Email, client.IP)
+	if err != nil {
+		return err
+	}
+	if locked {
+		s.audit(ctx, domain.AuditAccountLocked, user, user.Email, client)
+		if err := s.sendUnlockMail(ctx, user); err != nil {
+			log.Printf("Failed to send unlock mail to %s: %v", user.Email, err)
+		}
+	}
+	return domain.ErrInvalidMFACode
+}
+
+// mfaUser trả về user đã bật 2FA
+func (s *authService) mfaUser(ctx context.Context, userID uint) (*domain.User, error) {
+	user, err := s.userRepo.FindById(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !user.MFAEnabled() {
+		return nil, domain.ErrMFANotEnabled
+	}
+	return user, nil
+}
+
+// checkMFACode chấp nhận mã TOTP 6 số chưa dùng, hoặc một recovery code chưa dùng
+func (s *authService) checkMFACode(ctx context.Context, user *domain.User, code string, now time.Time) error {
+	code = strings.TrimSpace(code)
+	if len(code) == totp.Digits {
+		step, ok := totp.Validate(user.TOTPSecret, code, now)
+		if !ok {
+			return domain.ErrInvalidMFACode
+		}
+		fresh, err := s.userRepo.UseTOTPStep(ctx, user.ID, step)
+		if err != nil {
+			return err
+		}
+		if !fresh {
+			return domain.ErrInvalidMFACode
+		}
+		return nil
+	}
+	used, err := s.recoveryCodes.Use(ctx, user.ID, hashToken(normalizeRecoveryCode(code)), now)
+	if err != nil {
+		return err
+	}
+	if !used {
+		return domain.ErrInvalidMFACode
+	}
+	return nil
+}
+
+// replaceRecoveryCodes tạo recovery code mới; chỉ hash được lưu, mã gốc chỉ trả về một lần này
+func (s *authService) replaceRecoveryCodes(ctx context.Context, userID uint) ([]string, error) {
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		raw := make([]byte, 10)
+		if _, err := rand.Read(raw); err != nil {
+			return nil, err
+		}
+		// 10 ký tự base32 chữ thường (50 bit), dạng xxxxx-xxxxx cho dễ chép
+		c := strings.ToLower(base32.StdEncoding.EncodeToString(raw)[:10])
+		codes[i] = c[:5] + "-" + c[5:]
+		hashes[i] = hashToken(normalizeRecoveryCode(codes[i]))
+	}
+	if err := s.recoveryCodes.Replace(ctx, userID, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// normalizeRecoveryCode bỏ dấu gạch, khoảng trắng và chữ hoa người dùng gõ thêm
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+}
+
+// loginFailed ghi nhận lần sai. Email không tồn tại cũng bị đếm và bị khoá như
+// email thật, chỉ là không có mail mở khoá.
+func (s *authService) loginFailed(ctx context.Context, user *domain.User, email string, client Client) error {
+	s.audit(ctx, domain.AuditLoginFailed, user, email, client)
+	locked, err := s.throttle.Failed(ctx, email, client.IP)
+	if err != nil {
+		return err
 	}
+	if locked {
+		s.audit(ctx, domain.AuditAccountLocked, user, email, client)
+		if user != nil {
+			if err := s.sendUnlockMail(ctx, user); err != nil {
+				log.Printf("Failed to send unlock mail to %s: %v", user.Email, err)
+			}
+		}
+	}
+	return domain.ErrInvalidCredentials
+}
 
-	return tokenString, nil
+func (s *authService) sendUnlockMail(ctx context.Context, user *domain.User) error {
+	raw, err := s.replaceUserToken(ctx, user.ID, domain.TokenAccountUnlock, s.cfg.UnlockTTL)
+	if err != nil {
+		return err
+	}
+	return s.mailer.Send(ctx, unlockMail(user.Email, s.link("/unlock-account", raw), s.cfg.UnlockTTL))
 }
 
-func (s *authService) FindByEmail(email string) (*domain.User, error) {
+func (s *authService) UnlockAccount(ctx context.Context, raw string, client Client) error {
+	var userID uint
+	err := s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		t, err := s.useToken(ctx, raw, domain.TokenAccountUnlock, time.Now())
+		if err != nil {
+			return err
+		}
+		userID = t.UserID
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	user, err := s.userRepo.FindById(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if err := s.throttle.Unlock(ctx, user.Email); err != nil {
+		return err
+	}
+	s.audit(ctx, domain.AuditAccountUnlocked, user, user.Email, client)
+	return nil
+}
+
+// audit ghi một dòng audit log; lỗi chỉ được log để không chặn việc đăng nhập
+func (s *authService) audit(ctx context.Context, action domain.AuditAction, user *domain.User, email string, client Client) {
+	entry := &domain.AuditLog{
+		Action:    action,
+		Email:     truncate(email, 255),
+		IP:        truncate(client.IP, 45),
+		UserAgent: truncate(client.UserAgent, 255),
+	}
+	if user != nil {
+		entry.UserID = &user.ID
+	}
+	if err := s.auditLogs.Create(ctx, entry); err != nil {
+		log.Printf("Failed to write audit log %s for %s: %v", action, email, err)
+	}
+}
+
+// truncate cắt s còn tối đa n byte, không cắt giữa một ký tự UTF-8
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+// Refresh xoay vòng refresh token. Token đã bị xoay vòng mà được dùng lại nghĩa
+// là nó đã bị lộ: cả family bị thu hồi, access token của user cũng vậy.
+func (s *authService) Refresh(ctx context.Context, refreshToken string) (*TokenPair, error) {
+	now := time.Now()
+	var pair *TokenPair
+	var reusedBy uint
+	err := s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		current, err := s.refreshTokens.FindByHashForUpdate(ctx, hashToken(refreshToken))
+		if errors.Is(err, domain.ErrNotFound) {
+			return domain.ErrInvalidRefreshToken
+		}
+		if err != nil {
+			return err
+		}
+		if current.RevokedAt != nil {
+			if current.ReplacedByID == nil {
+				return domain.ErrInvalidRefreshToken
+			}
+			// Commit việc thu hồi rồi mới báo lỗi
+			reusedBy = current.UserID
+			return s.refreshTokens.RevokeFamily(ctx, current.FamilyID, now)
+		}
+		if !now.Before(current.ExpiresAt) {
+			return domain.ErrInvalidRefreshToken
+		}
+
+		user, err := s.userRepo.FindById(ctx, current.UserID)
+		if err != nil {
+			return err
+		}
+		var next *domain.RefreshToken
+		pair, next, err = s.issue(ctx, user, current.FamilyID, current.MFA, now)
+		if err != nil {
+			return err
+		}
+		return s.refreshTokens.Rotate(ctx, current.ID, next.ID, now)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if reusedBy != 0 {
+		log.Printf("Refresh token reuse detected for user %d, session revoked", reusedBy)
+		if err := s.revocations.RevokeSubject(ctx, strconv.FormatUint(uint64(reusedBy), 10), now, s.tokenTTL); err != nil {
+			log.Printf("Failed to revoke access tokens of user %d: %v", reusedBy, err)
+		}
+		return nil, domain.ErrRefreshTokenReused
+	}
+	return pair, nil
+}
+
+func (s *authService) Logout(ctx context.Context, claims jwt.MapClaims) error {
+	if jti, _ := claims["jti"].(string); jti != "" {
+		exp, err := claims.GetExpirationTime()
+		if err != nil || exp == nil {
+			return errors.New("token has no expiration time")
+		}
+		if err := s.revocations.RevokeToken(ctx, jti, exp.Time); err != nil {
+			return err
+		}
+	}
+	if sid, _ := claims["sid"].(string); sid != "" {
+		return s.refreshTokens.RevokeFamily(ctx, sid, time.Now())
+	}
+	return nil
+}
+
+func (s *authService) LogoutAll(ctx context.Context, claims jwt.MapClaims) error {
+	userID, err := s.userIDFromClaims(ctx, claims)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	if err := s.refreshTokens.RevokeUser(ctx, userID, now); err != nil {
+		return err
+	}
+	return s.revocations.RevokeSubject(ctx, strconv.FormatUint(uint64(userID), 10), now, s.tokenTTL)
+}
+
+func (s *authService) FindByEmail(ctx context.Context, email string) (*domain.User, error) {
 	log.Println("Finding user by email:", email)
-	return s.userRepo.FindByEmail(email)
-}
\ No newline at end of file
+	return s.userRepo.FindByEmail(ctx, email)
+}
+
+func (s *authService) ChangeRole(ctx context.Context, userID uint, role domain.Role) (*domain.User, error) {
+	if !role.Valid() {
+		return nil, domain.ErrBadParamInput
+	}
+	if err := s.userRepo.UpdateRole(ctx, userID, role); err != nil {
+		return nil, err
+	}
+	// Refresh token vẫn dùng được: token mới đọc role từ DB
+	if err := s.revocations.RevokeSubject(ctx, strconv.FormatUint(uint64(userID), 10), time.Now(), s.tokenTTL); err != nil {
+		return nil, err
+	}
+	user, err := s.userRepo.FindById(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	user.Password = ""
+	return user, nil
+}
+
+func (s *authService) VerifyEmail(ctx context.Context, raw string) error {
+	now := time.Now()
+	return s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		t, err := s.useToken(ctx, raw, domain.TokenEmailVerification, now)
+		if err != nil {
+			return err
+		}
+		return s.userRepo.MarkEmailVerified(ctx, t.UserID, now)
+	})
+}
+
+func (s *authService) ResendVerification(ctx context.Context, email string) error {
+	user, err := s.userRepo.FindByEmail(ctx, email)
+	if err != nil {
+		return err
+	}
+	// Không tiết lộ email nào đã đăng ký
+	if user == nil || user.EmailVerifiedAt != nil {
+		return nil
+	}
+	raw, err := s.replaceUserToken(ctx, user.ID, domain.TokenEmailVerification, s.cfg.VerificationTTL)
+	if err != nil {
+		return err
+	}
+	return s.mailer.Send(ctx, verificationMail(user.Email, s.link("/verify-email", raw), s.cfg.VerificationTTL))
+}
+
+func (s *authService) RequestPasswordReset(ctx context.Context, email string) error {
+	user, err := s.userRepo.FindByEmail(ctx, email)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		log.Println("Password reset requested for unknown email:", email)
+		return nil
+	}
+	raw, err := s.replaceUserToken(ctx, user.ID, domain.TokenPasswordReset, s.cfg.ResetTTL)
+	if err != nil {
+		return err
+	}
+	return s.mailer.Send(ctx, resetMail(user.Email, s.link("/reset-password", raw), s.cfg.ResetTTL))
+}
+
+func (s *authService) ResetPassword(ctx context.Context, raw, password string) error {
+	hashedPassword, err := HashPassword(password)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	var userID uint
+	err = s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		t, err := s.useToken(ctx, raw, domain.TokenPasswordReset, now)
+		if err != nil {
+			return err
+		}
+		userID = t.UserID
+		if err := s.userRepo.UpdatePassword(ctx, userID, hashedPassword); err != nil {
+			return err
+		}
+		// Các link đặt lại khác đã gửi cũng hết hiệu lực
+		if err := s.userTokens.InvalidateUser(ctx, userID, domain.TokenPasswordReset, now); err != nil {
+			return err
+		}
+		// Nhận được mail nghĩa là sở hữu email đó
+		if err := s.userRepo.MarkEmailVerified(ctx, userID, now); err != nil {
+			return err
+		}
+		return s.refreshTokens.RevokeUser(ctx, userID, now)
+	})
+	if err != nil {
+		return err
+	}
+	// Password đã đổi: access token đang dùng cũng không còn hiệu lực
+	if err := s.revocations.RevokeSubject(ctx, strconv.FormatUint(uint64(userID), 10), now, s.tokenTTL); err != nil {
+		return err
+	}
+	// Đặt lại password cũng mở khoá đăng nhập
+	user, err := s.userRepo.FindById(ctx, userID)
+	if err != nil {
+		return err
+	}
+	return s.throttle.Unlock(ctx, user.Email)
+}
+
+// useToken tìm token còn hiệu lực theo purpose và đánh dấu đã dùng
+func (s *authService) useToken(ctx context.Context, raw string, purpose domain.TokenPurpose, now time.Time) (*domain.UserToken, error) {
+	t, err := s.userTokens.FindByHashForUpdate(ctx, hashToken(raw), purpose)
+	if errors.Is(err, domain.ErrNotFound) {
+		return nil, domain.ErrInvalidUserToken
+	}
+	if err != nil {
+		return nil, err
+	}
+	if t.UsedAt != nil || !now.Before(t.ExpiresAt) {
+		return nil, domain.ErrInvalidUserToken
+	}
+	if err := s.userTokens.MarkUsed(ctx, t.ID, now); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// replaceUserToken tạo token mới; các token cũ cùng purpose của user hết hiệu lực
+func (s *authService) replaceUserToken(ctx context.Context, userID uint, purpose domain.TokenPurpose, ttl time.Duration) (string, error) {
+	now := time.Now()
+	var raw string
+	err := s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.userTokens.InvalidateUser(ctx, userID, purpose, now); err != nil {
+			return err
+		}
+		var err error
+		raw, err = s.newUserToken(ctx, userID, purpose, ttl, now)
+		return err
+	})
+	return raw, err
+}
+
+// newUserToken lưu hash của một token ngẫu nhiên và trả về token gốc để gửi qua mail
+func (s *authService) newUserToken(ctx context.Context, userID uint, purpose domain.TokenPurpose, ttl time.Duration, now time.Time) (string, error) {
+	raw, err := random.Token()
+	if err != nil {
+		return "", err
+	}
+	err = s.userTokens.Create(ctx, &domain.UserToken{
+		UserID:    userID,
+		Purpose:   purpose,
+		TokenHash: hashToken(raw),
+		ExpiresAt: now.Add(ttl),
+	})
+	return raw, err
+}
+
+func (s *authService) link(path, raw string) string {
+	return s.cfg.BaseURL + path + "?token=" + url.QueryEscape(raw)
+}
+
+func verificationMail(to, link string, ttl time.Duration) mailer.Message {
+	return mailer.Message{
+		To:      to,
+		Subject: "Confirm your email address",
+		Body: fmt.Sprintf("Welcome to Ticket App!\n\nPlease confirm your email address to start booking tickets:\n\n%s\n\n"+
+			"This link expires in %s.\n", link, ttl),
+	}
+}
+
+func resetMail(to, link string, ttl time.Duration) mailer.Message {
+	return mailer.Message{
+		To:      to,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Someone asked to reset the password of your Ticket App account.\n\n"+
+			"Open this link to choose a new password:\n\n%s\n\n"+
+			"This link expires in %s and can only be used once. If you did not ask for it, ignore this email.\n", link, ttl),
+	}
+}
+
+func unlockMail(to, link string, ttl time.Duration) mailer.Message {
+	return mailer.Message{
+		To:      to,
+		Subject: "Your account has been locked",
+		Body: fmt.Sprintf("There were too many failed attempts to log in to your Ticket App account, so logging in is blocked for a while.\n\n"+
+			"If it was you, open this link to unlock your account right away:\n\n%s\n\n"+
+			"This link expires in %s. If it was not you, consider resetting your password.\n", link, ttl),
+	}
+}
+
+// issue tạo access token và refresh token mới thuộc family. mfa cho biết phiên
+// đã qua bước 2FA; nếu chưa, role cần 2FA chỉ được ghi là customer.
+func (s *authService) issue(ctx context.Context, user *domain.User, family string, mfa bool, now time.Time) (*TokenPair, *domain.RefreshToken, error) {
+	refresh, err := random.Token()
+	if err != nil {
+		return nil, nil, err
+	}
+	record := &domain.RefreshToken{
+		UserID:    user.ID,
+		FamilyID:  family,
+		TokenHash: hashToken(refresh),
+		ExpiresAt: now.Add(s.refreshTTL),
+		MFA:       mfa,
+	}
+	if err := s.refreshTokens.Create(ctx, record); err != nil {
+		return nil, nil, err
+	}
+
+	// Tạo Access Token (hết hạn sau tokenTTL)
+	claims := jwt.MapClaims{
+		"sub":   strconv.FormatUint(uint64(user.ID), 10),
+		"email": user.Email,
+		"role":  string(user.Role),
+		"jti":   uuid.NewString(),
+		"sid":   family,
+		"iat":   now.Unix(),
+		"exp":   now.Add(s.tokenTTL).Unix(),
+	}
+	if mfa {
+		claims["mfa"] = true
+	} else if s.mfaRequired(user.Role) {
+		claims["role"] = string(domain.RoleCustomer)
+		claims["mfa_required"] = true
+	}
+	access, err := s.tokens.Sign(claims)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &TokenPair{
+		AccessToken:  access,
+		RefreshToken: refresh,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(s.tokenTTL / time.Second),
+	}, record, nil
+}
+
+func (s *authService) mfaRequired(role domain.Role) bool {
+	for _, r := range s.cfg.MFARequiredRoles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// userIDFromClaims đọc user ID từ "sub"; token cũ chưa có "sub" thì tìm theo email
+func (s *authService) userIDFromClaims(ctx context.Context, claims jwt.MapClaims) (uint, error) {
+	if sub, _ := claims["sub"].(string); sub != "" {
+		id, err := strconv.ParseUint(sub, 10, 64)
+		if err != nil {
+			return 0, errors.New("invalid subject in token")
+		}
+		return uint(id), nil
+	}
+	email, _ := claims["email"].(string)
+	user, err := s.userRepo.FindByEmail(ctx, email)
+	if err != nil {
+		return 0, err
+	}
+	if user == nil {
+		return 0, errors.New("user not found")
+	}
+	return user.ID, nil
+}
+
+// hashToken là SHA-256 của token, giá trị duy nhất được lưu trong DB
+func hashToken(t string) string {
+	sum := sha256.Sum256([]byte(t))
+	return hex.EncodeToString(sum[:])
+}
@@ -6,47 +6,180 @@ import (
 	"log"
 	"ticket_app/domain"
 	"ticket_app/internal/queue"
+	"ticket_app/internal/random"
+	"ticket_app/internal/repository"
 	"ticket_app/internal/repository/booking"
 	eventRepo "ticket_app/internal/repository/event"
 	userRepo "ticket_app/internal/repository/user"
 	payment "ticket_app/payment"
 	"time"
+
+	"gorm.io/gorm"
 )
 
 type BookingService interface {
-	CreateBooking(ctx context.Context, userID uint, eventID uint, quantity int) (*domain.Booking, error)
+	// CreateBooking đặt vé theo đúng một trong quantity, items (mỗi hạng vé một
+	// dòng), seat IDs hoặc hold token của req
+	CreateBooking(ctx context.Context, userID uint, req domain.BookingRequest) (*domain.Booking, error)
+	// CreateHold giữ vé theo quantity, items hoặc seat IDs của req trong vài phút
+	CreateHold(ctx context.Context, userID uint, req domain.BookingRequest) (*domain.Hold, error)
+	// GetHold trả về domain.ErrHoldNotFound nếu token không có hoặc của user khác
+	GetHold(ctx context.Context, userID uint, token string) (*domain.Hold, error)
+	// ReleaseHold trả về domain.ErrHoldNotActive nếu hold đã được dùng, đã hết hạn hoặc đã bỏ
+	ReleaseHold(ctx context.Context, userID uint, token string) error
 	// GetAllBookings() ([]domain.Booking, error)
-	GetBookingById(id uint) (*domain.Booking, error)
-	UpdateBooking(booking *domain.Booking) error
-	CountBookings() (int64, error)
-	GetAllBookingsWithPagination(offset int, limit int) ([]domain.Booking, error)
-	CancelBooking(id uint) (*domain.Booking, error)
-	ConfirmBooking(id uint) (*domain.Booking, error)
+	GetBookingById(ctx context.Context, id uint) (*domain.Booking, error)
+	UpdateBooking(ctx context.Context, booking *domain.Booking) error
+	CountBookings(ctx context.Context) (int64, error)
+	GetAllBookingsWithPagination(ctx context.Context, offset int, limit int) ([]domain.Booking, error)
+	// ListUserBookings trả về một trang booking của userID và tổng số booking khớp filter
+	ListUserBookings(ctx context.Context, userID uint, filter domain.BookingFilter, offset int, limit int) ([]domain.Booking, int64, error)
+	// GetUserBooking trả về booking id của userID, domain.ErrNotFound nếu không có hoặc của user khác
+	GetUserBooking(ctx context.Context, userID uint, id uint) (*domain.Booking, error)
+	CancelBooking(ctx context.Context, id uint) (*domain.Booking, error)
+	ConfirmBooking(ctx context.Context, id uint) (*domain.Booking, error)
 }
 
 type bookingService struct {
 	bookingRepo booking.BookingRepository
+	holdRepo booking.HoldRepository
 	userRepo userRepo.UserRepository
 	eventRepo eventRepo.EventRepository
 	paymentService payment.PaymentService
-	queueService *queue.QueueService
+	queueService queue.PaymentQueue
+	holdQueue queue.HoldQueue
+	waitlist queue.WaitlistQueue
+	holdTTL time.Duration // thời gian giữ vé của event không đặt hold_minutes
+	txManager repository.TxManager
 }
 
-func NewBookingService(bookingRepo booking.BookingRepository, userRepo userRepo.UserRepository, eventRepo eventRepo.EventRepository) BookingService {
-	return &bookingService{bookingRepo: bookingRepo, userRepo: userRepo, eventRepo: eventRepo}
+func NewBookingService(txManager repository.TxManager, bookingRepo booking.BookingRepository, holdRepo booking.HoldRepository, userRepo userRepo.UserRepository, eventRepo eventRepo.EventRepository, paymentService payment.PaymentService, queueService queue.PaymentQueue, holdQueue queue.HoldQueue, waitlist queue.WaitlistQueue, holdTTL time.Duration) BookingService {
+	return &bookingService{
+		txManager:      txManager,
+		bookingRepo:    bookingRepo,
+		holdRepo:       holdRepo,
+		userRepo:       userRepo,
+		eventRepo:      eventRepo,
+		paymentService: paymentService,
+		queueService:   queueService,
+		holdQueue:      holdQueue,
+		waitlist:       waitlist,
+		holdTTL:        holdTTL,
+	}
 }
 
-func (s *bookingService) CreateBooking(ctx context.Context, userID uint, eventID uint, quantity int) (*domain.Booking, error) {
+// CreateBooking tạo booking mới và giữ vé cho user, hoặc biến hold
+// req.HoldToken của user thành booking.
+//
+// The inventory check, the ticket decrement and the booking/payment inserts run
+// in a single transaction holding a row lock on the event (see
+// BookingRepository.CreateWithReservation), so concurrent requests can never
+// sell more tickets than the event or a ticket type has, nor the same seat
+// twice. A hold already took its tickets; converting it only moves them to the
+// new booking (HoldRepository.Convert). The payment job is only enqueued after
+// the transaction has committed.
+func (s *bookingService) CreateBooking(ctx context.Context, userID uint, req domain.BookingRequest) (*domain.Booking, error) {
+	payment := &domain.Payment{
+		Status: domain.PaymentStatusPending,
+	}
+	var booking *domain.Booking
+	if req.HoldToken != "" {
+		if req.Quantity != 0 || len(req.Items) > 0 || len(req.SeatIDs) > 0 {
+			return nil, domain.ErrBadParamInput
+		}
+		booking = &domain.Booking{}
+		if err := s.holdRepo.Convert(ctx, req.HoldToken, userID, booking, payment); err != nil {
+			return nil, err
+		}
+	} else {
+		var err error
+		if booking, _, err = s.prepare(ctx, userID, req); err != nil {
+			return nil, err
+		}
+		if err := s.bookingRepo.CreateWithReservation(ctx, booking, payment); err != nil {
+			return nil, err
+		}
+	}
 
-	user, err := s.userRepo.FindById(userID)
-	if err != nil {
+	// Enqueue payment job
+	paymentJob := queue.PaymentJob{
+		BookingID: booking.ID,
+		Amount:    booking.TotalPrice,
+	}
+	log.Println("Enqueuing payment job")
+	if err := s.queueService.EnqueuePayment(ctx, paymentJob); err != nil {
 		return nil, err
 	}
+	return booking, nil
+}
+
+// prepare kiểm tra req, user và event, rồi dựng booking chưa lưu cho
+// CreateBooking và CreateHold
+func (s *bookingService) prepare(ctx context.Context, userID uint, req domain.BookingRequest) (*domain.Booking, *domain.Event, error) {
+	modes := 0
+	for _, used := range []bool{req.Quantity != 0, len(req.Items) > 0, len(req.SeatIDs) > 0} {
+		if used {
+			modes++
+		}
+	}
+	if modes != 1 || req.Quantity < 0 {
+		return nil, nil, domain.ErrBadParamInput
+	}
+	seen := make(map[uint]bool, len(req.Items))
+	for _, item := range req.Items {
+		if item.TicketTypeID == 0 || item.Quantity < 1 || seen[item.TicketTypeID] {
+			return nil, nil, domain.ErrBadParamInput
+		}
+		seen[item.TicketTypeID] = true
+	}
+	seats := make([]domain.BookingSeat, len(req.SeatIDs))
+	seenSeats := make(map[uint]bool, len(req.SeatIDs))
+	for i, id := range req.SeatIDs {
+		if id == 0 || seenSeats[id] {
+			return nil, nil, domain.ErrBadParamInput
+		}
+		seenSeats[id] = true
+		seats[i] = domain.BookingSeat{SeatID: id}
+	}
+	eventID := req.EventID
+
+	if err := checkBooker(ctx, s.userRepo, userID); err != nil {
+		return nil, nil, err
+	}
+	event, err := openEvent(ctx, s.eventRepo, eventID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	booking := &domain.Booking{
+		UserID:   userID,
+		EventID:  eventID,
+		Quantity: req.Quantity,
+		Status:   domain.BookingStatusPending,
+		Items:    req.Items,
+		Seats:    seats,
+	}
+	return booking, event, nil
+}
+
+// checkBooker kiểm tra userID được đặt vé: chỉ user đã xác nhận email
+func checkBooker(ctx context.Context, users userRepo.UserRepository, userID uint) error {
+	user, err := users.FindById(ctx, userID)
+	if err != nil {
+		return err
+	}
 	if user == nil {
-		return nil, errors.New("user not found")
+		return errors.New("user not found")
+	}
+	if user.EmailVerifiedAt == nil {
+		return domain.ErrEmailNotVerified
 	}
+	return nil
+}
 
-	event, err := s.eventRepo.FindById(eventID)
+// openEvent trả về event eventID nếu còn nhận đặt vé: đang ACTIVE và chưa bắt đầu
+func openEvent(ctx context.Context, events eventRepo.EventRepository, eventID uint) (*domain.Event, error) {
+	event, err := events.FindById(ctx, eventID)
 	if err != nil {
 		return nil, err
 	}
@@ -54,116 +187,181 @@ func (s *bookingService) CreateBooking(ctx context.Context, userID uint, eventID
 		return nil, errors.New("event not found")
 	}
 	if event.Status != domain.EventStatusActive {
-		return nil, errors.New("event is not active")
-	}
-	if event.TotalTickets < quantity {
-		return nil, errors.New("not enough tickets available")
+		return nil, domain.ErrEventNotActive
 	}
 	if event.StartDate.Before(time.Now()) {
 		return nil, errors.New("event has already started")
 	}
+	return event, nil
+}
 
-	booking := &domain.Booking{
-		UserID: userID,
-		EventID: eventID,
-		Quantity: quantity,
-		TotalPrice: float64(quantity) * event.TicketPrice,
-		Status: domain.BookingStatusPending,
+// CreateHold giữ vé của req cho user trong thời gian giữ vé của event, chưa
+// tạo booking hay payment. Vé được trừ trong cùng transaction khoá event như
+// khi đặt vé (HoldRepository.Create); giờ hết hạn được hẹn trên queue, và hold
+// không được dùng kịp thì queue trả vé lại.
+func (s *bookingService) CreateHold(ctx context.Context, userID uint, req domain.BookingRequest) (*domain.Hold, error) {
+	if req.HoldToken != "" {
+		return nil, domain.ErrBadParamInput
 	}
-
-	err = s.bookingRepo.Create(booking)
+	booking, event, err := s.prepare(ctx, userID, req)
 	if err != nil {
 		return nil, err
 	}
-	// Cập nhật số lượng vé còn lại
-	event.TotalTickets -= quantity
-	if err := s.eventRepo.Update(event); err != nil {
+	token, err := random.Token()
+	if err != nil {
 		return nil, err
 	}
-	payment := domain.Payment{
-		BookingID: booking.ID,
-		Amount:    booking.TotalPrice,
-		Status:    domain.PaymentStatusPending,
+	hold := &domain.Hold{
+		Token:     token,
+		UserID:    userID,
+		EventID:   booking.EventID,
+		Quantity:  booking.Quantity,
+		Seats:     booking.Seats,
+		ExpiresAt: time.Now().Add(event.HoldTTL(s.holdTTL)),
 	}
-	if err := s.paymentService.CreatePayment(&payment); err != nil {
-		return nil, err
+	for _, item := range booking.Items {
+		hold.Items = append(hold.Items, domain.HoldItem{TicketTypeID: item.TicketTypeID, Quantity: item.Quantity})
 	}
-
-	// Enqueue payment job
-	paymentJob := queue.PaymentJob{
-		BookingID: booking.ID,
-		Amount:    booking.TotalPrice,
+	if err := s.holdRepo.Create(ctx, hold); err != nil {
+		return nil, err
 	}
-	log.Println("Enqueuing payment job")
-	if err := s.queueService.EnqueuePayment(paymentJob); err != nil {
+	if err := s.holdQueue.ScheduleHoldExpiry(ctx, hold.ID, hold.ExpiresAt); err != nil {
+		// Không hẹn được giờ hết hạn thì trả vé ngay, để vé không bị giữ mãi
+		if _, releaseErr := s.holdRepo.Release(ctx, hold.ID, domain.HoldStatusReleased); releaseErr != nil {
+			log.Printf("Failed to release hold %d: %v", hold.ID, releaseErr)
+		}
 		return nil, err
 	}
-	return booking, nil
+	log.Printf("Hold %d created until %s", hold.ID, hold.ExpiresAt.Format(time.RFC3339))
+	return hold, nil
 }
 
-func (s *bookingService) GetAllBookingsWithPagination(offset int, limit int) ([]domain.Booking, error) {
-	return s.bookingRepo.FindAllWithPagination(offset, limit)
+func (s *bookingService) GetHold(ctx context.Context, userID uint, token string) (*domain.Hold, error) {
+	hold, err := s.holdRepo.FindByToken(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	// Hold của user khác cũng báo không tìm thấy
+	if hold.UserID != userID {
+		return nil, domain.ErrHoldNotFound
+	}
+	return hold, nil
 }
-func (s *bookingService) CountBookings() (int64, error) {
-	return s.bookingRepo.Count()
+
+// ReleaseHold trả vé của hold ngay khi user bỏ giỏ hàng, không chờ hết hạn.
+// Bỏ một hold offer của waitlist là từ chối offer, vé chuyển cho người tiếp theo.
+func (s *bookingService) ReleaseHold(ctx context.Context, userID uint, token string) error {
+	hold, err := s.GetHold(ctx, userID, token)
+	if err != nil {
+		return err
+	}
+	released, err := s.holdRepo.Release(ctx, hold.ID, domain.HoldStatusReleased)
+	if err != nil {
+		return err
+	}
+	if released == nil {
+		return domain.ErrHoldNotActive
+	}
+	notifyWaitlist(ctx, s.waitlist, released.EventID)
+	return nil
 }
 
-func (s *bookingService) GetBookingById(id uint) (*domain.Booking, error) {
-	return s.bookingRepo.FindById(id)
+func (s *bookingService) GetAllBookingsWithPagination(ctx context.Context, offset int, limit int) ([]domain.Booking, error) {
+	return s.bookingRepo.FindAllWithPagination(ctx, offset, limit)
+}
+func (s *bookingService) CountBookings(ctx context.Context) (int64, error) {
+	return s.bookingRepo.Count(ctx)
 }
 
-func (s *bookingService) UpdateBooking(booking *domain.Booking) error {
-	return s.bookingRepo.Update(booking)
+func (s *bookingService) ListUserBookings(ctx context.Context, userID uint, filter domain.BookingFilter, offset int, limit int) ([]domain.Booking, int64, error) {
+	return s.bookingRepo.FindByUser(ctx, userID, filter, offset, limit)
 }
 
-func (s *bookingService) CancelBooking(id uint) (*domain.Booking, error) {
-	booking, err := s.bookingRepo.FindById(id)
-	if err != nil {
-		return nil, err
+func (s *bookingService) GetUserBooking(ctx context.Context, userID uint, id uint) (*domain.Booking, error) {
+	booking, err := s.bookingRepo.FindById(ctx, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, domain.ErrNotFound
 	}
-	if booking.Status != domain.BookingStatusPending {
-		return nil, errors.New("booking is not pending")
-	}
-	booking.Status = domain.BookingStatusCancelled
-	if err := s.bookingRepo.Update(booking); err != nil {
-		return nil, err
-	}
-	payment, err := s.paymentService.FindByBookingID(booking.ID)
 	if err != nil {
 		return nil, err
 	}
-	payment.Status = domain.PaymentStatusFailed
-	if err := s.paymentService.ConfirmPayment(payment); err != nil {
-		return nil, err
-	}
-	event := booking.Event
-	event.TotalTickets += booking.Quantity
-	if err := s.eventRepo.Update(&event); err != nil {
-		return nil, err
+	// Booking của user khác cũng báo không tìm thấy, để không lộ ID nào tồn tại
+	if booking.UserID != userID {
+		return nil, domain.ErrNotFound
 	}
-	
 	return booking, nil
 }
 
-func (s *bookingService) ConfirmBooking(id uint) (*domain.Booking, error) {
-	booking, err := s.bookingRepo.FindById(id)
+func (s *bookingService) GetBookingById(ctx context.Context, id uint) (*domain.Booking, error) {
+	return s.bookingRepo.FindById(ctx, id)
+}
+
+func (s *bookingService) UpdateBooking(ctx context.Context, booking *domain.Booking) error {
+	return s.bookingRepo.Update(ctx, booking)
+}
+
+// CancelBooking huỷ booking đang PENDING, đánh dấu payment FAILED và trả lại vé
+// cho waitlist của event. Booking của một order chỉ được huỷ cùng order
+// (domain.ErrBookingInOrder).
+// All three writes commit or roll back together; the booking row is locked so
+// two concurrent cancels cannot release the same tickets twice.
+func (s *bookingService) CancelBooking(ctx context.Context, id uint) (*domain.Booking, error) {
+	var booking *domain.Booking
+	err := s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		var err error
+		booking, err = s.bookingRepo.FindByIdForUpdate(ctx, id)
+		if err != nil {
+			return err
+		}
+		if booking.OrderID != nil {
+			return domain.ErrBookingInOrder
+		}
+		if booking.Status != domain.BookingStatusPending {
+			return domain.ErrBookingNotPending
+		}
+		booking.Status = domain.BookingStatusCancelled
+		if err := s.bookingRepo.Update(ctx, booking); err != nil {
+			return err
+		}
+		if err := s.paymentService.CancelPayment(ctx, &domain.Payment{BookingID: &booking.ID}); err != nil {
+			return err
+		}
+		return s.eventRepo.ReleaseTickets(ctx, booking)
+	})
 	if err != nil {
 		return nil, err
 	}
-	if booking.Status != domain.BookingStatusPending {
-		return nil, errors.New("booking is not pending")
-	}
-	booking.Status = domain.BookingStatusConfirmed
-	if err := s.bookingRepo.Update(booking); err != nil {
-		return nil, err
-	}
-	payment, err := s.paymentService.FindByBookingID(booking.ID)
+	notifyWaitlist(ctx, s.waitlist, booking.EventID)
+	return booking, nil
+}
+
+// ConfirmBooking xác nhận booking đang PENDING và đánh dấu payment COMPLETED trong cùng một transaction.
+func (s *bookingService) ConfirmBooking(ctx context.Context, id uint) (*domain.Booking, error) {
+	var booking *domain.Booking
+	err := s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		var err error
+		booking, err = s.bookingRepo.FindByIdForUpdate(ctx, id)
+		if err != nil {
+			return err
+		}
+		if booking.OrderID != nil {
+			return domain.ErrBookingInOrder
+		}
+		if booking.Status != domain.BookingStatusPending {
+			return domain.ErrBookingNotPending
+		}
+		booking.Status = domain.BookingStatusConfirmed
+		if err := s.bookingRepo.Update(ctx, booking); err != nil {
+			return err
+		}
+		payment, err := s.paymentService.FindByBookingID(ctx, booking.ID)
+		if err != nil {
+			return err
+		}
+		return s.paymentService.ConfirmPayment(ctx, payment)
+	})
 	if err != nil {
 		return nil, err
 	}
-	payment.Status = domain.PaymentStatusCompleted
-	if err := s.paymentService.ConfirmPayment(payment); err != nil {
-		return nil, err
-	}
 	return booking, nil
-}
\ No newline at end of file
+}
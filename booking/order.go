@@ -0,0 +1,160 @@
+package booking
+
+import (
+	"context"
+	"log"
+
+	"ticket_app/domain"
+	"ticket_app/internal/queue"
+	"ticket_app/internal/repository"
+	"ticket_app/internal/repository/booking"
+	eventRepo "ticket_app/internal/repository/event"
+	userRepo "ticket_app/internal/repository/user"
+	payment "ticket_app/payment"
+)
+
+type OrderService interface {
+	// CreateOrder đặt vé của nhiều event trong một order với một payment. Mỗi
+	// item là vé giá thường của một event (TicketTypeID nil) hoặc vé của một
+	// hạng vé; event chia hạng vé chỉ nhận item theo hạng vé.
+	CreateOrder(ctx context.Context, userID uint, items []domain.OrderItem) (*domain.Order, error)
+	// GetOrder trả về order id với items, bookings và payment, hoặc domain.ErrNotFound
+	GetOrder(ctx context.Context, id uint) (*domain.Order, error)
+	// CancelOrder huỷ order đang PENDING cùng mọi booking của nó, hoặc trả về domain.ErrOrderNotPending
+	CancelOrder(ctx context.Context, id uint) (*domain.Order, error)
+}
+
+type orderService struct {
+	orderRepo      booking.OrderRepository
+	bookingRepo    booking.BookingRepository
+	userRepo       userRepo.UserRepository
+	eventRepo      eventRepo.EventRepository
+	paymentService payment.PaymentService
+	queueService   queue.PaymentQueue
+	waitlist       queue.WaitlistQueue
+	txManager      repository.TxManager
+}
+
+func NewOrderService(txManager repository.TxManager, orderRepo booking.OrderRepository, bookingRepo booking.BookingRepository, userRepo userRepo.UserRepository, eventRepo eventRepo.EventRepository, paymentService payment.PaymentService, queueService queue.PaymentQueue, waitlist queue.WaitlistQueue) OrderService {
+	return &orderService{
+		txManager:      txManager,
+		orderRepo:      orderRepo,
+		bookingRepo:    bookingRepo,
+		userRepo:       userRepo,
+		eventRepo:      eventRepo,
+		paymentService: paymentService,
+		queueService:   queueService,
+		waitlist:       waitlist,
+	}
+}
+
+// CreateOrder gom items thành một booking cho mỗi event, rồi giữ vé của mọi
+// event trong một transaction (OrderRepository.CreateWithReservation): một
+// event hết vé thì cả order thất bại và không event nào bị trừ vé. Payment job
+// của order chỉ được enqueue sau khi transaction commit.
+func (s *orderService) CreateOrder(ctx context.Context, userID uint, items []domain.OrderItem) (*domain.Order, error) {
+	if len(items) == 0 {
+		return nil, domain.ErrBadParamInput
+	}
+	var bookings []domain.Booking
+	index := make(map[uint]int) // event -> booking
+	for _, item := range items {
+		if item.EventID == 0 || item.Quantity < 1 {
+			return nil, domain.ErrBadParamInput
+		}
+		i, ok := index[item.EventID]
+		if !ok {
+			i = len(bookings)
+			index[item.EventID] = i
+			bookings = append(bookings, domain.Booking{EventID: item.EventID})
+		}
+		b := &bookings[i]
+		// Mỗi event hoặc có đúng một item giá thường, hoặc chỉ có item theo hạng vé, mỗi hạng một item
+		if b.Quantity > 0 || (item.TicketTypeID == nil && len(b.Items) > 0) {
+			return nil, domain.ErrBadParamInput
+		}
+		if item.TicketTypeID == nil {
+			b.Quantity = item.Quantity
+			continue
+		}
+		for _, existing := range b.Items {
+			if existing.TicketTypeID == *item.TicketTypeID {
+				return nil, domain.ErrBadParamInput
+			}
+		}
+		b.Items = append(b.Items, domain.BookingItem{TicketTypeID: *item.TicketTypeID, Quantity: item.Quantity})
+	}
+
+	if err := checkBooker(ctx, s.userRepo, userID); err != nil {
+		return nil, err
+	}
+	for _, b := range bookings {
+		if _, err := openEvent(ctx, s.eventRepo, b.EventID); err != nil {
+			return nil, err
+		}
+	}
+
+	order := &domain.Order{UserID: userID, Bookings: bookings}
+	payment := &domain.Payment{Status: domain.PaymentStatusPending}
+	if err := s.orderRepo.CreateWithReservation(ctx, order, payment); err != nil {
+		return nil, err
+	}
+	order.Payment = payment
+
+	if err := s.queueService.EnqueuePayment(ctx, queue.PaymentJob{OrderID: order.ID, Amount: order.TotalPrice}); err != nil {
+		return nil, err
+	}
+	log.Printf("Order %d created with %d bookings", order.ID, len(order.Bookings))
+	return order, nil
+}
+
+func (s *orderService) GetOrder(ctx context.Context, id uint) (*domain.Order, error) {
+	return s.orderRepo.FindById(ctx, id)
+}
+
+// CancelOrder huỷ order, huỷ các booking của nó, đánh dấu payment FAILED và
+// trả lại vé của mọi event trong cùng một transaction; order row bị khoá nên
+// hai lần huỷ đồng thời không trả vé hai lần. Sau khi commit, vé trả lại được
+// báo cho waitlist của từng event.
+func (s *orderService) CancelOrder(ctx context.Context, id uint) (*domain.Order, error) {
+	var released []uint // events
+	err := s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		order, err := s.orderRepo.FindByIdForUpdate(ctx, id)
+		if err != nil {
+			return err
+		}
+		if order.Status != domain.BookingStatusPending {
+			return domain.ErrOrderNotPending
+		}
+		order.Status = domain.BookingStatusCancelled
+		if err := s.orderRepo.Update(ctx, order); err != nil {
+			return err
+		}
+		for i := range order.Bookings {
+			booking := &order.Bookings[i]
+			if booking.Status != domain.BookingStatusPending {
+				continue
+			}
+			booking.Status = domain.BookingStatusCancelled
+			if err := s.bookingRepo.Update(ctx, booking); err != nil {
+				return err
+			}
+			if err := s.eventRepo.ReleaseTickets(ctx, booking); err != nil {
+				return err
+			}
+			released = append(released, booking.EventID)
+		}
+		payment, err := s.paymentService.FindByOrderID(ctx, order.ID)
+		if err != nil {
+			return err
+		}
+		return s.paymentService.CancelPayment(ctx, payment)
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, eventID := range released {
+		notifyWaitlist(ctx, s.waitlist, eventID)
+	}
+	return s.orderRepo.FindById(ctx, id)
+}
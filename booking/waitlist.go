@@ -0,0 +1,116 @@
+package booking
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	"gorm.io/gorm"
+
+	"ticket_app/domain"
+	"ticket_app/internal/queue"
+	"ticket_app/internal/repository/booking"
+	eventRepo "ticket_app/internal/repository/event"
+	userRepo "ticket_app/internal/repository/user"
+)
+
+type WaitlistService interface {
+	// JoinWaitlist xếp userID vào waitlist của event, hoặc của hạng vé
+	// ticketTypeID, cho quantity vé. Chỉ vào được khi số vé đó không còn đặt
+	// được (domain.ErrTicketsAvailable), mỗi event hoặc hạng vé một chỗ.
+	JoinWaitlist(ctx context.Context, userID uint, eventID uint, ticketTypeID *uint, quantity int) (*domain.WaitlistEntry, error)
+	// ListUserWaitlist trả về các entry của userID, kèm hold offer nếu có
+	ListUserWaitlist(ctx context.Context, userID uint) ([]domain.WaitlistEntry, error)
+	// LeaveWaitlist bỏ entry id của userID, trả vé của offer đang có. Trả về
+	// domain.ErrNotFound nếu không có hoặc của user khác, và
+	// domain.ErrWaitlistEntryNotActive nếu entry đã kết thúc.
+	LeaveWaitlist(ctx context.Context, userID uint, id uint) (*domain.WaitlistEntry, error)
+}
+
+type waitlistService struct {
+	waitlistRepo booking.WaitlistRepository
+	holdRepo     booking.HoldRepository
+	userRepo     userRepo.UserRepository
+	eventRepo    eventRepo.EventRepository
+	waitlist     queue.WaitlistQueue
+}
+
+func NewWaitlistService(waitlistRepo booking.WaitlistRepository, holdRepo booking.HoldRepository, userRepo userRepo.UserRepository, eventRepo eventRepo.EventRepository, waitlist queue.WaitlistQueue) WaitlistService {
+	return &waitlistService{
+		waitlistRepo: waitlistRepo,
+		holdRepo:     holdRepo,
+		userRepo:     userRepo,
+		eventRepo:    eventRepo,
+		waitlist:     waitlist,
+	}
+}
+
+// JoinWaitlist kiểm tra user và event như khi đặt vé; số vé còn lại được kiểm
+// tra lại trong WaitlistRepository.Join khi đã khoá event
+func (s *waitlistService) JoinWaitlist(ctx context.Context, userID uint, eventID uint, ticketTypeID *uint, quantity int) (*domain.WaitlistEntry, error) {
+	if eventID == 0 || quantity < 1 {
+		return nil, domain.ErrBadParamInput
+	}
+	if err := checkBooker(ctx, s.userRepo, userID); err != nil {
+		return nil, err
+	}
+	if _, err := openEvent(ctx, s.eventRepo, eventID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, domain.ErrNotFound
+		}
+		return nil, err
+	}
+	entry := &domain.WaitlistEntry{UserID: userID, EventID: eventID, TicketTypeID: ticketTypeID, Quantity: quantity}
+	if err := s.waitlistRepo.Join(ctx, entry); err != nil {
+		return nil, err
+	}
+	log.Printf("User %d joined the waitlist of event %d as entry %d", userID, eventID, entry.ID)
+	return entry, nil
+}
+
+func (s *waitlistService) ListUserWaitlist(ctx context.Context, userID uint) ([]domain.WaitlistEntry, error) {
+	return s.waitlistRepo.FindByUser(ctx, userID)
+}
+
+// LeaveWaitlist huỷ entry đang chờ, hoặc trả hold của entry đang có offer như
+// khi user bỏ hold đó: entry CANCELLED và vé chuyển cho người tiếp theo
+func (s *waitlistService) LeaveWaitlist(ctx context.Context, userID uint, id uint) (*domain.WaitlistEntry, error) {
+	entry, err := s.waitlistRepo.FindById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	// Entry của user khác cũng báo không tìm thấy
+	if entry.UserID != userID {
+		return nil, domain.ErrNotFound
+	}
+	switch {
+	case entry.Status == domain.WaitlistStatusWaiting:
+		cancelled, err := s.waitlistRepo.Cancel(ctx, entry.ID)
+		if err != nil {
+			return nil, err
+		}
+		if !cancelled {
+			return nil, domain.ErrWaitlistEntryNotActive
+		}
+	case entry.Status == domain.WaitlistStatusOffered && entry.HoldID != nil:
+		released, err := s.holdRepo.Release(ctx, *entry.HoldID, domain.HoldStatusReleased)
+		if err != nil {
+			return nil, err
+		}
+		if released == nil {
+			return nil, domain.ErrWaitlistEntryNotActive
+		}
+		notifyWaitlist(ctx, s.waitlist, released.EventID)
+	default:
+		return nil, domain.ErrWaitlistEntryNotActive
+	}
+	return s.waitlistRepo.FindById(ctx, entry.ID)
+}
+
+// notifyWaitlist báo cho waitlist của eventID rằng có vé được trả lại. Vé đã
+// được trả trong transaction đã commit, nên lỗi chỉ được ghi log.
+func notifyWaitlist(ctx context.Context, waitlist queue.WaitlistQueue, eventID uint) {
+	if err := waitlist.NotifyTicketsReleased(ctx, eventID); err != nil {
+		log.Printf("Failed to notify waitlist of event %d: %v", eventID, err)
+	}
+}
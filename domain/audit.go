@@ -0,0 +1,41 @@
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	// ErrInvalidCredentials will throw if the email or the password is wrong; both get the same error
+	ErrInvalidCredentials = errors.New("invalid email or password")
+	// ErrTooManyLoginAttempts will throw if logins for the email or from the IP are throttled or locked
+	ErrTooManyLoginAttempts = errors.New("too many login attempts, please try again later")
+)
+
+// AuditAction is what happened in an AuditLog entry
+type AuditAction string
+
+const (
+	AuditLoginSucceeded AuditAction = "login.succeeded"
+	AuditLoginFailed    AuditAction = "login.failed"
+	// AuditLoginThrottled là lần đăng nhập bị từ chối vì đang bị chờ hoặc bị khoá, password không được kiểm tra
+	AuditLoginThrottled  AuditAction = "login.throttled"
+	AuditAccountLocked   AuditAction = "account.locked"
+	AuditAccountUnlocked AuditAction = "account.unlocked"
+	// AuditMFAFailed là mã 2FA sai ở bước thứ hai của đăng nhập
+	AuditMFAFailed   AuditAction = "login.mfa_failed"
+	AuditMFAEnabled  AuditAction = "mfa.enabled"
+	AuditMFADisabled AuditAction = "mfa.disabled"
+)
+
+// AuditLog records a security relevant event. UserID is nil when the email
+// does not belong to any user.
+type AuditLog struct {
+	ID        uint        `gorm:"primaryKey;autoIncrement" json:"id"`
+	UserID    *uint       `gorm:"index" json:"user_id"`
+	Action    AuditAction `gorm:"type:varchar(32);not null" json:"action"`
+	Email     string      `gorm:"type:varchar(255)" json:"email"`
+	IP        string      `gorm:"type:varchar(45)" json:"ip"`
+	UserAgent string      `gorm:"type:varchar(255)" json:"user_agent"`
+	CreatedAt time.Time   `json:"created_at"`
+}
@@ -1,6 +1,12 @@
 package domain
 
-import "time"
+import (
+    "errors"
+    "time"
+)
+
+// ErrBookingNotPending will throw if a booking that is already confirmed or cancelled is confirmed or cancelled again
+var ErrBookingNotPending = errors.New("booking is not pending")
 
 // BookingStatus represents the status of a booking
 type BookingStatus string
@@ -28,8 +34,28 @@ type Booking struct {
     Quantity    int          `gorm:"not null" json:"quantity"`
     TotalPrice  float64      `gorm:"not null" json:"total_price"`
     Status      BookingStatus `gorm:"type:varchar(20);not null;default:'PENDING'" json:"status"`
+    OrderID     *uint        `gorm:"index" json:"order_id,omitempty"` // order chứa booking; payment và huỷ đi theo order
     CreatedAt   time.Time    `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
     UpdatedAt   time.Time    `gorm:"default:CURRENT_TIMESTAMP" json:"updated_at"`
     User        User         `gorm:"references:ID"` // Quan hệ ngược (optional)
     Event       Event        `gorm:"references:ID"` // Quan hệ ngược (optional)
-}
\ No newline at end of file
+    Items       []BookingItem `gorm:"foreignKey:BookingID" json:"items,omitempty"` // vé theo từng hạng, rỗng với event không chia hạng
+    Seats       []BookingSeat `gorm:"foreignKey:BookingID" json:"seats,omitempty"` // ghế đã chọn, rỗng với event không xếp chỗ
+}
+
+// BookingRequest là nội dung một lần đặt vé hoặc giữ vé. Dùng đúng một trong:
+// Quantity vé giá thường, Items theo hạng vé, SeatIDs với event có sơ đồ ghế,
+// hoặc HoldToken của một hold còn hiệu lực (chỉ khi đặt vé; EventID lấy từ hold).
+type BookingRequest struct {
+    EventID   uint
+    Quantity  int
+    Items     []BookingItem
+    SeatIDs   []uint
+    HoldToken string
+}
+
+// BookingFilter lọc danh sách booking của một user; giá trị zero là không lọc
+type BookingFilter struct {
+    Status  BookingStatus
+    EventID uint
+}
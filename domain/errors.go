@@ -11,4 +11,8 @@ var (
 	ErrConflict = errors.New("your Item already exist")
 	// ErrBadParamInput will throw if the given request-body or params is not valid
 	ErrBadParamInput = errors.New("given Param is not valid")
+	// ErrNotEnoughTickets will throw if the event has fewer remaining tickets than requested
+	ErrNotEnoughTickets = errors.New("not enough tickets available")
+	// ErrEventNotActive will throw if tickets are requested for an inactive event
+	ErrEventNotActive = errors.New("event is not active")
 )
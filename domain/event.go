@@ -22,14 +22,36 @@ type Event struct {
     UpdatedAt   time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"updated_at"`
     Bookings    []*Booking `gorm:"foreignKey:EventID"` // Quan hệ 1-n với Booking
     Status      EventStatus    `gorm:"type:varchar(255);not null;default:'ACTIVE'" json:"status"`
-    // EventStats  *EventStats `gorm:"foreignKey:EventID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE"` // Quan hệ 1-1 với EventStats
-
+    OrganizerID *uint     `json:"organizer_id"` // user tạo event, nil với event tạo trước khi có role
+    VenueID     *uint     `json:"venue_id"`     // venue có sơ đồ ghế; nil là event không xếp chỗ
+    HoldMinutes int       `gorm:"not null;default:0" json:"hold_minutes"` // thời gian giữ vé trước khi đặt; 0 là mặc định HOLD_TTL
 }
 
 
 
+// HoldTTL trả về thời gian giữ vé của event, hoặc fallback khi event không đặt riêng
+func (e *Event) HoldTTL(fallback time.Duration) time.Duration {
+    if e.HoldMinutes > 0 {
+        return time.Duration(e.HoldMinutes) * time.Minute
+    }
+    return fallback
+}
+
 // EventWithRemainingTickets chứa thông tin Event và số vé còn lại
 type EventWithRemainingTickets struct {
     Event
     RemainingTickets int64 `json:"remaining_tickets"`
+}
+
+// EventStats chứa số liệu bán vé của một event, tính từ bookings và payments
+type EventStats struct {
+    EventID           uint    `json:"event_id"`
+    EventName         string  `json:"event_name"`
+    TicketsSold       int64   `json:"tickets_sold"`      // vé thuộc booking CONFIRMED
+    TicketsPending    int64   `json:"tickets_pending"`   // vé đang giữ chờ thanh toán
+    TicketsCancelled  int64   `json:"tickets_cancelled"` // vé đã được trả lại
+    ConfirmedRevenue  float64 `json:"confirmed_revenue"` // tổng payment COMPLETED của booking CONFIRMED
+    TotalBookings     int64   `json:"total_bookings"`
+    ConfirmedBookings int64   `json:"confirmed_bookings"`
+    ConversionRate    float64 `json:"conversion_rate"` // ConfirmedBookings / TotalBookings, 0 nếu chưa có booking
 }
\ No newline at end of file
@@ -0,0 +1,59 @@
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	// ErrHoldNotFound will throw if a hold token is unknown or belongs to another user
+	ErrHoldNotFound = errors.New("hold not found")
+	// ErrHoldExpired will throw if a hold is used after it expired
+	ErrHoldExpired = errors.New("hold has expired")
+	// ErrHoldNotActive will throw if a hold was already turned into a booking or released
+	ErrHoldNotActive = errors.New("hold was already used or released")
+)
+
+// HoldStatus là trạng thái của một hold
+type HoldStatus string
+
+const (
+	HoldStatusActive    HoldStatus = "ACTIVE"
+	HoldStatusConverted HoldStatus = "CONVERTED" // đã thành booking
+	HoldStatusExpired   HoldStatus = "EXPIRED"
+	HoldStatusReleased  HoldStatus = "RELEASED" // user tự bỏ
+)
+
+// Hold giữ vé (và ghế) cho user trong vài phút trước khi đặt: vé được trừ khỏi
+// event và hạng vé như một booking, nhưng chưa có booking hay payment nào.
+// POST /bookings với Token biến hold thành booking; hold hết hạn thì trả vé lại.
+type Hold struct {
+	ID         uint          `gorm:"primaryKey;autoIncrement" json:"-"`
+	Token      string        `gorm:"type:varchar(64);not null;uniqueIndex" json:"token"`
+	UserID     uint          `gorm:"not null;index" json:"user_id"`
+	EventID    uint          `gorm:"not null" json:"event_id"`
+	Quantity   int           `gorm:"not null" json:"quantity"`
+	TotalPrice float64       `gorm:"type:decimal(10,2);not null" json:"total_price"`
+	Status     HoldStatus    `gorm:"type:varchar(20);not null;default:'ACTIVE'" json:"status"`
+	ExpiresAt  time.Time     `gorm:"not null" json:"expires_at"`
+	BookingID  *uint         `json:"booking_id,omitempty"` // booking tạo từ hold
+	Items      []HoldItem    `gorm:"foreignKey:HoldID" json:"items,omitempty"`
+	Seats      []BookingSeat `gorm:"foreignKey:HoldID" json:"seats,omitempty"`
+	CreatedAt  time.Time     `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+	UpdatedAt  time.Time     `gorm:"default:CURRENT_TIMESTAMP" json:"updated_at"`
+}
+
+// Expired cho biết hold đã hết hạn tại at
+func (h *Hold) Expired(at time.Time) bool {
+	return !at.Before(h.ExpiresAt)
+}
+
+// HoldItem là số vé của một hạng vé trong hold, với giá tại lúc giữ
+type HoldItem struct {
+	ID           uint        `gorm:"primaryKey;autoIncrement" json:"-"`
+	HoldID       uint        `gorm:"not null;index" json:"-"`
+	TicketTypeID uint        `gorm:"not null" json:"ticket_type_id"`
+	Quantity     int         `gorm:"not null" json:"quantity"`
+	UnitPrice    float64     `gorm:"type:decimal(10,2);not null" json:"unit_price"`
+	TicketType   *TicketType `gorm:"foreignKey:TicketTypeID" json:"ticket_type,omitempty"`
+}
@@ -0,0 +1,25 @@
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	// ErrInvalidMFACode will throw if a TOTP or recovery code is wrong or was already used
+	ErrInvalidMFACode = errors.New("invalid two-factor authentication code")
+	// ErrMFAAlreadyEnabled will throw if a user with 2FA enabled starts enrolling again
+	ErrMFAAlreadyEnabled = errors.New("two-factor authentication is already enabled")
+	// ErrMFANotEnabled will throw if 2FA is confirmed, disabled or changed before enrolling
+	ErrMFANotEnabled = errors.New("two-factor authentication is not enabled")
+)
+
+// RecoveryCode is a single-use code that replaces a TOTP code when the
+// authenticator is lost. Only its SHA-256 hash is stored.
+type RecoveryCode struct {
+	ID        uint   `gorm:"primaryKey;autoIncrement"`
+	UserID    uint   `gorm:"not null;index"`
+	CodeHash  string `gorm:"type:char(64);not null"`
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
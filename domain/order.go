@@ -0,0 +1,42 @@
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	// ErrOrderNotPending will throw if an order that was already paid or cancelled is cancelled
+	ErrOrderNotPending = errors.New("order is not pending")
+	// ErrBookingInOrder will throw if a booking of an order is cancelled or confirmed on its own
+	ErrBookingInOrder = errors.New("booking belongs to an order, cancel or pay the order instead")
+)
+
+// Order gom vé của nhiều event vào một lần thanh toán. Mỗi event có một Booking
+// riêng (để báo cáo theo event như trước), nhưng chỉ order có Payment; order và
+// các booking của nó luôn cùng Status.
+type Order struct {
+	ID         uint          `gorm:"primaryKey;autoIncrement" json:"id"`
+	UserID     uint          `gorm:"not null;index" json:"user_id"`
+	TotalPrice float64       `gorm:"type:decimal(10,2);not null" json:"total_price"`
+	Status     BookingStatus `gorm:"type:varchar(20);not null;default:'PENDING'" json:"status"`
+	CreatedAt  time.Time     `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+	UpdatedAt  time.Time     `gorm:"default:CURRENT_TIMESTAMP" json:"updated_at"`
+	Items      []OrderItem   `gorm:"foreignKey:OrderID" json:"items"`
+	Bookings   []Booking     `gorm:"foreignKey:OrderID" json:"bookings,omitempty"`
+	Payment    *Payment      `gorm:"foreignKey:OrderID" json:"payment,omitempty"`
+}
+
+// OrderItem là số vé của một event, hoặc của một hạng vé của event, trong
+// order, với giá tại lúc đặt. BookingID là booking của event đó.
+type OrderItem struct {
+	ID           uint        `gorm:"primaryKey;autoIncrement" json:"id"`
+	OrderID      uint        `gorm:"not null;index" json:"order_id"`
+	BookingID    uint        `gorm:"not null;index" json:"booking_id"`
+	EventID      uint        `gorm:"not null" json:"event_id"`
+	TicketTypeID *uint       `json:"ticket_type_id,omitempty"` // nil với event không chia hạng vé
+	Quantity     int         `gorm:"not null" json:"quantity"`
+	UnitPrice    float64     `gorm:"type:decimal(10,2);not null" json:"unit_price"`
+	Event        *Event      `gorm:"foreignKey:EventID" json:"event,omitempty"`
+	TicketType   *TicketType `gorm:"foreignKey:TicketTypeID" json:"ticket_type,omitempty"`
+}
@@ -23,7 +23,8 @@ func (s PaymentStatus) Validate() bool {
 // Payment represents a payment entity
 type Payment struct {
     ID        uint         `gorm:"primaryKey;autoIncrement" json:"id"`
-    BookingID uint         `gorm:"not null;index" json:"booking_id"` // FK to Booking.ID
+    BookingID *uint        `gorm:"index" json:"booking_id,omitempty"` // FK to Booking.ID, nil với payment của order
+    OrderID   *uint        `gorm:"index" json:"order_id,omitempty"`   // FK to Order.ID, một payment cho mọi booking của order
     Amount    float64      `gorm:"type:decimal(10,2);not null" json:"amount"`
     Status    PaymentStatus `gorm:"type:varchar(20);not null;default:'PENDING'" json:"status"`
     CreatedAt time.Time    `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
@@ -0,0 +1,28 @@
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	// ErrInvalidRefreshToken will throw if a refresh token is unknown, expired or revoked
+	ErrInvalidRefreshToken = errors.New("invalid or expired refresh token")
+	// ErrRefreshTokenReused will throw if an already rotated refresh token is presented again
+	ErrRefreshTokenReused = errors.New("refresh token reuse detected, please log in again")
+)
+
+// RefreshToken is one refresh token of a login session. Only its SHA-256 hash
+// is stored. Every refresh replaces the token with a new one of the same
+// family, so a family is one login session.
+type RefreshToken struct {
+	ID           uint       `gorm:"primaryKey;autoIncrement"`
+	UserID       uint       `gorm:"not null;index"`
+	FamilyID     string     `gorm:"type:varchar(36);not null;index"`
+	TokenHash    string     `gorm:"type:char(64);not null;unique"`
+	ExpiresAt    time.Time  `gorm:"not null"`
+	RevokedAt    *time.Time // set when rotated, logged out or revoked
+	ReplacedByID *uint      // the token issued when this one was rotated
+	MFA          bool       `gorm:"not null;default:false"` // the session was opened with a TOTP or recovery code
+	CreatedAt    time.Time
+}
@@ -0,0 +1,91 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	// ErrTicketTypeNotFound will throw if a booking names a ticket type the event does not have
+	ErrTicketTypeNotFound = errors.New("ticket type not found")
+	// ErrTicketTypeRequired will throw if an event with ticket types is booked without choosing one
+	ErrTicketTypeRequired = errors.New("this event sells tickets by type, choose a ticket type")
+	// ErrTicketTypeNotOnSale will throw if a ticket type is booked outside its sales window
+	ErrTicketTypeNotOnSale = errors.New("ticket type is not on sale")
+	// ErrTicketQuantityOutOfRange will throw if a quantity is outside the per-order limits of its ticket type
+	ErrTicketQuantityOutOfRange = errors.New("quantity is outside the per-order limits of the ticket type")
+	// ErrTicketTypeInUse will throw if a ticket type that was already booked is deleted
+	ErrTicketTypeInUse = errors.New("ticket type has bookings and cannot be deleted")
+	// ErrQuotaBelowReserved will throw if a quota is lowered below the tickets already reserved
+	ErrQuotaBelowReserved = errors.New("quota is lower than the tickets already reserved")
+)
+
+// TicketType là một hạng vé của event (VIP, standard, student...) với giá,
+// quota và giới hạn mỗi đơn riêng. Reserved đếm vé của booking PENDING và
+// CONFIRMED; vé còn lại là Quota - Reserved.
+type TicketType struct {
+	ID          uint       `gorm:"primaryKey;autoIncrement" json:"id"`
+	EventID     uint       `gorm:"not null;index" json:"event_id"`
+	Name        string     `gorm:"type:varchar(100);not null" json:"name"`
+	Price       float64    `gorm:"type:decimal(10,2);not null" json:"price"`
+	Quota       int        `gorm:"not null" json:"quota"`
+	Reserved    int        `gorm:"not null;default:0" json:"reserved"`
+	MinPerOrder int        `gorm:"not null;default:1" json:"min_per_order"`
+	MaxPerOrder int        `gorm:"not null;default:0" json:"max_per_order"` // 0 là không giới hạn
+	SalesStart  *time.Time `json:"sales_start,omitempty"`                   // nil là mở bán ngay
+	SalesEnd    *time.Time `json:"sales_end,omitempty"`                     // nil là bán đến khi event bắt đầu
+	SectionID   *uint      `json:"section_id,omitempty"`                    // ghế của section này được bán theo hạng vé này
+	CreatedAt   time.Time  `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+	UpdatedAt   time.Time  `gorm:"default:CURRENT_TIMESTAMP" json:"updated_at"`
+}
+
+// Available trả về số vé còn có thể đặt
+func (t *TicketType) Available() int {
+	return max(t.Quota-t.Reserved, 0)
+}
+
+// OnSale cho biết at có nằm trong thời gian mở bán không
+func (t *TicketType) OnSale(at time.Time) bool {
+	if t.SalesStart != nil && at.Before(*t.SalesStart) {
+		return false
+	}
+	return t.SalesEnd == nil || at.Before(*t.SalesEnd)
+}
+
+// CheckQuantity trả về ErrTicketQuantityOutOfRange khi quantity nằm ngoài giới hạn mỗi đơn
+func (t *TicketType) CheckQuantity(quantity int) error {
+	if quantity < max(t.MinPerOrder, 1) || (t.MaxPerOrder > 0 && quantity > t.MaxPerOrder) {
+		return ErrTicketQuantityOutOfRange
+	}
+	return nil
+}
+
+// Validate kiểm tra các trường mà organizer nhập; lỗi bọc ErrBadParamInput
+func (t *TicketType) Validate() error {
+	switch {
+	case t.Name == "":
+		return fmt.Errorf("%w: name is required", ErrBadParamInput)
+	case t.Price < 0:
+		return fmt.Errorf("%w: price must not be negative", ErrBadParamInput)
+	case t.Quota < 0:
+		return fmt.Errorf("%w: quota must not be negative", ErrBadParamInput)
+	case t.MinPerOrder < 1:
+		return fmt.Errorf("%w: min_per_order must be at least 1", ErrBadParamInput)
+	case t.MaxPerOrder != 0 && t.MaxPerOrder < t.MinPerOrder:
+		return fmt.Errorf("%w: max_per_order must be 0 or at least min_per_order", ErrBadParamInput)
+	case t.SalesStart != nil && t.SalesEnd != nil && !t.SalesStart.Before(*t.SalesEnd):
+		return fmt.Errorf("%w: sales_start must be before sales_end", ErrBadParamInput)
+	}
+	return nil
+}
+
+// BookingItem là một dòng của booking: số vé của một hạng vé, với giá tại lúc đặt
+type BookingItem struct {
+	ID           uint        `gorm:"primaryKey;autoIncrement" json:"id"`
+	BookingID    uint        `gorm:"not null;index" json:"booking_id"`
+	TicketTypeID uint        `gorm:"not null;index" json:"ticket_type_id"`
+	Quantity     int         `gorm:"not null" json:"quantity"`
+	UnitPrice    float64     `gorm:"type:decimal(10,2);not null" json:"unit_price"`
+	TicketType   *TicketType `gorm:"foreignKey:TicketTypeID" json:"ticket_type,omitempty"`
+}
@@ -2,12 +2,42 @@ package domain
 
 import "time"
 
+// Role quyết định user được gọi những API nào
+type Role string
+
+const (
+    // RoleCustomer mua vé; chỉ xem và huỷ được booking của chính mình
+    RoleCustomer Role = "customer"
+    // RoleOrganizer tạo event và quản lý event của mình
+    RoleOrganizer Role = "organizer"
+    // RoleAdmin quản lý mọi event, booking, payment và user
+    RoleAdmin Role = "admin"
+)
+
+// Valid tells whether r is one of the known roles
+func (r Role) Valid() bool {
+    return r == RoleCustomer || r == RoleOrganizer || r == RoleAdmin
+}
+
 // User represents a user entity
 type User struct {
     ID        uint      `gorm:"primaryKey;autoIncrement" json:"id"`
     Email     string    `gorm:"type:varchar(255);unique;not null" json:"email"`
     Password  string    `gorm:"type:varchar(255);not null" json:"-"` // Lưu password đã hash       
+    Role      Role      `gorm:"type:varchar(20);not null;default:'customer'" json:"role"`
+    // EmailVerifiedAt là lúc user xác nhận email; nil thì chưa được đặt vé
+    EmailVerifiedAt *time.Time `json:"email_verified_at"`
+    // TOTPSecret có giá trị từ lúc bắt đầu đăng ký 2FA; 2FA chỉ bật khi TOTPEnabledAt khác nil
+    TOTPSecret    string     `gorm:"type:varchar(64)" json:"-"`
+    TOTPEnabledAt *time.Time `json:"totp_enabled_at"`
+    // TOTPLastStep là time step của mã TOTP dùng gần nhất, để một mã không dùng được hai lần
+    TOTPLastStep int64 `gorm:"not null;default:0" json:"-"`
     CreatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
     UpdatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"updated_at"`
     Bookings  []Booking `gorm:"foreignKey:UserID"` // Quan hệ 1-n với Booking
-}
\ No newline at end of file
+}
+
+// MFAEnabled tells whether logging in needs a TOTP code
+func (u *User) MFAEnabled() bool {
+    return u.TOTPEnabledAt != nil
+}
@@ -0,0 +1,39 @@
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	// ErrInvalidUserToken will throw if a verification or reset token is unknown, expired or already used
+	ErrInvalidUserToken = errors.New("invalid or expired token")
+	// ErrEmailNotVerified will throw if an unverified user tries to book
+	ErrEmailNotVerified = errors.New("email address is not verified")
+)
+
+// TokenPurpose tells what a UserToken may be used for
+type TokenPurpose string
+
+const (
+	// TokenEmailVerification xác nhận email sau khi đăng ký
+	TokenEmailVerification TokenPurpose = "email_verification"
+	// TokenPasswordReset cho phép đặt lại password khi quên
+	TokenPasswordReset TokenPurpose = "password_reset"
+	// TokenAccountUnlock mở khoá tài khoản bị khoá vì đăng nhập sai nhiều lần
+	TokenAccountUnlock TokenPurpose = "account_unlock"
+	// TokenMFAChallenge nối bước nhập password với bước nhập mã 2FA khi đăng nhập
+	TokenMFAChallenge TokenPurpose = "mfa_challenge"
+)
+
+// UserToken is a single-use token mailed to a user. Like refresh tokens, only
+// its SHA-256 hash is stored.
+type UserToken struct {
+	ID        uint         `gorm:"primaryKey;autoIncrement"`
+	UserID    uint         `gorm:"not null;index"`
+	Purpose   TokenPurpose `gorm:"type:varchar(32);not null"`
+	TokenHash string       `gorm:"type:char(64);not null;unique"`
+	ExpiresAt time.Time    `gorm:"not null"`
+	UsedAt    *time.Time   // set when used, or when a newer token replaces it
+	CreatedAt time.Time
+}
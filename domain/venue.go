@@ -0,0 +1,113 @@
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	// ErrSeatNotFound will throw if a booking names a seat that is not in the venue of the event
+	ErrSeatNotFound = errors.New("seat not found in the venue of this event")
+	// ErrSeatTaken will throw if a requested seat is already held or sold for the event
+	ErrSeatTaken = errors.New("one or more seats are already taken")
+	// ErrSeatSelectionRequired will throw if an event with a seat map is booked without choosing seats
+	ErrSeatSelectionRequired = errors.New("this event has reserved seating, choose seats")
+	// ErrNoSeatMap will throw if seats are requested for an event without a venue
+	ErrNoSeatMap = errors.New("this event has no reserved seating")
+	// ErrSeatNotOnSale will throw if the section of a seat has no ticket type in an event that sells by type
+	ErrSeatNotOnSale = errors.New("seat is not on sale for this event")
+)
+
+// Venue là địa điểm có sơ đồ ghế: Section > Row > Seat. Event gắn với một
+// Venue được đặt vé theo từng ghế.
+type Venue struct {
+	ID        uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	Name      string    `gorm:"type:varchar(255);not null" json:"name"`
+	Address   string    `gorm:"type:text" json:"address"`
+	Sections  []Section `gorm:"foreignKey:VenueID" json:"sections,omitempty"`
+	CreatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+	UpdatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"updated_at"`
+}
+
+// Section là một khu của venue (Orchestra, Balcony...)
+type Section struct {
+	ID      uint   `gorm:"primaryKey;autoIncrement" json:"id"`
+	VenueID uint   `gorm:"not null;index" json:"venue_id"`
+	Name    string `gorm:"type:varchar(100);not null" json:"name"`
+	Rows    []Row  `gorm:"foreignKey:SectionID" json:"rows,omitempty"`
+}
+
+// Row là một hàng ghế trong section; Position là thứ tự hiển thị
+type Row struct {
+	ID        uint     `gorm:"primaryKey;autoIncrement" json:"id"`
+	SectionID uint     `gorm:"not null;index" json:"section_id"`
+	Label     string   `gorm:"type:varchar(10);not null" json:"label"`
+	Position  int      `gorm:"not null" json:"position"`
+	Seats     []Seat   `gorm:"foreignKey:RowID" json:"seats,omitempty"`
+	Section   *Section `gorm:"foreignKey:SectionID" json:"-"`
+}
+
+// TableName: "rows" trùng từ khoá SQL
+func (Row) TableName() string {
+	return "seat_rows"
+}
+
+// Seat là một ghế trong hàng, đánh số từ 1
+type Seat struct {
+	ID     uint `gorm:"primaryKey;autoIncrement" json:"id"`
+	RowID  uint `gorm:"not null;index" json:"row_id"`
+	Number int  `gorm:"not null" json:"number"`
+	Row    *Row `gorm:"foreignKey:RowID" json:"-"`
+}
+
+// BookingSeat giữ một ghế của event cho booking, hoặc cho hold trước khi có
+// booking. Một ghế chỉ có một BookingSeat chưa release cho mỗi event (unique
+// index trong database); ReleasedAt được gán khi booking bị huỷ hoặc hold hết hạn.
+type BookingSeat struct {
+	ID         uint       `gorm:"primaryKey;autoIncrement" json:"id"`
+	BookingID  *uint      `gorm:"index" json:"booking_id,omitempty"`
+	HoldID     *uint      `gorm:"index" json:"-"`
+	EventID    uint       `gorm:"not null" json:"event_id"`
+	SeatID     uint       `gorm:"not null" json:"seat_id"`
+	UnitPrice  float64    `gorm:"type:decimal(10,2);not null" json:"unit_price"`
+	ReleasedAt *time.Time `json:"released_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	Seat       *Seat      `gorm:"foreignKey:SeatID" json:"seat,omitempty"`
+}
+
+// SeatStatus là trạng thái của một ghế trong sơ đồ ghế của event
+type SeatStatus string
+
+const (
+	SeatAvailable SeatStatus = "AVAILABLE"
+	SeatHeld      SeatStatus = "HELD" // thuộc booking PENDING
+	SeatSold      SeatStatus = "SOLD" // thuộc booking CONFIRMED
+)
+
+// SeatMap là sơ đồ ghế của một event, với trạng thái và giá của từng ghế
+type SeatMap struct {
+	EventID  uint             `json:"event_id"`
+	VenueID  uint             `json:"venue_id"`
+	Venue    string           `json:"venue"`
+	Sections []SeatMapSection `json:"sections"`
+}
+
+type SeatMapSection struct {
+	ID           uint         `json:"id"`
+	Name         string       `json:"name"`
+	TicketTypeID *uint        `json:"ticket_type_id,omitempty"` // nil khi event không chia hạng vé
+	Price        *float64     `json:"price,omitempty"`          // nil khi khu này không mở bán
+	Rows         []SeatMapRow `json:"rows"`
+}
+
+type SeatMapRow struct {
+	ID    uint          `json:"id"`
+	Label string        `json:"label"`
+	Seats []SeatMapSeat `json:"seats"`
+}
+
+type SeatMapSeat struct {
+	ID     uint       `json:"id"`
+	Number int        `json:"number"`
+	Status SeatStatus `json:"status"`
+}
@@ -0,0 +1,60 @@
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	// ErrAlreadyWaitlisted will throw if a user joins the waitlist of an event or ticket type twice
+	ErrAlreadyWaitlisted = errors.New("already on the waitlist for these tickets")
+	// ErrTicketsAvailable will throw if a user joins a waitlist while the tickets can still be booked
+	ErrTicketsAvailable = errors.New("tickets are still available, book them instead")
+	// ErrWaitlistEntryNotActive will throw if an entry that was already served or left is left again
+	ErrWaitlistEntryNotActive = errors.New("waitlist entry is no longer waiting")
+)
+
+// WaitlistStatus là trạng thái của một chỗ trong waitlist
+type WaitlistStatus string
+
+const (
+	WaitlistStatusWaiting   WaitlistStatus = "WAITING"
+	WaitlistStatusOffered   WaitlistStatus = "OFFERED"   // đang có hold offer
+	WaitlistStatusAccepted  WaitlistStatus = "ACCEPTED"  // hold offer đã thành booking
+	WaitlistStatusExpired   WaitlistStatus = "EXPIRED"   // hold offer hết hạn
+	WaitlistStatusCancelled WaitlistStatus = "CANCELLED" // user rời waitlist hoặc bỏ offer
+)
+
+// Active cho biết entry còn giữ chỗ trong waitlist
+func (s WaitlistStatus) Active() bool {
+	return s == WaitlistStatusWaiting || s == WaitlistStatusOffered
+}
+
+// WaitlistStatusForHold là trạng thái của entry khi hold offer của nó kết thúc với status
+func WaitlistStatusForHold(status HoldStatus) WaitlistStatus {
+	switch status {
+	case HoldStatusConverted:
+		return WaitlistStatusAccepted
+	case HoldStatusExpired:
+		return WaitlistStatusExpired
+	}
+	return WaitlistStatusCancelled
+}
+
+// WaitlistEntry là một user chờ Quantity vé của event đã hết vé, hoặc của một
+// hạng vé khi có TicketTypeID. Các entry của cùng event và hạng vé được phục vụ
+// theo thứ tự ID: khi có vé trả lại, entry đầu tiên nhận một hold offer (Hold)
+// có hạn, đặt vé bằng hold token đó hoặc để offer chuyển sang người tiếp theo.
+type WaitlistEntry struct {
+	ID           uint           `gorm:"primaryKey;autoIncrement" json:"id"`
+	UserID       uint           `gorm:"not null;index" json:"user_id"`
+	EventID      uint           `gorm:"not null" json:"event_id"`
+	TicketTypeID *uint          `json:"ticket_type_id,omitempty"`
+	Quantity     int            `gorm:"not null" json:"quantity"`
+	Status       WaitlistStatus `gorm:"type:varchar(20);not null;default:'WAITING'" json:"status"`
+	HoldID       *uint          `json:"-"`
+	Hold         *Hold          `gorm:"foreignKey:HoldID" json:"hold,omitempty"`
+	OfferedAt    *time.Time     `json:"offered_at,omitempty"`
+	CreatedAt    time.Time      `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+	UpdatedAt    time.Time      `gorm:"default:CURRENT_TIMESTAMP" json:"updated_at"`
+}
@@ -1,9 +1,11 @@
 package event
 
 import (
+	"context"
 	"log"
 	"ticket_app/domain"
 	eventRepo "ticket_app/internal/repository/event"
+	ticketTypeRepo "ticket_app/internal/repository/tickettype"
 	"ticket_app/internal/rest/middleware"
 	"time"
 
@@ -12,30 +14,40 @@ import (
 
 // EventService định nghĩa các phương thức của service
 type EventService interface {
-	CreateEvent(event *domain.Event) error
-	GetAllEvents() ([]domain.Event, error)
-	GetEventById(id uint) (*domain.Event, error)
-	UpdateEvent(event *domain.Event) error
-	DeleteEvent(id uint) error
-	GetEventsWithRemainingTickets(pagination middleware.Pagination) (middleware.PaginatedResponse, error)
+	CreateEvent(ctx context.Context, event *domain.Event) error
+	GetAllEvents(ctx context.Context) ([]domain.Event, error)
+	GetEventById(ctx context.Context, id uint) (*domain.Event, error)
+	UpdateEvent(ctx context.Context, event *domain.Event) error
+	DeleteEvent(ctx context.Context, id uint) error
+	GetEventsWithRemainingTickets(ctx context.Context, pagination middleware.Pagination) (middleware.PaginatedResponse, error)
+	GetEventStats(ctx context.Context, id uint) (*domain.EventStats, error)
+	GetAllEventStats(ctx context.Context, pagination middleware.Pagination) (middleware.PaginatedResponse, error)
+	ListTicketTypes(ctx context.Context, eventID uint) ([]domain.TicketType, error)
+	// GetTicketType trả về domain.ErrNotFound nếu hạng vé không có hoặc thuộc event khác
+	GetTicketType(ctx context.Context, eventID uint, id uint) (*domain.TicketType, error)
+	CreateTicketType(ctx context.Context, ticketType *domain.TicketType) error
+	UpdateTicketType(ctx context.Context, ticketType *domain.TicketType) error
+	DeleteTicketType(ctx context.Context, eventID uint, id uint) error
 }
 
 // eventService triển khai EventService
 type eventService struct {
 	validate *validator.Validate
 	eventRepo eventRepo.EventRepository
+	ticketTypeRepo ticketTypeRepo.TicketTypeRepository
 }
 
 // NewEventService tạo instance của EventService
-func NewEventService(eventRepo eventRepo.EventRepository) EventService {
+func NewEventService(eventRepo eventRepo.EventRepository, ticketTypeRepo ticketTypeRepo.TicketTypeRepository) EventService {
 	return &eventService{
 		validate: validator.New(),
 		eventRepo: eventRepo,
+		ticketTypeRepo: ticketTypeRepo,
 	}
 }
 
 // CreateEvent xử lý logic tạo sự kiện
-func (s *eventService) CreateEvent(event *domain.Event) error {
+func (s *eventService) CreateEvent(ctx context.Context, event *domain.Event) error {
 	// Validate input
 	if err := s.validate.Struct(event); err != nil {
 		return err
@@ -49,7 +61,7 @@ func (s *eventService) CreateEvent(event *domain.Event) error {
 	if event.EndDate.IsZero() {
 		event.EndDate = event.StartDate.Add(30 * 24 * time.Hour)
 	}
-	err := s.eventRepo.Create(event)
+	err := s.eventRepo.Create(ctx, event)
 	if err != nil {
 		return err
 	}
@@ -58,9 +70,9 @@ func (s *eventService) CreateEvent(event *domain.Event) error {
 }
 
 // GetAllEvents lấy tất cả sự kiện		
-func (s *eventService) GetAllEvents() ([]domain.Event, error) {
+func (s *eventService) GetAllEvents(ctx context.Context) ([]domain.Event, error) {
 	log.Println("Getting all events")
-	events, err := s.eventRepo.FindAll()
+	events, err := s.eventRepo.FindAll(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -69,9 +81,9 @@ func (s *eventService) GetAllEvents() ([]domain.Event, error) {
 }
 
 // GetEventById lấy sự kiện theo ID
-func (s *eventService) GetEventById(id uint) (*domain.Event, error) {
+func (s *eventService) GetEventById(ctx context.Context, id uint) (*domain.Event, error) {
 	log.Println("Getting event by id")
-	event, err := s.eventRepo.FindById(id)
+	event, err := s.eventRepo.FindById(ctx, id)
 	if err != nil {
 		return nil, err
 	}
@@ -80,9 +92,9 @@ func (s *eventService) GetEventById(id uint) (*domain.Event, error) {
 }
 
 // UpdateEvent cập nhật sự kiện
-func (s *eventService) UpdateEvent(event *domain.Event) error {
+func (s *eventService) UpdateEvent(ctx context.Context, event *domain.Event) error {
 	log.Println("Updating event")
-	err := s.eventRepo.Update(event)
+	err := s.eventRepo.Update(ctx, event)
 	if err != nil {
 		return err
 	}
@@ -91,9 +103,9 @@ func (s *eventService) UpdateEvent(event *domain.Event) error {
 }
 
 // DeleteEvent xóa sự kiện
-func (s *eventService) DeleteEvent(id uint) error {
+func (s *eventService) DeleteEvent(ctx context.Context, id uint) error {
 	log.Println("Deleting event")
-	err := s.eventRepo.Delete(id)
+	err := s.eventRepo.Delete(ctx, id)
 	if err != nil {
 		return err
 	}
@@ -101,12 +113,82 @@ func (s *eventService) DeleteEvent(id uint) error {
 	return nil 
 }
 
-func (s *eventService) GetEventsWithRemainingTickets(pagination middleware.Pagination) (middleware.PaginatedResponse, error) {
+func (s *eventService) GetEventsWithRemainingTickets(ctx context.Context, pagination middleware.Pagination) (middleware.PaginatedResponse, error) {
 	log.Println("Getting events with remaining tickets")
-	events, err := s.eventRepo.GetEventsWithRemainingTickets(pagination)
+	events, err := s.eventRepo.GetEventsWithRemainingTickets(ctx, pagination)
 	if err != nil {
 		return middleware.PaginatedResponse{}, err
 	}
 	log.Println("Events fetched successfully")
 	return events, nil
-}
\ No newline at end of file
+}
+
+// GetEventStats lấy thống kê vé đã bán và doanh thu của một event
+func (s *eventService) GetEventStats(ctx context.Context, id uint) (*domain.EventStats, error) {
+	log.Println("Getting event stats")
+	stats, err := s.eventRepo.GetEventStats(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	log.Println("Event stats fetched successfully")
+	return stats, nil
+}
+
+// GetAllEventStats lấy thống kê của tất cả event, có phân trang
+func (s *eventService) GetAllEventStats(ctx context.Context, pagination middleware.Pagination) (middleware.PaginatedResponse, error) {
+	log.Println("Getting stats for all events")
+	stats, err := s.eventRepo.GetAllEventStats(ctx, pagination)
+	if err != nil {
+		return middleware.PaginatedResponse{}, err
+	}
+	log.Println("Event stats fetched successfully")
+	return stats, nil
+}
+
+// ListTicketTypes lấy các hạng vé của event, rẻ nhất trước
+func (s *eventService) ListTicketTypes(ctx context.Context, eventID uint) ([]domain.TicketType, error) {
+	return s.ticketTypeRepo.FindByEvent(ctx, eventID)
+}
+
+func (s *eventService) GetTicketType(ctx context.Context, eventID uint, id uint) (*domain.TicketType, error) {
+	return s.ticketTypeRepo.FindById(ctx, eventID, id)
+}
+
+// CreateTicketType thêm hạng vé cho event; MinPerOrder mặc định là 1
+func (s *eventService) CreateTicketType(ctx context.Context, ticketType *domain.TicketType) error {
+	if ticketType.MinPerOrder == 0 {
+		ticketType.MinPerOrder = 1
+	}
+	if err := ticketType.Validate(); err != nil {
+		return err
+	}
+	if err := s.ticketTypeRepo.Create(ctx, ticketType); err != nil {
+		return err
+	}
+	log.Println("Ticket type created successfully")
+	return nil
+}
+
+// UpdateTicketType sửa hạng vé; quota không được nhỏ hơn số vé đã giữ
+func (s *eventService) UpdateTicketType(ctx context.Context, ticketType *domain.TicketType) error {
+	if ticketType.MinPerOrder == 0 {
+		ticketType.MinPerOrder = 1
+	}
+	if err := ticketType.Validate(); err != nil {
+		return err
+	}
+	if err := s.ticketTypeRepo.Update(ctx, ticketType); err != nil {
+		return err
+	}
+	log.Println("Ticket type updated successfully")
+	return nil
+}
+
+// DeleteTicketType xoá hạng vé chưa có booking nào
+func (s *eventService) DeleteTicketType(ctx context.Context, eventID uint, id uint) error {
+	if err := s.ticketTypeRepo.Delete(ctx, eventID, id); err != nil {
+		return err
+	}
+	log.Println("Ticket type deleted successfully")
+	return nil
+}
@@ -20,6 +20,8 @@ type HealthServiceImpl struct {
 	redisClient *redis.Client
 }
 
+// NewHealthService checks db, and Redis unless redisClient is nil (a deployment
+// that does not use Redis)
 func NewHealthService(db *gorm.DB, redisClient *redis.Client) HealthService {
 	return &HealthServiceImpl{
 		db:          db,
@@ -43,12 +45,14 @@ func (s *HealthServiceImpl) CheckHealth(ctx context.Context) (map[string]interfa
 	}
 
 	// Check Redis
-	redisCheck := s.checkRedis(ctx)
-	if redisCheck != nil {
-		health["status"] = "unhealthy"
-		health["details"].(map[string]interface{})["redis"] = redisCheck.Error()
-	} else {
-		health["details"].(map[string]interface{})["redis"] = "ok"
+	if s.redisClient != nil {
+		redisCheck := s.checkRedis(ctx)
+		if redisCheck != nil {
+			health["status"] = "unhealthy"
+			health["details"].(map[string]interface{})["redis"] = redisCheck.Error()
+		} else {
+			health["details"].(map[string]interface{})["redis"] = "ok"
+		}
 	}
 
 	if health["status"] == "unhealthy" {
@@ -63,7 +67,7 @@ func (s *HealthServiceImpl) checkDB(ctx context.Context) error {
 	defer cancel()
 
 	var ping int
-	if err := s.db.Raw("SELECT 1").Scan(&ping).Error; err != nil {
+	if err := s.db.WithContext(ctx).Raw("SELECT 1").Scan(&ping).Error; err != nil {
 		return err
 	}
 	return nil
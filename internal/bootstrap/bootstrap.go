@@ -0,0 +1,351 @@
+package bootstrap
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+
+	goredis "github.com/redis/go-redis/v9"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+
+	"ticket_app/auth"
+	"ticket_app/booking"
+	"ticket_app/domain"
+	"ticket_app/event"
+	"ticket_app/health"
+	"ticket_app/internal/config"
+	"ticket_app/internal/idempotency"
+	"ticket_app/internal/lifecycle"
+	"ticket_app/internal/mailer"
+	"ticket_app/internal/migration"
+	"ticket_app/internal/pgstore"
+	"ticket_app/internal/queue"
+	"ticket_app/internal/redis"
+	"ticket_app/internal/repository"
+	auditRepo "ticket_app/internal/repository/audit"
+	bookingRepo "ticket_app/internal/repository/booking"
+	eventRepo "ticket_app/internal/repository/event"
+	paymentRepo "ticket_app/internal/repository/payment"
+	"ticket_app/internal/repository/recoverycode"
+	"ticket_app/internal/repository/refreshtoken"
+	"ticket_app/internal/repository/tickettype"
+	userRepo "ticket_app/internal/repository/user"
+	"ticket_app/internal/repository/usertoken"
+	venueRepo "ticket_app/internal/repository/venue"
+	"ticket_app/internal/token"
+	"ticket_app/migrations"
+	"ticket_app/payment"
+	"ticket_app/venue"
+)
+
+// Container wires the application for one run mode. Every dependency is built
+// on first use and then reused, so `serve`, `worker`, `migrate` and `seed` only
+// connect to what they actually need. Redis is only opened on the redis queue
+// backend; on the postgres and memory backends the queue, the idempotency keys,
+// the token revocations and the login throttle all live in Postgres.
+//
+// Connections are registered with Lifecycle as they are opened; call Shutdown
+// once the command is done with them.
+type Container struct {
+	Config    *config.Config
+	Lifecycle *lifecycle.Manager
+
+	mu       sync.Mutex
+	db       *gorm.DB
+	redis    *redis.Redis
+	queue    *queue.QueueService
+	tokens   *token.Manager
+	mailer   mailer.Mailer
+	services *Services
+}
+
+// Services are the application services used by the HTTP API
+type Services struct {
+	Auth        auth.AuthService
+	Event       event.EventService
+	Payment     payment.PaymentService
+	Booking     booking.BookingService
+	Order       booking.OrderService
+	Waitlist    booking.WaitlistService
+	Venue       venue.VenueService
+	Health      health.HealthService
+	Queue       *queue.QueueService
+	Revocations token.RevocationStore
+	Idempotency idempotency.Store
+}
+
+func New(cfg *config.Config) *Container {
+	return &Container{Config: cfg, Lifecycle: lifecycle.NewManager()}
+}
+
+// Shutdown stops the workers started on Lifecycle and closes the connections
+func (c *Container) Shutdown(ctx context.Context) error {
+	return c.Lifecycle.Shutdown(ctx)
+}
+
+// DB connects to the Postgres database in Config.Postgres
+func (c *Container) DB() (*gorm.DB, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.dbLocked()
+}
+
+func (c *Container) dbLocked() (*gorm.DB, error) {
+	if c.db != nil {
+		return c.db, nil
+	}
+	db, err := gorm.Open(postgres.Open(c.Config.Postgres.DSN()), &gorm.Config{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get database pool: %w", err)
+	}
+	// Closers run in reverse order, so the pool is closed after Redis
+	c.Lifecycle.OnClose("database", sqlDB.Close)
+	c.db = db
+	return db, nil
+}
+
+// Redis connects to the Redis server in Config.Redis
+func (c *Container) Redis() (*redis.Redis, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.redisLocked()
+}
+
+func (c *Container) redisLocked() (*redis.Redis, error) {
+	if c.redis != nil {
+		return c.redis, nil
+	}
+	r, err := redis.NewRedis(c.Config.Redis)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize Redis: %w", err)
+	}
+	c.Lifecycle.OnClose("redis", r.Close)
+	c.redis = r
+	return r, nil
+}
+
+// CheckMigrations refuses to run against a schema with pending migrations
+func (c *Container) CheckMigrations(ctx context.Context) error {
+	db, err := c.DB()
+	if err != nil {
+		return err
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	migrator, err := migration.NewMigrator(sqlDB, migrations.FS)
+	if err != nil {
+		return err
+	}
+	pending, err := migrator.Pending(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to check migrations: %w", err)
+	}
+	if len(pending) > 0 {
+		return fmt.Errorf("database has %d pending migration(s), starting with %06d_%s; run `ticket_app migrate up`",
+			len(pending), pending[0].Version, pending[0].Name)
+	}
+	return nil
+}
+
+// Tokens builds the JWT key ring from Config.JWT
+func (c *Container) Tokens() (*token.Manager, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.tokensLocked()
+}
+
+func (c *Container) tokensLocked() (*token.Manager, error) {
+	if c.tokens != nil {
+		return c.tokens, nil
+	}
+	tokens, err := token.FromConfig(c.Config.JWT)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load JWT keys: %w", err)
+	}
+	c.tokens = tokens
+	return tokens, nil
+}
+
+// Mailer builds the mail backend in Config.Mail
+func (c *Container) Mailer() (mailer.Mailer, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.mailerLocked()
+}
+
+func (c *Container) mailerLocked() (mailer.Mailer, error) {
+	if c.mailer != nil {
+		return c.mailer, nil
+	}
+	m, err := mailer.FromConfig(c.Config.Mail)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize mailer: %w", err)
+	}
+	log.Printf("Using %s mail backend", c.Config.Mail.Backend)
+	c.mailer = m
+	return m, nil
+}
+
+// QueueService builds the payment queue on the backend in Config.Queue
+func (c *Container) QueueService() (*queue.QueueService, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.queueLocked()
+}
+
+func (c *Container) queueLocked() (*queue.QueueService, error) {
+	if c.queue != nil {
+		return c.queue, nil
+	}
+	backend, err := queue.ParseBackend(c.Config.Queue.Backend)
+	if err != nil {
+		return nil, fmt.Errorf("invalid QUEUE_BACKEND: %w", err)
+	}
+	db, err := c.dbLocked()
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("Using %s queue backend", backend)
+
+	var jobs, timeouts, holds, offers queue.JobQueue
+	var deadLetters queue.DeadLetterStore
+	switch backend {
+	case queue.BackendPostgres:
+		jobs = queue.NewPostgresJobQueue(db, queue.StreamName, queue.ClaimMinIdle)
+		timeouts = queue.NewPostgresJobQueue(db, queue.TimeoutStreamName, queue.ClaimMinIdle)
+		holds = queue.NewPostgresJobQueue(db, queue.HoldStreamName, queue.ClaimMinIdle)
+		offers = queue.NewPostgresJobQueue(db, queue.WaitlistStreamName, queue.ClaimMinIdle)
+		deadLetters = queue.NewPostgresDeadLetterStore(db, queue.StreamName)
+	case queue.BackendMemory:
+		log.Println("Memory queue backend keeps jobs in this process only, do not use it in production")
+		jobs = queue.NewMemoryJobQueue(queue.ClaimMinIdle)
+		timeouts = queue.NewMemoryJobQueue(queue.ClaimMinIdle)
+		holds = queue.NewMemoryJobQueue(queue.ClaimMinIdle)
+		offers = queue.NewMemoryJobQueue(queue.ClaimMinIdle)
+		deadLetters = queue.NewMemoryDeadLetterStore()
+	default:
+		r, err := c.redisLocked()
+		if err != nil {
+			return nil, err
+		}
+		jobs = queue.NewRedisJobQueue(r, queue.StreamName, queue.ConsumerGroup, queue.DelayedSetName)
+		timeouts = queue.NewRedisJobQueue(r, queue.TimeoutStreamName, queue.TimeoutConsumerGroup, queue.TimeoutSetName)
+		holds = queue.NewRedisJobQueue(r, queue.HoldStreamName, queue.HoldConsumerGroup, queue.HoldSetName)
+		offers = queue.NewRedisJobQueue(r, queue.WaitlistStreamName, queue.WaitlistConsumerGroup, queue.WaitlistSetName)
+		deadLetters = queue.NewRedisDeadLetterStore(r, queue.DeadLetterSetName)
+	}
+
+	qs := queue.NewQueueService(jobs, timeouts, holds, offers, deadLetters, repository.NewGormTxManager(db),
+		paymentRepo.NewGormPaymentRepository(db), bookingRepo.NewGormBookingRepository(db), eventRepo.NewGormEventRepository(db),
+		bookingRepo.NewGormHoldRepository(db), bookingRepo.NewGormOrderRepository(db), bookingRepo.NewGormWaitlistRepository(db))
+	qs.SetRetryPolicy(queue.RetryPolicy{
+		MaxAttempts: c.Config.Queue.MaxAttempts,
+		BaseDelay:   c.Config.Queue.BackoffBase,
+		MaxDelay:    c.Config.Queue.BackoffMax,
+		Jitter:      queue.DefaultRetryPolicy.Jitter,
+	})
+	qs.SetOfferTTL(c.Config.Booking.OfferTTL)
+	c.queue = qs
+	return qs, nil
+}
+
+// Services builds the application services behind the HTTP API
+func (c *Container) Services() (*Services, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.services != nil {
+		return c.services, nil
+	}
+	db, err := c.dbLocked()
+	if err != nil {
+		return nil, err
+	}
+	qs, err := c.queueLocked()
+	if err != nil {
+		return nil, err
+	}
+	tokens, err := c.tokensLocked()
+	if err != nil {
+		return nil, err
+	}
+	m, err := c.mailerLocked()
+	if err != nil {
+		return nil, err
+	}
+
+	// Same backend as the queue, so a deployment without Redis never needs it
+	backend, err := queue.ParseBackend(c.Config.Queue.Backend)
+	if err != nil {
+		return nil, fmt.Errorf("invalid QUEUE_BACKEND: %w", err)
+	}
+	var revocations token.RevocationStore
+	var throttle auth.LoginThrottle
+	var idempotencyStore idempotency.Store
+	var redisClient *goredis.Client
+	if backend == queue.BackendRedis {
+		r, err := c.redisLocked()
+		if err != nil {
+			return nil, err
+		}
+		revocations = redis.NewRevocationStore(r)
+		throttle = redis.NewLoginThrottle(r, c.Config.Login)
+		idempotencyStore = redis.NewIdempotencyStore(r)
+		redisClient = r.GetClient()
+	} else {
+		revocations = pgstore.NewRevocationStore(db)
+		throttle = pgstore.NewLoginThrottle(db, c.Config.Login)
+		idempotencyStore = pgstore.NewIdempotencyStore(db)
+	}
+
+	txManager := repository.NewGormTxManager(db)
+	paymentService := payment.NewPaymentService(paymentRepo.NewGormPaymentRepository(db))
+	bookingService := booking.NewBookingService(txManager, bookingRepo.NewGormBookingRepository(db), bookingRepo.NewGormHoldRepository(db),
+		userRepo.NewGormUserRepository(db), eventRepo.NewGormEventRepository(db), paymentService, qs, qs, qs, c.Config.Booking.HoldTTL)
+	orderService := booking.NewOrderService(txManager, bookingRepo.NewGormOrderRepository(db), bookingRepo.NewGormBookingRepository(db),
+		userRepo.NewGormUserRepository(db), eventRepo.NewGormEventRepository(db), paymentService, qs, qs)
+	waitlistService := booking.NewWaitlistService(bookingRepo.NewGormWaitlistRepository(db), bookingRepo.NewGormHoldRepository(db),
+		userRepo.NewGormUserRepository(db), eventRepo.NewGormEventRepository(db), qs)
+	c.services = &Services{
+		Auth: auth.NewAuthService(userRepo.NewGormUserRepository(db), refreshtoken.NewGormRefreshTokenRepository(db),
+			usertoken.NewGormUserTokenRepository(db), recoverycode.NewGormRecoveryCodeRepository(db), auditRepo.NewGormAuditRepository(db),
+			txManager, tokens, revocations,
+			throttle, m, auth.Config{
+				TokenTTL:         c.Config.JWT.TokenTTL,
+				RefreshTTL:       c.Config.JWT.RefreshTTL,
+				VerificationTTL:  c.Config.Mail.VerificationTTL,
+				ResetTTL:         c.Config.Mail.ResetTTL,
+				UnlockTTL:        c.Config.Login.Lockout,
+				MFAChallengeTTL:  c.Config.MFA.ChallengeTTL,
+				MFAIssuer:        c.Config.MFA.Issuer,
+				MFARequiredRoles: mfaRoles(c.Config.MFA.RequiredRoles),
+				BaseURL:          c.Config.Mail.BaseURL,
+			}),
+		Event:       event.NewEventService(eventRepo.NewGormEventRepository(db), tickettype.NewGormTicketTypeRepository(db)),
+		Payment:     paymentService,
+		Booking:     bookingService,
+		Order:       orderService,
+		Waitlist:    waitlistService,
+		Venue:       venue.NewVenueService(venueRepo.NewGormVenueRepository(db), eventRepo.NewGormEventRepository(db)),
+		Health:      health.NewHealthService(db, redisClient),
+		Queue:       qs,
+		Revocations: revocations,
+		Idempotency: idempotencyStore,
+	}
+	return c.services, nil
+}
+
+func mfaRoles(names []string) []domain.Role {
+	roles := make([]domain.Role, len(names))
+	for i, name := range names {
+		roles[i] = domain.Role(name)
+	}
+	return roles
+}
@@ -0,0 +1,22 @@
+package bootstrap_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"ticket_app/internal/bootstrap"
+	"ticket_app/internal/config"
+)
+
+func TestQueueServiceRejectsUnknownBackendBeforeConnecting(t *testing.T) {
+	c := bootstrap.New(&config.Config{Queue: config.QueueConfig{Backend: "kafka"}})
+
+	_, err := c.QueueService()
+	assert.ErrorContains(t, err, "invalid QUEUE_BACKEND")
+
+	// Nothing was opened, so there is nothing to close
+	require.NoError(t, c.Shutdown(context.Background()))
+}
@@ -0,0 +1,640 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
+)
+
+// Config is the whole application configuration, resolved once at startup
+type Config struct {
+	Server   ServerConfig
+	Postgres PostgresConfig
+	Redis    RedisConfig
+	JWT      JWTConfig
+	Queue    QueueConfig
+	Mail     MailConfig
+	Login    LoginConfig
+	MFA      MFAConfig
+	Booking  BookingConfig
+
+	// values holds the resolved raw value of every key, for String
+	values map[string]string
+}
+
+type ServerConfig struct {
+	Address         string
+	RequestTimeout  time.Duration
+	ShutdownTimeout time.Duration
+	// TrustedProxies are the IPs or CIDRs of reverse proxies whose
+	// X-Forwarded-For header gives the client IP
+	TrustedProxies []string
+}
+
+type PostgresConfig struct {
+	Host     string
+	Port     int
+	User     string
+	Password string
+	DB       string
+	SSLMode  string
+}
+
+type RedisConfig struct {
+	Host     string
+	Port     int
+	Password string
+	DB       int
+}
+
+type JWTConfig struct {
+	// Secret signs HS256 tokens under the key ID KeyID
+	Secret string
+	KeyID  string
+	// PreviousKeys are retired HS256 secrets by key ID, still accepted for verification
+	PreviousKeys map[string]string
+	// KeysFile replaces the three settings above with a YAML/JSON key file,
+	// which may also hold RS256 and EdDSA keys
+	KeysFile   string
+	TokenTTL   time.Duration
+	RefreshTTL time.Duration
+}
+
+type QueueConfig struct {
+	Backend     string
+	MaxAttempts int
+	BackoffBase time.Duration
+	BackoffMax  time.Duration
+}
+
+type MailConfig struct {
+	// Backend is smtp, file (one .eml per message in Dir) or log
+	Backend string
+	From    string
+	Dir     string
+	SMTP    SMTPConfig
+	// BaseURL is the frontend that serves the /verify-email and /reset-password pages linked from mails
+	BaseURL         string
+	VerificationTTL time.Duration
+	ResetTTL        time.Duration
+}
+
+// LoginConfig limits password guessing on /login
+type LoginConfig struct {
+	// MaxFailures failed logins for one email within FailureWindow lock it for Lockout
+	MaxFailures   int
+	FailureWindow time.Duration
+	Lockout       time.Duration
+	// IPMaxFailures failed logins from one IP within FailureWindow block that IP until the window ends
+	IPMaxFailures int
+	// After each failure the email waits DelayBase, doubled per further failure up to DelayMax
+	DelayBase time.Duration
+	DelayMax  time.Duration
+}
+
+type MFAConfig struct {
+	// Issuer is the account name shown by authenticator apps
+	Issuer string
+	// RequiredRoles only get their role in access tokens after a TOTP login
+	RequiredRoles []string
+	// ChallengeTTL is how long the token between the password and the TOTP step lives
+	ChallengeTTL time.Duration
+}
+
+type BookingConfig struct {
+	// HoldTTL is how long a hold keeps its tickets, for events without their own hold_minutes
+	HoldTTL time.Duration
+	// OfferTTL is how long a waitlist offer keeps its tickets before it passes to the next user
+	OfferTTL time.Duration
+}
+
+type SMTPConfig struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+}
+
+// FailureDelay is how long an email waits after its nth failed login:
+// DelayBase after the first, doubled for each further one, at most DelayMax
+func (l LoginConfig) FailureDelay(failures int) time.Duration {
+	d := l.DelayBase
+	for i := 1; i < failures && d < l.DelayMax; i++ {
+		d *= 2
+	}
+	return min(d, l.DelayMax)
+}
+
+// DSN returns the key=value connection string used by the Gorm postgres driver
+func (p PostgresConfig) DSN() string {
+	return fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
+		quoteDSN(p.Host), p.Port, quoteDSN(p.User), quoteDSN(p.Password), quoteDSN(p.DB), p.SSLMode)
+}
+
+// URL returns the postgres:// form of the connection string, used by pgx
+func (p PostgresConfig) URL() string {
+	u := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(p.User, p.Password),
+		Host:     net.JoinHostPort(p.Host, strconv.Itoa(p.Port)),
+		Path:     "/" + p.DB,
+		RawQuery: "sslmode=" + url.QueryEscape(p.SSLMode),
+	}
+	return u.String()
+}
+
+// URL returns the redis:// connection string
+func (r RedisConfig) URL() string {
+	u := url.URL{
+		Scheme: "redis",
+		Host:   net.JoinHostPort(r.Host, strconv.Itoa(r.Port)),
+		Path:   "/" + strconv.Itoa(r.DB),
+	}
+	if r.Password != "" {
+		u.User = url.UserPassword("", r.Password)
+	}
+	return u.String()
+}
+
+// quoteDSN quotes a value of a key=value DSN when it is empty or contains
+// spaces, quotes or backslashes
+func quoteDSN(v string) string {
+	if v != "" && !strings.ContainsAny(v, ` '\`) {
+		return v
+	}
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(v) + "'"
+}
+
+// field describes one configuration key. Durations given as a plain number
+// are read in unit; anything else must be a Go duration such as "1m30s".
+type field struct {
+	key    string
+	def    string
+	secret bool
+	set    func(c *Config, v string) error
+}
+
+var fields = []field{
+	{key: "SERVER_ADDRESS", def: ":9090", set: func(c *Config, v string) error {
+		c.Server.Address = v
+		return required(v)
+	}},
+	{key: "CONTEXT_TIMEOUT", def: "30", set: func(c *Config, v string) (err error) {
+		c.Server.RequestTimeout, err = positiveDuration(v, time.Second)
+		return
+	}},
+	{key: "SHUTDOWN_TIMEOUT", def: "30", set: func(c *Config, v string) (err error) {
+		c.Server.ShutdownTimeout, err = positiveDuration(v, time.Second)
+		return
+	}},
+	{key: "SERVER_TRUSTED_PROXIES", set: func(c *Config, v string) error {
+		c.Server.TrustedProxies = nil
+		for _, p := range strings.Split(v, ",") {
+			if p = strings.TrimSpace(p); p == "" {
+				continue
+			}
+			if net.ParseIP(p) == nil {
+				if _, _, err := net.ParseCIDR(p); err != nil {
+					return fmt.Errorf("must be a list of IPs or CIDRs, got %q", p)
+				}
+			}
+			c.Server.TrustedProxies = append(c.Server.TrustedProxies, p)
+		}
+		return nil
+	}},
+
+	{key: "POSTGRES_HOST", def: "localhost", set: func(c *Config, v string) error {
+		c.Postgres.Host = v
+		return required(v)
+	}},
+	{key: "POSTGRES_PORT", def: "5432", set: func(c *Config, v string) (err error) {
+		c.Postgres.Port, err = port(v)
+		return
+	}},
+	{key: "POSTGRES_USER", def: "postgres", set: func(c *Config, v string) error {
+		c.Postgres.User = v
+		return required(v)
+	}},
+	{key: "POSTGRES_PASSWORD", secret: true, set: func(c *Config, v string) error {
+		c.Postgres.Password = v
+		return nil
+	}},
+	{key: "POSTGRES_DB", def: "postgres", set: func(c *Config, v string) error {
+		c.Postgres.DB = v
+		return required(v)
+	}},
+	{key: "POSTGRES_SSLMODE", def: "disable", set: func(c *Config, v string) error {
+		c.Postgres.SSLMode = v
+		return oneOf(v, "disable", "allow", "prefer", "require", "verify-ca", "verify-full")
+	}},
+
+	{key: "REDIS_HOST", def: "localhost", set: func(c *Config, v string) error {
+		c.Redis.Host = v
+		return required(v)
+	}},
+	{key: "REDIS_PORT", def: "6379", set: func(c *Config, v string) (err error) {
+		c.Redis.Port, err = port(v)
+		return
+	}},
+	{key: "REDIS_PASSWORD", secret: true, set: func(c *Config, v string) error {
+		c.Redis.Password = v
+		return nil
+	}},
+	{key: "REDIS_DB", def: "0", set: func(c *Config, v string) error {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return errors.New("must be a non-negative integer")
+		}
+		c.Redis.DB = n
+		return nil
+	}},
+
+	{key: "JWT_SECRET", secret: true, set: func(c *Config, v string) error {
+		c.JWT.Secret = v
+		return nil
+	}},
+	{key: "JWT_KEY_ID", def: "primary", set: func(c *Config, v string) error {
+		c.JWT.KeyID = v
+		return required(v)
+	}},
+	{key: "JWT_PREVIOUS_KEYS", secret: true, set: func(c *Config, v string) (err error) {
+		c.JWT.PreviousKeys, err = keyList(v)
+		return
+	}},
+	{key: "JWT_KEYS_FILE", set: func(c *Config, v string) error {
+		c.JWT.KeysFile = v
+		return nil
+	}},
+	{key: "JWT_EXPIRATION_TIME", def: "60", set: func(c *Config, v string) (err error) {
+		c.JWT.TokenTTL, err = positiveDuration(v, time.Minute)
+		return
+	}},
+	{key: "JWT_REFRESH_EXPIRATION_TIME", def: "720h", set: func(c *Config, v string) (err error) {
+		c.JWT.RefreshTTL, err = positiveDuration(v, time.Minute)
+		return
+	}},
+
+	{key: "MAIL_BACKEND", def: "file", set: func(c *Config, v string) error {
+		c.Mail.Backend = strings.ToLower(v)
+		return oneOf(c.Mail.Backend, "smtp", "file", "log")
+	}},
+	{key: "MAIL_FROM", def: "Ticket App <no-reply@localhost>", set: func(c *Config, v string) error {
+		c.Mail.From = v
+		return required(v)
+	}},
+	{key: "MAIL_DIR", def: "mail", set: func(c *Config, v string) error {
+		c.Mail.Dir = v
+		return nil
+	}},
+	{key: "SMTP_HOST", set: func(c *Config, v string) error {
+		c.Mail.SMTP.Host = v
+		return nil
+	}},
+	{key: "SMTP_PORT", def: "587", set: func(c *Config, v string) (err error) {
+		c.Mail.SMTP.Port, err = port(v)
+		return
+	}},
+	{key: "SMTP_USERNAME", set: func(c *Config, v string) error {
+		c.Mail.SMTP.Username = v
+		return nil
+	}},
+	{key: "SMTP_PASSWORD", secret: true, set: func(c *Config, v string) error {
+		c.Mail.SMTP.Password = v
+		return nil
+	}},
+	{key: "APP_BASE_URL", def: "http://localhost:3000", set: func(c *Config, v string) error {
+		c.Mail.BaseURL = strings.TrimRight(v, "/")
+		u, err := url.Parse(v)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("must be an http(s) URL, got %q", v)
+		}
+		return nil
+	}},
+	{key: "EMAIL_VERIFICATION_TTL", def: "24h", set: func(c *Config, v string) (err error) {
+		c.Mail.VerificationTTL, err = positiveDuration(v, time.Minute)
+		return
+	}},
+	{key: "PASSWORD_RESET_TTL", def: "1h", set: func(c *Config, v string) (err error) {
+		c.Mail.ResetTTL, err = positiveDuration(v, time.Minute)
+		return
+	}},
+
+	{key: "LOGIN_MAX_FAILURES", def: "5", set: func(c *Config, v string) (err error) {
+		c.Login.MaxFailures, err = positiveInt(v)
+		return
+	}},
+	{key: "LOGIN_FAILURE_WINDOW", def: "15m", set: func(c *Config, v string) (err error) {
+		c.Login.FailureWindow, err = positiveDuration(v, time.Minute)
+		return
+	}},
+	{key: "LOGIN_LOCKOUT_DURATION", def: "15m", set: func(c *Config, v string) (err error) {
+		c.Login.Lockout, err = positiveDuration(v, time.Minute)
+		return
+	}},
+	{key: "LOGIN_IP_MAX_FAILURES", def: "50", set: func(c *Config, v string) (err error) {
+		c.Login.IPMaxFailures, err = positiveInt(v)
+		return
+	}},
+	{key: "LOGIN_DELAY_BASE", def: "1s", set: func(c *Config, v string) (err error) {
+		c.Login.DelayBase, err = positiveDuration(v, time.Second)
+		return
+	}},
+	{key: "LOGIN_DELAY_MAX", def: "30s", set: func(c *Config, v string) (err error) {
+		c.Login.DelayMax, err = positiveDuration(v, time.Second)
+		return
+	}},
+
+	{key: "MFA_ISSUER", def: "Ticket App", set: func(c *Config, v string) error {
+		c.MFA.Issuer = v
+		return required(v)
+	}},
+	{key: "MFA_REQUIRED_ROLES", def: "organizer,admin", set: func(c *Config, v string) error {
+		c.MFA.RequiredRoles = nil
+		for _, role := range strings.Split(v, ",") {
+			if role = strings.ToLower(strings.TrimSpace(role)); role == "" {
+				continue
+			}
+			if err := oneOf(role, "customer", "organizer", "admin"); err != nil {
+				return err
+			}
+			c.MFA.RequiredRoles = append(c.MFA.RequiredRoles, role)
+		}
+		return nil
+	}},
+	{key: "MFA_CHALLENGE_TTL", def: "5m", set: func(c *Config, v string) (err error) {
+		c.MFA.ChallengeTTL, err = positiveDuration(v, time.Minute)
+		return
+	}},
+
+	{key: "HOLD_TTL", def: "10m", set: func(c *Config, v string) (err error) {
+		c.Booking.HoldTTL, err = positiveDuration(v, time.Minute)
+		return
+	}},
+	{key: "WAITLIST_OFFER_TTL", def: "30m", set: func(c *Config, v string) (err error) {
+		c.Booking.OfferTTL, err = positiveDuration(v, time.Minute)
+		return
+	}},
+
+	{key: "QUEUE_BACKEND", def: "redis", set: func(c *Config, v string) error {
+		c.Queue.Backend = strings.ToLower(v)
+		return oneOf(c.Queue.Backend, "redis", "postgres", "memory")
+	}},
+	{key: "PAYMENT_JOB_MAX_ATTEMPTS", def: "5", set: func(c *Config, v string) (err error) {
+		c.Queue.MaxAttempts, err = positiveInt(v)
+		return
+	}},
+	{key: "PAYMENT_JOB_BACKOFF_BASE", def: "2s", set: func(c *Config, v string) (err error) {
+		c.Queue.BackoffBase, err = positiveDuration(v, time.Second)
+		return
+	}},
+	{key: "PAYMENT_JOB_BACKOFF_MAX", def: "1m", set: func(c *Config, v string) (err error) {
+		c.Queue.BackoffMax, err = positiveDuration(v, time.Second)
+		return
+	}},
+}
+
+// Options tells Load where to read the configuration from
+type Options struct {
+	// EnvFile is an optional dotenv file, ".env" when empty. A missing file is not an error.
+	EnvFile string
+	// ConfigFile is an optional YAML or JSON file, CONFIG_FILE when empty
+	ConfigFile string
+	// LookupEnv reads the process environment, os.LookupEnv when nil
+	LookupEnv func(key string) (string, bool)
+}
+
+// Load resolves every key from, in order of precedence, the environment, the
+// .env file, the file named by CONFIG_FILE and the defaults
+func Load() (*Config, error) {
+	return LoadWith(Options{})
+}
+
+// LoadWith is Load with explicit sources. When some keys are invalid it still
+// returns the Config, along with a *ValidationError listing all of them.
+func LoadWith(opts Options) (*Config, error) {
+	if opts.LookupEnv == nil {
+		opts.LookupEnv = os.LookupEnv
+	}
+	if opts.EnvFile == "" {
+		opts.EnvFile = ".env"
+	}
+	if opts.ConfigFile == "" {
+		opts.ConfigFile, _ = opts.LookupEnv("CONFIG_FILE")
+	}
+
+	var problems []string
+	dotenv, err := godotenv.Read(opts.EnvFile)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("read %s: %w", opts.EnvFile, err)
+	}
+	fromFile := map[string]string{}
+	if opts.ConfigFile != "" {
+		if fromFile, err = readFile(opts.ConfigFile); err != nil {
+			return nil, err
+		}
+		for key := range fromFile {
+			if !known(key) {
+				problems = append(problems, fmt.Sprintf("%s: unknown key in %s", key, opts.ConfigFile))
+			}
+		}
+	}
+
+	cfg := &Config{values: map[string]string{}}
+	for _, f := range fields {
+		v, ok := opts.LookupEnv(f.key)
+		if !ok {
+			v, ok = dotenv[f.key]
+		}
+		if !ok {
+			v, ok = fromFile[f.key]
+		}
+		if !ok {
+			v = f.def
+		}
+		v = strings.TrimSpace(v)
+		cfg.values[f.key] = v
+		if err := f.set(cfg, v); err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", f.key, err))
+		}
+	}
+	if cfg.JWT.KeysFile == "" && cfg.JWT.Secret == "" {
+		problems = append(problems, "JWT_SECRET: is required unless JWT_KEYS_FILE is set")
+	}
+	if _, ok := cfg.JWT.PreviousKeys[cfg.JWT.KeyID]; ok {
+		problems = append(problems, "JWT_PREVIOUS_KEYS: must not reuse JWT_KEY_ID "+cfg.JWT.KeyID)
+	}
+	if cfg.Mail.Backend == "smtp" && cfg.Mail.SMTP.Host == "" {
+		problems = append(problems, "SMTP_HOST: is required when MAIL_BACKEND is smtp")
+	}
+	if cfg.Mail.Backend == "file" && cfg.Mail.Dir == "" {
+		problems = append(problems, "MAIL_DIR: is required when MAIL_BACKEND is file")
+	}
+	if cfg.Login.DelayBase > 0 && cfg.Login.DelayMax > 0 && cfg.Login.DelayMax < cfg.Login.DelayBase {
+		problems = append(problems, "LOGIN_DELAY_MAX: must not be less than LOGIN_DELAY_BASE")
+	}
+	if cfg.Queue.BackoffBase > 0 && cfg.Queue.BackoffMax > 0 && cfg.Queue.BackoffMax < cfg.Queue.BackoffBase {
+		problems = append(problems, "PAYMENT_JOB_BACKOFF_MAX: must not be less than PAYMENT_JOB_BACKOFF_BASE")
+	}
+
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return cfg, &ValidationError{Problems: problems}
+	}
+	return cfg, nil
+}
+
+// ValidationError lists every invalid key, so they can all be fixed at once
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "invalid configuration:\n  " + strings.Join(e.Problems, "\n  ")
+}
+
+// String prints the resolved configuration as KEY=value lines, with secrets redacted
+func (c *Config) String() string {
+	var b strings.Builder
+	for _, f := range fields {
+		v := c.values[f.key]
+		if f.secret && v != "" {
+			v = "******"
+		}
+		fmt.Fprintf(&b, "%s=%s\n", f.key, v)
+	}
+	return b.String()
+}
+
+// readFile reads a YAML or JSON file. Nested sections are joined to the flat
+// key names, so {"postgres": {"host": "db"}} sets POSTGRES_HOST.
+func readFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read config file: %w", err)
+	}
+	var tree map[string]interface{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &tree)
+	case ".json":
+		err = json.Unmarshal(data, &tree)
+	default:
+		return nil, fmt.Errorf("config file %s: unsupported format, use .yaml, .yml or .json", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parse config file %s: %w", path, err)
+	}
+	values := map[string]string{}
+	flatten("", tree, values)
+	return values, nil
+}
+
+func flatten(prefix string, tree map[string]interface{}, out map[string]string) {
+	for k, v := range tree {
+		key := strings.ToUpper(k)
+		if prefix != "" {
+			key = prefix + "_" + key
+		}
+		if sub, ok := v.(map[string]interface{}); ok {
+			flatten(key, sub, out)
+			continue
+		}
+		if v == nil {
+			out[key] = ""
+			continue
+		}
+		out[key] = fmt.Sprint(v)
+	}
+}
+
+func known(key string) bool {
+	for _, f := range fields {
+		if f.key == key {
+			return true
+		}
+	}
+	return false
+}
+
+func required(v string) error {
+	if v == "" {
+		return errors.New("is required")
+	}
+	return nil
+}
+
+func oneOf(v string, allowed ...string) error {
+	for _, a := range allowed {
+		if v == a {
+			return nil
+		}
+	}
+	return fmt.Errorf("must be one of %s, got %q", strings.Join(allowed, ", "), v)
+}
+
+// keyList reads "kid1=secret1,kid2=secret2"
+func keyList(v string) (map[string]string, error) {
+	keys := map[string]string{}
+	if v == "" {
+		return keys, nil
+	}
+	for _, pair := range strings.Split(v, ",") {
+		kid, secret, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok || kid == "" || secret == "" {
+			return nil, errors.New("must be a comma separated list of kid=secret")
+		}
+		if _, dup := keys[kid]; dup {
+			return nil, fmt.Errorf("key id %q is listed twice", kid)
+		}
+		keys[kid] = secret
+	}
+	return keys, nil
+}
+
+func port(v string) (int, error) {
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 1 || n > 65535 {
+		return 0, fmt.Errorf("must be a port number, got %q", v)
+	}
+	return n, nil
+}
+
+func positiveInt(v string) (int, error) {
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 1 {
+		return 0, errors.New("must be a positive integer")
+	}
+	return n, nil
+}
+
+// positiveDuration reads a plain number in unit, or a Go duration
+func positiveDuration(v string, unit time.Duration) (time.Duration, error) {
+	var d time.Duration
+	if n, err := strconv.Atoi(v); err == nil {
+		d = time.Duration(n) * unit
+	} else if d, err = time.ParseDuration(v); err != nil {
+		return 0, fmt.Errorf("must be a number of %s or a duration such as 1m30s, got %q", unitName(unit), v)
+	}
+	if d <= 0 {
+		return 0, errors.New("must be positive")
+	}
+	return d, nil
+}
+
+func unitName(unit time.Duration) string {
+	if unit == time.Minute {
+		return "minutes"
+	}
+	return "seconds"
+}
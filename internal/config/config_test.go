@@ -0,0 +1,248 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"ticket_app/internal/config"
+)
+
+func lookup(env map[string]string) func(string) (string, bool) {
+	return func(key string) (string, bool) {
+		v, ok := env[key]
+		return v, ok
+	}
+}
+
+func writeFile(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	return path
+}
+
+func TestLoadDefaults(t *testing.T) {
+	cfg, err := config.LoadWith(config.Options{
+		EnvFile:   filepath.Join(t.TempDir(), "missing.env"),
+		LookupEnv: lookup(map[string]string{"JWT_SECRET": "s3cret"}),
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, ":9090", cfg.Server.Address)
+	assert.Equal(t, 30*time.Second, cfg.Server.RequestTimeout)
+	assert.Equal(t, 5432, cfg.Postgres.Port)
+	assert.Equal(t, "redis", cfg.Queue.Backend)
+	assert.Equal(t, 60*time.Minute, cfg.JWT.TokenTTL)
+	assert.Equal(t, 5, cfg.Queue.MaxAttempts)
+	assert.Equal(t, time.Minute, cfg.Queue.BackoffMax)
+	assert.Equal(t, 10*time.Minute, cfg.Booking.HoldTTL)
+	assert.Equal(t, 30*time.Minute, cfg.Booking.OfferTTL)
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	file := writeFile(t, "config.yaml", `
+server:
+  address: ":8000"
+postgres:
+  host: file-db
+  port: 6000
+  user: file-user
+jwt:
+  secret: from-file
+`)
+	envFile := writeFile(t, ".env", "POSTGRES_HOST=dotenv-db\nPOSTGRES_USER=dotenv-user\nDEBUG=True\n")
+
+	cfg, err := config.LoadWith(config.Options{
+		EnvFile:    envFile,
+		ConfigFile: file,
+		LookupEnv:  lookup(map[string]string{"POSTGRES_HOST": "env-db"}),
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "env-db", cfg.Postgres.Host)      // environment wins
+	assert.Equal(t, "dotenv-user", cfg.Postgres.User) // then .env
+	assert.Equal(t, 6000, cfg.Postgres.Port)          // then the config file
+	assert.Equal(t, ":8000", cfg.Server.Address)
+	assert.Equal(t, "from-file", cfg.JWT.Secret)
+	assert.Equal(t, "postgres", cfg.Postgres.DB) // then the default
+}
+
+func TestLoadJSONFileWithFlatKeys(t *testing.T) {
+	file := writeFile(t, "config.json", `{"JWT_SECRET": "x", "QUEUE_BACKEND": "postgres", "payment_job": {"backoff_base": "500ms"}}`)
+
+	cfg, err := config.LoadWith(config.Options{
+		EnvFile:    filepath.Join(t.TempDir(), "missing.env"),
+		ConfigFile: file,
+		LookupEnv:  lookup(nil),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "postgres", cfg.Queue.Backend)
+	assert.Equal(t, 500*time.Millisecond, cfg.Queue.BackoffBase)
+}
+
+func TestLoadListsEveryInvalidKey(t *testing.T) {
+	file := writeFile(t, "config.yaml", "postgres:\n  hostname: db\n")
+
+	cfg, err := config.LoadWith(config.Options{
+		EnvFile:    filepath.Join(t.TempDir(), "missing.env"),
+		ConfigFile: file,
+		LookupEnv: lookup(map[string]string{
+			"POSTGRES_PORT":            "abc",
+			"QUEUE_BACKEND":            "kafka",
+			"CONTEXT_TIMEOUT":          "-1",
+			"PAYMENT_JOB_BACKOFF_BASE": "2m",
+		}),
+	})
+	require.NotNil(t, cfg)
+	var invalid *config.ValidationError
+	require.ErrorAs(t, err, &invalid)
+	assert.Len(t, invalid.Problems, 6)
+	for _, key := range []string{"CONTEXT_TIMEOUT", "JWT_SECRET", "PAYMENT_JOB_BACKOFF_MAX", "POSTGRES_HOSTNAME", "POSTGRES_PORT", "QUEUE_BACKEND"} {
+		assert.Contains(t, err.Error(), key)
+	}
+}
+
+func TestStringRedactsSecrets(t *testing.T) {
+	cfg, err := config.LoadWith(config.Options{
+		EnvFile: filepath.Join(t.TempDir(), "missing.env"),
+		LookupEnv: lookup(map[string]string{
+			"JWT_SECRET":        "jwt-secret-value",
+			"POSTGRES_PASSWORD": "pg-secret-value",
+		}),
+	})
+	require.NoError(t, err)
+
+	out := cfg.String()
+	assert.Contains(t, out, "POSTGRES_HOST=localhost\n")
+	assert.Contains(t, out, "JWT_SECRET=******\n")
+	assert.Contains(t, out, "REDIS_PASSWORD=\n")
+	assert.NotContains(t, out, "secret-value")
+}
+
+func TestConnectionStrings(t *testing.T) {
+	pg := config.PostgresConfig{Host: "db", Port: 5432, User: "admin", Password: "p@ss word", DB: "ticket_box", SSLMode: "disable"}
+	assert.Equal(t, "host=db port=5432 user=admin password='p@ss word' dbname=ticket_box sslmode=disable", pg.DSN())
+	assert.Equal(t, "postgres://admin:p%40ss%20word@db:5432/ticket_box?sslmode=disable", pg.URL())
+
+	r := config.RedisConfig{Host: "cache", Port: 6379, Password: "admin", DB: 2}
+	assert.Equal(t, "redis://:admin@cache:6379/2", r.URL())
+}
+
+func TestLoadJWTKeys(t *testing.T) {
+	missing := filepath.Join(t.TempDir(), "missing.env")
+
+	cfg, err := config.LoadWith(config.Options{EnvFile: missing, LookupEnv: lookup(map[string]string{
+		"JWT_SECRET":        "new",
+		"JWT_KEY_ID":        "2025-02",
+		"JWT_PREVIOUS_KEYS": "2025-01=old, 2024-12=older",
+	})})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"2025-01": "old", "2024-12": "older"}, cfg.JWT.PreviousKeys)
+	assert.Contains(t, cfg.String(), "JWT_PREVIOUS_KEYS=******\n")
+
+	// A key file replaces JWT_SECRET
+	_, err = config.LoadWith(config.Options{EnvFile: missing, LookupEnv: lookup(map[string]string{"JWT_KEYS_FILE": "keys.yaml"})})
+	assert.NoError(t, err)
+
+	_, err = config.LoadWith(config.Options{EnvFile: missing, LookupEnv: lookup(map[string]string{
+		"JWT_SECRET":        "new",
+		"JWT_PREVIOUS_KEYS": "primary=old,broken",
+	})})
+	assert.ErrorContains(t, err, "JWT_PREVIOUS_KEYS: must be a comma separated list")
+}
+
+func TestLoadMail(t *testing.T) {
+	missing := filepath.Join(t.TempDir(), "missing.env")
+
+	cfg, err := config.LoadWith(config.Options{EnvFile: missing, LookupEnv: lookup(map[string]string{"JWT_SECRET": "s"})})
+	require.NoError(t, err)
+	assert.Equal(t, "file", cfg.Mail.Backend)
+	assert.Equal(t, 24*time.Hour, cfg.Mail.VerificationTTL)
+	assert.Equal(t, time.Hour, cfg.Mail.ResetTTL)
+
+	cfg, err = config.LoadWith(config.Options{EnvFile: missing, LookupEnv: lookup(map[string]string{
+		"JWT_SECRET":    "s",
+		"MAIL_BACKEND":  "SMTP",
+		"SMTP_HOST":     "smtp.example.com",
+		"SMTP_PASSWORD": "hunter2",
+		"APP_BASE_URL":  "https://tickets.example.com/",
+	})})
+	require.NoError(t, err)
+	assert.Equal(t, "smtp", cfg.Mail.Backend)
+	assert.Equal(t, 587, cfg.Mail.SMTP.Port)
+	assert.Equal(t, "https://tickets.example.com", cfg.Mail.BaseURL)
+	assert.Contains(t, cfg.String(), "SMTP_PASSWORD=******\n")
+
+	_, err = config.LoadWith(config.Options{EnvFile: missing, LookupEnv: lookup(map[string]string{
+		"JWT_SECRET":   "s",
+		"MAIL_BACKEND": "smtp",
+		"APP_BASE_URL": "tickets.example.com",
+	})})
+	var verr *config.ValidationError
+	require.ErrorAs(t, err, &verr)
+	assert.Contains(t, verr.Problems, "SMTP_HOST: is required when MAIL_BACKEND is smtp")
+	assert.Contains(t, verr.Problems, `APP_BASE_URL: must be an http(s) URL, got "tickets.example.com"`)
+}
+
+func TestLoadLogin(t *testing.T) {
+	missing := filepath.Join(t.TempDir(), "missing.env")
+
+	cfg, err := config.LoadWith(config.Options{EnvFile: missing, LookupEnv: lookup(map[string]string{"JWT_SECRET": "s"})})
+	require.NoError(t, err)
+	assert.Equal(t, config.LoginConfig{
+		MaxFailures:   5,
+		FailureWindow: 15 * time.Minute,
+		Lockout:       15 * time.Minute,
+		IPMaxFailures: 50,
+		DelayBase:     time.Second,
+		DelayMax:      30 * time.Second,
+	}, cfg.Login)
+
+	_, err = config.LoadWith(config.Options{EnvFile: missing, LookupEnv: lookup(map[string]string{
+		"JWT_SECRET":             "s",
+		"LOGIN_MAX_FAILURES":     "0",
+		"LOGIN_DELAY_BASE":       "1m",
+		"LOGIN_DELAY_MAX":        "10",
+		"SERVER_TRUSTED_PROXIES": "10.0.0.1, proxy.local",
+	})})
+	var verr *config.ValidationError
+	require.ErrorAs(t, err, &verr)
+	assert.Contains(t, verr.Problems, "LOGIN_MAX_FAILURES: must be a positive integer")
+	assert.Contains(t, verr.Problems, "LOGIN_DELAY_MAX: must not be less than LOGIN_DELAY_BASE")
+	assert.Contains(t, verr.Problems, `SERVER_TRUSTED_PROXIES: must be a list of IPs or CIDRs, got "proxy.local"`)
+
+	cfg, err = config.LoadWith(config.Options{EnvFile: missing, LookupEnv: lookup(map[string]string{
+		"JWT_SECRET":             "s",
+		"SERVER_TRUSTED_PROXIES": "10.0.0.1, 172.16.0.0/12,",
+	})})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"10.0.0.1", "172.16.0.0/12"}, cfg.Server.TrustedProxies)
+}
+
+func TestLoadMFA(t *testing.T) {
+	missing := filepath.Join(t.TempDir(), "missing.env")
+
+	cfg, err := config.LoadWith(config.Options{EnvFile: missing, LookupEnv: lookup(map[string]string{"JWT_SECRET": "s"})})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"organizer", "admin"}, cfg.MFA.RequiredRoles)
+	assert.Equal(t, 5*time.Minute, cfg.MFA.ChallengeTTL)
+
+	cfg, err = config.LoadWith(config.Options{EnvFile: missing, LookupEnv: lookup(map[string]string{
+		"JWT_SECRET":         "s",
+		"MFA_REQUIRED_ROLES": "",
+	})})
+	require.NoError(t, err)
+	assert.Empty(t, cfg.MFA.RequiredRoles)
+
+	_, err = config.LoadWith(config.Options{EnvFile: missing, LookupEnv: lookup(map[string]string{
+		"JWT_SECRET":         "s",
+		"MFA_REQUIRED_ROLES": "Admin, root",
+	})})
+	var verr *config.ValidationError
+	require.ErrorAs(t, err, &verr)
+	assert.Contains(t, verr.Problems, `MFA_REQUIRED_ROLES: must be one of customer, organizer, admin, got "root"`)
+}
@@ -4,10 +4,11 @@ import (
 	"context"
 	"fmt"
 	"log"
-	"os"
 	"time"
 
 	"github.com/jackc/pgx/v5/pgxpool"
+
+	"ticket_app/internal/config"
 )
 
 // DB holds the database connection pool
@@ -16,53 +17,16 @@ type DB struct {
 }
 
 // NewDB initializes a new PostgreSQL connection pool
-func NewDB() (*DB, error) {
-	db := &DB{}
-	dsn := db.ConfigDSN()
-	log.Printf("Attempting to connect to PostgreSQL with DSN: %s", dsn)
+func NewDB(cfg config.PostgresConfig) (*DB, error) {
+	log.Printf("Attempting to connect to PostgreSQL at %s:%d/%s", cfg.Host, cfg.Port, cfg.DB)
 
-	db, err := NewPostgresConn(dsn, 5, time.Second)
+	db, err := NewPostgresConn(cfg.URL(), 5, time.Second)
 	if err != nil {
 		return nil, fmt.Errorf("failed to initialize PostgreSQL connection: %v", err)
 	}
 	return db, nil
 }
 
-// ConfigDSN returns the Data Source Name (DSN) for PostgreSQL
-func (db *DB) ConfigDSN() string {
-	dbHost := os.Getenv("POSTGRES_HOST")
-	dbPort := os.Getenv("POSTGRES_PORT")
-	dbUser := os.Getenv("POSTGRES_USER")
-	dbPass := os.Getenv("POSTGRES_PASSWORD")
-	dbName := os.Getenv("POSTGRES_DB")
-
-	// Kiểm tra và sử dụng giá trị mặc định nếu biến môi trường trống
-	if dbHost == "" {
-		dbHost = "localhost"
-		log.Printf("Using default POSTGRES_HOST: %s", dbHost)
-	}
-	if dbPort == "" {
-		dbPort = "5432"
-		log.Printf("Using default POSTGRES_PORT: %s", dbPort)
-	}
-	if dbUser == "" {
-		dbUser = "postgres"
-		log.Printf("Using default POSTGRES_USER: %s", dbUser)
-	}
-	if dbPass == "" {
-		log.Printf("POSTGRES_PASSWORD not set, using empty password")
-	}
-	if dbName == "" {
-		dbName = "postgres" // Database mặc định nếu không có
-		log.Printf("Using default POSTGRES_DB: %s", dbName)
-	}
-
-	// Tạo DSN theo định dạng postgres://
-	dsn := fmt.Sprintf("postgres://%s:%s@%s:%s/%s?sslmode=disable",
-		dbUser, dbPass, dbHost, dbPort, dbName)
-	return dsn
-}
-
 // NewPostgresConn initializes a new PostgreSQL connection with retry logic
 func NewPostgresConn(dsn string, maxRetries int, retryDelay time.Duration) (*DB, error) {
 	var db *DB
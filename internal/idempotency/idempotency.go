@@ -0,0 +1,27 @@
+// Package idempotency holds what middleware.Idempotency stores for each
+// Idempotency-Key, and the Store interface the Redis and Postgres backends
+// implement.
+package idempotency
+
+import (
+	"context"
+	"time"
+)
+
+// Record is what the store keeps for one Idempotency-Key
+type Record struct {
+	Fingerprint string `json:"fingerprint"`
+	Completed   bool   `json:"completed"`
+	StatusCode  int    `json:"status_code,omitempty"`
+	ContentType string `json:"content_type,omitempty"`
+	Body        []byte `json:"body,omitempty"`
+}
+
+// Store persists idempotency records
+type Store interface {
+	// Reserve stores rec under key only if the key is free. When it is taken,
+	// Reserve returns false together with the record already stored.
+	Reserve(ctx context.Context, key string, rec Record, ttl time.Duration) (*Record, bool, error)
+	Save(ctx context.Context, key string, rec Record, ttl time.Duration) error
+	Delete(ctx context.Context, key string) error
+}
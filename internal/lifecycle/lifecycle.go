@@ -0,0 +1,82 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+)
+
+// Manager runs the background workers of the process and shuts everything
+// down in order: workers are cancelled and drained first, then resources are
+// closed in reverse registration order (like defer), so a resource is only
+// closed once nothing registered after it can still be using it.
+type Manager struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	mu      sync.Mutex
+	closers []closer
+}
+
+type closer struct {
+	name string
+	fn   func() error
+}
+
+func NewManager() *Manager {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Manager{ctx: ctx, cancel: cancel}
+}
+
+// Go runs fn in a goroutine. fn must return soon after its ctx is cancelled.
+func (m *Manager) Go(name string, fn func(ctx context.Context)) {
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		fn(m.ctx)
+		log.Printf("%s exited", name)
+	}()
+}
+
+// OnClose registers fn to release a resource once the workers have stopped
+func (m *Manager) OnClose(name string, fn func() error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.closers = append(m.closers, closer{name: name, fn: fn})
+}
+
+// Shutdown cancels the workers and waits for them until ctx is done, then runs
+// the closers. Resources are closed even if the workers did not stop in time;
+// the returned error then includes ctx.Err().
+func (m *Manager) Shutdown(ctx context.Context) error {
+	m.cancel()
+
+	drained := make(chan struct{})
+	go func() {
+		m.wg.Wait()
+		close(drained)
+	}()
+
+	var errs []error
+	select {
+	case <-drained:
+		log.Println("All workers stopped")
+	case <-ctx.Done():
+		errs = append(errs, fmt.Errorf("workers did not stop in time: %w", ctx.Err()))
+	}
+
+	m.mu.Lock()
+	closers := m.closers
+	m.closers = nil
+	m.mu.Unlock()
+	for i := len(closers) - 1; i >= 0; i-- {
+		log.Printf("Closing %s", closers[i].name)
+		if err := closers[i].fn(); err != nil {
+			errs = append(errs, fmt.Errorf("close %s: %w", closers[i].name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
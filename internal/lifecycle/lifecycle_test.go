@@ -0,0 +1,67 @@
+package lifecycle_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"ticket_app/internal/lifecycle"
+)
+
+func TestShutdownDrainsWorkersBeforeClosing(t *testing.T) {
+	m := lifecycle.NewManager()
+
+	var mu sync.Mutex
+	var events []string
+	record := func(e string) {
+		mu.Lock()
+		defer mu.Unlock()
+		events = append(events, e)
+	}
+
+	m.Go("worker", func(ctx context.Context) {
+		<-ctx.Done()
+		time.Sleep(20 * time.Millisecond) // finish the in-flight job
+		record("worker stopped")
+	})
+	m.OnClose("database", func() error { record("database closed"); return nil })
+	m.OnClose("redis", func() error { record("redis closed"); return nil })
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	require.NoError(t, m.Shutdown(ctx))
+
+	assert.Equal(t, []string{"worker stopped", "redis closed", "database closed"}, events)
+}
+
+func TestShutdownClosesResourcesAfterDeadline(t *testing.T) {
+	m := lifecycle.NewManager()
+	release := make(chan struct{})
+	defer close(release)
+	m.Go("stuck worker", func(ctx context.Context) { <-release })
+
+	closed := false
+	m.OnClose("database", func() error { closed = true; return nil })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	err := m.Shutdown(ctx)
+
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.True(t, closed)
+}
+
+func TestShutdownReportsCloseErrors(t *testing.T) {
+	m := lifecycle.NewManager()
+	boom := errors.New("boom")
+	m.OnClose("redis", func() error { return boom })
+
+	err := m.Shutdown(context.Background())
+	assert.ErrorIs(t, err, boom)
+	assert.Contains(t, err.Error(), "close redis")
+}
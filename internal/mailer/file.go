@@ -0,0 +1,46 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FileMailer writes every message to its own .eml file in a directory, so
+// links in development mails can be opened without a mail server
+type FileMailer struct {
+	dir  string
+	from *mail.Address
+
+	mu  sync.Mutex
+	seq int
+}
+
+// NewFileMailer creates dir if needed
+func NewFileMailer(dir string, from *mail.Address) (*FileMailer, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("create mail dir: %w", err)
+	}
+	return &FileMailer{dir: dir, from: from}, nil
+}
+
+func (m *FileMailer) Send(ctx context.Context, msg Message) error {
+	now := time.Now()
+	data, err := format(m.from, msg, now)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	m.seq++
+	seq := m.seq
+	m.mu.Unlock()
+
+	to := strings.NewReplacer("@", "_at_", "/", "_", `\`, "_").Replace(msg.To)
+	name := fmt.Sprintf("%s-%03d-%s.eml", now.UTC().Format("20060102T150405.000"), seq, to)
+	return os.WriteFile(filepath.Join(m.dir, name), data, 0o600)
+}
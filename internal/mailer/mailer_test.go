@@ -0,0 +1,147 @@
+package mailer_test
+
+import (
+	"bufio"
+	"context"
+	"mime"
+	"net"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"ticket_app/internal/config"
+	"ticket_app/internal/mailer"
+)
+
+var from = &mail.Address{Name: "Ticket App", Address: "no-reply@tickets.example.com"}
+
+func TestFileMailerWritesEML(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "mail")
+	m, err := mailer.NewFileMailer(dir, from)
+	require.NoError(t, err)
+
+	require.NoError(t, m.Send(context.Background(), mailer.Message{
+		To:      "alice@example.com",
+		Subject: "Xác nhận email",
+		Body:    "Hello\nhttps://tickets.example.com/verify-email?token=abc",
+	}))
+
+	files, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	assert.True(t, strings.HasSuffix(files[0].Name(), "-alice_at_example.com.eml"))
+
+	data, err := os.ReadFile(filepath.Join(dir, files[0].Name()))
+	require.NoError(t, err)
+	parsed, err := mail.ReadMessage(strings.NewReader(string(data)))
+	require.NoError(t, err)
+	assert.Equal(t, `"Ticket App" <no-reply@tickets.example.com>`, parsed.Header.Get("From"))
+	assert.Equal(t, "<alice@example.com>", parsed.Header.Get("To"))
+	subject, err := new(mime.WordDecoder).DecodeHeader(parsed.Header.Get("Subject"))
+	require.NoError(t, err)
+	assert.Equal(t, "Xác nhận email", subject)
+	assert.Contains(t, string(data), "Hello\r\nhttps://tickets.example.com/verify-email?token=abc\r\n")
+}
+
+func TestMailerRejectsBadHeaders(t *testing.T) {
+	m, err := mailer.NewFileMailer(t.TempDir(), from)
+	require.NoError(t, err)
+	assert.Error(t, m.Send(context.Background(), mailer.Message{To: "not an address", Subject: "Hi"}))
+	assert.Error(t, m.Send(context.Background(), mailer.Message{To: "a@example.com", Subject: "Hi\r\nBcc: evil@example.com"}))
+}
+
+func TestMemoryMailer(t *testing.T) {
+	m := mailer.NewMemoryMailer()
+	ctx := context.Background()
+	require.NoError(t, m.Send(ctx, mailer.Message{To: "a@example.com", Subject: "1"}))
+	require.NoError(t, m.Send(ctx, mailer.Message{To: "b@example.com", Subject: "2"}))
+	require.NoError(t, m.Send(ctx, mailer.Message{To: "a@example.com", Subject: "3"}))
+
+	assert.Len(t, m.Messages(), 3)
+	last, ok := m.Last("a@example.com")
+	assert.True(t, ok)
+	assert.Equal(t, "3", last.Subject)
+	_, ok = m.Last("c@example.com")
+	assert.False(t, ok)
+}
+
+func TestFromConfig(t *testing.T) {
+	m, err := mailer.FromConfig(config.MailConfig{Backend: "log", From: "Ticket App <no-reply@example.com>"})
+	require.NoError(t, err)
+	assert.IsType(t, &mailer.LogMailer{}, m)
+
+	_, err = mailer.FromConfig(config.MailConfig{Backend: "log", From: "not an address"})
+	assert.ErrorContains(t, err, "invalid MAIL_FROM")
+}
+
+// fakeSMTP accepts one plain SMTP session and returns the commands and the DATA it received
+func fakeSMTP(t *testing.T) (port int, received <-chan []string) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { ln.Close() })
+	out := make(chan []string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		var lines []string
+		r := bufio.NewReader(conn)
+		reply := func(s string) { conn.Write([]byte(s + "\r\n")) }
+		reply("220 localhost ESMTP")
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				break
+			}
+			line = strings.TrimRight(line, "\r\n")
+			lines = append(lines, line)
+			switch cmd := strings.ToUpper(strings.SplitN(line, " ", 2)[0]); cmd {
+			case "EHLO":
+				reply("250-localhost")
+				reply("250 8BITMIME")
+			case "DATA":
+				reply("354 go ahead")
+				for {
+					l, err := r.ReadString('\n')
+					if err != nil || l == ".\r\n" {
+						break
+					}
+					lines = append(lines, strings.TrimRight(l, "\r\n"))
+				}
+				reply("250 queued")
+			case "QUIT":
+				reply("221 bye")
+				out <- lines
+				return
+			default:
+				reply("250 ok")
+			}
+		}
+		out <- lines
+	}()
+	return ln.Addr().(*net.TCPAddr).Port, out
+}
+
+func TestSMTPMailer(t *testing.T) {
+	port, received := fakeSMTP(t)
+	m := mailer.NewSMTPMailer(config.SMTPConfig{Host: "127.0.0.1", Port: port}, from)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	require.NoError(t, m.Send(ctx, mailer.Message{To: "bob@example.com", Subject: "Reset", Body: "Use this link"}))
+
+	lines := <-received
+	assert.Contains(t, lines, "MAIL FROM:<no-reply@tickets.example.com> BODY=8BITMIME")
+	assert.Contains(t, lines, "RCPT TO:<bob@example.com>")
+	assert.Contains(t, lines, "Subject: Reset")
+	assert.Contains(t, lines, "Use this link")
+	assert.Equal(t, "QUIT", lines[len(lines)-1])
+}
@@ -0,0 +1,197 @@
+package migration_test
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	_ "github.com/jackc/pgx/v5/stdlib"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	sqlmock "gopkg.in/DATA-DOG/go-sqlmock.v1"
+
+	"ticket_app/internal/migration"
+	"ticket_app/migrations"
+)
+
+func testFS() fstest.MapFS {
+	return fstest.MapFS{
+		"000002_add_venue.up.sql":     {Data: []byte("ALTER TABLE events ADD COLUMN venue TEXT;")},
+		"000002_add_venue.down.sql":   {Data: []byte("ALTER TABLE events DROP COLUMN venue;")},
+		"000001_init_schema.up.sql":   {Data: []byte("CREATE TABLE events (id BIGSERIAL PRIMARY KEY);")},
+		"000001_init_schema.down.sql": {Data: []byte("DROP TABLE events;")},
+		"README.md":                   {Data: []byte("not a migration")},
+	}
+}
+
+func TestLoadSortsByVersion(t *testing.T) {
+	migrations, err := migration.Load(testFS())
+	require.NoError(t, err)
+	require.Len(t, migrations, 2)
+	assert.Equal(t, int64(1), migrations[0].Version)
+	assert.Equal(t, "init_schema", migrations[0].Name)
+	assert.Equal(t, "add_venue", migrations[1].Name)
+	assert.Contains(t, migrations[1].Down, "DROP COLUMN venue")
+}
+
+func TestLoadRejectsIncompleteMigrations(t *testing.T) {
+	_, err := migration.Load(fstest.MapFS{
+		"000001_init.up.sql": {Data: []byte("SELECT 1;")},
+	})
+	assert.ErrorContains(t, err, "needs both an up and a down file")
+
+	_, err = migration.Load(fstest.MapFS{
+		"000001_init.up.sql":    {Data: []byte("SELECT 1;")},
+		"000001_other.down.sql": {Data: []byte("SELECT 1;")},
+	})
+	assert.ErrorContains(t, err, "is used by both")
+}
+
+func TestEmbeddedMigrationsLoad(t *testing.T) {
+	migrations, err := migration.Load(migrations.FS)
+	require.NoError(t, err)
+	require.NotEmpty(t, migrations)
+	assert.Equal(t, "init_schema", migrations[0].Name)
+}
+
+func expectLocked(mock sqlmock.Sqlmock, applied *sqlmock.Rows) {
+	mock.ExpectExec(`SELECT pg_advisory_lock`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`CREATE TABLE IF NOT EXISTS schema_migrations`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`SELECT version, applied_at FROM schema_migrations`).WillReturnRows(applied)
+}
+
+func TestUpAppliesPendingMigrationsInOrder(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	expectLocked(mock, sqlmock.NewRows([]string{"version", "applied_at"}).AddRow(1, time.Now()))
+	mock.ExpectBegin()
+	mock.ExpectExec(`ALTER TABLE events ADD COLUMN venue`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`INSERT INTO schema_migrations`).WithArgs(2, "add_venue").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectExec(`SELECT pg_advisory_unlock`).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	m, err := migration.NewMigrator(db, testFS())
+	require.NoError(t, err)
+	applied, err := m.Up(context.Background())
+	require.NoError(t, err)
+	require.Len(t, applied, 1)
+	assert.Equal(t, int64(2), applied[0].Version)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUpRollsBackFailedMigration(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	expectLocked(mock, sqlmock.NewRows([]string{"version", "applied_at"}))
+	mock.ExpectBegin()
+	mock.ExpectExec(`CREATE TABLE events`).WillReturnError(sql.ErrConnDone)
+	mock.ExpectRollback()
+	mock.ExpectExec(`SELECT pg_advisory_unlock`).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	m, err := migration.NewMigrator(db, testFS())
+	require.NoError(t, err)
+	applied, err := m.Up(context.Background())
+	assert.ErrorContains(t, err, "migration 1_init_schema up")
+	assert.Empty(t, applied)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDownRevertsNewestFirst(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	expectLocked(mock, sqlmock.NewRows([]string{"version", "applied_at"}).AddRow(1, time.Now()).AddRow(2, time.Now()))
+	mock.ExpectBegin()
+	mock.ExpectExec(`ALTER TABLE events DROP COLUMN venue`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`DELETE FROM schema_migrations`).WithArgs(2).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectExec(`SELECT pg_advisory_unlock`).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	m, err := migration.NewMigrator(db, testFS())
+	require.NoError(t, err)
+	reverted, err := m.Down(context.Background(), 1)
+	require.NoError(t, err)
+	require.Len(t, reverted, 1)
+	assert.Equal(t, "add_venue", reverted[0].Name)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestStatus(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	at := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	expectLocked(mock, sqlmock.NewRows([]string{"version", "applied_at"}).AddRow(1, at))
+	mock.ExpectExec(`SELECT pg_advisory_unlock`).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	m, err := migration.NewMigrator(db, testFS())
+	require.NoError(t, err)
+	statuses, err := m.Status(context.Background())
+	require.NoError(t, err)
+	require.Len(t, statuses, 2)
+	require.NotNil(t, statuses[0].AppliedAt)
+	assert.Equal(t, at, *statuses[0].AppliedAt)
+	assert.Nil(t, statuses[1].AppliedAt)
+}
+
+func TestCreateNumbersAfterHighestVersion(t *testing.T) {
+	dir := t.TempDir()
+	for name, file := range testFS() {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), file.Data, 0o644))
+	}
+
+	up, down, err := migration.Create(dir, "add_ticket_types")
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "000003_add_ticket_types.up.sql"), up)
+	assert.Equal(t, filepath.Join(dir, "000003_add_ticket_types.down.sql"), down)
+
+	migrations, err := migration.Load(os.DirFS(dir))
+	require.NoError(t, err)
+	assert.Len(t, migrations, 3)
+
+	_, _, err = migration.Create(dir, "Bad Name")
+	assert.Error(t, err)
+}
+
+// TestEmbeddedMigrationsUpDown applies and reverts the real migrations against
+// the Postgres instance in TEST_POSTGRES_DSN. Use a throwaway database: the
+// test drops every table it creates.
+func TestEmbeddedMigrationsUpDown(t *testing.T) {
+	dsn := os.Getenv("TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("TEST_POSTGRES_DSN not set, skipping Postgres integration test")
+	}
+	db, err := sql.Open("pgx", dsn)
+	require.NoError(t, err)
+	defer db.Close()
+
+	m, err := migration.NewMigrator(db, migrations.FS)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	_, err = m.Up(ctx)
+	require.NoError(t, err)
+	pending, err := m.Pending(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, pending)
+
+	all, err := migration.Load(migrations.FS)
+	require.NoError(t, err)
+	reverted, err := m.Down(ctx, len(all))
+	require.NoError(t, err)
+	assert.Len(t, reverted, len(all))
+
+	applied, err := m.Up(ctx)
+	require.NoError(t, err)
+	assert.Len(t, applied, len(all))
+}
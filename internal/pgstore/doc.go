@@ -0,0 +1,5 @@
+// Package pgstore implements on Postgres the stores that internal/redis
+// implements on Redis: idempotency keys, token revocations and the login
+// throttle. Deployments on the postgres or memory queue backend use these, so
+// they can run without Redis.
+package pgstore
@@ -0,0 +1,107 @@
+package pgstore
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+
+	"ticket_app/internal/idempotency"
+	"ticket_app/internal/repository"
+)
+
+// idempotencyRow is a row of idempotency_keys
+type idempotencyRow struct {
+	Key         string `gorm:"primaryKey"`
+	Fingerprint string
+	Completed   bool
+	StatusCode  int
+	ContentType string
+	Body        []byte
+	ExpiresAt   time.Time
+}
+
+func (idempotencyRow) TableName() string {
+	return "idempotency_keys"
+}
+
+// reserveIdempotencyKeySQL inserts the record, or takes over a row that has
+// expired. A live row is left alone and no row is affected.
+const reserveIdempotencyKeySQL = `
+INSERT INTO idempotency_keys (key, fingerprint, completed, status_code, content_type, body, expires_at)
+VALUES (?, ?, ?, ?, ?, ?, ?)
+ON CONFLICT (key) DO UPDATE SET
+	fingerprint = EXCLUDED.fingerprint, completed = EXCLUDED.completed, status_code = EXCLUDED.status_code,
+	content_type = EXCLUDED.content_type, body = EXCLUDED.body, expires_at = EXCLUDED.expires_at
+WHERE idempotency_keys.expires_at <= ?`
+
+// saveIdempotencyKeySQL overwrites the record whether or not the key is taken
+const saveIdempotencyKeySQL = `
+INSERT INTO idempotency_keys (key, fingerprint, completed, status_code, content_type, body, expires_at)
+VALUES (?, ?, ?, ?, ?, ?, ?)
+ON CONFLICT (key) DO UPDATE SET
+	fingerprint = EXCLUDED.fingerprint, completed = EXCLUDED.completed, status_code = EXCLUDED.status_code,
+	content_type = EXCLUDED.content_type, body = EXCLUDED.body, expires_at = EXCLUDED.expires_at`
+
+// purgeIdempotencyKeysSQL deletes a batch of expired rows, so that the table
+// does not keep every key ever used
+const purgeIdempotencyKeysSQL = `
+DELETE FROM idempotency_keys WHERE key IN (
+	SELECT key FROM idempotency_keys WHERE expires_at < ? LIMIT 100
+)`
+
+// IdempotencyStore implements idempotency.Store on the idempotency_keys table
+type IdempotencyStore struct {
+	db *gorm.DB
+}
+
+// NewIdempotencyStore creates an IdempotencyStore backed by db
+func NewIdempotencyStore(db *gorm.DB) *IdempotencyStore {
+	return &IdempotencyStore{db: db}
+}
+
+// Reserve relies on the primary key so that only one request can claim a key
+func (s *IdempotencyStore) Reserve(ctx context.Context, key string, rec idempotency.Record, ttl time.Duration) (*idempotency.Record, bool, error) {
+	now := time.Now()
+	res := repository.Conn(ctx, s.db).Exec(reserveIdempotencyKeySQL,
+		key, rec.Fingerprint, rec.Completed, rec.StatusCode, rec.ContentType, rec.Body, now.Add(ttl), now)
+	if res.Error != nil {
+		return nil, false, res.Error
+	}
+	if res.RowsAffected > 0 {
+		return nil, true, nil
+	}
+
+	var row idempotencyRow
+	err := repository.Conn(ctx, s.db).Where("key = ? AND expires_at > ?", key, now).Take(&row).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		// The key expired between the insert and the read; let the caller try again later.
+		return &idempotency.Record{Fingerprint: rec.Fingerprint}, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return &idempotency.Record{
+		Fingerprint: row.Fingerprint,
+		Completed:   row.Completed,
+		StatusCode:  row.StatusCode,
+		ContentType: row.ContentType,
+		Body:        row.Body,
+	}, false, nil
+}
+
+// Save overwrites the record stored under key, and clears out some expired ones
+func (s *IdempotencyStore) Save(ctx context.Context, key string, rec idempotency.Record, ttl time.Duration) error {
+	now := time.Now()
+	if err := repository.Conn(ctx, s.db).Exec(saveIdempotencyKeySQL,
+		key, rec.Fingerprint, rec.Completed, rec.StatusCode, rec.ContentType, rec.Body, now.Add(ttl)).Error; err != nil {
+		return err
+	}
+	return repository.Conn(ctx, s.db).Exec(purgeIdempotencyKeysSQL, now).Error
+}
+
+// Delete frees key
+func (s *IdempotencyStore) Delete(ctx context.Context, key string) error {
+	return repository.Conn(ctx, s.db).Where("key = ?", key).Delete(&idempotencyRow{}).Error
+}
@@ -0,0 +1,64 @@
+package pgstore_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	sqlmock "gopkg.in/DATA-DOG/go-sqlmock.v1"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"ticket_app/internal/idempotency"
+	"ticket_app/internal/pgstore"
+)
+
+func TestIdempotencyStoreReserve(t *testing.T) {
+	db, mock := newMockDB(t)
+	store := pgstore.NewIdempotencyStore(db)
+	ctx := context.Background()
+	reserve := `INSERT INTO idempotency_keys .+ON CONFLICT \(key\) DO UPDATE SET .+WHERE idempotency_keys.expires_at <= \$8`
+
+	// A free (or expired) key is claimed by the upsert
+	mock.ExpectExec(reserve).
+		WithArgs("k1", "fp", false, 0, "", sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	existing, reserved, err := store.Reserve(ctx, "k1", idempotency.Record{Fingerprint: "fp"}, time.Minute)
+	require.NoError(t, err)
+	assert.True(t, reserved)
+	assert.Nil(t, existing)
+
+	// A live key is left alone and its record is returned
+	mock.ExpectExec(reserve).
+		WithArgs("k1", "fp", false, 0, "", sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`SELECT \* FROM "idempotency_keys" WHERE key = \$1 AND expires_at > \$2`).
+		WillReturnRows(sqlmock.NewRows([]string{"key", "fingerprint", "completed", "status_code", "content_type", "body", "expires_at"}).
+			AddRow("k1", "fp", true, 201, "application/json", []byte(`{"id":1}`), time.Now().Add(time.Hour)))
+	existing, reserved, err = store.Reserve(ctx, "k1", idempotency.Record{Fingerprint: "fp"}, time.Minute)
+	require.NoError(t, err)
+	assert.False(t, reserved)
+	assert.Equal(t, &idempotency.Record{
+		Fingerprint: "fp",
+		Completed:   true,
+		StatusCode:  201,
+		ContentType: "application/json",
+		Body:        []byte(`{"id":1}`),
+	}, existing)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func newMockDB(t *testing.T) (*gorm.DB, sqlmock.Sqlmock) {
+	sqlDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { sqlDB.Close() })
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: sqlDB}), &gorm.Config{
+		Logger:                 logger.Default.LogMode(logger.Silent),
+		SkipDefaultTransaction: true,
+	})
+	require.NoError(t, err)
+	return db, mock
+}
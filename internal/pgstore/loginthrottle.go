@@ -0,0 +1,122 @@
+package pgstore
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"ticket_app/internal/config"
+	"ticket_app/internal/repository"
+)
+
+const (
+	loginEmailPrefix = "email:"
+	loginIPPrefix    = "ip:"
+)
+
+// loginThrottleRow is a row of login_throttles, for one email or one IP
+type loginThrottleRow struct {
+	Key          string `gorm:"primaryKey"`
+	Failures     int
+	WindowEndsAt *time.Time
+	DelayUntil   *time.Time
+	LockedUntil  *time.Time
+}
+
+func (loginThrottleRow) TableName() string {
+	return "login_throttles"
+}
+
+// countLoginFailureSQL adds a failure to key. The window starts at the first
+// failure; once it has ended the count starts over.
+const countLoginFailureSQL = `
+INSERT INTO login_throttles (key, failures, window_ends_at) VALUES (?, 1, ?)
+ON CONFLICT (key) DO UPDATE SET
+	failures = CASE WHEN login_throttles.window_ends_at > ? THEN login_throttles.failures + 1 ELSE 1 END,
+	window_ends_at = CASE WHEN login_throttles.window_ends_at > ? THEN login_throttles.window_ends_at ELSE EXCLUDED.window_ends_at END
+RETURNING failures`
+
+// LoginThrottle counts failed logins in login_throttles, per email and per
+// IP, with the same rules as redis.LoginThrottle.
+type LoginThrottle struct {
+	db  *gorm.DB
+	cfg config.LoginConfig
+}
+
+// NewLoginThrottle creates a LoginThrottle backed by db
+func NewLoginThrottle(db *gorm.DB, cfg config.LoginConfig) *LoginThrottle {
+	return &LoginThrottle{db: db, cfg: cfg}
+}
+
+// Wait returns how long a login for email from ip has to wait; 0 means it may go ahead
+func (t *LoginThrottle) Wait(ctx context.Context, email, ip string) (time.Duration, error) {
+	emailKey, ipKey := loginEmailPrefix+normalizeEmail(email), loginIPPrefix+ip
+	var rows []loginThrottleRow
+	if err := repository.Conn(ctx, t.db).Where("key IN ?", []string{emailKey, ipKey}).Find(&rows).Error; err != nil {
+		return 0, err
+	}
+
+	now := time.Now()
+	var wait time.Duration
+	until := func(at *time.Time) {
+		if at != nil {
+			wait = max(wait, at.Sub(now))
+		}
+	}
+	for _, row := range rows {
+		switch row.Key {
+		case emailKey:
+			until(row.LockedUntil)
+			until(row.DelayUntil)
+		case ipKey:
+			if row.WindowEndsAt != nil && row.WindowEndsAt.After(now) && row.Failures >= t.cfg.IPMaxFailures {
+				until(row.WindowEndsAt)
+			}
+		}
+	}
+	return wait, nil
+}
+
+// Failed records a failed login. It returns true when this failure locked the email.
+func (t *LoginThrottle) Failed(ctx context.Context, email, ip string) (bool, error) {
+	emailKey, ipKey := loginEmailPrefix+normalizeEmail(email), loginIPPrefix+ip
+	now := time.Now()
+	windowEnd := now.Add(t.cfg.FailureWindow)
+	var n int
+	if err := repository.Conn(ctx, t.db).Raw(countLoginFailureSQL, emailKey, windowEnd, now, now).Scan(&n).Error; err != nil {
+		return false, err
+	}
+	if err := repository.Conn(ctx, t.db).Exec(countLoginFailureSQL, ipKey, windowEnd, now, now).Error; err != nil {
+		return false, err
+	}
+
+	if n >= t.cfg.MaxFailures {
+		err := repository.Conn(ctx, t.db).Model(&loginThrottleRow{}).Where("key = ?", emailKey).Updates(map[string]any{
+			"locked_until":   now.Add(t.cfg.Lockout),
+			"failures":       0,
+			"window_ends_at": nil,
+			"delay_until":    nil,
+		}).Error
+		return err == nil, err
+	}
+	return false, repository.Conn(ctx, t.db).Model(&loginThrottleRow{}).Where("key = ?", emailKey).
+		Update("delay_until", now.Add(t.cfg.FailureDelay(n))).Error
+}
+
+// Succeeded forgets the failures of email, but not those of the IP
+func (t *LoginThrottle) Succeeded(ctx context.Context, email string) error {
+	return repository.Conn(ctx, t.db).Model(&loginThrottleRow{}).Where("key = ?", loginEmailPrefix+normalizeEmail(email)).
+		Updates(map[string]any{"failures": 0, "window_ends_at": nil, "delay_until": nil}).Error
+}
+
+// Unlock lifts the lock of email and forgets its failures
+func (t *LoginThrottle) Unlock(ctx context.Context, email string) error {
+	return repository.Conn(ctx, t.db).Where("key = ?", loginEmailPrefix+normalizeEmail(email)).Delete(&loginThrottleRow{}).Error
+}
+
+// normalizeEmail makes Alice@Example.com and alice@example.com share one row
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
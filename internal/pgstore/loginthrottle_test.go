@@ -0,0 +1,96 @@
+package pgstore_test
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"ticket_app/internal/config"
+	"ticket_app/internal/migration"
+	"ticket_app/internal/pgstore"
+	"ticket_app/migrations"
+)
+
+func TestLoginThrottle(t *testing.T) {
+	db := openTestDB(t)
+	suffix := time.Now().Format("150405.000000000")
+	alice, bob, carol, dave := "alice"+suffix+"@example.com", "bob"+suffix+"@example.com",
+		"carol"+suffix+"@example.com", "dave"+suffix+"@example.com"
+	ip := "10.0.0." + suffix
+	t.Cleanup(func() { db.Exec("DELETE FROM login_throttles WHERE key LIKE ?", "%"+suffix+"%") })
+
+	throttle := pgstore.NewLoginThrottle(db, config.LoginConfig{
+		MaxFailures:   3,
+		FailureWindow: 15 * time.Minute,
+		Lockout:       10 * time.Minute,
+		IPMaxFailures: 5,
+		DelayBase:     time.Second,
+		DelayMax:      30 * time.Second,
+	})
+	ctx := context.Background()
+	wait := func(email, ip string) time.Duration {
+		d, err := throttle.Wait(ctx, email, ip)
+		require.NoError(t, err)
+		return d
+	}
+
+	assert.Zero(t, wait(alice, ip))
+
+	// Progressive delay: 1s, then 2s
+	locked, err := throttle.Failed(ctx, alice, ip)
+	require.NoError(t, err)
+	assert.False(t, locked)
+	assert.InDelta(t, time.Second.Seconds(), wait(" ALICE"+suffix+"@example.com", "other").Seconds(), 0.2)
+	_, err = throttle.Failed(ctx, alice, ip)
+	require.NoError(t, err)
+	assert.InDelta(t, (2 * time.Second).Seconds(), wait(alice, ip).Seconds(), 0.2)
+
+	// The third failure locks the email, whatever the IP
+	locked, err = throttle.Failed(ctx, alice, ip)
+	require.NoError(t, err)
+	assert.True(t, locked)
+	assert.InDelta(t, (10 * time.Minute).Seconds(), wait(alice, "other").Seconds(), 1)
+	assert.Zero(t, wait(bob, ip))
+	require.NoError(t, throttle.Unlock(ctx, alice))
+	assert.Zero(t, wait(alice, ip))
+
+	// A success clears the email's failures but not the IP's
+	_, err = throttle.Failed(ctx, bob, ip)
+	require.NoError(t, err)
+	require.NoError(t, throttle.Succeeded(ctx, bob))
+	assert.Zero(t, wait(bob, ip))
+
+	// The fifth failure from the IP blocks it for every email until the window ends
+	_, err = throttle.Failed(ctx, carol, ip)
+	require.NoError(t, err)
+	assert.Greater(t, wait(dave, ip), 14*time.Minute)
+	assert.Zero(t, wait(dave, "other"))
+	require.NoError(t, db.Exec("UPDATE login_throttles SET window_ends_at = ? WHERE key = ?",
+		time.Now().Add(-time.Second), "ip:"+ip).Error)
+	assert.Zero(t, wait(dave, ip))
+}
+
+// openTestDB connects to the Postgres instance in TEST_POSTGRES_DSN and skips
+// the test when the variable is not set
+func openTestDB(t *testing.T) *gorm.DB {
+	dsn := os.Getenv("TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("TEST_POSTGRES_DSN not set, skipping Postgres integration test")
+	}
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	require.NoError(t, err)
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	migrator, err := migration.NewMigrator(sqlDB, migrations.FS)
+	require.NoError(t, err)
+	_, err = migrator.Up(context.Background())
+	require.NoError(t, err)
+	return db
+}
@@ -0,0 +1,65 @@
+package pgstore
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+
+	"ticket_app/internal/repository"
+)
+
+const revokeTokenSQL = `
+INSERT INTO revoked_tokens (jti, expires_at) VALUES (?, ?)
+ON CONFLICT (jti) DO UPDATE SET expires_at = EXCLUDED.expires_at`
+
+const revokeSubjectSQL = `
+INSERT INTO revoked_subjects (subject, revoked_at, expires_at) VALUES (?, ?, ?)
+ON CONFLICT (subject) DO UPDATE SET revoked_at = EXCLUDED.revoked_at, expires_at = EXCLUDED.expires_at`
+
+// isRevokedSQL reads both tables in one round trip; rows past expires_at are ignored
+const isRevokedSQL = `
+SELECT
+	EXISTS (SELECT 1 FROM revoked_tokens WHERE jti = ? AND expires_at > ?) AS token_revoked,
+	(SELECT revoked_at FROM revoked_subjects WHERE subject = ? AND expires_at > ?) AS cutoff`
+
+// RevocationStore implements token.RevocationStore on the revoked_tokens and
+// revoked_subjects tables, with the same rules as redis.RevocationStore.
+type RevocationStore struct {
+	db *gorm.DB
+}
+
+// NewRevocationStore creates a RevocationStore backed by db
+func NewRevocationStore(db *gorm.DB) *RevocationStore {
+	return &RevocationStore{db: db}
+}
+
+func (s *RevocationStore) RevokeToken(ctx context.Context, jti string, expiresAt time.Time) error {
+	if !expiresAt.After(time.Now()) {
+		return nil // already expired, nothing to remember
+	}
+	return repository.Conn(ctx, s.db).Exec(revokeTokenSQL, jti, expiresAt).Error
+}
+
+func (s *RevocationStore) RevokeSubject(ctx context.Context, subject string, at time.Time, ttl time.Duration) error {
+	return repository.Conn(ctx, s.db).Exec(revokeSubjectSQL, subject, at, time.Now().Add(ttl)).Error
+}
+
+func (s *RevocationStore) IsRevoked(ctx context.Context, jti, subject string, issuedAt time.Time) (bool, error) {
+	now := time.Now()
+	var row struct {
+		TokenRevoked bool
+		Cutoff       *time.Time
+	}
+	if err := repository.Conn(ctx, s.db).Raw(isRevokedSQL, jti, now, subject, now).Scan(&row).Error; err != nil {
+		return false, err
+	}
+	if jti != "" && row.TokenRevoked {
+		return true, nil
+	}
+	if subject != "" && row.Cutoff != nil {
+		// Compare whole seconds like the Redis store, since iat has no finer precision
+		return issuedAt.Unix() <= row.Cutoff.Unix(), nil
+	}
+	return false, nil
+}
@@ -0,0 +1,54 @@
+package pgstore_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	sqlmock "gopkg.in/DATA-DOG/go-sqlmock.v1"
+
+	"ticket_app/internal/pgstore"
+)
+
+func TestRevocationStoreIsRevoked(t *testing.T) {
+	db, mock := newMockDB(t)
+	store := pgstore.NewRevocationStore(db)
+	ctx := context.Background()
+	cutoff := time.Now().Truncate(time.Second)
+	isRevoked := `SELECT\s+EXISTS \(SELECT 1 FROM revoked_tokens WHERE jti = \$1 AND expires_at > \$2\)`
+	columns := []string{"token_revoked", "cutoff"}
+
+	// A revoked jti
+	mock.ExpectQuery(isRevoked).WithArgs("jti-1", sqlmock.AnyArg(), "7", sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows(columns).AddRow(true, nil))
+	revoked, err := store.IsRevoked(ctx, "jti-1", "7", cutoff.Add(time.Minute))
+	require.NoError(t, err)
+	assert.True(t, revoked)
+
+	// Tokens issued up to the logout-all cutoff are revoked, later ones are not
+	for _, tc := range []struct {
+		issuedAt time.Time
+		revoked  bool
+	}{
+		{cutoff.Add(-time.Minute), true},
+		{cutoff, true},
+		{cutoff.Add(time.Second), false},
+	} {
+		mock.ExpectQuery(isRevoked).WithArgs("jti-2", sqlmock.AnyArg(), "7", sqlmock.AnyArg()).
+			WillReturnRows(sqlmock.NewRows(columns).AddRow(false, cutoff))
+		revoked, err := store.IsRevoked(ctx, "jti-2", "7", tc.issuedAt)
+		require.NoError(t, err)
+		assert.Equal(t, tc.revoked, revoked, tc.issuedAt)
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRevocationStoreSkipsExpiredTokens(t *testing.T) {
+	db, mock := newMockDB(t)
+	store := pgstore.NewRevocationStore(db)
+
+	require.NoError(t, store.RevokeToken(context.Background(), "jti-1", time.Now().Add(-time.Second)))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,92 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DeadLetterSetName is the Redis hash holding dead-lettered jobs, keyed by job ID
+const DeadLetterSetName = "payment:dead"
+
+// DeadLetter is a payment job that exhausted its retry policy
+type DeadLetter struct {
+	Job      PaymentJob `json:"job"`
+	FailedAt time.Time  `json:"failed_at"`
+}
+
+// DeadLetterStore persists dead letters for a backend. Get and Delete return
+// domain.ErrNotFound for an unknown job ID.
+type DeadLetterStore interface {
+	Add(ctx context.Context, letter DeadLetter) error
+	List(ctx context.Context) ([]DeadLetter, error)
+	Get(ctx context.Context, id string) (*DeadLetter, error)
+	Delete(ctx context.Context, id string) error
+	Purge(ctx context.Context) (int64, error)
+}
+
+// DeadLetterQueue lets operators inspect and recover dead-lettered payment jobs
+type DeadLetterQueue interface {
+	ListDeadLetters(ctx context.Context) ([]DeadLetter, error)
+	GetDeadLetter(ctx context.Context, id string) (*DeadLetter, error)
+	RequeueDeadLetter(ctx context.Context, id string) error
+	DeleteDeadLetter(ctx context.Context, id string) error
+	PurgeDeadLetters(ctx context.Context) (int64, error)
+}
+
+func (s *QueueService) deadLetter(ctx context.Context, job PaymentJob, failedAt time.Time) error {
+	if job.ID == "" {
+		job.ID = uuid.NewString() // jobs enqueued before IDs were introduced
+	}
+	return s.deadLetters.Add(ctx, DeadLetter{Job: job, FailedAt: failedAt})
+}
+
+// ListDeadLetters returns all dead letters, most recent failure first
+func (s *QueueService) ListDeadLetters(ctx context.Context) ([]DeadLetter, error) {
+	return s.deadLetters.List(ctx)
+}
+
+// GetDeadLetter returns domain.ErrNotFound if id is not dead-lettered
+func (s *QueueService) GetDeadLetter(ctx context.Context, id string) (*DeadLetter, error) {
+	return s.deadLetters.Get(ctx, id)
+}
+
+// RequeueDeadLetter puts the job back on the queue with a fresh attempt budget.
+// The dead letter is deleted first, so of two concurrent requeues only one
+// enqueues the job; if enqueueing fails the dead letter is restored.
+func (s *QueueService) RequeueDeadLetter(ctx context.Context, id string) error {
+	letter, err := s.deadLetters.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := s.deadLetters.Delete(ctx, id); err != nil {
+		return err
+	}
+	job := letter.Job
+	job.Attempts = 0
+	job.LastError = ""
+	data, err := json.Marshal(job)
+	if err == nil {
+		err = s.jobs.Enqueue(ctx, data)
+	}
+	if err != nil {
+		if addErr := s.deadLetters.Add(ctx, *letter); addErr != nil {
+			log.Printf("Failed to restore dead letter %s: %v", id, addErr)
+		}
+		return err
+	}
+	return nil
+}
+
+// DeleteDeadLetter drops one dead letter
+func (s *QueueService) DeleteDeadLetter(ctx context.Context, id string) error {
+	return s.deadLetters.Delete(ctx, id)
+}
+
+// PurgeDeadLetters drops every dead letter and returns how many there were
+func (s *QueueService) PurgeDeadLetters(ctx context.Context) (int64, error) {
+	return s.deadLetters.Purge(ctx)
+}
@@ -0,0 +1,48 @@
+package queue
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Message is one delivery of a job. ID identifies the delivery to the backend
+// and must be passed back, with the message, to Ack or Nack.
+type Message struct {
+	ID      string
+	Payload []byte
+}
+
+// JobQueue is the transport behind QueueService. A consumed message stays
+// invisible to other consumers until it is acked or nacked; a message that is
+// neither (the consumer crashed) is delivered again once its lease expires.
+type JobQueue interface {
+	// Enqueue makes payload available to consumers immediately
+	Enqueue(ctx context.Context, payload []byte) error
+	// EnqueueAt makes payload available to consumers from at onwards
+	EnqueueAt(ctx context.Context, payload []byte, at time.Time) error
+	// Consume returns up to max due messages, waiting up to wait for the first one
+	Consume(ctx context.Context, max int, wait time.Duration) ([]Message, error)
+	// Ack removes a handled message for good
+	Ack(ctx context.Context, msg Message) error
+	// Nack puts msg back, with its possibly updated payload, to be delivered again at retryAt
+	Nack(ctx context.Context, msg Message, retryAt time.Time) error
+}
+
+// Backend names accepted by QUEUE_BACKEND
+const (
+	BackendRedis    = "redis"
+	BackendPostgres = "postgres"
+	BackendMemory   = "memory"
+)
+
+// ParseBackend validates a QUEUE_BACKEND value; empty means BackendRedis
+func ParseBackend(name string) (string, error) {
+	switch name {
+	case "":
+		return BackendRedis, nil
+	case BackendRedis, BackendPostgres, BackendMemory:
+		return name, nil
+	}
+	return "", fmt.Errorf("unknown queue backend %q, expected %s, %s or %s", name, BackendRedis, BackendPostgres, BackendMemory)
+}
@@ -0,0 +1,240 @@
+package queue
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	sqlmock "gopkg.in/DATA-DOG/go-sqlmock.v1"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"ticket_app/internal/migration"
+	"ticket_app/migrations"
+)
+
+// jobQueueBackend builds a fresh queue and a hook that makes every leased
+// message look abandoned, as if its consumer had crashed.
+type jobQueueBackend struct {
+	name         string
+	newQueue     func(t *testing.T) JobQueue
+	expireLeases func(t *testing.T, q JobQueue)
+}
+
+func jobQueueBackends() []jobQueueBackend {
+	return []jobQueueBackend{
+		{
+			name:     BackendMemory,
+			newQueue: func(t *testing.T) JobQueue { return NewMemoryJobQueue(20 * time.Millisecond) },
+			expireLeases: func(t *testing.T, q JobQueue) {
+				time.Sleep(30 * time.Millisecond)
+			},
+		},
+		{
+			name: BackendRedis,
+			newQueue: func(t *testing.T) JobQueue {
+				return NewRedisJobQueue(newTestRedis(t, miniredis.RunT(t)), "test_stream", "test_workers", "test:delayed")
+			},
+			expireLeases: func(t *testing.T, q JobQueue) {
+				rq := q.(*RedisJobQueue)
+				rq.claimMinIdle = 0
+				rq.lastReclaim = time.Time{}
+			},
+		},
+		{
+			name: BackendPostgres,
+			newQueue: func(t *testing.T) JobQueue {
+				db := openTestDB(t)
+				name := "test-" + time.Now().Format("150405.000000000")
+				t.Cleanup(func() { db.Where("queue = ?", name).Delete(&JobRecord{}) })
+				return NewPostgresJobQueue(db, name, time.Minute)
+			},
+			expireLeases: func(t *testing.T, q JobQueue) {
+				pq := q.(*PostgresJobQueue)
+				require.NoError(t, pq.db.Model(&JobRecord{}).Where("queue = ?", pq.queue).
+					Update("locked_until", time.Now().Add(-time.Second)).Error)
+			},
+		},
+	}
+}
+
+// openTestDB connects to the Postgres instance in TEST_POSTGRES_DSN and skips
+// the test when the variable is not set
+func openTestDB(t *testing.T) *gorm.DB {
+	dsn := os.Getenv("TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("TEST_POSTGRES_DSN not set, skipping Postgres integration test")
+	}
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	require.NoError(t, err)
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	migrator, err := migration.NewMigrator(sqlDB, migrations.FS)
+	require.NoError(t, err)
+	_, err = migrator.Up(context.Background())
+	require.NoError(t, err)
+	return db
+}
+
+func payloads(msgs []Message) []string {
+	out := make([]string, 0, len(msgs))
+	for _, msg := range msgs {
+		out = append(out, string(msg.Payload))
+	}
+	return out
+}
+
+func TestJobQueueBackends(t *testing.T) {
+	for _, backend := range jobQueueBackends() {
+		backend := backend
+		t.Run(backend.name, func(t *testing.T) {
+			t.Run("EnqueueConsumeAck", func(t *testing.T) {
+				q := backend.newQueue(t)
+				ctx := context.Background()
+				require.NoError(t, q.Enqueue(ctx, []byte("a")))
+				require.NoError(t, q.Enqueue(ctx, []byte("b")))
+
+				msgs, err := q.Consume(ctx, 10, 50*time.Millisecond)
+				require.NoError(t, err)
+				assert.ElementsMatch(t, []string{"a", "b"}, payloads(msgs))
+				for _, msg := range msgs {
+					require.NoError(t, q.Ack(ctx, msg))
+				}
+
+				backend.expireLeases(t, q)
+				msgs, err = q.Consume(ctx, 10, 10*time.Millisecond)
+				require.NoError(t, err)
+				assert.Empty(t, msgs, "acked messages must not come back")
+			})
+
+			t.Run("EnqueueAtWaitsUntilDue", func(t *testing.T) {
+				q := backend.newQueue(t)
+				ctx := context.Background()
+				require.NoError(t, q.EnqueueAt(ctx, []byte("later"), time.Now().Add(time.Hour)))
+				require.NoError(t, q.EnqueueAt(ctx, []byte("now"), time.Now().Add(-time.Second)))
+
+				msgs, err := q.Consume(ctx, 10, 50*time.Millisecond)
+				require.NoError(t, err)
+				assert.Equal(t, []string{"now"}, payloads(msgs))
+			})
+
+			t.Run("NackRedeliversUpdatedPayload", func(t *testing.T) {
+				q := backend.newQueue(t)
+				ctx := context.Background()
+				require.NoError(t, q.Enqueue(ctx, []byte("v1")))
+				msgs, err := q.Consume(ctx, 10, 50*time.Millisecond)
+				require.NoError(t, err)
+				require.Len(t, msgs, 1)
+
+				msg := msgs[0]
+				msg.Payload = []byte("v2")
+				require.NoError(t, q.Nack(ctx, msg, time.Now().Add(-time.Millisecond)))
+
+				msgs, err = q.Consume(ctx, 10, 50*time.Millisecond)
+				require.NoError(t, err)
+				assert.Equal(t, []string{"v2"}, payloads(msgs))
+			})
+
+			t.Run("UnackedMessageIsRedelivered", func(t *testing.T) {
+				q := backend.newQueue(t)
+				ctx := context.Background()
+				require.NoError(t, q.Enqueue(ctx, []byte("job")))
+				msgs, err := q.Consume(ctx, 10, 50*time.Millisecond)
+				require.NoError(t, err)
+				require.Len(t, msgs, 1)
+
+				msgs, err = q.Consume(ctx, 10, 10*time.Millisecond)
+				require.NoError(t, err)
+				assert.Empty(t, msgs, "a leased message is invisible to other consumers")
+
+				backend.expireLeases(t, q)
+				msgs, err = q.Consume(ctx, 10, 50*time.Millisecond)
+				require.NoError(t, err)
+				assert.Equal(t, []string{"job"}, payloads(msgs))
+			})
+		})
+	}
+}
+
+func TestParseBackend(t *testing.T) {
+	backend, err := ParseBackend("")
+	require.NoError(t, err)
+	assert.Equal(t, BackendRedis, backend)
+
+	backend, err = ParseBackend("postgres")
+	require.NoError(t, err)
+	assert.Equal(t, BackendPostgres, backend)
+
+	_, err = ParseBackend("kafka")
+	assert.Error(t, err)
+}
+
+func TestPostgresJobQueueClaimsWithSkipLocked(t *testing.T) {
+	sqlDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { sqlDB.Close() })
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: sqlDB}), &gorm.Config{
+		Logger:                 logger.Default.LogMode(logger.Silent),
+		SkipDefaultTransaction: true,
+	})
+	require.NoError(t, err)
+
+	mock.ExpectQuery(`UPDATE queue_jobs SET locked_until = .+FOR UPDATE SKIP LOCKED`).
+		WithArgs(sqlmock.AnyArg(), "payments", sqlmock.AnyArg(), sqlmock.AnyArg(), 10).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "payload"}).AddRow(7, `{"booking_id":1}`))
+	mock.ExpectExec(`DELETE FROM "queue_jobs" WHERE id = \$1`).
+		WithArgs("7").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	q := NewPostgresJobQueue(db, "payments", time.Minute)
+	msgs, err := q.Consume(context.Background(), 10, 0)
+	require.NoError(t, err)
+	require.Equal(t, []Message{{ID: "7", Payload: []byte(`{"booking_id":1}`)}}, msgs)
+	require.NoError(t, q.Ack(context.Background(), msgs[0]))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresDeadLetterConflictsOnQueueAndID(t *testing.T) {
+	sqlDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { sqlDB.Close() })
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: sqlDB}), &gorm.Config{
+		Logger:                 logger.Default.LogMode(logger.Silent),
+		SkipDefaultTransaction: true,
+	})
+	require.NoError(t, err)
+
+	mock.ExpectExec(`INSERT INTO "queue_dead_letters" .+ON CONFLICT \("queue","id"\) DO UPDATE`).
+		WithArgs("payments", "job-1", sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	store := NewPostgresDeadLetterStore(db, "payments")
+	require.NoError(t, store.Add(context.Background(), DeadLetter{Job: PaymentJob{ID: "job-1"}, FailedAt: time.Now()}))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Job IDs only need to be unique per queue: the same ID in two queues is two dead letters
+func TestPostgresDeadLettersAreKeyedPerQueue(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+	id := "dup-" + time.Now().Format("150405.000000000")
+	payments := NewPostgresDeadLetterStore(db, "payments")
+	timeouts := NewPostgresDeadLetterStore(db, "timeouts")
+
+	require.NoError(t, payments.Add(ctx, DeadLetter{Job: PaymentJob{ID: id, BookingID: 1}, FailedAt: time.Now()}))
+	require.NoError(t, timeouts.Add(ctx, DeadLetter{Job: PaymentJob{ID: id, BookingID: 2}, FailedAt: time.Now()}))
+
+	letter, err := payments.Get(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, uint(1), letter.Job.BookingID)
+	require.NoError(t, timeouts.Delete(ctx, id))
+	letter, err = payments.Get(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, uint(1), letter.Job.BookingID)
+	require.NoError(t, payments.Delete(ctx, id))
+}
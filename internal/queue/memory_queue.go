@@ -0,0 +1,209 @@
+package queue
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"ticket_app/domain"
+)
+
+// MemoryJobQueue keeps jobs in process memory. It is meant for unit tests and
+// local development: jobs do not survive a restart and cannot be shared
+// between processes.
+type MemoryJobQueue struct {
+	mu     sync.Mutex
+	seq    uint64
+	ready  map[string]memoryJob // waiting for delivery, due at job.at
+	leased map[string]memoryJob // delivered, redelivered once job.at (lease end) passes
+	lease  time.Duration
+	notify chan struct{}
+}
+
+type memoryJob struct {
+	seq     uint64
+	payload []byte
+	at      time.Time
+}
+
+// NewMemoryJobQueue redelivers a consumed message that was neither acked nor nacked after lease
+func NewMemoryJobQueue(lease time.Duration) *MemoryJobQueue {
+	return &MemoryJobQueue{
+		ready:  map[string]memoryJob{},
+		leased: map[string]memoryJob{},
+		lease:  lease,
+		notify: make(chan struct{}, 1),
+	}
+}
+
+func (q *MemoryJobQueue) Enqueue(ctx context.Context, payload []byte) error {
+	return q.EnqueueAt(ctx, payload, time.Now())
+}
+
+func (q *MemoryJobQueue) EnqueueAt(ctx context.Context, payload []byte, at time.Time) error {
+	q.mu.Lock()
+	q.seq++
+	q.ready[strconv.FormatUint(q.seq, 10)] = memoryJob{seq: q.seq, payload: append([]byte(nil), payload...), at: at}
+	q.mu.Unlock()
+	q.wake()
+	return nil
+}
+
+func (q *MemoryJobQueue) Consume(ctx context.Context, max int, wait time.Duration) ([]Message, error) {
+	deadline := time.Now().Add(wait)
+	for {
+		msgs, next := q.take(max, time.Now())
+		if len(msgs) > 0 || !time.Now().Before(deadline) {
+			return msgs, nil
+		}
+		delay := time.Until(deadline)
+		if !next.IsZero() && time.Until(next) < delay {
+			delay = time.Until(next)
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-q.notify:
+			timer.Stop()
+		case <-timer.C:
+		}
+	}
+}
+
+// take leases up to max due jobs, oldest first, and reports when the next one is due
+func (q *MemoryJobQueue) take(max int, now time.Time) ([]Message, time.Time) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for id, job := range q.leased {
+		if !job.at.After(now) {
+			delete(q.leased, id)
+			q.ready[id] = job
+		}
+	}
+
+	due := make([]string, 0)
+	var next time.Time
+	for _, job := range q.leased {
+		if next.IsZero() || job.at.Before(next) {
+			next = job.at
+		}
+	}
+	for id, job := range q.ready {
+		if !job.at.After(now) {
+			due = append(due, id)
+		} else if next.IsZero() || job.at.Before(next) {
+			next = job.at
+		}
+	}
+	sort.Slice(due, func(i, j int) bool {
+		a, b := q.ready[due[i]], q.ready[due[j]]
+		if a.at.Equal(b.at) {
+			return a.seq < b.seq
+		}
+		return a.at.Before(b.at)
+	})
+	if len(due) > max {
+		due = due[:max]
+	}
+
+	msgs := make([]Message, 0, len(due))
+	for _, id := range due {
+		job := q.ready[id]
+		delete(q.ready, id)
+		job.at = now.Add(q.lease)
+		q.leased[id] = job
+		msgs = append(msgs, Message{ID: id, Payload: job.payload})
+	}
+	return msgs, next
+}
+
+func (q *MemoryJobQueue) Ack(ctx context.Context, msg Message) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	delete(q.leased, msg.ID)
+	return nil
+}
+
+func (q *MemoryJobQueue) Nack(ctx context.Context, msg Message, retryAt time.Time) error {
+	q.mu.Lock()
+	job, ok := q.leased[msg.ID]
+	if ok {
+		delete(q.leased, msg.ID)
+		job.payload = append([]byte(nil), msg.Payload...)
+		job.at = retryAt
+		q.ready[msg.ID] = job
+	}
+	q.mu.Unlock()
+	if !ok {
+		return domain.ErrNotFound
+	}
+	q.wake()
+	return nil
+}
+
+func (q *MemoryJobQueue) wake() {
+	select {
+	case q.notify <- struct{}{}:
+	default:
+	}
+}
+
+// MemoryDeadLetterStore keeps dead letters in process memory
+type MemoryDeadLetterStore struct {
+	mu      sync.Mutex
+	letters map[string]DeadLetter
+}
+
+func NewMemoryDeadLetterStore() *MemoryDeadLetterStore {
+	return &MemoryDeadLetterStore{letters: map[string]DeadLetter{}}
+}
+
+func (d *MemoryDeadLetterStore) Add(ctx context.Context, letter DeadLetter) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.letters[letter.Job.ID] = letter
+	return nil
+}
+
+func (d *MemoryDeadLetterStore) List(ctx context.Context) ([]DeadLetter, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	letters := make([]DeadLetter, 0, len(d.letters))
+	for _, letter := range d.letters {
+		letters = append(letters, letter)
+	}
+	sort.Slice(letters, func(i, j int) bool { return letters[i].FailedAt.After(letters[j].FailedAt) })
+	return letters, nil
+}
+
+func (d *MemoryDeadLetterStore) Get(ctx context.Context, id string) (*DeadLetter, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	letter, ok := d.letters[id]
+	if !ok {
+		return nil, domain.ErrNotFound
+	}
+	return &letter, nil
+}
+
+func (d *MemoryDeadLetterStore) Delete(ctx context.Context, id string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if _, ok := d.letters[id]; !ok {
+		return domain.ErrNotFound
+	}
+	delete(d.letters, id)
+	return nil
+}
+
+func (d *MemoryDeadLetterStore) Purge(ctx context.Context) (int64, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	n := int64(len(d.letters))
+	d.letters = map[string]DeadLetter{}
+	return n, nil
+}
@@ -0,0 +1,206 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"ticket_app/domain"
+	"ticket_app/internal/repository"
+)
+
+// JobRecord is a job waiting in the Postgres queue. A consumer leases it by
+// setting LockedUntil; it becomes visible again when the lease runs out.
+type JobRecord struct {
+	ID          uint64    `gorm:"primaryKey"`
+	Queue       string    `gorm:"size:100;not null;index:idx_queue_jobs_due,priority:1"`
+	Payload     string    `gorm:"type:text;not null"`
+	RunAt       time.Time `gorm:"not null;index:idx_queue_jobs_due,priority:2"`
+	LockedUntil *time.Time
+	CreatedAt   time.Time
+}
+
+func (JobRecord) TableName() string {
+	return "queue_jobs"
+}
+
+// DeadLetterRecord is a dead letter kept in Postgres; Job holds the PaymentJob
+// as JSON. Job IDs are only unique within a queue, so the key is (Queue, ID).
+type DeadLetterRecord struct {
+	Queue    string    `gorm:"primaryKey;size:100"`
+	ID       string    `gorm:"primaryKey;size:64"`
+	Job      string    `gorm:"type:text;not null"`
+	FailedAt time.Time `gorm:"not null"`
+}
+
+func (DeadLetterRecord) TableName() string {
+	return "queue_dead_letters"
+}
+
+const postgresPollInterval = 500 * time.Millisecond
+
+// claimJobsSQL leases up to N due jobs. FOR UPDATE SKIP LOCKED lets several
+// workers claim from the same queue at once without blocking on, or taking,
+// each other's rows.
+const claimJobsSQL = `
+UPDATE queue_jobs SET locked_until = ?
+WHERE id IN (
+	SELECT id FROM queue_jobs
+	WHERE queue = ? AND run_at <= ? AND (locked_until IS NULL OR locked_until < ?)
+	ORDER BY run_at, id
+	LIMIT ?
+	FOR UPDATE SKIP LOCKED
+)
+RETURNING id, payload`
+
+// PostgresJobQueue runs the queue on the application database, so small
+// deployments do not need Redis. Enqueue joins the transaction in ctx, if any.
+type PostgresJobQueue struct {
+	db    *gorm.DB
+	queue string
+	lease time.Duration
+}
+
+// NewPostgresJobQueue stores jobs of queue in queue_jobs; a consumed job is
+// redelivered if it is neither acked nor nacked within lease
+func NewPostgresJobQueue(db *gorm.DB, queue string, lease time.Duration) *PostgresJobQueue {
+	return &PostgresJobQueue{db: db, queue: queue, lease: lease}
+}
+
+func (q *PostgresJobQueue) Enqueue(ctx context.Context, payload []byte) error {
+	return q.EnqueueAt(ctx, payload, time.Now())
+}
+
+func (q *PostgresJobQueue) EnqueueAt(ctx context.Context, payload []byte, at time.Time) error {
+	return repository.Conn(ctx, q.db).Create(&JobRecord{Queue: q.queue, Payload: string(payload), RunAt: at}).Error
+}
+
+func (q *PostgresJobQueue) Consume(ctx context.Context, max int, wait time.Duration) ([]Message, error) {
+	deadline := time.Now().Add(wait)
+	for {
+		msgs, err := q.claim(ctx, max, time.Now())
+		if err != nil || len(msgs) > 0 || !time.Now().Before(deadline) {
+			return msgs, err
+		}
+		delay := time.Until(deadline)
+		if delay > postgresPollInterval {
+			delay = postgresPollInterval
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+}
+
+func (q *PostgresJobQueue) claim(ctx context.Context, max int, now time.Time) ([]Message, error) {
+	var rows []JobRecord
+	err := q.db.WithContext(ctx).Raw(claimJobsSQL, now.Add(q.lease), q.queue, now, now, max).Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	msgs := make([]Message, 0, len(rows))
+	for _, row := range rows {
+		msgs = append(msgs, Message{ID: strconv.FormatUint(row.ID, 10), Payload: []byte(row.Payload)})
+	}
+	return msgs, nil
+}
+
+func (q *PostgresJobQueue) Ack(ctx context.Context, msg Message) error {
+	return repository.Conn(ctx, q.db).Where("id = ?", msg.ID).Delete(&JobRecord{}).Error
+}
+
+func (q *PostgresJobQueue) Nack(ctx context.Context, msg Message, retryAt time.Time) error {
+	res := repository.Conn(ctx, q.db).Model(&JobRecord{}).Where("id = ?", msg.ID).Updates(map[string]interface{}{
+		"payload":      string(msg.Payload),
+		"run_at":       retryAt,
+		"locked_until": nil,
+	})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return domain.ErrNotFound
+	}
+	return nil
+}
+
+// PostgresDeadLetterStore keeps the dead letters of queue in queue_dead_letters
+type PostgresDeadLetterStore struct {
+	db    *gorm.DB
+	queue string
+}
+
+func NewPostgresDeadLetterStore(db *gorm.DB, queue string) *PostgresDeadLetterStore {
+	return &PostgresDeadLetterStore{db: db, queue: queue}
+}
+
+func (d *PostgresDeadLetterStore) Add(ctx context.Context, letter DeadLetter) error {
+	data, err := json.Marshal(letter.Job)
+	if err != nil {
+		return err
+	}
+	record := DeadLetterRecord{ID: letter.Job.ID, Queue: d.queue, Job: string(data), FailedAt: letter.FailedAt}
+	return repository.Conn(ctx, d.db).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "queue"}, {Name: "id"}},
+		UpdateAll: true,
+	}).Create(&record).Error
+}
+
+func (d *PostgresDeadLetterStore) List(ctx context.Context) ([]DeadLetter, error) {
+	var records []DeadLetterRecord
+	if err := repository.Conn(ctx, d.db).Where("queue = ?", d.queue).Order("failed_at DESC").Find(&records).Error; err != nil {
+		return nil, err
+	}
+	letters := make([]DeadLetter, 0, len(records))
+	for _, record := range records {
+		letter, err := record.toDeadLetter()
+		if err != nil {
+			return nil, err
+		}
+		letters = append(letters, *letter)
+	}
+	return letters, nil
+}
+
+func (d *PostgresDeadLetterStore) Get(ctx context.Context, id string) (*DeadLetter, error) {
+	var record DeadLetterRecord
+	err := repository.Conn(ctx, d.db).Where("queue = ? AND id = ?", d.queue, id).First(&record).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, domain.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return record.toDeadLetter()
+}
+
+func (d *PostgresDeadLetterStore) Delete(ctx context.Context, id string) error {
+	res := repository.Conn(ctx, d.db).Where("queue = ? AND id = ?", d.queue, id).Delete(&DeadLetterRecord{})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return domain.ErrNotFound
+	}
+	return nil
+}
+
+func (d *PostgresDeadLetterStore) Purge(ctx context.Context) (int64, error) {
+	res := repository.Conn(ctx, d.db).Where("queue = ?", d.queue).Delete(&DeadLetterRecord{})
+	return res.RowsAffected, res.Error
+}
+
+func (r DeadLetterRecord) toDeadLetter() (*DeadLetter, error) {
+	var job PaymentJob
+	if err := json.Unmarshal([]byte(r.Job), &job); err != nil {
+		return nil, err
+	}
+	return &DeadLetter{Job: job, FailedAt: r.FailedAt}, nil
+}
@@ -5,229 +5,393 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
-	"strings"
 	"time"
 
-	"strconv"
+	"github.com/google/uuid"
+
 	"ticket_app/domain"
-	"ticket_app/internal/redis"
+	"ticket_app/internal/repository"
 	bookingRepo "ticket_app/internal/repository/booking"
 	eventRepo "ticket_app/internal/repository/event"
 	paymentRepo "ticket_app/internal/repository/payment"
 )
 
+// PaymentQueue is what the booking flow needs from the queue
+type PaymentQueue interface {
+	EnqueuePayment(ctx context.Context, job PaymentJob) error
+}
+
+// HoldQueue is what the hold flow needs from the queue
+type HoldQueue interface {
+	ScheduleHoldExpiry(ctx context.Context, holdID uint, at time.Time) error
+}
+
+// WaitlistQueue is told about tickets given back to an event, so they can be
+// offered to its waitlist
+type WaitlistQueue interface {
+	NotifyTicketsReleased(ctx context.Context, eventID uint) error
+}
+
+// QueueService processes payment jobs, payment deadlines, hold expiries and
+// waitlist offers. It does not care which JobQueue backend carries them.
 type QueueService struct {
-	redis       *redis.Redis
-	ctx         context.Context
-	paymentRepo paymentRepo.PaymentRepository
-	bookingRepo bookingRepo.BookingRepository
-	eventRepo   eventRepo.EventRepository
+	jobs         JobQueue // PaymentJob payloads
+	timeouts     JobQueue // booking IDs, delivered at their payment deadline
+	holds        JobQueue // hold IDs, delivered when the hold expires
+	offers       JobQueue // event IDs that got tickets back
+	deadLetters  DeadLetterStore
+	txManager    repository.TxManager
+	paymentRepo  paymentRepo.PaymentRepository
+	bookingRepo  bookingRepo.BookingRepository
+	eventRepo    eventRepo.EventRepository
+	holdRepo     bookingRepo.HoldRepository
+	orderRepo    bookingRepo.OrderRepository
+	waitlistRepo bookingRepo.WaitlistRepository
+	retryPolicy  RetryPolicy
+	offerTTL     time.Duration
 }
 
+// PaymentJob carries its own retry state, so whichever worker picks it up next
+// knows how many attempts were made and why the last one failed. It pays either
+// one booking or, when OrderID is set, every booking of an order.
 type PaymentJob struct {
-	BookingID uint    `json:"booking_id"`
+	ID        string  `json:"id"`
+	BookingID uint    `json:"booking_id,omitempty"`
+	OrderID   uint    `json:"order_id,omitempty"`
 	Amount    float64 `json:"amount"`
+	Attempts  int     `json:"attempts"`
+	LastError string  `json:"last_error,omitempty"`
+}
+
+// subject names what job pays, for logs
+func (j PaymentJob) subject() string {
+	if j.OrderID != 0 {
+		return fmt.Sprintf("order %d", j.OrderID)
+	}
+	return fmt.Sprintf("booking %d", j.BookingID)
 }
 
 const (
-	QueueName      = "payment_queue"
-	PaymentTimeout = 15 * time.Minute
+	PaymentTimeout         = 15 * time.Minute
+	PaymentRecheckInterval = 30 * time.Second
+
+	consumeBatchSize     = 10
+	consumeWait          = 5 * time.Second
+	consumeRetryInterval = 5 * time.Second
 )
 
-func NewQueueService(redisClient *redis.Redis, paymentRepo paymentRepo.PaymentRepository, bookingRepo bookingRepo.BookingRepository, eventRepo eventRepo.EventRepository) *QueueService {
-	if redisClient == nil {
-		log.Fatalf("Redis client is nil in NewQueueService")
-	}
-	log.Printf("QueueService created with redisClient: %p, GetClient: %p", redisClient, redisClient.GetClient())
+func NewQueueService(jobs JobQueue, timeouts JobQueue, holds JobQueue, offers JobQueue, deadLetters DeadLetterStore, txManager repository.TxManager, paymentRepo paymentRepo.PaymentRepository, bookingRepo bookingRepo.BookingRepository, eventRepo eventRepo.EventRepository, holdRepo bookingRepo.HoldRepository, orderRepo bookingRepo.OrderRepository, waitlistRepo bookingRepo.WaitlistRepository) *QueueService {
 	return &QueueService{
-		redis:       redisClient,
-		ctx:         context.Background(),
-		paymentRepo: paymentRepo,
-		bookingRepo: bookingRepo,
-		eventRepo:   eventRepo,
+		jobs:         jobs,
+		timeouts:     timeouts,
+		holds:        holds,
+		offers:       offers,
+		deadLetters:  deadLetters,
+		txManager:    txManager,
+		paymentRepo:  paymentRepo,
+		bookingRepo:  bookingRepo,
+		eventRepo:    eventRepo,
+		holdRepo:     holdRepo,
+		orderRepo:    orderRepo,
+		waitlistRepo: waitlistRepo,
+		retryPolicy:  DefaultRetryPolicy,
+		offerTTL:     DefaultOfferTTL,
 	}
 }
 
-func (s *QueueService) EnqueuePayment(job PaymentJob) error {
-	log.Printf("Enqueuing payment job for booking %d", job.BookingID)
-	if s.redis == nil {
-		return fmt.Errorf("QueueService redis instance is nil")
-	}
-	client := s.redis.GetClient()
-	if client == nil {
-		return fmt.Errorf("Redis client is nil")
+// SetRetryPolicy overrides DefaultRetryPolicy for failing payment jobs
+func (s *QueueService) SetRetryPolicy(policy RetryPolicy) {
+	s.retryPolicy = policy
+}
+
+// SetOfferTTL overrides DefaultOfferTTL, how long a waitlist offer keeps its tickets
+func (s *QueueService) SetOfferTTL(ttl time.Duration) {
+	s.offerTTL = ttl
+}
+
+func (s *QueueService) EnqueuePayment(ctx context.Context, job PaymentJob) error {
+	log.Printf("Enqueuing payment job for %s", job.subject())
+	if job.ID == "" {
+		job.ID = uuid.NewString()
 	}
-	log.Printf("Check Redis Client: %p", client)
-	jobData, err := json.Marshal(job)
+	data, err := json.Marshal(job)
 	if err != nil {
 		return err
 	}
-	log.Printf("Enqueuing job: %v", jobData)
-	if err := client.RPush(s.ctx, QueueName, jobData).Err(); err != nil {
+	if err := s.jobs.Enqueue(ctx, data); err != nil {
 		return err
 	}
-	timeoutKey := fmt.Sprintf("payment:timeout:%d", job.BookingID)
-	if err := client.Set(s.ctx, timeoutKey, "pending", PaymentTimeout).Err(); err != nil {
+	deadline := time.Now().Add(PaymentTimeout)
+	if job.OrderID != 0 {
+		if err := s.ScheduleOrderTimeout(ctx, job.OrderID, deadline); err != nil {
+			return err
+		}
+	} else if err := s.ScheduleTimeout(ctx, job.BookingID, deadline); err != nil {
 		return err
 	}
-	log.Printf("Successfully enqueued job for booking %d", job.BookingID)
+	log.Printf("Successfully enqueued job for %s", job.subject())
 	return nil
 }
 
-func (s *QueueService) StartWorker() {
-	log.Printf("Starting worker with redisClient: %p", s.redis)
-	for {
-		if s.redis == nil {
-			log.Printf("QueueService redis instance is nil, skipping worker iteration")
-			time.Sleep(5 * time.Second)
-			continue
-		}
-		client := s.redis.GetClient()
-		if client == nil {
-			log.Printf("Redis client is nil, skipping worker iteration")
-			time.Sleep(5 * time.Second)
-			continue
-		}
+// StartWorker consumes payment jobs until ctx is cancelled
+func (s *QueueService) StartWorker(ctx context.Context) {
+	s.consume(ctx, "Payment worker", s.jobs, s.handlePaymentMessage)
+}
 
-		result, err := client.BLPop(s.ctx, 5*time.Second, QueueName).Result()
+// StartTimeoutChecker expires bookings whose payment deadline passed, until ctx is cancelled
+func (s *QueueService) StartTimeoutChecker(ctx context.Context) {
+	s.consume(ctx, "Timeout checker", s.timeouts, s.handleTimeoutMessage)
+}
+
+// StartHoldExpirer gives back the inventory of holds that expired, until ctx is cancelled
+func (s *QueueService) StartHoldExpirer(ctx context.Context) {
+	s.consume(ctx, "Hold expirer", s.holds, s.handleHoldMessage)
+}
+
+// StartWaitlistOfferer offers tickets given back to events to their waitlists, until ctx is cancelled
+func (s *QueueService) StartWaitlistOfferer(ctx context.Context) {
+	s.consume(ctx, "Waitlist offerer", s.offers, s.handleOfferMessage)
+}
+
+// consume reads q until ctx is cancelled. Messages already read are still
+// handled to the end on a context that is not cancelled with ctx, so a
+// shutdown drains in-flight payments instead of aborting them halfway.
+func (s *QueueService) consume(ctx context.Context, name string, q JobQueue, handle func(ctx context.Context, msg Message)) {
+	log.Printf("%s started", name)
+	handleCtx := context.WithoutCancel(ctx)
+	for ctx.Err() == nil {
+		msgs, err := q.Consume(ctx, consumeBatchSize, consumeWait)
 		if err != nil {
-			log.Printf("Error popping from queue: %v", err)
-			if err.Error() == "redis: connection closed" || err.Error() == "dial tcp 127.0.0.1:6379: connect: connection refused" {
-				log.Printf("Redis connection lost, retrying in 5 seconds...")
-				time.Sleep(5 * time.Second)
+			if ctx.Err() != nil {
+				break
+			}
+			log.Printf("%s failed to read jobs: %v, retrying in %v", name, err, consumeRetryInterval)
+			select {
+			case <-ctx.Done():
+			case <-time.After(consumeRetryInterval):
 			}
 			continue
 		}
-		if len(result) == 0 {
-			continue
-		}
-		if len(result) < 2 {
-			log.Printf("Invalid result format from queue: %v", result)
-			continue
+		for _, msg := range msgs {
+			handle(handleCtx, msg)
 		}
+	}
+	log.Printf("%s stopped", name)
+}
 
-		var job PaymentJob
-		if err := json.Unmarshal([]byte(result[1]), &job); err != nil {
-			log.Printf("Error unmarshaling job: %v", err)
-			continue
+// handlePaymentMessage processes one payment job. The message is acked once the
+// job is finished or dead-lettered, and nacked to be delivered again when the
+// payment is still pending or the attempt failed. If even that fails the
+// message stays leased and the backend redelivers it later.
+func (s *QueueService) handlePaymentMessage(ctx context.Context, msg Message) {
+	var job PaymentJob
+	if err := json.Unmarshal(msg.Payload, &job); err != nil {
+		log.Printf("Error unmarshaling job %s: %v", msg.ID, err)
+		s.ack(ctx, s.jobs, msg)
+		return
+	}
+
+	done, err := s.processPayment(ctx, job)
+	if err != nil {
+		log.Printf("Error processing payment for %s (attempt %d): %v", job.subject(), job.Attempts+1, err)
+		if err := s.retryOrDeadLetter(ctx, msg, job, err, time.Now()); err != nil {
+			log.Printf("Failed to retry payment job for %s: %v", job.subject(), err)
+		}
+		return
+	}
+	if !done {
+		if err := s.jobs.Nack(ctx, msg, time.Now().Add(PaymentRecheckInterval)); err != nil {
+			log.Printf("Failed to re-schedule payment job for %s: %v", job.subject(), err)
 		}
+		return
+	}
+	s.ack(ctx, s.jobs, msg)
+}
 
-		if err := s.processPayment(job); err != nil {
-			log.Printf("Error processing payment for booking %d: %v", job.BookingID, err)
+// retryOrDeadLetter records the failed attempt on job and either nacks it for
+// the policy's backoff or, once attempts are exhausted, moves it to the
+// dead-letter store and acks it.
+func (s *QueueService) retryOrDeadLetter(ctx context.Context, msg Message, job PaymentJob, cause error, now time.Time) error {
+	job.Attempts++
+	job.LastError = cause.Error()
+	if s.retryPolicy.Exhausted(job.Attempts) {
+		log.Printf("Payment job %s for %s failed %d times, moving it to the dead-letter queue", job.ID, job.subject(), job.Attempts)
+		if err := s.deadLetter(ctx, job, now); err != nil {
+			return err
 		}
+		return s.jobs.Ack(ctx, msg)
+	}
+	data, err := json.Marshal(job)
+	if err != nil {
+		return err
 	}
+	msg.Payload = data
+	return s.jobs.Nack(ctx, msg, now.Add(s.retryPolicy.Backoff(job.Attempts)))
 }
-func (s *QueueService) processPayment(job PaymentJob) error {
+
+func (s *QueueService) ack(ctx context.Context, q JobQueue, msg Message) {
+	if err := q.Ack(ctx, msg); err != nil {
+		log.Printf("Failed to ack job %s: %v", msg.ID, err)
+	}
+}
+
+// processPayment checks the payment of job.BookingID (or job.OrderID) and moves
+// the booking (or order) to its final status. It reports done=false while the payment is still PENDING
+// and the booking is waiting for it, so the caller re-schedules the job.
+func (s *QueueService) processPayment(ctx context.Context, job PaymentJob) (bool, error) {
+	if job.OrderID != 0 {
+		return s.processOrderPayment(ctx, job)
+	}
 	log.Printf("Checking payment status for booking %d", job.BookingID)
 	// Simulate checking payment status from external system (no update)
-	payment, err := s.paymentRepo.FindByBookingID(job.BookingID)
+	payment, err := s.paymentRepo.FindByBookingID(ctx, job.BookingID)
 	if err != nil || payment == nil {
 		log.Printf("Payment for booking %d not found or error: %v", job.BookingID, err)
-		return fmt.Errorf("payment not found or error")
+		return false, fmt.Errorf("payment not found or error")
 	}
 
 	// Assume status is updated externally, simulate for testing
 	// In real case, replace with actual payment status check
-	if payment.Status == domain.PaymentStatusCompleted {
-		if err := s.updateBookingStatus(job.BookingID, string(domain.BookingStatusConfirmed)); err != nil {
-			return err
+	switch payment.Status {
+	case domain.PaymentStatusCompleted:
+		if err := s.updateBookingStatus(ctx, job.BookingID, domain.BookingStatusConfirmed); err != nil {
+			return false, err
 		}
-		timeoutKey := fmt.Sprintf("payment:timeout:%d", job.BookingID)
-		if s.redis == nil {
-			log.Printf("Redis instance is nil, cannot clear timeout key for booking %d", job.BookingID)
-			return fmt.Errorf("Redis instance is nil")
+	case domain.PaymentStatusFailed:
+		if err := s.updateBookingStatus(ctx, job.BookingID, domain.BookingStatusCancelled); err != nil {
+			return false, err
 		}
-		if err := s.redis.GetClient().Del(s.ctx, timeoutKey).Err(); err != nil {
-			log.Printf("Failed to clear timeout key for booking %d: %v", job.BookingID, err)
-		}
-	} else if payment.Status == domain.PaymentStatusFailed {
-		if err := s.updateBookingStatus(job.BookingID, string(domain.BookingStatusCancelled)); err != nil {
-			return err
-		}
-		timeoutKey := fmt.Sprintf("payment:timeout:%d", job.BookingID)
-		if s.redis == nil {
-			log.Printf("Redis instance is nil, cannot clear timeout key for booking %d", job.BookingID)
-			return fmt.Errorf("Redis instance is nil")
+	default:
+		booking, err := s.bookingRepo.FindById(ctx, job.BookingID)
+		if err != nil {
+			return false, err
 		}
-		if err := s.redis.GetClient().Del(s.ctx, timeoutKey).Err(); err != nil {
-			log.Printf("Failed to clear timeout key for booking %d: %v", job.BookingID, err)
+		if booking.Status == domain.BookingStatusPending {
+			return false, nil
 		}
+		log.Printf("Booking %d is already %s, dropping payment job", job.BookingID, booking.Status)
+		return true, nil
 	}
-	return nil
+	return true, nil
 }
 
-func (s *QueueService) StartTimeoutChecker() {
-	log.Printf("Starting timeout checker with redisClient: %p", s.redis)
-	ticker := time.NewTicker(1 * time.Minute)
-	defer ticker.Stop()
-
-	for range ticker.C {
-		if s.redis == nil {
-			log.Printf("QueueService redis instance is nil, skipping timeout check")
-			continue
+// updateBookingStatus moves a PENDING booking to status. Cancelling also marks
+// a still-pending payment as FAILED and releases the tickets; all writes share
+// one transaction with the booking row locked. Released tickets go to the
+// waitlist of the event once the transaction committed.
+func (s *QueueService) updateBookingStatus(ctx context.Context, bookingID uint, status domain.BookingStatus) error {
+	var released *domain.Booking
+	err := s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		booking, err := s.bookingRepo.FindByIdForUpdate(ctx, bookingID)
+		if err != nil {
+			return err
 		}
-		client := s.redis.GetClient()
-		if client == nil {
-			log.Printf("Redis client is nil, skipping timeout check")
-			continue
+		if booking.Status == status {
+			return nil // No update needed if status is already the same
 		}
-
-		keys, err := client.Keys(s.ctx, "payment:timeout:*").Result()
-		if err != nil {
-			log.Printf("Error fetching timeout keys: %v", err)
-			continue
+		if booking.Status != domain.BookingStatusPending {
+			log.Printf("Booking %d is already %s, not moving it to %s", bookingID, booking.Status, status)
+			return nil
 		}
-
-		for _, key := range keys {
-			bookingIDStr := strings.TrimPrefix(key, "payment:timeout:")
-			bookingID, err := strconv.Atoi(bookingIDStr)
-			if err != nil {
-				log.Printf("Invalid booking ID in key %s: %v", key, err)
-				continue
-			}
-
-			// Check if timeout has expired (key still exists after 15 minutes)
-			if exists := client.Exists(s.ctx, key).Val() > 0; exists {
-				payment, err := s.paymentRepo.FindByBookingID(uint(bookingID))
-				if err != nil || payment == nil {
-					log.Printf("Payment for booking %d not found or error: %v, updating booking to FAILED", bookingID, err)
-					if err := s.updateBookingStatus(uint(bookingID), "FAILED"); err != nil {
-						log.Printf("Error updating booking %d to FAILED: %v", bookingID, err)
-					}
-					continue
-				}
-				if payment.Status != "PAID" {
-					log.Printf("Payment for booking %d is %s, updating booking to FAILED", bookingID, payment.Status)
-					if err := s.updateBookingStatus(uint(bookingID), "FAILED"); err != nil {
-						log.Printf("Error updating booking %d to FAILED: %v", bookingID, err)
-					}
-				} else {
-					log.Printf("Payment for booking %d is PAID, no action needed", bookingID)
+		booking.Status = status
+		if status == domain.BookingStatusCancelled {
+			payment, err := s.paymentRepo.FindByBookingID(ctx, bookingID)
+			if err == nil && payment.Status == domain.PaymentStatusPending {
+				payment.Status = domain.PaymentStatusFailed
+				if err := s.paymentRepo.UpdatePayment(ctx, payment); err != nil {
+					return err
 				}
 			}
+			// Release tickets if cancelled
+			if err := s.eventRepo.ReleaseTickets(ctx, booking); err != nil {
+				return err
+			}
+			released = booking
 		}
+		return s.bookingRepo.Update(ctx, booking)
+	})
+	if err == nil && released != nil {
+		s.notifyTicketsReleased(ctx, released.EventID)
 	}
+	return err
 }
 
-func (s *QueueService) updateBookingStatus(bookingID uint, status string) error {
-	booking, err := s.bookingRepo.FindById(bookingID)
+// processOrderPayment is processPayment for the single payment of an order
+func (s *QueueService) processOrderPayment(ctx context.Context, job PaymentJob) (bool, error) {
+	log.Printf("Checking payment status for order %d", job.OrderID)
+	payment, err := s.paymentRepo.FindByOrderID(ctx, job.OrderID)
+	if err != nil || payment == nil {
+		log.Printf("Payment for order %d not found or error: %v", job.OrderID, err)
+		return false, fmt.Errorf("payment not found or error")
+	}
+
+	switch payment.Status {
+	case domain.PaymentStatusCompleted:
+		return true, s.updateOrderStatus(ctx, job.OrderID, domain.BookingStatusConfirmed)
+	case domain.PaymentStatusFailed:
+		return true, s.updateOrderStatus(ctx, job.OrderID, domain.BookingStatusCancelled)
+	}
+	order, err := s.orderRepo.FindById(ctx, job.OrderID)
 	if err != nil {
-		return err
+		return false, err
 	}
-	if booking.Status == "status" {
-		return nil // No update needed if status is already the same
-	}
-	booking.Status = "status"
-	if status == "FAILED" {
-		// Release tickets if failed
-		event, err := s.eventRepo.FindByBookingID(booking.EventID)
-		if err == nil {
-			event.TotalTickets += booking.Quantity
-			if err := s.eventRepo.Update(event); err != nil {
-				log.Printf("Failed to release tickets for booking %d: %v", bookingID, err)
+	if order.Status == domain.BookingStatusPending {
+		return false, nil
+	}
+	log.Printf("Order %d is already %s, dropping payment job", job.OrderID, order.Status)
+	return true, nil
+}
+
+// updateOrderStatus moves a PENDING order and its pending bookings to status.
+// Cancelling also marks a still-pending payment as FAILED and releases the
+// tickets of every booking; all writes share one transaction with the order
+// row locked.
+func (s *QueueService) updateOrderStatus(ctx context.Context, orderID uint, status domain.BookingStatus) error {
+	var released []uint // events
+	err := s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		order, err := s.orderRepo.FindByIdForUpdate(ctx, orderID)
+		if err != nil {
+			return err
+		}
+		if order.Status == status {
+			return nil
+		}
+		if order.Status != domain.BookingStatusPending {
+			log.Printf("Order %d is already %s, not moving it to %s", orderID, order.Status, status)
+			return nil
+		}
+		order.Status = status
+		if status == domain.BookingStatusCancelled {
+			payment, err := s.paymentRepo.FindByOrderID(ctx, orderID)
+			if err == nil && payment.Status == domain.PaymentStatusPending {
+				payment.Status = domain.PaymentStatusFailed
+				if err := s.paymentRepo.UpdatePayment(ctx, payment); err != nil {
+					return err
+				}
 			}
 		}
+		for i := range order.Bookings {
+			booking := &order.Bookings[i]
+			if booking.Status != domain.BookingStatusPending {
+				continue
+			}
+			booking.Status = status
+			if status == domain.BookingStatusCancelled {
+				if err := s.eventRepo.ReleaseTickets(ctx, booking); err != nil {
+					return err
+				}
+				released = append(released, booking.EventID)
+			}
+			if err := s.bookingRepo.Update(ctx, booking); err != nil {
+				return err
+			}
+		}
+		return s.orderRepo.Update(ctx, order)
+	})
+	if err == nil {
+		for _, eventID := range released {
+			s.notifyTicketsReleased(ctx, eventID)
+		}
 	}
-	return s.bookingRepo.Update(booking)
+	return err
 }
-
@@ -0,0 +1,710 @@
+package queue
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	goredis "github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"ticket_app/domain"
+	"ticket_app/internal/config"
+	"ticket_app/internal/redis"
+	bookingRepo "ticket_app/internal/repository/booking"
+	eventRepo "ticket_app/internal/repository/event"
+	paymentRepo "ticket_app/internal/repository/payment"
+)
+
+// fakeStore is an in-memory stand-in for the booking, event, payment, hold,
+// order and waitlist repositories. Only the methods used by QueueService are
+// implemented.
+type fakeStore struct {
+	mu            sync.Mutex
+	bookings      map[uint]*domain.Booking
+	payments      map[uint]*domain.Payment // by booking ID
+	holds         map[uint]*domain.Hold
+	orders        map[uint]*domain.Order
+	orderPayments map[uint]*domain.Payment // by order ID
+	entries       map[uint]*domain.WaitlistEntry
+	released      map[uint]int // tickets released per event
+	free          map[uint]int // tickets a waitlist offer can take, per ticket type (0 for event tickets)
+	locks         map[uint]int // FindByIdForUpdate calls per booking
+}
+
+func newFakeStore() *fakeStore {
+	return &fakeStore{
+		bookings: map[uint]*domain.Booking{},
+		payments: map[uint]*domain.Payment{},
+		holds:         map[uint]*domain.Hold{},
+		orders:        map[uint]*domain.Order{},
+		orderPayments: map[uint]*domain.Payment{},
+		entries:       map[uint]*domain.WaitlistEntry{},
+		released:      map[uint]int{},
+		free:          map[uint]int{},
+		locks:         map[uint]int{},
+	}
+}
+
+func (f *fakeStore) addBooking(id, eventID uint, qty int, paymentStatus domain.PaymentStatus) {
+	f.bookings[id] = &domain.Booking{ID: id, EventID: eventID, Quantity: qty, Status: domain.BookingStatusPending}
+	f.payments[id] = &domain.Payment{ID: id, BookingID: &id, Status: paymentStatus}
+}
+
+// addOrder adds a pending order with one booking per event of qty, starting at booking ID id*10
+func (f *fakeStore) addOrder(id uint, qty map[uint]int, paymentStatus domain.PaymentStatus) {
+	f.orders[id] = &domain.Order{ID: id, Status: domain.BookingStatusPending}
+	bookingID := id * 10
+	for eventID, q := range qty {
+		f.bookings[bookingID] = &domain.Booking{ID: bookingID, EventID: eventID, Quantity: q, Status: domain.BookingStatusPending, OrderID: &id}
+		bookingID++
+	}
+	f.orderPayments[id] = &domain.Payment{ID: 100 + id, OrderID: &id, Status: paymentStatus}
+}
+
+type fakeBookingRepo struct {
+	bookingRepo.BookingRepository
+	*fakeStore
+}
+
+func (r fakeBookingRepo) FindById(ctx context.Context, id uint) (*domain.Booking, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	b, ok := r.bookings[id]
+	if !ok {
+		return nil, domain.ErrNotFound
+	}
+	cp := *b
+	return &cp, nil
+}
+
+func (r fakeBookingRepo) FindByIdForUpdate(ctx context.Context, id uint) (*domain.Booking, error) {
+	r.mu.Lock()
+	r.locks[id]++
+	r.mu.Unlock()
+	return r.FindById(ctx, id)
+}
+
+func (r fakeBookingRepo) Update(ctx context.Context, booking *domain.Booking) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	cp := *booking
+	r.bookings[booking.ID] = &cp
+	return nil
+}
+
+type fakePaymentRepo struct {
+	paymentRepo.PaymentRepository
+	*fakeStore
+}
+
+func (r fakePaymentRepo) FindByBookingID(ctx context.Context, bookingID uint) (*domain.Payment, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	p, ok := r.payments[bookingID]
+	if !ok {
+		return nil, domain.ErrNotFound
+	}
+	cp := *p
+	return &cp, nil
+}
+
+func (r fakePaymentRepo) UpdatePayment(ctx context.Context, payment *domain.Payment) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	cp := *payment
+	if payment.OrderID != nil {
+		r.orderPayments[*payment.OrderID] = &cp
+		return nil
+	}
+	r.payments[*payment.BookingID] = &cp
+	return nil
+}
+
+func (r fakePaymentRepo) FindByOrderID(ctx context.Context, orderID uint) (*domain.Payment, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	p, ok := r.orderPayments[orderID]
+	if !ok {
+		return nil, domain.ErrNotFound
+	}
+	cp := *p
+	return &cp, nil
+}
+
+type fakeEventRepo struct {
+	eventRepo.EventRepository
+	*fakeStore
+}
+
+func (r fakeEventRepo) ReleaseTickets(ctx context.Context, booking *domain.Booking) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.released[booking.EventID] += booking.Quantity
+	r.free[0] += booking.Quantity
+	return nil
+}
+
+type fakeHoldRepo struct {
+	bookingRepo.HoldRepository
+	*fakeStore
+}
+
+func (r fakeHoldRepo) Release(ctx context.Context, id uint, status domain.HoldStatus) (*domain.Hold, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	h, ok := r.holds[id]
+	if !ok {
+		return nil, domain.ErrHoldNotFound
+	}
+	if h.Status != domain.HoldStatusActive {
+		return nil, nil
+	}
+	h.Status = status
+	r.released[h.EventID] += h.Quantity
+	var tier uint
+	for _, item := range h.Items {
+		tier = item.TicketTypeID
+	}
+	r.free[tier] += h.Quantity
+	for _, e := range r.entries {
+		if e.HoldID != nil && *e.HoldID == id && e.Status == domain.WaitlistStatusOffered {
+			e.Status = domain.WaitlistStatusForHold(status)
+		}
+	}
+	cp := *h
+	return &cp, nil
+}
+
+type fakeWaitlistRepo struct {
+	bookingRepo.WaitlistRepository
+	*fakeStore
+}
+
+func (r fakeWaitlistRepo) Waiting(ctx context.Context, eventID uint) ([]domain.WaitlistEntry, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var entries []domain.WaitlistEntry
+	for _, e := range r.entries {
+		if e.EventID == eventID && e.Status == domain.WaitlistStatusWaiting {
+			entries = append(entries, *e)
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].ID < entries[j].ID })
+	return entries, nil
+}
+
+// Offer takes the tickets from free instead of reserving them on an event
+func (r fakeWaitlistRepo) Offer(ctx context.Context, id uint, hold *domain.Hold) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	e, ok := r.entries[id]
+	if !ok || e.Status != domain.WaitlistStatusWaiting {
+		return false, nil
+	}
+	var tier uint
+	if e.TicketTypeID != nil {
+		tier = *e.TicketTypeID
+		hold.Items = []domain.HoldItem{{TicketTypeID: tier, Quantity: e.Quantity}}
+	}
+	if r.free[tier] < e.Quantity {
+		return false, domain.ErrNotEnoughTickets
+	}
+	r.free[tier] -= e.Quantity
+	hold.ID = uint(len(r.holds) + 100)
+	hold.UserID, hold.EventID, hold.Quantity = e.UserID, e.EventID, e.Quantity
+	hold.Status = domain.HoldStatusActive
+	cp := *hold
+	r.holds[hold.ID] = &cp
+	e.Status = domain.WaitlistStatusOffered
+	e.HoldID = &cp.ID
+	return true, nil
+}
+
+type fakeOrderRepo struct {
+	bookingRepo.OrderRepository
+	*fakeStore
+}
+
+func (r fakeOrderRepo) FindById(ctx context.Context, id uint) (*domain.Order, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	o, ok := r.orders[id]
+	if !ok {
+		return nil, domain.ErrNotFound
+	}
+	cp := *o
+	cp.Bookings = nil
+	for _, b := range r.bookings {
+		if b.OrderID != nil && *b.OrderID == id {
+			cp.Bookings = append(cp.Bookings, *b)
+		}
+	}
+	return &cp, nil
+}
+
+func (r fakeOrderRepo) FindByIdForUpdate(ctx context.Context, id uint) (*domain.Order, error) {
+	return r.FindById(ctx, id)
+}
+
+func (r fakeOrderRepo) Update(ctx context.Context, order *domain.Order) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	cp := *order
+	r.orders[order.ID] = &cp
+	return nil
+}
+
+type fakeTxManager struct{}
+
+func (fakeTxManager) WithinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
+
+func newTestRedis(t *testing.T, mr *miniredis.Miniredis) *redis.Redis {
+	port, err := strconv.Atoi(mr.Port())
+	require.NoError(t, err)
+	r, err := redis.NewRedis(config.RedisConfig{Host: mr.Host(), Port: port})
+	require.NoError(t, err)
+	t.Cleanup(func() { r.Close() })
+	return r
+}
+
+func newTestQueueService(t *testing.T, mr *miniredis.Miniredis, store *fakeStore) *QueueService {
+	r := newTestRedis(t, mr)
+	return NewQueueService(
+		NewRedisJobQueue(r, StreamName, ConsumerGroup, DelayedSetName),
+		NewRedisJobQueue(r, TimeoutStreamName, TimeoutConsumerGroup, TimeoutSetName),
+		NewRedisJobQueue(r, HoldStreamName, HoldConsumerGroup, HoldSetName),
+		NewRedisJobQueue(r, WaitlistStreamName, WaitlistConsumerGroup, WaitlistSetName),
+		NewRedisDeadLetterStore(r, DeadLetterSetName),
+		fakeTxManager{}, fakePaymentRepo{fakeStore: store}, fakeBookingRepo{fakeStore: store}, fakeEventRepo{fakeStore: store},
+		fakeHoldRepo{fakeStore: store}, fakeOrderRepo{fakeStore: store}, fakeWaitlistRepo{fakeStore: store},
+	)
+}
+
+func newMemoryQueueService(store *fakeStore) *QueueService {
+	return NewQueueService(
+		NewMemoryJobQueue(time.Minute), NewMemoryJobQueue(time.Minute), NewMemoryJobQueue(time.Minute), NewMemoryJobQueue(time.Minute),
+		NewMemoryDeadLetterStore(),
+		fakeTxManager{}, fakePaymentRepo{fakeStore: store}, fakeBookingRepo{fakeStore: store}, fakeEventRepo{fakeStore: store},
+		fakeHoldRepo{fakeStore: store}, fakeOrderRepo{fakeStore: store}, fakeWaitlistRepo{fakeStore: store},
+	)
+}
+
+// drain hands every message currently due on q to handle
+func drain(t *testing.T, q JobQueue, handle func(ctx context.Context, msg Message)) {
+	ctx := context.Background()
+	for {
+		msgs, err := q.Consume(ctx, 10, 10*time.Millisecond)
+		require.NoError(t, err)
+		if len(msgs) == 0 {
+			return
+		}
+		for _, msg := range msgs {
+			handle(ctx, msg)
+		}
+	}
+}
+
+func TestCheckTimeoutsCancelsOnlyDueBookings(t *testing.T) {
+	mr := miniredis.RunT(t)
+	store := newFakeStore()
+	store.addBooking(1, 10, 2, domain.PaymentStatusPending)
+	store.addBooking(2, 10, 3, domain.PaymentStatusPending)
+	store.addBooking(3, 10, 4, domain.PaymentStatusCompleted)
+	s := newTestQueueService(t, mr, store)
+
+	ctx := context.Background()
+	now := time.Now()
+	require.NoError(t, s.ScheduleTimeout(ctx, 1, now.Add(-time.Minute)))
+	require.NoError(t, s.ScheduleTimeout(ctx, 2, now.Add(time.Minute)))
+	require.NoError(t, s.ScheduleTimeout(ctx, 3, now.Add(-time.Second)))
+
+	drain(t, s.timeouts, s.handleTimeoutMessage)
+
+	assert.Equal(t, domain.BookingStatusCancelled, store.bookings[1].Status)
+	assert.Equal(t, domain.PaymentStatusFailed, store.payments[1].Status)
+	assert.Equal(t, domain.BookingStatusPending, store.bookings[2].Status)
+	assert.Equal(t, domain.BookingStatusConfirmed, store.bookings[3].Status)
+	assert.Equal(t, 2, store.released[10])
+
+	members, err := mr.ZMembers(TimeoutSetName)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"2"}, members)
+}
+
+func TestTimeoutOfFinishedBookingDoesNothing(t *testing.T) {
+	store := newFakeStore()
+	store.addBooking(1, 10, 2, domain.PaymentStatusCompleted)
+	s := newMemoryQueueService(store)
+
+	ctx := context.Background()
+	require.NoError(t, s.EnqueuePayment(ctx, PaymentJob{BookingID: 1, Amount: 20}))
+	drain(t, s.jobs, s.handlePaymentMessage)
+	require.Equal(t, domain.BookingStatusConfirmed, store.bookings[1].Status)
+
+	require.NoError(t, s.ScheduleTimeout(ctx, 1, time.Now().Add(-time.Second)))
+	drain(t, s.timeouts, s.handleTimeoutMessage)
+
+	assert.Equal(t, domain.BookingStatusConfirmed, store.bookings[1].Status)
+	assert.Zero(t, store.released[10])
+}
+
+func TestCheckTimeoutsClaimsEachBookingOnce(t *testing.T) {
+	mr := miniredis.RunT(t)
+	store := newFakeStore()
+	const bookings = 300
+	for id := uint(1); id <= bookings; id++ {
+		store.addBooking(id, 10, 1, domain.PaymentStatusPending)
+	}
+
+	// Several instances sharing the same Redis, as with multiple app replicas.
+	instances := make([]*QueueService, 4)
+	for i := range instances {
+		instances[i] = newTestQueueService(t, mr, store)
+		instances[i].timeouts.(*RedisJobQueue).consumer = fmt.Sprintf("instance-%d", i)
+	}
+	ctx := context.Background()
+	now := time.Now()
+	for id := uint(1); id <= bookings; id++ {
+		require.NoError(t, instances[0].ScheduleTimeout(ctx, id, now.Add(-time.Second)))
+	}
+
+	var wg sync.WaitGroup
+	for _, s := range instances {
+		wg.Add(1)
+		go func(s *QueueService) {
+			defer wg.Done()
+			drain(t, s.timeouts, s.handleTimeoutMessage)
+		}(s)
+	}
+	wg.Wait()
+
+	for id := uint(1); id <= bookings; id++ {
+		assert.Equal(t, 1, store.locks[id], "booking %d", id)
+		assert.Equal(t, domain.BookingStatusCancelled, store.bookings[id].Status)
+	}
+	assert.Equal(t, bookings, store.released[10])
+}
+
+func TestHoldExpiryReleasesOnlyDueActiveHolds(t *testing.T) {
+	mr := miniredis.RunT(t)
+	store := newFakeStore()
+	store.holds[1] = &domain.Hold{ID: 1, EventID: 10, Quantity: 2, Status: domain.HoldStatusActive}
+	store.holds[2] = &domain.Hold{ID: 2, EventID: 10, Quantity: 3, Status: domain.HoldStatusActive}
+	store.holds[3] = &domain.Hold{ID: 3, EventID: 10, Quantity: 4, Status: domain.HoldStatusConverted}
+	s := newTestQueueService(t, mr, store)
+
+	ctx := context.Background()
+	now := time.Now()
+	require.NoError(t, s.ScheduleHoldExpiry(ctx, 1, now.Add(-time.Second)))
+	require.NoError(t, s.ScheduleHoldExpiry(ctx, 2, now.Add(time.Minute)))
+	require.NoError(t, s.ScheduleHoldExpiry(ctx, 3, now.Add(-time.Second)))
+	require.NoError(t, s.ScheduleHoldExpiry(ctx, 4, now.Add(-time.Second))) // hold không còn
+
+	drain(t, s.holds, s.handleHoldMessage)
+
+	assert.Equal(t, domain.HoldStatusExpired, store.holds[1].Status)
+	assert.Equal(t, domain.HoldStatusActive, store.holds[2].Status)
+	assert.Equal(t, domain.HoldStatusConverted, store.holds[3].Status)
+	assert.Equal(t, 2, store.released[10])
+
+	members, err := mr.ZMembers(HoldSetName)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"2"}, members)
+}
+
+func TestWaitlistOffersReleasedTicketsFirstComeFirstServed(t *testing.T) {
+	store := newFakeStore()
+	store.addBooking(1, 10, 2, domain.PaymentStatusPending)
+	store.addBooking(2, 10, 1, domain.PaymentStatusPending)
+	vip := uint(7)
+	store.entries[1] = &domain.WaitlistEntry{ID: 1, UserID: 1, EventID: 10, Quantity: 3, Status: domain.WaitlistStatusWaiting}
+	store.entries[2] = &domain.WaitlistEntry{ID: 2, UserID: 2, EventID: 10, Quantity: 1, Status: domain.WaitlistStatusWaiting}
+	store.entries[3] = &domain.WaitlistEntry{ID: 3, UserID: 3, EventID: 10, TicketTypeID: &vip, Quantity: 1, Status: domain.WaitlistStatusWaiting}
+	store.entries[4] = &domain.WaitlistEntry{ID: 4, UserID: 4, EventID: 11, Quantity: 1, Status: domain.WaitlistStatusWaiting}
+	store.free[vip] = 1
+	s := newMemoryQueueService(store)
+	ctx := context.Background()
+
+	// Booking 1 hết hạn trả 2 vé: entry 1 cần 3 nên chờ, entry 2 xếp sau nó cũng
+	// chờ; hàng đợi của hạng VIP riêng nên entry 3 nhận offer
+	require.NoError(t, s.ScheduleTimeout(ctx, 1, time.Now().Add(-time.Second)))
+	drain(t, s.timeouts, s.handleTimeoutMessage)
+	drain(t, s.offers, s.handleOfferMessage)
+	assert.Equal(t, domain.WaitlistStatusWaiting, store.entries[1].Status)
+	assert.Equal(t, domain.WaitlistStatusWaiting, store.entries[2].Status)
+	require.Equal(t, domain.WaitlistStatusOffered, store.entries[3].Status)
+	offer := store.holds[*store.entries[3].HoldID]
+	assert.Equal(t, uint(3), offer.UserID)
+	assert.NotEmpty(t, offer.Token)
+	assert.WithinDuration(t, time.Now().Add(DefaultOfferTTL), offer.ExpiresAt, time.Minute)
+	assert.Equal(t, domain.WaitlistStatusWaiting, store.entries[4].Status)
+
+	// Thêm 1 vé: đủ cho entry 1
+	require.NoError(t, s.ScheduleTimeout(ctx, 2, time.Now().Add(-time.Second)))
+	drain(t, s.timeouts, s.handleTimeoutMessage)
+	drain(t, s.offers, s.handleOfferMessage)
+	require.Equal(t, domain.WaitlistStatusOffered, store.entries[1].Status)
+	assert.Equal(t, domain.WaitlistStatusWaiting, store.entries[2].Status)
+	assert.Zero(t, store.free[0])
+
+	// Offer của entry 1 hết hạn: vé chuyển cho entry 2
+	require.NoError(t, s.ScheduleHoldExpiry(ctx, *store.entries[1].HoldID, time.Now().Add(-time.Second)))
+	drain(t, s.holds, s.handleHoldMessage)
+	drain(t, s.offers, s.handleOfferMessage)
+	assert.Equal(t, domain.WaitlistStatusExpired, store.entries[1].Status)
+	require.Equal(t, domain.WaitlistStatusOffered, store.entries[2].Status)
+	assert.Equal(t, 2, store.free[0])
+}
+
+func pendingCount(t *testing.T, s *QueueService) int64 {
+	client, err := s.jobs.(*RedisJobQueue).client()
+	require.NoError(t, err)
+	pending, err := client.XPending(context.Background(), StreamName, ConsumerGroup).Result()
+	require.NoError(t, err)
+	return pending.Count
+}
+
+func TestWorkerConfirmsPaidBooking(t *testing.T) {
+	mr := miniredis.RunT(t)
+	store := newFakeStore()
+	store.addBooking(1, 10, 2, domain.PaymentStatusCompleted)
+	s := newTestQueueService(t, mr, store)
+	ctx := context.Background()
+
+	require.NoError(t, s.EnqueuePayment(ctx, PaymentJob{BookingID: 1, Amount: 20}))
+	drain(t, s.jobs, s.handlePaymentMessage)
+
+	assert.Equal(t, domain.BookingStatusConfirmed, store.bookings[1].Status)
+	assert.Zero(t, pendingCount(t, s))
+}
+
+func TestWorkerConfirmsPaidOrder(t *testing.T) {
+	store := newFakeStore()
+	store.addOrder(1, map[uint]int{10: 2, 11: 1}, domain.PaymentStatusCompleted)
+	s := newMemoryQueueService(store)
+	ctx := context.Background()
+
+	require.NoError(t, s.EnqueuePayment(ctx, PaymentJob{OrderID: 1, Amount: 30}))
+	drain(t, s.jobs, s.handlePaymentMessage)
+
+	assert.Equal(t, domain.BookingStatusConfirmed, store.orders[1].Status)
+	assert.Equal(t, domain.BookingStatusConfirmed, store.bookings[10].Status)
+	assert.Equal(t, domain.BookingStatusConfirmed, store.bookings[11].Status)
+
+	// Deadline của order đến sau khi đã trả tiền thì không huỷ gì
+	require.NoError(t, s.ScheduleOrderTimeout(ctx, 1, time.Now().Add(-time.Second)))
+	drain(t, s.timeouts, s.handleTimeoutMessage)
+	assert.Equal(t, domain.BookingStatusConfirmed, store.orders[1].Status)
+	assert.Empty(t, store.released)
+}
+
+func TestOrderTimeoutCancelsEveryBooking(t *testing.T) {
+	mr := miniredis.RunT(t)
+	store := newFakeStore()
+	store.addOrder(1, map[uint]int{10: 2, 11: 3}, domain.PaymentStatusPending)
+	store.addOrder(2, map[uint]int{10: 4}, domain.PaymentStatusPending)
+	store.addBooking(1, 10, 1, domain.PaymentStatusPending)
+	s := newTestQueueService(t, mr, store)
+
+	ctx := context.Background()
+	now := time.Now()
+	require.NoError(t, s.ScheduleOrderTimeout(ctx, 1, now.Add(-time.Minute)))
+	require.NoError(t, s.ScheduleOrderTimeout(ctx, 2, now.Add(time.Minute)))
+	require.NoError(t, s.ScheduleTimeout(ctx, 1, now.Add(time.Minute)))
+
+	drain(t, s.timeouts, s.handleTimeoutMessage)
+
+	assert.Equal(t, domain.BookingStatusCancelled, store.orders[1].Status)
+	assert.Equal(t, domain.PaymentStatusFailed, store.orderPayments[1].Status)
+	assert.Equal(t, domain.BookingStatusCancelled, store.bookings[10].Status)
+	assert.Equal(t, domain.BookingStatusCancelled, store.bookings[11].Status)
+	assert.Equal(t, map[uint]int{10: 2, 11: 3}, store.released)
+	assert.Equal(t, domain.BookingStatusPending, store.orders[2].Status)
+	assert.Equal(t, domain.BookingStatusPending, store.bookings[1].Status)
+
+	members, err := mr.ZMembers(TimeoutSetName)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"order:2", "1"}, members)
+}
+
+func TestWorkerReschedulesPendingPayment(t *testing.T) {
+	mr := miniredis.RunT(t)
+	store := newFakeStore()
+	store.addBooking(1, 10, 2, domain.PaymentStatusPending)
+	s := newTestQueueService(t, mr, store)
+	jobs := s.jobs.(*RedisJobQueue)
+	ctx := context.Background()
+
+	require.NoError(t, s.EnqueuePayment(ctx, PaymentJob{BookingID: 1, Amount: 20}))
+	drain(t, s.jobs, s.handlePaymentMessage)
+
+	assert.Equal(t, domain.BookingStatusPending, store.bookings[1].Status)
+	assert.Zero(t, pendingCount(t, s))
+	delayed, err := mr.ZMembers(DelayedSetName)
+	require.NoError(t, err)
+	assert.Len(t, delayed, 1)
+
+	// Once the re-check interval has passed the job is back on the stream.
+	require.NoError(t, jobs.promoteDue(ctx, time.Now().Add(PaymentRecheckInterval+time.Second)))
+	store.payments[1].Status = domain.PaymentStatusFailed
+	drain(t, s.jobs, s.handlePaymentMessage)
+	assert.Equal(t, domain.BookingStatusCancelled, store.bookings[1].Status)
+	assert.Equal(t, 2, store.released[10])
+}
+
+func TestWorkerReclaimsJobsOfCrashedConsumer(t *testing.T) {
+	mr := miniredis.RunT(t)
+	store := newFakeStore()
+	store.addBooking(1, 10, 2, domain.PaymentStatusCompleted)
+	s := newTestQueueService(t, mr, store)
+	jobs := s.jobs.(*RedisJobQueue)
+	ctx := context.Background()
+
+	require.NoError(t, jobs.ensureGroup(ctx))
+	require.NoError(t, s.EnqueuePayment(ctx, PaymentJob{BookingID: 1, Amount: 20}))
+
+	// Another consumer reads the job and dies before acking it.
+	client, err := jobs.client()
+	require.NoError(t, err)
+	_, err = client.XReadGroup(ctx, &goredis.XReadGroupArgs{
+		Group: ConsumerGroup, Consumer: "crashed", Streams: []string{StreamName, ">"}, Count: 1,
+	}).Result()
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, pendingCount(t, s))
+
+	jobs.claimMinIdle = 0
+	drain(t, s.jobs, s.handlePaymentMessage)
+	assert.Equal(t, domain.BookingStatusConfirmed, store.bookings[1].Status)
+	assert.Zero(t, pendingCount(t, s))
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	p := RetryPolicy{MaxAttempts: 5, BaseDelay: time.Second, MaxDelay: 10 * time.Second, Jitter: 0.5}
+	for attempt, want := range map[int]time.Duration{1: time.Second, 2: 2 * time.Second, 3: 4 * time.Second, 5: 10 * time.Second} {
+		for i := 0; i < 20; i++ {
+			d := p.Backoff(attempt)
+			assert.LessOrEqual(t, d, want, "attempt %d", attempt)
+			assert.GreaterOrEqual(t, d, want/2, "attempt %d", attempt)
+		}
+	}
+	assert.False(t, p.Exhausted(4))
+	assert.True(t, p.Exhausted(5))
+}
+
+func TestWorkerRetriesThenDeadLettersFailingJob(t *testing.T) {
+	mr := miniredis.RunT(t)
+	store := newFakeStore()
+	// No payment row, so processPayment fails on every attempt.
+	store.bookings[1] = &domain.Booking{ID: 1, EventID: 10, Quantity: 2, Status: domain.BookingStatusPending}
+	s := newTestQueueService(t, mr, store)
+	s.SetRetryPolicy(RetryPolicy{MaxAttempts: 3, BaseDelay: time.Second, MaxDelay: time.Minute})
+	jobs := s.jobs.(*RedisJobQueue)
+	ctx := context.Background()
+
+	require.NoError(t, s.EnqueuePayment(ctx, PaymentJob{BookingID: 1, Amount: 20}))
+
+	for attempt := 1; attempt <= 3; attempt++ {
+		drain(t, s.jobs, s.handlePaymentMessage)
+		assert.Zero(t, pendingCount(t, s), "attempt %d should be acked", attempt)
+		if attempt < 3 {
+			delayed, err := mr.ZMembers(DelayedSetName)
+			require.NoError(t, err)
+			require.Len(t, delayed, 1)
+			require.NoError(t, jobs.promoteDue(ctx, time.Now().Add(time.Hour)))
+		}
+	}
+
+	letters, err := s.ListDeadLetters(ctx)
+	require.NoError(t, err)
+	require.Len(t, letters, 1)
+	job := letters[0].Job
+	assert.NotEmpty(t, job.ID)
+	assert.Equal(t, uint(1), job.BookingID)
+	assert.Equal(t, 3, job.Attempts)
+	assert.Equal(t, "payment not found or error", job.LastError)
+	assert.False(t, mr.Exists(DelayedSetName))
+
+	// Requeue resets the attempt budget and puts the job back on the stream.
+	bookingID := uint(1)
+	store.payments[1] = &domain.Payment{ID: 1, BookingID: &bookingID, Status: domain.PaymentStatusCompleted}
+	require.NoError(t, s.RequeueDeadLetter(ctx, job.ID))
+	_, err = s.GetDeadLetter(ctx, job.ID)
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+	drain(t, s.jobs, s.handlePaymentMessage)
+	assert.Equal(t, domain.BookingStatusConfirmed, store.bookings[1].Status)
+
+	assert.ErrorIs(t, s.RequeueDeadLetter(ctx, job.ID), domain.ErrNotFound)
+}
+
+func TestMemoryBackendRetriesThenDeadLetters(t *testing.T) {
+	store := newFakeStore()
+	store.bookings[1] = &domain.Booking{ID: 1, EventID: 10, Quantity: 2, Status: domain.BookingStatusPending}
+	s := newMemoryQueueService(store)
+	s.SetRetryPolicy(RetryPolicy{MaxAttempts: 2, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond})
+	ctx := context.Background()
+
+	require.NoError(t, s.EnqueuePayment(ctx, PaymentJob{BookingID: 1, Amount: 20}))
+	drain(t, s.jobs, s.handlePaymentMessage)
+	time.Sleep(5 * time.Millisecond)
+	drain(t, s.jobs, s.handlePaymentMessage)
+
+	letters, err := s.ListDeadLetters(ctx)
+	require.NoError(t, err)
+	require.Len(t, letters, 1)
+	assert.Equal(t, 2, letters[0].Job.Attempts)
+}
+
+func TestDeleteAndPurgeDeadLetters(t *testing.T) {
+	mr := miniredis.RunT(t)
+	s := newTestQueueService(t, mr, newFakeStore())
+	ctx := context.Background()
+	now := time.Now()
+	for _, id := range []string{"a", "b", "c"} {
+		require.NoError(t, s.deadLetter(ctx, PaymentJob{ID: id, BookingID: 1}, now))
+	}
+
+	require.NoError(t, s.DeleteDeadLetter(ctx, "a"))
+	assert.ErrorIs(t, s.DeleteDeadLetter(ctx, "a"), domain.ErrNotFound)
+
+	purged, err := s.PurgeDeadLetters(ctx)
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, purged)
+	letters, err := s.ListDeadLetters(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, letters)
+}
+
+func TestStartWorkerStopsOnCancel(t *testing.T) {
+	store := newFakeStore()
+	store.addBooking(1, 10, 2, domain.PaymentStatusCompleted)
+	s := newMemoryQueueService(store)
+	require.NoError(t, s.EnqueuePayment(context.Background(), PaymentJob{BookingID: 1, Amount: 20}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		s.StartWorker(ctx)
+		close(stopped)
+	}()
+
+	require.Eventually(t, func() bool {
+		b, err := fakeBookingRepo{fakeStore: store}.FindById(context.Background(), 1)
+		return err == nil && b.Status == domain.BookingStatusConfirmed
+	}, time.Second, 5*time.Millisecond)
+
+	cancel()
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("worker did not stop after cancel")
+	}
+}
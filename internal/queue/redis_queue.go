@@ -0,0 +1,360 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	goredis "github.com/redis/go-redis/v9"
+
+	"ticket_app/domain"
+	"ticket_app/internal/redis"
+)
+
+// RedisJobQueue delivers jobs through a Redis Stream read by a consumer group,
+// so that several worker replicas share the load and a job is only removed
+// after it has been acknowledged. Entries left un-acked by a crashed consumer
+// are taken over with XAUTOCLAIM once they have been idle for ClaimMinIdle.
+// Jobs enqueued for later, or nacked, are parked in a sorted set scored by due
+// time and moved onto the stream by promoteDueScript.
+const (
+	StreamName     = "payment_stream"
+	ConsumerGroup  = "payment_workers"
+	DelayedSetName = "payment:delayed"
+	ClaimMinIdle   = time.Minute
+
+	streamMaxLen     = 100000
+	reclaimInterval  = 30 * time.Second
+	delayedBatchSize = 100
+)
+
+// promoteDueScript moves up to ARGV[2] members of the sorted set KEYS[1] whose
+// score is <= ARGV[1] onto the stream KEYS[2], atomically
+var promoteDueScript = goredis.NewScript(`
+local items = redis.call('ZRANGEBYSCORE', KEYS[1], '-inf', ARGV[1], 'LIMIT', 0, ARGV[2])
+for _, item in ipairs(items) do
+	redis.call('XADD', KEYS[2], 'MAXLEN', '~', ARGV[3], '*', 'job', item)
+end
+if #items > 0 then
+	redis.call('ZREM', KEYS[1], unpack(items))
+end
+return #items
+`)
+
+// consumerName identifies this process inside the consumer group
+func consumerName() string {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "worker"
+	}
+	return fmt.Sprintf("%s-%d", host, os.Getpid())
+}
+
+type RedisJobQueue struct {
+	redis    *redis.Redis
+	stream   string
+	group    string
+	delayed  string
+	consumer string
+	// claimMinIdle is how long an entry must sit un-acked before another consumer may take it
+	claimMinIdle time.Duration
+
+	mu            sync.Mutex
+	groupReady    bool
+	lastReclaim   time.Time
+	reclaimCursor string
+}
+
+// NewRedisJobQueue reads stream as member of group and parks delayed jobs in the sorted set delayed
+func NewRedisJobQueue(r *redis.Redis, stream, group, delayed string) *RedisJobQueue {
+	return &RedisJobQueue{
+		redis:         r,
+		stream:        stream,
+		group:         group,
+		delayed:       delayed,
+		consumer:      consumerName(),
+		claimMinIdle:  ClaimMinIdle,
+		reclaimCursor: "0-0",
+	}
+}
+
+func (q *RedisJobQueue) client() (*goredis.Client, error) {
+	if q.redis == nil {
+		return nil, fmt.Errorf("RedisJobQueue redis instance is nil")
+	}
+	client := q.redis.GetClient()
+	if client == nil {
+		return nil, fmt.Errorf("Redis client is nil")
+	}
+	return client, nil
+}
+
+func (q *RedisJobQueue) Enqueue(ctx context.Context, payload []byte) error {
+	client, err := q.client()
+	if err != nil {
+		return err
+	}
+	return client.XAdd(ctx, &goredis.XAddArgs{
+		Stream: q.stream,
+		MaxLen: streamMaxLen,
+		Approx: true,
+		Values: map[string]interface{}{"job": payload},
+	}).Err()
+}
+
+// EnqueueAt parks payload until at. Identical payloads share one entry of the
+// sorted set, so callers keep them unique (e.g. with a job ID).
+func (q *RedisJobQueue) EnqueueAt(ctx context.Context, payload []byte, at time.Time) error {
+	client, err := q.client()
+	if err != nil {
+		return err
+	}
+	return client.ZAdd(ctx, q.delayed, goredis.Z{Score: float64(at.UnixMilli()), Member: payload}).Err()
+}
+
+func (q *RedisJobQueue) Consume(ctx context.Context, max int, wait time.Duration) ([]Message, error) {
+	if err := q.ensureGroup(ctx); err != nil {
+		return nil, err
+	}
+	if err := q.promoteDue(ctx, time.Now()); err != nil {
+		return nil, err
+	}
+	msgs, err := q.reclaimStale(ctx, max)
+	if err != nil || len(msgs) > 0 {
+		return msgs, err
+	}
+
+	client, err := q.client()
+	if err != nil {
+		return nil, err
+	}
+	block := wait
+	if block <= 0 {
+		block = -1 // BLOCK 0 would wait forever
+	}
+	streams, err := client.XReadGroup(ctx, &goredis.XReadGroupArgs{
+		Group:    q.group,
+		Consumer: q.consumer,
+		Streams:  []string{q.stream, ">"},
+		Count:    int64(max),
+		Block:    block,
+	}).Result()
+	if errors.Is(err, goredis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	for _, stream := range streams {
+		msgs = append(msgs, q.toMessages(ctx, stream.Messages)...)
+	}
+	return msgs, nil
+}
+
+func (q *RedisJobQueue) Ack(ctx context.Context, msg Message) error {
+	client, err := q.client()
+	if err != nil {
+		return err
+	}
+	return client.XAck(ctx, q.stream, q.group, msg.ID).Err()
+}
+
+// Nack parks msg in the delayed set and acks the stream entry in one MULTI, so
+// the job is never lost nor delivered twice
+func (q *RedisJobQueue) Nack(ctx context.Context, msg Message, retryAt time.Time) error {
+	client, err := q.client()
+	if err != nil {
+		return err
+	}
+	_, err = client.TxPipelined(ctx, func(pipe goredis.Pipeliner) error {
+		pipe.ZAdd(ctx, q.delayed, goredis.Z{Score: float64(retryAt.UnixMilli()), Member: msg.Payload})
+		pipe.XAck(ctx, q.stream, q.group, msg.ID)
+		return nil
+	})
+	return err
+}
+
+func (q *RedisJobQueue) ensureGroup(ctx context.Context) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.groupReady {
+		return nil
+	}
+	client, err := q.client()
+	if err != nil {
+		return err
+	}
+	err = client.XGroupCreateMkStream(ctx, q.stream, q.group, "0").Err()
+	if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return fmt.Errorf("failed to create consumer group %s: %w", q.group, err)
+	}
+	q.groupReady = true
+	return nil
+}
+
+func (q *RedisJobQueue) promoteDue(ctx context.Context, now time.Time) error {
+	client, err := q.client()
+	if err != nil {
+		return err
+	}
+	return promoteDueScript.Run(ctx, client, []string{q.delayed, q.stream}, now.UnixMilli(), delayedBatchSize, streamMaxLen).Err()
+}
+
+// reclaimStale takes over entries that another consumer read but never acked.
+// It runs every reclaimInterval and then keeps going, one batch per call, until
+// it has walked the whole pending list.
+func (q *RedisJobQueue) reclaimStale(ctx context.Context, max int) ([]Message, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.reclaimCursor == "0-0" && time.Since(q.lastReclaim) < reclaimInterval {
+		return nil, nil
+	}
+	client, err := q.client()
+	if err != nil {
+		return nil, err
+	}
+	msgs, next, err := client.XAutoClaim(ctx, &goredis.XAutoClaimArgs{
+		Stream:   q.stream,
+		Group:    q.group,
+		Consumer: q.consumer,
+		MinIdle:  q.claimMinIdle,
+		Start:    q.reclaimCursor,
+		Count:    int64(max),
+	}).Result()
+	if err != nil {
+		return nil, err
+	}
+	q.reclaimCursor = next
+	if next == "0-0" || len(msgs) == 0 {
+		q.reclaimCursor = "0-0"
+		q.lastReclaim = time.Now()
+	}
+	for _, msg := range msgs {
+		log.Printf("Reclaimed job %s from %s", msg.ID, q.stream)
+	}
+	return q.toMessages(ctx, msgs), nil
+}
+
+// toMessages drops, and acks, entries that carry no job
+func (q *RedisJobQueue) toMessages(ctx context.Context, entries []goredis.XMessage) []Message {
+	msgs := make([]Message, 0, len(entries))
+	for _, entry := range entries {
+		raw, ok := entry.Values["job"].(string)
+		if !ok {
+			log.Printf("Invalid job %s on %s: %v", entry.ID, q.stream, entry.Values)
+			if err := q.Ack(ctx, Message{ID: entry.ID}); err != nil {
+				log.Printf("Failed to ack job %s: %v", entry.ID, err)
+			}
+			continue
+		}
+		msgs = append(msgs, Message{ID: entry.ID, Payload: []byte(raw)})
+	}
+	return msgs
+}
+
+// RedisDeadLetterStore keeps dead letters in a Redis hash keyed by job ID
+type RedisDeadLetterStore struct {
+	redis *redis.Redis
+	key   string
+}
+
+func NewRedisDeadLetterStore(r *redis.Redis, key string) *RedisDeadLetterStore {
+	return &RedisDeadLetterStore{redis: r, key: key}
+}
+
+func (d *RedisDeadLetterStore) client() (*goredis.Client, error) {
+	if d.redis == nil || d.redis.GetClient() == nil {
+		return nil, fmt.Errorf("Redis client is nil")
+	}
+	return d.redis.GetClient(), nil
+}
+
+func (d *RedisDeadLetterStore) Add(ctx context.Context, letter DeadLetter) error {
+	client, err := d.client()
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(letter)
+	if err != nil {
+		return err
+	}
+	return client.HSet(ctx, d.key, letter.Job.ID, data).Err()
+}
+
+func (d *RedisDeadLetterStore) List(ctx context.Context) ([]DeadLetter, error) {
+	client, err := d.client()
+	if err != nil {
+		return nil, err
+	}
+	raw, err := client.HGetAll(ctx, d.key).Result()
+	if err != nil {
+		return nil, err
+	}
+	letters := make([]DeadLetter, 0, len(raw))
+	for _, v := range raw {
+		var letter DeadLetter
+		if err := json.Unmarshal([]byte(v), &letter); err != nil {
+			return nil, err
+		}
+		letters = append(letters, letter)
+	}
+	sort.Slice(letters, func(i, j int) bool { return letters[i].FailedAt.After(letters[j].FailedAt) })
+	return letters, nil
+}
+
+func (d *RedisDeadLetterStore) Get(ctx context.Context, id string) (*DeadLetter, error) {
+	client, err := d.client()
+	if err != nil {
+		return nil, err
+	}
+	raw, err := client.HGet(ctx, d.key, id).Result()
+	if errors.Is(err, goredis.Nil) {
+		return nil, domain.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	var letter DeadLetter
+	if err := json.Unmarshal([]byte(raw), &letter); err != nil {
+		return nil, err
+	}
+	return &letter, nil
+}
+
+func (d *RedisDeadLetterStore) Delete(ctx context.Context, id string) error {
+	client, err := d.client()
+	if err != nil {
+		return err
+	}
+	n, err := client.HDel(ctx, d.key, id).Result()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return domain.ErrNotFound
+	}
+	return nil
+}
+
+func (d *RedisDeadLetterStore) Purge(ctx context.Context) (int64, error) {
+	client, err := d.client()
+	if err != nil {
+		return 0, err
+	}
+	var count *goredis.IntCmd
+	if _, err := client.TxPipelined(ctx, func(pipe goredis.Pipeliner) error {
+		count = pipe.HLen(ctx, d.key)
+		pipe.Del(ctx, d.key)
+		return nil
+	}); err != nil {
+		return 0, err
+	}
+	return count.Val(), nil
+}
@@ -0,0 +1,46 @@
+package queue
+
+import (
+	"math"
+	"math/rand"
+	"time"
+)
+
+// RetryPolicy decides how often and how fast a failing payment job is retried
+// before it is moved to the dead-letter queue.
+type RetryPolicy struct {
+	MaxAttempts int           // attempts including the first one
+	BaseDelay   time.Duration // delay before the first retry
+	MaxDelay    time.Duration // upper bound of a single delay
+	Jitter      float64       // fraction of the delay that is randomised, 0..1
+}
+
+// DefaultRetryPolicy retries 5 times over roughly a minute
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 5,
+	BaseDelay:   2 * time.Second,
+	MaxDelay:    time.Minute,
+	Jitter:      0.2,
+}
+
+// Exhausted reports whether a job that has failed attempts times must be dead-lettered
+func (p RetryPolicy) Exhausted(attempts int) bool {
+	return attempts >= p.MaxAttempts
+}
+
+// Backoff returns the delay before retry number attempt (1-based): BaseDelay
+// doubled per attempt, capped at MaxDelay, with up to Jitter of it randomised
+// so that jobs failing together do not retry together.
+func (p RetryPolicy) Backoff(attempt int) time.Duration {
+	if attempt < 1 {
+		attempt = 1
+	}
+	delay := float64(p.BaseDelay) * math.Pow(2, float64(attempt-1))
+	if p.MaxDelay > 0 && delay > float64(p.MaxDelay) {
+		delay = float64(p.MaxDelay)
+	}
+	if p.Jitter > 0 {
+		delay -= delay * p.Jitter * rand.Float64()
+	}
+	return time.Duration(delay)
+}
@@ -0,0 +1,139 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"ticket_app/domain"
+)
+
+// Payment deadlines travel on their own JobQueue: the payload is the booking ID
+// and it is delivered at the deadline. Every delivery goes to exactly one
+// consumer, so when several app instances share the queue each booking is
+// expired once. A scheduled timeout is never withdrawn; once the booking is
+// confirmed or cancelled, expireBooking simply finds nothing left to do.
+const (
+	TimeoutStreamName    = "payment_timeouts"
+	TimeoutConsumerGroup = "timeout_workers"
+	TimeoutSetName       = "payment:timeouts"
+	// timeoutRetryDelay is used to deliver a timeout again when expiring the booking failed
+	timeoutRetryDelay = 30 * time.Second
+)
+
+// ScheduleTimeout registers bookingID to be cancelled at deadline unless it is paid before
+func (s *QueueService) ScheduleTimeout(ctx context.Context, bookingID uint, deadline time.Time) error {
+	return s.timeouts.EnqueueAt(ctx, []byte(strconv.FormatUint(uint64(bookingID), 10)), deadline)
+}
+
+func (s *QueueService) handleTimeoutMessage(ctx context.Context, msg Message) {
+	if payload, ok := strings.CutPrefix(string(msg.Payload), orderTimeoutPrefix); ok {
+		s.handleOrderTimeout(ctx, msg, payload)
+		return
+	}
+	id, err := strconv.ParseUint(string(msg.Payload), 10, 64)
+	if err != nil {
+		log.Printf("Invalid booking ID %q in timeout %s: %v", msg.Payload, msg.ID, err)
+		s.ack(ctx, s.timeouts, msg)
+		return
+	}
+	bookingID := uint(id)
+	if err := s.expireBooking(ctx, bookingID); err != nil {
+		log.Printf("Error expiring booking %d: %v, retrying in %v", bookingID, err, timeoutRetryDelay)
+		if err := s.timeouts.Nack(ctx, msg, time.Now().Add(timeoutRetryDelay)); err != nil {
+			log.Printf("Failed to reschedule timeout for booking %d: %v", bookingID, err)
+		}
+		return
+	}
+	s.ack(ctx, s.timeouts, msg)
+}
+
+// The deadline of an order travels on the same JobQueue as the payload
+// "order:<id>", since the order has one payment for all its bookings
+const orderTimeoutPrefix = "order:"
+
+// ScheduleOrderTimeout registers orderID to be cancelled at deadline unless it is paid before
+func (s *QueueService) ScheduleOrderTimeout(ctx context.Context, orderID uint, deadline time.Time) error {
+	return s.timeouts.EnqueueAt(ctx, []byte(orderTimeoutPrefix+strconv.FormatUint(uint64(orderID), 10)), deadline)
+}
+
+func (s *QueueService) handleOrderTimeout(ctx context.Context, msg Message, payload string) {
+	id, err := strconv.ParseUint(payload, 10, 64)
+	if err != nil {
+		log.Printf("Invalid order ID %q in timeout %s: %v", payload, msg.ID, err)
+		s.ack(ctx, s.timeouts, msg)
+		return
+	}
+	orderID := uint(id)
+	if err := s.expireOrder(ctx, orderID); err != nil {
+		log.Printf("Error expiring order %d: %v, retrying in %v", orderID, err, timeoutRetryDelay)
+		if err := s.timeouts.Nack(ctx, msg, time.Now().Add(timeoutRetryDelay)); err != nil {
+			log.Printf("Failed to reschedule timeout for order %d: %v", orderID, err)
+		}
+		return
+	}
+	s.ack(ctx, s.timeouts, msg)
+}
+
+// expireOrder cancels orderID with all its bookings unless its payment completed in the meantime
+func (s *QueueService) expireOrder(ctx context.Context, orderID uint) error {
+	payment, err := s.paymentRepo.FindByOrderID(ctx, orderID)
+	if err == nil && payment.Status == domain.PaymentStatusCompleted {
+		log.Printf("Payment for order %d is COMPLETED, confirming instead of cancelling", orderID)
+		return s.updateOrderStatus(ctx, orderID, domain.BookingStatusConfirmed)
+	}
+	log.Printf("Payment for order %d timed out, cancelling order", orderID)
+	return s.updateOrderStatus(ctx, orderID, domain.BookingStatusCancelled)
+}
+
+// expireBooking cancels bookingID and releases its tickets unless its payment completed in the meantime
+func (s *QueueService) expireBooking(ctx context.Context, bookingID uint) error {
+	payment, err := s.paymentRepo.FindByBookingID(ctx, bookingID)
+	if err == nil && payment.Status == domain.PaymentStatusCompleted {
+		log.Printf("Payment for booking %d is COMPLETED, confirming instead of cancelling", bookingID)
+		return s.updateBookingStatus(ctx, bookingID, domain.BookingStatusConfirmed)
+	}
+	log.Printf("Payment for booking %d timed out, cancelling booking", bookingID)
+	return s.updateBookingStatus(ctx, bookingID, domain.BookingStatusCancelled)
+}
+
+// Hold expiries work like payment deadlines on a third JobQueue: the payload is
+// the hold ID, delivered when the hold expires. A hold turned into a booking or
+// released by its user in the meantime is left alone. The tickets of an
+// expired hold, a waitlist offer included, go to the waitlist of its event.
+const (
+	HoldStreamName    = "hold_expiries"
+	HoldConsumerGroup = "hold_workers"
+	HoldSetName       = "holds:expiries"
+)
+
+// ScheduleHoldExpiry registers holdID to be released at expiresAt unless it is used before
+func (s *QueueService) ScheduleHoldExpiry(ctx context.Context, holdID uint, expiresAt time.Time) error {
+	return s.holds.EnqueueAt(ctx, []byte(strconv.FormatUint(uint64(holdID), 10)), expiresAt)
+}
+
+func (s *QueueService) handleHoldMessage(ctx context.Context, msg Message) {
+	id, err := strconv.ParseUint(string(msg.Payload), 10, 64)
+	if err != nil {
+		log.Printf("Invalid hold ID %q in hold expiry %s: %v", msg.Payload, msg.ID, err)
+		s.ack(ctx, s.holds, msg)
+		return
+	}
+	holdID := uint(id)
+	hold, err := s.holdRepo.Release(ctx, holdID, domain.HoldStatusExpired)
+	if err != nil && !errors.Is(err, domain.ErrHoldNotFound) {
+		log.Printf("Error expiring hold %d: %v, retrying in %v", holdID, err, timeoutRetryDelay)
+		if err := s.holds.Nack(ctx, msg, time.Now().Add(timeoutRetryDelay)); err != nil {
+			log.Printf("Failed to reschedule expiry of hold %d: %v", holdID, err)
+		}
+		return
+	}
+	if hold != nil {
+		log.Printf("Hold %d expired, tickets released", holdID)
+		s.notifyTicketsReleased(ctx, hold.EventID)
+	}
+	s.ack(ctx, s.holds, msg)
+}
@@ -0,0 +1,114 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strconv"
+	"time"
+
+	"ticket_app/domain"
+	"ticket_app/internal/random"
+)
+
+// Tickets given back to an event travel on a fourth JobQueue as the event ID.
+// The offerer walks the waitlist of the event and gives each waiting entry, in
+// order, a hold of DefaultOfferTTL (the offer) while there are tickets. An
+// offer that expires or is dropped releases its tickets, which brings the
+// event back here for the next entry. Handling an event twice is harmless:
+// entries are locked and only offered while WAITING.
+const (
+	WaitlistStreamName    = "waitlist_offers"
+	WaitlistConsumerGroup = "waitlist_workers"
+	WaitlistSetName       = "waitlist:offers"
+
+	DefaultOfferTTL = 30 * time.Minute
+)
+
+// NotifyTicketsReleased asks the waitlist offerer to offer the free tickets of eventID
+func (s *QueueService) NotifyTicketsReleased(ctx context.Context, eventID uint) error {
+	return s.offers.Enqueue(ctx, []byte(strconv.FormatUint(uint64(eventID), 10)))
+}
+
+// notifyTicketsReleased is NotifyTicketsReleased for callers that already
+// committed the release: a failure is only logged, and the tickets reach the
+// waitlist with the next release of the event
+func (s *QueueService) notifyTicketsReleased(ctx context.Context, eventID uint) {
+	if err := s.NotifyTicketsReleased(ctx, eventID); err != nil {
+		log.Printf("Failed to notify waitlist of event %d: %v", eventID, err)
+	}
+}
+
+func (s *QueueService) handleOfferMessage(ctx context.Context, msg Message) {
+	id, err := strconv.ParseUint(string(msg.Payload), 10, 64)
+	if err != nil {
+		log.Printf("Invalid event ID %q in waitlist offer %s: %v", msg.Payload, msg.ID, err)
+		s.ack(ctx, s.offers, msg)
+		return
+	}
+	eventID := uint(id)
+	if err := s.offerTickets(ctx, eventID); err != nil {
+		log.Printf("Error offering tickets of event %d: %v, retrying in %v", eventID, err, timeoutRetryDelay)
+		if err := s.offers.Nack(ctx, msg, time.Now().Add(timeoutRetryDelay)); err != nil {
+			log.Printf("Failed to reschedule waitlist offer for event %d: %v", eventID, err)
+		}
+		return
+	}
+	s.ack(ctx, s.offers, msg)
+}
+
+// offerTickets offers the free tickets of eventID to its waiting entries. The
+// waitlist of each ticket type (0 for events without ticket types) is served
+// first come first served: once its first entry cannot be offered, the entries
+// behind it wait too, even if they ask for fewer tickets.
+func (s *QueueService) offerTickets(ctx context.Context, eventID uint) error {
+	entries, err := s.waitlistRepo.Waiting(ctx, eventID)
+	if err != nil {
+		return err
+	}
+	blocked := make(map[uint]bool) // ticket type -> its queue waits
+	for _, entry := range entries {
+		var tier uint
+		if entry.TicketTypeID != nil {
+			tier = *entry.TicketTypeID
+		}
+		if blocked[tier] {
+			continue
+		}
+		token, err := random.Token()
+		if err != nil {
+			return err
+		}
+		hold := &domain.Hold{Token: token, ExpiresAt: time.Now().Add(s.offerTTL)}
+		offered, err := s.waitlistRepo.Offer(ctx, entry.ID, hold)
+		switch {
+		case errors.Is(err, domain.ErrNotEnoughTickets),
+			errors.Is(err, domain.ErrTicketTypeNotOnSale),
+			errors.Is(err, domain.ErrEventNotActive):
+			blocked[tier] = true
+			continue
+		case errors.Is(err, domain.ErrTicketTypeRequired),
+			errors.Is(err, domain.ErrTicketTypeNotFound),
+			errors.Is(err, domain.ErrTicketQuantityOutOfRange),
+			errors.Is(err, domain.ErrSeatSelectionRequired):
+			// Event đổi cách bán vé sau khi entry vào waitlist; entry này không
+			// được phục vụ nữa, nhưng không chặn người phía sau
+			log.Printf("Waitlist entry %d cannot be offered: %v", entry.ID, err)
+			continue
+		case err != nil:
+			return err
+		case !offered:
+			continue
+		}
+
+		if err := s.ScheduleHoldExpiry(ctx, hold.ID, hold.ExpiresAt); err != nil {
+			// Như CreateHold: offer không hẹn được giờ hết hạn thì trả vé ngay
+			if _, releaseErr := s.holdRepo.Release(ctx, hold.ID, domain.HoldStatusExpired); releaseErr != nil {
+				log.Printf("Failed to release offer hold %d: %v", hold.ID, releaseErr)
+			}
+			return err
+		}
+		log.Printf("Waitlist entry %d offered hold %d until %s", entry.ID, hold.ID, hold.ExpiresAt.Format(time.RFC3339))
+	}
+	return nil
+}
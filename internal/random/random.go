@@ -0,0 +1,21 @@
+// Package random generates the opaque tokens handed out to clients: refresh
+// tokens, links sent by mail, hold and waitlist offer tokens.
+package random
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+)
+
+// tokenBytes gives tokens 256 bits of entropy
+const tokenBytes = 32
+
+// Token returns tokenBytes random bytes, base64 encoded so that they can be
+// used in a URL as is
+func Token() (string, error) {
+	raw := make([]byte, tokenBytes)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
@@ -0,0 +1,23 @@
+package random_test
+
+import (
+	"encoding/base64"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"ticket_app/internal/random"
+)
+
+func TestToken(t *testing.T) {
+	a, err := random.Token()
+	require.NoError(t, err)
+	b, err := random.Token()
+	require.NoError(t, err)
+
+	assert.NotEqual(t, a, b)
+	raw, err := base64.RawURLEncoding.DecodeString(a)
+	require.NoError(t, err)
+	assert.Len(t, raw, 32)
+}
@@ -0,0 +1,78 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"ticket_app/internal/idempotency"
+)
+
+// IdempotencyStore implements idempotency.Store on Redis
+type IdempotencyStore struct {
+	redis *Redis
+}
+
+// NewIdempotencyStore creates an IdempotencyStore backed by r
+func NewIdempotencyStore(r *Redis) *IdempotencyStore {
+	return &IdempotencyStore{redis: r}
+}
+
+// Reserve uses SET NX so that only one request can claim a key
+func (s *IdempotencyStore) Reserve(ctx context.Context, key string, rec idempotency.Record, ttl time.Duration) (*idempotency.Record, bool, error) {
+	client := s.redis.GetClient()
+	if client == nil {
+		return nil, false, fmt.Errorf("Redis client is nil")
+	}
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return nil, false, err
+	}
+	ok, err := client.SetNX(ctx, key, data, ttl).Result()
+	if err != nil {
+		return nil, false, err
+	}
+	if ok {
+		return nil, true, nil
+	}
+
+	raw, err := client.Get(ctx, key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		// The key expired between SETNX and GET; let the caller try again later.
+		return &idempotency.Record{Fingerprint: rec.Fingerprint}, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	var existing idempotency.Record
+	if err := json.Unmarshal(raw, &existing); err != nil {
+		return nil, false, err
+	}
+	return &existing, false, nil
+}
+
+// Save overwrites the record stored under key
+func (s *IdempotencyStore) Save(ctx context.Context, key string, rec idempotency.Record, ttl time.Duration) error {
+	client := s.redis.GetClient()
+	if client == nil {
+		return fmt.Errorf("Redis client is nil")
+	}
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	return client.Set(ctx, key, data, ttl).Err()
+}
+
+// Delete frees key
+func (s *IdempotencyStore) Delete(ctx context.Context, key string) error {
+	client := s.redis.GetClient()
+	if client == nil {
+		return fmt.Errorf("Redis client is nil")
+	}
+	return client.Del(ctx, key).Err()
+}
@@ -0,0 +1,131 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"ticket_app/internal/config"
+)
+
+const (
+	loginEmailFailuresPrefix = "auth:login:fail:email:"
+	loginIPFailuresPrefix    = "auth:login:fail:ip:"
+	loginDelayPrefix         = "auth:login:delay:"
+	loginLockPrefix          = "auth:login:lock:"
+)
+
+// LoginThrottle counts failed logins in Redis, per email and per IP. Every
+// failure of an email makes it wait a little longer before the next attempt;
+// after cfg.MaxFailures failures within cfg.FailureWindow the email is locked
+// for cfg.Lockout. An IP with cfg.IPMaxFailures failures is blocked until its
+// window ends.
+type LoginThrottle struct {
+	redis *Redis
+	cfg   config.LoginConfig
+}
+
+// NewLoginThrottle creates a LoginThrottle backed by r
+func NewLoginThrottle(r *Redis, cfg config.LoginConfig) *LoginThrottle {
+	return &LoginThrottle{redis: r, cfg: cfg}
+}
+
+func (t *LoginThrottle) client() (*redis.Client, error) {
+	client := t.redis.GetClient()
+	if client == nil {
+		return nil, fmt.Errorf("Redis client is nil")
+	}
+	return client, nil
+}
+
+// Wait returns how long a login for email from ip has to wait; 0 means it may go ahead
+func (t *LoginThrottle) Wait(ctx context.Context, email, ip string) (time.Duration, error) {
+	client, err := t.client()
+	if err != nil {
+		return 0, err
+	}
+	email = normalizeEmail(email)
+	pipe := client.Pipeline()
+	lock := pipe.PTTL(ctx, loginLockPrefix+email)
+	delay := pipe.PTTL(ctx, loginDelayPrefix+email)
+	ipFailures := pipe.Get(ctx, loginIPFailuresPrefix+ip)
+	ipTTL := pipe.PTTL(ctx, loginIPFailuresPrefix+ip)
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return 0, err
+	}
+
+	wait := max(lock.Val(), delay.Val(), 0) // -1 and -2 mean no such key
+	if n, err := ipFailures.Int(); err == nil && n >= t.cfg.IPMaxFailures {
+		wait = max(wait, ipTTL.Val())
+	}
+	return wait, nil
+}
+
+// Failed records a failed login. It returns true when this failure locked the email.
+func (t *LoginThrottle) Failed(ctx context.Context, email, ip string) (bool, error) {
+	client, err := t.client()
+	if err != nil {
+		return false, err
+	}
+	email = normalizeEmail(email)
+	emailKey, ipKey := loginEmailFailuresPrefix+email, loginIPFailuresPrefix+ip
+	var emailFailures *redis.IntCmd
+	var emailTTL, ipTTL *redis.DurationCmd
+	if _, err := client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		emailFailures = pipe.Incr(ctx, emailKey)
+		emailTTL = pipe.PTTL(ctx, emailKey)
+		pipe.Incr(ctx, ipKey)
+		ipTTL = pipe.PTTL(ctx, ipKey)
+		return nil
+	}); err != nil {
+		return false, err
+	}
+	// The window starts at the first failure. A counter without TTL (the
+	// process died between INCR and EXPIRE) gets one on the next failure.
+	for key, ttl := range map[string]*redis.DurationCmd{emailKey: emailTTL, ipKey: ipTTL} {
+		if ttl.Val() < 0 {
+			if err := client.Expire(ctx, key, t.cfg.FailureWindow).Err(); err != nil {
+				return false, err
+			}
+		}
+	}
+
+	n := int(emailFailures.Val())
+	if n >= t.cfg.MaxFailures {
+		_, err := client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.Set(ctx, loginLockPrefix+email, 1, t.cfg.Lockout)
+			pipe.Del(ctx, emailKey, loginDelayPrefix+email)
+			return nil
+		})
+		return err == nil, err
+	}
+	return false, client.Set(ctx, loginDelayPrefix+email, 1, t.cfg.FailureDelay(n)).Err()
+}
+
+// Succeeded forgets the failures of email, but not those of the IP
+func (t *LoginThrottle) Succeeded(ctx context.Context, email string) error {
+	client, err := t.client()
+	if err != nil {
+		return err
+	}
+	email = normalizeEmail(email)
+	return client.Del(ctx, loginEmailFailuresPrefix+email, loginDelayPrefix+email).Err()
+}
+
+// Unlock lifts the lock of email and forgets its failures
+func (t *LoginThrottle) Unlock(ctx context.Context, email string) error {
+	client, err := t.client()
+	if err != nil {
+		return err
+	}
+	email = normalizeEmail(email)
+	return client.Del(ctx, loginLockPrefix+email, loginEmailFailuresPrefix+email, loginDelayPrefix+email).Err()
+}
+
+// normalizeEmail makes Alice@Example.com and alice@example.com share one counter
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
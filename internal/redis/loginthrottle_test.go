@@ -0,0 +1,84 @@
+package redis_test
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"ticket_app/internal/config"
+	"ticket_app/internal/redis"
+)
+
+func TestLoginThrottle(t *testing.T) {
+	mr := miniredis.RunT(t)
+	port, err := strconv.Atoi(mr.Port())
+	require.NoError(t, err)
+	r, err := redis.NewRedis(config.RedisConfig{Host: mr.Host(), Port: port})
+	require.NoError(t, err)
+	defer r.Close()
+
+	throttle := redis.NewLoginThrottle(r, config.LoginConfig{
+		MaxFailures:   3,
+		FailureWindow: 15 * time.Minute,
+		Lockout:       10 * time.Minute,
+		IPMaxFailures: 5,
+		DelayBase:     time.Second,
+		DelayMax:      30 * time.Second,
+	})
+	ctx := context.Background()
+	wait := func(email, ip string) time.Duration {
+		d, err := throttle.Wait(ctx, email, ip)
+		require.NoError(t, err)
+		return d
+	}
+
+	assert.Zero(t, wait("alice@example.com", "10.0.0.1"))
+
+	// Progressive delay: 1s, then 2s
+	locked, err := throttle.Failed(ctx, "alice@example.com", "10.0.0.1")
+	require.NoError(t, err)
+	assert.False(t, locked)
+	assert.InDelta(t, time.Second.Seconds(), wait("ALICE@example.com", "10.0.0.2").Seconds(), 0.1)
+	mr.FastForward(time.Second)
+	assert.Zero(t, wait("alice@example.com", "10.0.0.1"))
+	_, err = throttle.Failed(ctx, "alice@example.com", "10.0.0.1")
+	require.NoError(t, err)
+	assert.InDelta(t, (2 * time.Second).Seconds(), wait("alice@example.com", "10.0.0.1").Seconds(), 0.1)
+	assert.InDelta(t, (15 * time.Minute).Seconds(), mr.TTL("auth:login:fail:email:alice@example.com").Seconds(), 1)
+
+	// The third failure locks the email, whatever the IP
+	locked, err = throttle.Failed(ctx, "alice@example.com", "10.0.0.1")
+	require.NoError(t, err)
+	assert.True(t, locked)
+	assert.InDelta(t, (10 * time.Minute).Seconds(), wait("alice@example.com", "10.0.0.3").Seconds(), 1)
+	assert.Zero(t, wait("bob@example.com", "10.0.0.1"))
+
+	require.NoError(t, throttle.Unlock(ctx, "Alice@Example.com"))
+	assert.Zero(t, wait("alice@example.com", "10.0.0.1"))
+
+	// A success clears the email's failures but not the IP's
+	_, err = throttle.Failed(ctx, "bob@example.com", "10.0.0.1")
+	require.NoError(t, err)
+	require.NoError(t, throttle.Succeeded(ctx, "bob@example.com"))
+	assert.Zero(t, wait("bob@example.com", "10.0.0.1"))
+	assert.Equal(t, "4", mustGet(t, mr, "auth:login:fail:ip:10.0.0.1"))
+
+	// The fifth failure from the IP blocks it for every email until the window ends
+	_, err = throttle.Failed(ctx, "carol@example.com", "10.0.0.1")
+	require.NoError(t, err)
+	assert.Greater(t, wait("dave@example.com", "10.0.0.1"), 14*time.Minute)
+	assert.Zero(t, wait("dave@example.com", "10.0.0.9"))
+	mr.FastForward(15 * time.Minute)
+	assert.Zero(t, wait("dave@example.com", "10.0.0.1"))
+}
+
+func mustGet(t *testing.T, mr *miniredis.Miniredis, key string) string {
+	v, err := mr.Get(key)
+	require.NoError(t, err)
+	return v
+}
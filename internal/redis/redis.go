@@ -4,10 +4,11 @@ import (
 	"context"
 	"fmt"
 	"log"
-	"os"
 	"sync"
 
 	"github.com/redis/go-redis/v9"
+
+	"ticket_app/internal/config"
 )
 
 // Redis holds the Redis client
@@ -17,13 +18,12 @@ type Redis struct {
 }
 
 // NewRedis initializes a new Redis client
-func NewRedis() (*Redis, error) {
+func NewRedis(cfg config.RedisConfig) (*Redis, error) {
 	r := &Redis{}
-	dsn := r.ConfigDSN()
-	log.Printf("Initializing Redis with DSN: %s", dsn)
+	log.Printf("Initializing Redis at %s:%d/%d", cfg.Host, cfg.Port, cfg.DB)
 
 	// Parse DSN to create Options
-	opt, err := redis.ParseURL(dsn)
+	opt, err := redis.ParseURL(cfg.URL())
 	if err != nil {
 		log.Printf("Failed to parse Redis DSN: %v", err)
 		return nil, fmt.Errorf("failed to parse Redis DSN: %v", err)
@@ -43,18 +43,6 @@ func NewRedis() (*Redis, error) {
 	return r, nil
 }
 
-// ConfigDSN returns the Data Source Name (DSN) for Redis
-func (r *Redis) ConfigDSN() string {
-	host := os.Getenv("REDIS_HOST")
-	port := os.Getenv("REDIS_PORT")
-	password := os.Getenv("REDIS_PASSWORD")
-	if host == "" || port == "" {
-		log.Printf("Redis environment variables not set, using default: localhost:6379")
-		return "redis://localhost:6379/0"
-	}
-	return fmt.Sprintf("redis://:%s@%s:%s/0", password, host, port)
-}
-
 // Close shuts down the Redis client
 func (r *Redis) Close() error {
 	r.mu.Lock()
@@ -0,0 +1,50 @@
+package redis_test
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"ticket_app/internal/config"
+	"ticket_app/internal/redis"
+)
+
+func TestRevocationStore(t *testing.T) {
+	mr := miniredis.RunT(t)
+	port, err := strconv.Atoi(mr.Port())
+	require.NoError(t, err)
+	r, err := redis.NewRedis(config.RedisConfig{Host: mr.Host(), Port: port})
+	require.NoError(t, err)
+	defer r.Close()
+
+	store := redis.NewRevocationStore(r)
+	ctx := context.Background()
+	now := time.Now()
+
+	revoked, err := store.IsRevoked(ctx, "jti-1", "7", now)
+	require.NoError(t, err)
+	assert.False(t, revoked)
+
+	require.NoError(t, store.RevokeToken(ctx, "jti-1", now.Add(time.Hour)))
+	revoked, err = store.IsRevoked(ctx, "jti-1", "7", now)
+	require.NoError(t, err)
+	assert.True(t, revoked)
+	assert.InDelta(t, time.Hour.Seconds(), mr.TTL("auth:revoked:jti:jti-1").Seconds(), 5)
+
+	require.NoError(t, store.RevokeSubject(ctx, "7", now, time.Hour))
+	revoked, err = store.IsRevoked(ctx, "jti-2", "7", now.Add(-time.Minute))
+	require.NoError(t, err)
+	assert.True(t, revoked, "tokens issued before logout-all are revoked")
+	revoked, err = store.IsRevoked(ctx, "jti-3", "7", now.Add(2*time.Second))
+	require.NoError(t, err)
+	assert.False(t, revoked, "tokens issued after logout-all stay valid")
+
+	// Revoking an already expired token stores nothing
+	require.NoError(t, store.RevokeToken(ctx, "old", now.Add(-time.Minute)))
+	assert.False(t, mr.Exists("auth:revoked:jti:old"))
+}
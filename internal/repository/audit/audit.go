@@ -0,0 +1,28 @@
+package audit
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+
+	"ticket_app/domain"
+	"ticket_app/internal/repository"
+)
+
+// AuditRepository stores audit log entries
+type AuditRepository interface {
+	Create(ctx context.Context, entry *domain.AuditLog) error
+}
+
+// GormAuditRepository implements AuditRepository using GORM
+type GormAuditRepository struct {
+	db *gorm.DB
+}
+
+func NewGormAuditRepository(db *gorm.DB) AuditRepository {
+	return &GormAuditRepository{db: db}
+}
+
+func (r *GormAuditRepository) Create(ctx context.Context, entry *domain.AuditLog) error {
+	return repository.Conn(ctx, r.db).Create(entry).Error
+}
@@ -1,21 +1,28 @@
 package booking
 
 import (
+	"context"
 	"log"
 	"ticket_app/domain"
+	"ticket_app/internal/repository"
+	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type BookingRepository interface {
-	Create(booking *domain.Booking) error
-	FindAll() ([]domain.Booking, error)
-	FindById(id uint) (*domain.Booking, error)
-	Update(booking *domain.Booking) error
-	Delete(id uint) error
-	UpdateStatusByID(id uint, status domain.BookingStatus) error
-	Count() (int64, error)
-	FindAllWithPagination(offset int, limit int) ([]domain.Booking, error)
+	Create(ctx context.Context, booking *domain.Booking) error
+	FindAll(ctx context.Context) ([]domain.Booking, error)
+	FindById(ctx context.Context, id uint) (*domain.Booking, error)
+	FindByIdForUpdate(ctx context.Context, id uint) (*domain.Booking, error)
+	Update(ctx context.Context, booking *domain.Booking) error
+	Delete(ctx context.Context, id uint) error
+	UpdateStatusByID(ctx context.Context, id uint, status domain.BookingStatus) error
+	Count(ctx context.Context) (int64, error)
+	FindAllWithPagination(ctx context.Context, offset int, limit int) ([]domain.Booking, error)
+	FindByUser(ctx context.Context, userID uint, filter domain.BookingFilter, offset int, limit int) ([]domain.Booking, int64, error)
+	CreateWithReservation(ctx context.Context, booking *domain.Booking, payment *domain.Payment) error
 }
 
 type GormBookingRepository struct {
@@ -26,56 +33,341 @@ func NewGormBookingRepository(db *gorm.DB) BookingRepository {
 	return &GormBookingRepository{db: db}
 }
 
-func (r *GormBookingRepository) Create(booking *domain.Booking) error {
+func (r *GormBookingRepository) conn(ctx context.Context) *gorm.DB {
+	return repository.Conn(ctx, r.db)
+}
+
+func (r *GormBookingRepository) Create(ctx context.Context, booking *domain.Booking) error {
 	log.Println("Creating booking:", booking)
-	return r.db.Create(booking).Error
+	return r.conn(ctx).Create(booking).Error
+}
+
+// CreateWithReservation reserves tickets and creates the booking, its items and its payment in one transaction.
+//
+// The event row is locked with SELECT ... FOR UPDATE, so concurrent bookings for
+// the same event are serialized: each one sees the inventory left by the previous
+// commit and fails with domain.ErrNotEnoughTickets instead of overselling. Every
+// booking takes its tickets from events.total_tickets; a booking with items also
+// takes them from the quota of each ticket type. Booking without items is only
+// allowed for events without ticket types, at events.ticket_price.
+//
+// Events with a venue are booked by seat (booking.Seats): each seat must be in
+// the venue and not held by another live booking of the event. The unique
+// index on booking_seats backs this up, so a seat can never be sold twice.
+//
+// Prices are taken from the locked rows: booking.Quantity, booking.TotalPrice,
+// the unit price of each item and seat, and payment.Amount are filled in before insert.
+// When ctx already carries a transaction the work runs in a savepoint of it.
+func (r *GormBookingRepository) CreateWithReservation(ctx context.Context, booking *domain.Booking, payment *domain.Payment) error {
+	return r.conn(ctx).Transaction(func(tx *gorm.DB) error {
+		event, err := reserve(tx, booking, time.Now())
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Omit(clause.Associations).Create(booking).Error; err != nil {
+			return err
+		}
+		for i := range booking.Items {
+			booking.Items[i].BookingID = booking.ID
+		}
+		if len(booking.Items) > 0 {
+			if err := tx.Omit(clause.Associations).Create(&booking.Items).Error; err != nil {
+				return err
+			}
+		}
+		for i := range booking.Seats {
+			booking.Seats[i].BookingID = &booking.ID
+			booking.Seats[i].EventID = event.ID
+		}
+		if err := insertSeats(tx, booking.Seats); err != nil {
+			return err
+		}
+
+		payment.BookingID = &booking.ID
+		payment.Amount = booking.TotalPrice
+		return tx.Omit(clause.Associations).Create(payment).Error
+	})
+}
+
+// reserve locks the event of booking, checks and takes the tickets (and
+// seats) of booking from its inventory, and prices booking. Nothing of booking
+// itself is inserted; holds reserve through the same path.
+func reserve(tx *gorm.DB, booking *domain.Booking, now time.Time) (*domain.Event, error) {
+	var event domain.Event
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Omit("Bookings").
+		First(&event, booking.EventID).Error; err != nil {
+		return nil, err
+	}
+	if event.Status != domain.EventStatusActive {
+		return nil, domain.ErrEventNotActive
+	}
+
+	switch {
+	case len(booking.Seats) > 0:
+		if event.VenueID == nil {
+			return nil, domain.ErrNoSeatMap
+		}
+		if err := reserveSeats(tx, booking, &event, now); err != nil {
+			return nil, err
+		}
+	case event.VenueID != nil:
+		return nil, domain.ErrSeatSelectionRequired
+	case len(booking.Items) == 0:
+		var ticketTypes int64
+		if err := tx.Model(&domain.TicketType{}).Where("event_id = ?", event.ID).Count(&ticketTypes).Error; err != nil {
+			return nil, err
+		}
+		if ticketTypes > 0 {
+			return nil, domain.ErrTicketTypeRequired
+		}
+		booking.TotalPrice = float64(booking.Quantity) * event.TicketPrice
+	default:
+		if err := reserveItems(tx, booking, now); err != nil {
+			return nil, err
+		}
+	}
+
+	if booking.Quantity < 1 {
+		return nil, domain.ErrBadParamInput
+	}
+	if event.TotalTickets < booking.Quantity {
+		return nil, domain.ErrNotEnoughTickets
+	}
+	if err := tx.Model(&domain.Event{}).
+		Where("id = ?", event.ID).
+		Update("total_tickets", gorm.Expr("total_tickets - ?", booking.Quantity)).Error; err != nil {
+		return nil, err
+	}
+	return &event, nil
+}
+
+// insertSeats inserts seats reserved by reserve. A seat taken by a concurrent
+// transaction that got past the event lock check fails on the unique index.
+func insertSeats(tx *gorm.DB, seats []domain.BookingSeat) error {
+	if len(seats) == 0 {
+		return nil
+	}
+	err := tx.Omit(clause.Associations).Create(&seats).Error
+	if repository.IsUniqueViolation(err) {
+		return domain.ErrSeatTaken
+	}
+	return err
+}
+
+// reserveItems locks the ticket types of booking.Items, checks them and adds
+// the quantities to their reserved counters. The caller holds the event lock.
+func reserveItems(tx *gorm.DB, booking *domain.Booking, now time.Time) error {
+	ids := make([]uint, len(booking.Items))
+	for i, item := range booking.Items {
+		ids[i] = item.TicketTypeID
+	}
+	var ticketTypes []domain.TicketType
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("event_id = ? AND id IN ?", booking.EventID, ids).
+		Order("id").
+		Find(&ticketTypes).Error; err != nil {
+		return err
+	}
+	byID := make(map[uint]*domain.TicketType, len(ticketTypes))
+	for i := range ticketTypes {
+		byID[ticketTypes[i].ID] = &ticketTypes[i]
+	}
+
+	booking.Quantity, booking.TotalPrice = 0, 0
+	for i := range booking.Items {
+		item := &booking.Items[i]
+		ticketType, ok := byID[item.TicketTypeID]
+		if !ok {
+			return domain.ErrTicketTypeNotFound
+		}
+		delete(byID, item.TicketTypeID) // mỗi hạng vé chỉ một dòng
+		if !ticketType.OnSale(now) {
+			return domain.ErrTicketTypeNotOnSale
+		}
+		if err := ticketType.CheckQuantity(item.Quantity); err != nil {
+			return err
+		}
+		if ticketType.Available() < item.Quantity {
+			return domain.ErrNotEnoughTickets
+		}
+		if err := tx.Model(&domain.TicketType{}).
+			Where("id = ?", ticketType.ID).
+			Update("reserved", gorm.Expr("reserved + ?", item.Quantity)).Error; err != nil {
+			return err
+		}
+		item.UnitPrice = ticketType.Price
+		booking.Quantity += item.Quantity
+		booking.TotalPrice += float64(item.Quantity) * ticketType.Price
+	}
+	return nil
 }
 
+// reserveSeats checks the seats of booking.Seats against the venue of event and
+// the live bookings of the event, and prices them. When the event sells by
+// ticket type, the seats become items of the ticket type of their section and
+// are reserved through reserveItems. The caller holds the event lock.
+func reserveSeats(tx *gorm.DB, booking *domain.Booking, event *domain.Event, now time.Time) error {
+	ids := make([]uint, len(booking.Seats))
+	for i, seat := range booking.Seats {
+		ids[i] = seat.SeatID
+	}
+	var seats []struct {
+		SeatID    uint
+		SectionID uint
+	}
+	if err := tx.Table("seats").
+		Select("seats.id AS seat_id, seat_rows.section_id").
+		Joins("JOIN seat_rows ON seat_rows.id = seats.row_id").
+		Joins("JOIN sections ON sections.id = seat_rows.section_id").
+		Where("sections.venue_id = ? AND seats.id IN ?", *event.VenueID, ids).
+		Scan(&seats).Error; err != nil {
+		return err
+	}
+	if len(seats) != len(ids) {
+		return domain.ErrSeatNotFound
+	}
+	sectionOf := make(map[uint]uint, len(seats))
+	for _, seat := range seats {
+		sectionOf[seat.SeatID] = seat.SectionID
+	}
+
+	var taken int64
+	if err := tx.Model(&domain.BookingSeat{}).
+		Where("event_id = ? AND seat_id IN ? AND released_at IS NULL", event.ID, ids).
+		Count(&taken).Error; err != nil {
+		return err
+	}
+	if taken > 0 {
+		return domain.ErrSeatTaken
+	}
+
+	var ticketTypes []domain.TicketType
+	if err := tx.Where("event_id = ?", event.ID).Find(&ticketTypes).Error; err != nil {
+		return err
+	}
+	if len(ticketTypes) == 0 {
+		for i := range booking.Seats {
+			booking.Seats[i].UnitPrice = event.TicketPrice
+		}
+		booking.Quantity = len(booking.Seats)
+		booking.TotalPrice = float64(booking.Quantity) * event.TicketPrice
+		return nil
+	}
+
+	typeOf := make(map[uint]uint, len(ticketTypes)) // section -> ticket type
+	for _, t := range ticketTypes {
+		if t.SectionID != nil {
+			typeOf[*t.SectionID] = t.ID
+		}
+	}
+	booking.Items = nil
+	index := make(map[uint]int) // ticket type -> item
+	for _, seat := range booking.Seats {
+		typeID, ok := typeOf[sectionOf[seat.SeatID]]
+		if !ok {
+			return domain.ErrSeatNotOnSale
+		}
+		if i, ok := index[typeID]; ok {
+			booking.Items[i].Quantity++
+			continue
+		}
+		index[typeID] = len(booking.Items)
+		booking.Items = append(booking.Items, domain.BookingItem{TicketTypeID: typeID, Quantity: 1})
+	}
+	if err := reserveItems(tx, booking, now); err != nil {
+		return err
+	}
+	for i := range booking.Seats {
+		item := booking.Items[index[typeOf[sectionOf[booking.Seats[i].SeatID]]]]
+		booking.Seats[i].UnitPrice = item.UnitPrice
+	}
+	return nil
+}
 
-func (r *GormBookingRepository) FindAll() ([]domain.Booking, error) {
+func (r *GormBookingRepository) FindAll(ctx context.Context) ([]domain.Booking, error) {
 	var bookings []domain.Booking
 	log.Println("Finding all bookings")
-	err := r.db.
+	err := r.conn(ctx).
 		Preload("User").
 		Preload("Event").
 		Find(&bookings).Error
 	return bookings, err
 }
 
-func (r *GormBookingRepository) FindAllWithPagination(offset int, limit int) ([]domain.Booking, error) {
+func (r *GormBookingRepository) FindAllWithPagination(ctx context.Context, offset int, limit int) ([]domain.Booking, error) {
 	var bookings []domain.Booking
-	err := r.db.
+	err := r.conn(ctx).
 		Preload("User").
 		Preload("Event").
 		Offset(offset).
 		Limit(limit).
-		Find(&bookings).Error 
+		Find(&bookings).Error
 	return bookings, err
 }
 
-func (r *GormBookingRepository) FindById(id uint) (*domain.Booking, error) {
+// FindByUser trả về một trang booking của userID, mới nhất trước, kèm event
+// (không kèm user), và tổng số booking khớp filter
+func (r *GormBookingRepository) FindByUser(ctx context.Context, userID uint, filter domain.BookingFilter, offset int, limit int) ([]domain.Booking, int64, error) {
+	query := r.conn(ctx).Model(&domain.Booking{}).Where("user_id = ?", userID)
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.EventID != 0 {
+		query = query.Where("event_id = ?", filter.EventID)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var bookings []domain.Booking
+	err := query.
+		Preload("Event", func(db *gorm.DB) *gorm.DB { return db.Omit("Bookings") }).
+		Preload("Items.TicketType").
+		Preload("Seats.Seat.Row.Section").
+		Order("created_at DESC, id DESC").
+		Offset(offset).
+		Limit(limit).
+		Find(&bookings).Error
+	return bookings, total, err
+}
+
+func (r *GormBookingRepository) FindById(ctx context.Context, id uint) (*domain.Booking, error) {
+	var booking domain.Booking
+	if err := r.conn(ctx).Preload("User").Preload("Event").Preload("Items.TicketType").Preload("Seats.Seat.Row.Section").
+		First(&booking, id).Error; err != nil {
+		return nil, err
+	}
+	return &booking, nil
+}
+
+// FindByIdForUpdate loads the booking and locks its row until the surrounding
+// transaction ends. Associations are not preloaded.
+func (r *GormBookingRepository) FindByIdForUpdate(ctx context.Context, id uint) (*domain.Booking, error) {
 	var booking domain.Booking
-	if err := r.db.Preload("User").Preload("Event").First(&booking, id).Error; err != nil {
+	if err := r.conn(ctx).Clauses(clause.Locking{Strength: "UPDATE"}).First(&booking, id).Error; err != nil {
 		return nil, err
 	}
 	return &booking, nil
 }
 
-func (r *GormBookingRepository) Update(booking *domain.Booking) error {
-	return r.db.Preload("User").Preload("Event").Save(booking).Error
+func (r *GormBookingRepository) Update(ctx context.Context, booking *domain.Booking) error {
+	return r.conn(ctx).Omit(clause.Associations).Save(booking).Error
 }
 
-func (r *GormBookingRepository) Delete(id uint) error {
-	return r.db.Delete(&domain.Booking{}, id).Error
+func (r *GormBookingRepository) Delete(ctx context.Context, id uint) error {
+	return r.conn(ctx).Delete(&domain.Booking{}, id).Error
 }
 
-func (r *GormBookingRepository) UpdateStatusByID(id uint, status domain.BookingStatus) error {
-	return r.db.Model(&domain.Booking{}).Where("id = ?", id).Update("status", status).Error
+func (r *GormBookingRepository) UpdateStatusByID(ctx context.Context, id uint, status domain.BookingStatus) error {
+	return r.conn(ctx).Model(&domain.Booking{}).Where("id = ?", id).Update("status", status).Error
 }
-func (r *GormBookingRepository) Count() (int64, error) {
+func (r *GormBookingRepository) Count(ctx context.Context) (int64, error) {
 	var count int64
-	if err := r.db.Model(&domain.Booking{}).Count(&count).Error; err != nil {
+	if err := r.conn(ctx).Model(&domain.Booking{}).Count(&count).Error; err != nil {
 		return 0, err
 	}
 	return count, nil
-}
\ No newline at end of file
+}
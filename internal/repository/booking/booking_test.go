@@ -0,0 +1,259 @@
+package booking_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"ticket_app/domain"
+	"ticket_app/internal/migration"
+	"ticket_app/internal/repository/booking"
+	eventRepo "ticket_app/internal/repository/event"
+	"ticket_app/migrations"
+)
+
+// openTestDB connects to the Postgres instance in TEST_POSTGRES_DSN, e.g.
+// "host=localhost user=admin password=admin@123 dbname=ticket_box_test port=6432 sslmode=disable".
+// The test is skipped when the variable is not set.
+func openTestDB(t *testing.T) *gorm.DB {
+	dsn := os.Getenv("TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("TEST_POSTGRES_DSN not set, skipping Postgres integration test")
+	}
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	require.NoError(t, err)
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	sqlDB.SetMaxOpenConns(50)
+	migrator, err := migration.NewMigrator(sqlDB, migrations.FS)
+	require.NoError(t, err)
+	_, err = migrator.Up(context.Background())
+	require.NoError(t, err)
+	return db
+}
+
+func TestCreateWithReservationNoOversell(t *testing.T) {
+	db := openTestDB(t)
+
+	const (
+		totalTickets = 100
+		requests     = 500
+	)
+
+	user := domain.User{Email: fmt.Sprintf("concurrency-%d@example.com", time.Now().UnixNano()), Password: "x"}
+	require.NoError(t, db.Create(&user).Error)
+	event := domain.Event{
+		Name:         "Concurrency test",
+		StartDate:    time.Now().Add(24 * time.Hour),
+		EndDate:      time.Now().Add(48 * time.Hour),
+		TotalTickets: totalTickets,
+		TicketPrice:  10,
+		Status:       domain.EventStatusActive,
+	}
+	require.NoError(t, db.Create(&event).Error)
+
+	repo := booking.NewGormBookingRepository(db)
+
+	var (
+		wg        sync.WaitGroup
+		succeeded int64
+		soldOut   int64
+		start     = make(chan struct{})
+	)
+	for i := 0; i < requests; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			b := &domain.Booking{UserID: user.ID, EventID: event.ID, Quantity: 1, Status: domain.BookingStatusPending}
+			p := &domain.Payment{Status: domain.PaymentStatusPending}
+			err := repo.CreateWithReservation(context.Background(), b, p)
+			switch {
+			case err == nil:
+				atomic.AddInt64(&succeeded, 1)
+			case errors.Is(err, domain.ErrNotEnoughTickets):
+				atomic.AddInt64(&soldOut, 1)
+			default:
+				t.Errorf("unexpected error: %v", err)
+			}
+		}()
+	}
+	close(start)
+	wg.Wait()
+
+	assert.EqualValues(t, totalTickets, succeeded)
+	assert.EqualValues(t, requests-totalTickets, soldOut)
+
+	var reloaded domain.Event
+	require.NoError(t, db.Omit("Bookings").First(&reloaded, event.ID).Error)
+	assert.Equal(t, 0, reloaded.TotalTickets)
+
+	var booked int64
+	require.NoError(t, db.Model(&domain.Booking{}).Where("event_id = ?", event.ID).Select("COALESCE(SUM(quantity), 0)").Scan(&booked).Error)
+	assert.EqualValues(t, totalTickets, booked)
+
+	var payments int64
+	require.NoError(t, db.Model(&domain.Payment{}).
+		Joins("JOIN bookings ON bookings.id = payments.booking_id").
+		Where("bookings.event_id = ?", event.ID).
+		Count(&payments).Error)
+	assert.EqualValues(t, totalTickets, payments)
+}
+
+func TestFindByUserFiltersAndPaginates(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+
+	owner := domain.User{Email: fmt.Sprintf("history-%d@example.com", time.Now().UnixNano()), Password: "x"}
+	other := domain.User{Email: fmt.Sprintf("history-other-%d@example.com", time.Now().UnixNano()), Password: "x"}
+	require.NoError(t, db.Create(&owner).Error)
+	require.NoError(t, db.Create(&other).Error)
+	var events [2]domain.Event
+	for i := range events {
+		events[i] = domain.Event{Name: fmt.Sprintf("History %d", i), TotalTickets: 10, TicketPrice: 5, Status: domain.EventStatusActive}
+		require.NoError(t, db.Create(&events[i]).Error)
+	}
+
+	base := time.Now().Add(-time.Hour)
+	create := func(userID, eventID uint, status domain.BookingStatus, minutes int) uint {
+		b := domain.Booking{UserID: userID, EventID: eventID, Quantity: 1, TotalPrice: 5, Status: status,
+			CreatedAt: base.Add(time.Duration(minutes) * time.Minute)}
+		require.NoError(t, db.Omit("User", "Event").Create(&b).Error)
+		return b.ID
+	}
+	first := create(owner.ID, events[0].ID, domain.BookingStatusConfirmed, 1)
+	second := create(owner.ID, events[1].ID, domain.BookingStatusPending, 2)
+	third := create(owner.ID, events[0].ID, domain.BookingStatusCancelled, 3)
+	create(other.ID, events[0].ID, domain.BookingStatusConfirmed, 4)
+
+	repo := booking.NewGormBookingRepository(db)
+
+	page, total, err := repo.FindByUser(ctx, owner.ID, domain.BookingFilter{}, 0, 2)
+	require.NoError(t, err)
+	assert.EqualValues(t, 3, total)
+	require.Len(t, page, 2)
+	assert.Equal(t, []uint{third, second}, []uint{page[0].ID, page[1].ID}, "newest first")
+	assert.Equal(t, events[0].Name, page[0].Event.Name)
+
+	page, total, err = repo.FindByUser(ctx, owner.ID, domain.BookingFilter{}, 2, 2)
+	require.NoError(t, err)
+	assert.EqualValues(t, 3, total)
+	require.Len(t, page, 1)
+	assert.Equal(t, first, page[0].ID)
+
+	page, total, err = repo.FindByUser(ctx, owner.ID, domain.BookingFilter{EventID: events[0].ID, Status: domain.BookingStatusConfirmed}, 0, 10)
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, total)
+	require.Len(t, page, 1)
+	assert.Equal(t, first, page[0].ID)
+}
+
+func TestCreateWithReservationTicketTypes(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+
+	user := domain.User{Email: fmt.Sprintf("tiers-%d@example.com", time.Now().UnixNano()), Password: "x"}
+	require.NoError(t, db.Create(&user).Error)
+	event := domain.Event{Name: "Tiers", StartDate: time.Now().Add(24 * time.Hour), TotalTickets: 100, TicketPrice: 10, Status: domain.EventStatusActive}
+	require.NoError(t, db.Create(&event).Error)
+	later := time.Now().Add(time.Hour)
+	vip := domain.TicketType{EventID: event.ID, Name: "VIP", Price: 100, Quota: 3, MinPerOrder: 1, MaxPerOrder: 2}
+	standard := domain.TicketType{EventID: event.ID, Name: "Standard", Price: 20, Quota: 50, MinPerOrder: 1}
+	earlyBird := domain.TicketType{EventID: event.ID, Name: "Early bird", Price: 5, Quota: 50, MinPerOrder: 1, SalesStart: &later}
+	for _, tt := range []*domain.TicketType{&vip, &standard, &earlyBird} {
+		require.NoError(t, db.Create(tt).Error)
+	}
+
+	repo := booking.NewGormBookingRepository(db)
+	book := func(items ...domain.BookingItem) (*domain.Booking, error) {
+		b := &domain.Booking{UserID: user.ID, EventID: event.ID, Status: domain.BookingStatusPending, Items: items}
+		return b, repo.CreateWithReservation(ctx, b, &domain.Payment{Status: domain.PaymentStatusPending})
+	}
+
+	b, err := book(domain.BookingItem{TicketTypeID: vip.ID, Quantity: 2}, domain.BookingItem{TicketTypeID: standard.ID, Quantity: 3})
+	require.NoError(t, err)
+	assert.Equal(t, 5, b.Quantity)
+	assert.Equal(t, float64(260), b.TotalPrice)
+
+	_, err = book(domain.BookingItem{TicketTypeID: vip.ID, Quantity: 2})
+	assert.ErrorIs(t, err, domain.ErrNotEnoughTickets, "only one VIP ticket left")
+	_, err = book(domain.BookingItem{TicketTypeID: standard.ID, Quantity: 0})
+	assert.ErrorIs(t, err, domain.ErrTicketQuantityOutOfRange)
+	_, err = book(domain.BookingItem{TicketTypeID: earlyBird.ID, Quantity: 1})
+	assert.ErrorIs(t, err, domain.ErrTicketTypeNotOnSale)
+	_, err = book()
+	assert.ErrorIs(t, err, domain.ErrTicketTypeRequired)
+
+	var reloaded domain.TicketType
+	require.NoError(t, db.First(&reloaded, vip.ID).Error)
+	assert.Equal(t, 2, reloaded.Reserved)
+	var reloadedEvent domain.Event
+	require.NoError(t, db.Omit("Bookings").First(&reloadedEvent, event.ID).Error)
+	assert.Equal(t, 95, reloadedEvent.TotalTickets)
+
+	found, err := repo.FindById(ctx, b.ID)
+	require.NoError(t, err)
+	require.Len(t, found.Items, 2)
+	assert.Equal(t, "VIP", found.Items[0].TicketType.Name)
+	assert.Equal(t, float64(100), found.Items[0].UnitPrice)
+}
+
+func TestCreateWithReservationSeats(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+
+	user := domain.User{Email: fmt.Sprintf("seats-%d@example.com", time.Now().UnixNano()), Password: "x"}
+	require.NoError(t, db.Create(&user).Error)
+	venue := domain.Venue{Name: "Hall", Sections: []domain.Section{{
+		Name: "Orchestra",
+		Rows: []domain.Row{{Label: "A", Position: 1, Seats: []domain.Seat{{Number: 1}, {Number: 2}, {Number: 3}}}},
+	}}}
+	require.NoError(t, db.Create(&venue).Error)
+	seats := venue.Sections[0].Rows[0].Seats
+	event := domain.Event{Name: "Seated", StartDate: time.Now().Add(24 * time.Hour), TotalTickets: 100, TicketPrice: 30,
+		Status: domain.EventStatusActive, VenueID: &venue.ID}
+	require.NoError(t, db.Create(&event).Error)
+
+	repo := booking.NewGormBookingRepository(db)
+	book := func(seatIDs ...uint) (*domain.Booking, error) {
+		b := &domain.Booking{UserID: user.ID, EventID: event.ID, Status: domain.BookingStatusPending}
+		for _, id := range seatIDs {
+			b.Seats = append(b.Seats, domain.BookingSeat{SeatID: id})
+		}
+		return b, repo.CreateWithReservation(ctx, b, &domain.Payment{Status: domain.PaymentStatusPending})
+	}
+
+	b, err := book(seats[0].ID, seats[1].ID)
+	require.NoError(t, err)
+	assert.Equal(t, 2, b.Quantity)
+	assert.Equal(t, float64(60), b.TotalPrice)
+
+	_, err = book(seats[1].ID, seats[2].ID)
+	assert.ErrorIs(t, err, domain.ErrSeatTaken)
+	_, err = book(seats[2].ID + 1000)
+	assert.ErrorIs(t, err, domain.ErrSeatNotFound)
+	_, err = book()
+	assert.ErrorIs(t, err, domain.ErrSeatSelectionRequired)
+
+	found, err := repo.FindById(ctx, b.ID)
+	require.NoError(t, err)
+	require.Len(t, found.Seats, 2)
+	assert.Equal(t, "A", found.Seats[0].Seat.Row.Label)
+	assert.Equal(t, "Orchestra", found.Seats[0].Seat.Row.Section.Name)
+
+	// Huỷ booking nhả ghế cho người khác đặt
+	require.NoError(t, eventRepo.NewGormEventRepository(db).ReleaseTickets(ctx, b))
+	_, err = book(seats[1].ID)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,240 @@
+package booking
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"ticket_app/domain"
+	"ticket_app/internal/repository"
+)
+
+// HoldRepository stores holds. A hold takes tickets and seats from the event
+// exactly like a booking, so it shares the reservation code of
+// BookingRepository and lives next to it.
+type HoldRepository interface {
+	// Create reserves the tickets of hold (Quantity, Items or Seats, as for a
+	// booking) and inserts it ACTIVE. Quantity, TotalPrice and the unit prices
+	// are filled in from the locked rows.
+	Create(ctx context.Context, hold *domain.Hold) error
+	// FindByToken returns domain.ErrHoldNotFound when no hold has token
+	FindByToken(ctx context.Context, token string) (*domain.Hold, error)
+	// Convert turns the active hold token of userID into booking with its items,
+	// seats and payment, in one transaction. The tickets stay reserved; only
+	// the owner changes. It returns domain.ErrHoldNotFound, domain.ErrHoldExpired
+	// or domain.ErrHoldNotActive when the hold cannot be used.
+	Convert(ctx context.Context, token string, userID uint, booking *domain.Booking, payment *domain.Payment) error
+	// Release gives the tickets and seats of an active hold back and moves it
+	// to status. It returns the released hold, or nil and changes nothing when
+	// the hold is no longer active.
+	Release(ctx context.Context, id uint, status domain.HoldStatus) (*domain.Hold, error)
+}
+
+// GormHoldRepository implements HoldRepository using GORM
+type GormHoldRepository struct {
+	db *gorm.DB
+}
+
+func NewGormHoldRepository(db *gorm.DB) HoldRepository {
+	return &GormHoldRepository{db: db}
+}
+
+func (r *GormHoldRepository) conn(ctx context.Context) *gorm.DB {
+	return repository.Conn(ctx, r.db)
+}
+
+func (r *GormHoldRepository) Create(ctx context.Context, hold *domain.Hold) error {
+	return r.conn(ctx).Transaction(func(tx *gorm.DB) error {
+		return createHold(tx, hold)
+	})
+}
+
+// createHold reserves the tickets of hold and inserts it with its items and
+// seats, for HoldRepository.Create and the offers of WaitlistRepository
+func createHold(tx *gorm.DB, hold *domain.Hold) error {
+	booking := &domain.Booking{EventID: hold.EventID, Quantity: hold.Quantity, Seats: hold.Seats}
+	for _, item := range hold.Items {
+		booking.Items = append(booking.Items, domain.BookingItem{TicketTypeID: item.TicketTypeID, Quantity: item.Quantity})
+	}
+	event, err := reserve(tx, booking, time.Now())
+	if err != nil {
+		return err
+	}
+
+	// reserveSeats có thể tạo items từ ghế, nên lấy items từ booking
+	hold.Quantity, hold.TotalPrice = booking.Quantity, booking.TotalPrice
+	hold.Items = make([]domain.HoldItem, len(booking.Items))
+	for i, item := range booking.Items {
+		hold.Items[i] = domain.HoldItem{TicketTypeID: item.TicketTypeID, Quantity: item.Quantity, UnitPrice: item.UnitPrice}
+	}
+	hold.Status = domain.HoldStatusActive
+	if err := tx.Omit(clause.Associations).Create(hold).Error; err != nil {
+		return err
+	}
+	for i := range hold.Items {
+		hold.Items[i].HoldID = hold.ID
+	}
+	if len(hold.Items) > 0 {
+		if err := tx.Omit(clause.Associations).Create(&hold.Items).Error; err != nil {
+			return err
+		}
+	}
+	hold.Seats = booking.Seats
+	for i := range hold.Seats {
+		hold.Seats[i].HoldID = &hold.ID
+		hold.Seats[i].EventID = event.ID
+	}
+	return insertSeats(tx, hold.Seats)
+}
+
+func (r *GormHoldRepository) FindByToken(ctx context.Context, token string) (*domain.Hold, error) {
+	var hold domain.Hold
+	err := r.conn(ctx).
+		Preload("Items.TicketType").
+		Preload("Seats", "released_at IS NULL").
+		Preload("Seats.Seat.Row.Section").
+		Where("token = ?", token).
+		First(&hold).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, domain.ErrHoldNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &hold, nil
+}
+
+// Convert locks the hold, so a conversion racing with the expiry queue or a
+// second conversion of the same token sees the status the other one left
+func (r *GormHoldRepository) Convert(ctx context.Context, token string, userID uint, booking *domain.Booking, payment *domain.Payment) error {
+	return r.conn(ctx).Transaction(func(tx *gorm.DB) error {
+		var hold domain.Hold
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("token = ?", token).First(&hold).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && hold.UserID != userID) {
+			return domain.ErrHoldNotFound
+		}
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		switch {
+		case hold.Status == domain.HoldStatusExpired,
+			hold.Status == domain.HoldStatusActive && hold.Expired(now):
+			return domain.ErrHoldExpired
+		case hold.Status != domain.HoldStatusActive:
+			return domain.ErrHoldNotActive
+		}
+		var status domain.EventStatus
+		if err := tx.Model(&domain.Event{}).Select("status").Where("id = ?", hold.EventID).Scan(&status).Error; err != nil {
+			return err
+		}
+		if status != domain.EventStatusActive {
+			return domain.ErrEventNotActive
+		}
+
+		booking.UserID = hold.UserID
+		booking.EventID = hold.EventID
+		booking.Quantity = hold.Quantity
+		booking.TotalPrice = hold.TotalPrice
+		booking.Status = domain.BookingStatusPending
+		if err := tx.Omit(clause.Associations).Create(booking).Error; err != nil {
+			return err
+		}
+
+		var items []domain.HoldItem
+		if err := tx.Where("hold_id = ?", hold.ID).Order("id").Find(&items).Error; err != nil {
+			return err
+		}
+		booking.Items = make([]domain.BookingItem, len(items))
+		for i, item := range items {
+			booking.Items[i] = domain.BookingItem{
+				BookingID: booking.ID, TicketTypeID: item.TicketTypeID, Quantity: item.Quantity, UnitPrice: item.UnitPrice,
+			}
+		}
+		if len(booking.Items) > 0 {
+			if err := tx.Omit(clause.Associations).Create(&booking.Items).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Model(&domain.BookingSeat{}).
+			Where("hold_id = ? AND released_at IS NULL", hold.ID).
+			Update("booking_id", booking.ID).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("booking_id = ?", booking.ID).Order("id").Find(&booking.Seats).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Model(&hold).Updates(map[string]interface{}{
+			"status": domain.HoldStatusConverted, "booking_id": booking.ID, "updated_at": now,
+		}).Error; err != nil {
+			return err
+		}
+		if err := settleOffer(tx, hold.ID, domain.HoldStatusConverted, now); err != nil {
+			return err
+		}
+
+		payment.BookingID = &booking.ID
+		payment.Amount = booking.TotalPrice
+		return tx.Omit(clause.Associations).Create(payment).Error
+	})
+}
+
+// Release trả vé cho event và hạng vé bằng các câu UPDATE nguyên tử, như
+// EventRepository.ReleaseTickets với booking, và nhả các ghế chưa thuộc booking nào
+func (r *GormHoldRepository) Release(ctx context.Context, id uint, status domain.HoldStatus) (*domain.Hold, error) {
+	var released *domain.Hold
+	err := r.conn(ctx).Transaction(func(tx *gorm.DB) error {
+		var hold domain.Hold
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&hold, id).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return domain.ErrHoldNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if hold.Status != domain.HoldStatusActive {
+			return nil
+		}
+
+		now := time.Now()
+		if err := tx.Model(&domain.Event{}).
+			Where("id = ?", hold.EventID).
+			Update("total_tickets", gorm.Expr("total_tickets + ?", hold.Quantity)).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec(`
+			UPDATE ticket_types
+			SET reserved = ticket_types.reserved - hold_items.quantity
+			FROM hold_items
+			WHERE hold_items.ticket_type_id = ticket_types.id AND hold_items.hold_id = ?
+		`, hold.ID).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&domain.BookingSeat{}).
+			Where("hold_id = ? AND booking_id IS NULL AND released_at IS NULL", hold.ID).
+			Update("released_at", now).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&hold).Updates(map[string]interface{}{"status": status, "updated_at": now}).Error; err != nil {
+			return err
+		}
+		if err := settleOffer(tx, hold.ID, status, now); err != nil {
+			return err
+		}
+		released = &hold
+		return nil
+	})
+	return released, err
+}
+
+// settleOffer kết thúc waitlist entry nhận holdID làm offer, nếu có, theo
+// status mới của hold
+func settleOffer(tx *gorm.DB, holdID uint, status domain.HoldStatus, now time.Time) error {
+	return tx.Model(&domain.WaitlistEntry{}).
+		Where("hold_id = ? AND status = ?", holdID, domain.WaitlistStatusOffered).
+		Updates(map[string]interface{}{"status": domain.WaitlistStatusForHold(status), "updated_at": now}).Error
+}
@@ -0,0 +1,83 @@
+package booking_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"ticket_app/domain"
+	"ticket_app/internal/repository/booking"
+)
+
+func TestHoldLifecycle(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+
+	user := domain.User{Email: fmt.Sprintf("holds-%d@example.com", time.Now().UnixNano()), Password: "x"}
+	require.NoError(t, db.Create(&user).Error)
+	venue := domain.Venue{Name: "Hall", Sections: []domain.Section{{
+		Name: "Orchestra",
+		Rows: []domain.Row{{Label: "A", Position: 1, Seats: []domain.Seat{{Number: 1}, {Number: 2}}}},
+	}}}
+	require.NoError(t, db.Create(&venue).Error)
+	seats := venue.Sections[0].Rows[0].Seats
+	event := domain.Event{Name: "Held", StartDate: time.Now().Add(24 * time.Hour), TotalTickets: 10, TicketPrice: 30,
+		Status: domain.EventStatusActive, VenueID: &venue.ID}
+	require.NoError(t, db.Create(&event).Error)
+
+	repo := booking.NewGormHoldRepository(db)
+	hold := func(token string, seatIDs ...uint) (*domain.Hold, error) {
+		h := &domain.Hold{Token: token, UserID: user.ID, EventID: event.ID, ExpiresAt: time.Now().Add(time.Minute)}
+		for _, id := range seatIDs {
+			h.Seats = append(h.Seats, domain.BookingSeat{SeatID: id})
+		}
+		return h, repo.Create(ctx, h)
+	}
+	remaining := func() int {
+		var e domain.Event
+		require.NoError(t, db.First(&e, event.ID).Error)
+		return e.TotalTickets
+	}
+
+	// Ghế đang được giữ không đặt hay giữ lại được
+	first, err := hold(fmt.Sprintf("a-%d", event.ID), seats[0].ID)
+	require.NoError(t, err)
+	assert.Equal(t, float64(30), first.TotalPrice)
+	assert.Equal(t, 9, remaining())
+	_, err = hold(fmt.Sprintf("b-%d", event.ID), seats[0].ID)
+	assert.ErrorIs(t, err, domain.ErrSeatTaken)
+
+	// Chỉ chủ hold mới đặt được; đặt xong ghế thuộc booking và hold không dùng lại được
+	b := &domain.Booking{}
+	err = repo.Convert(ctx, first.Token, user.ID+1000, b, &domain.Payment{Status: domain.PaymentStatusPending})
+	assert.ErrorIs(t, err, domain.ErrHoldNotFound)
+	b = &domain.Booking{}
+	require.NoError(t, repo.Convert(ctx, first.Token, user.ID, b, &domain.Payment{Status: domain.PaymentStatusPending}))
+	assert.Equal(t, 1, b.Quantity)
+	require.Len(t, b.Seats, 1)
+	assert.Equal(t, seats[0].ID, b.Seats[0].SeatID)
+	err = repo.Convert(ctx, first.Token, user.ID, &domain.Booking{}, &domain.Payment{Status: domain.PaymentStatusPending})
+	assert.ErrorIs(t, err, domain.ErrHoldNotActive)
+	released, err := repo.Release(ctx, first.ID, domain.HoldStatusExpired)
+	require.NoError(t, err)
+	assert.Nil(t, released)
+	assert.Equal(t, 9, remaining())
+
+	// Hold hết hạn trả vé và ghế
+	second, err := hold(fmt.Sprintf("c-%d", event.ID), seats[1].ID)
+	require.NoError(t, err)
+	assert.Equal(t, 8, remaining())
+	released, err = repo.Release(ctx, second.ID, domain.HoldStatusExpired)
+	require.NoError(t, err)
+	require.NotNil(t, released)
+	assert.Equal(t, event.ID, released.EventID)
+	assert.Equal(t, 9, remaining())
+	err = repo.Convert(ctx, second.Token, user.ID, &domain.Booking{}, &domain.Payment{Status: domain.PaymentStatusPending})
+	assert.ErrorIs(t, err, domain.ErrHoldExpired)
+	_, err = hold(fmt.Sprintf("d-%d", event.ID), seats[1].ID)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,154 @@
+package booking
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"ticket_app/domain"
+	"ticket_app/internal/repository"
+)
+
+// OrderRepository stores orders. An order reserves the tickets of each of its
+// events through the same code as a booking, so it lives next to
+// BookingRepository.
+type OrderRepository interface {
+	// CreateWithReservation reserves the tickets of every booking of order and
+	// inserts the order, its bookings, their items, the order items and the
+	// payment in one transaction: either every event has enough tickets or
+	// nothing is reserved. Prices are filled in from the locked rows.
+	CreateWithReservation(ctx context.Context, order *domain.Order, payment *domain.Payment) error
+	// FindById returns the order with its items, bookings and payment, or domain.ErrNotFound
+	FindById(ctx context.Context, id uint) (*domain.Order, error)
+	// FindByIdForUpdate loads the order with its bookings and locks the order
+	// row until the surrounding transaction ends
+	FindByIdForUpdate(ctx context.Context, id uint) (*domain.Order, error)
+	// Update saves the order row only, not its bookings or items
+	Update(ctx context.Context, order *domain.Order) error
+}
+
+// GormOrderRepository implements OrderRepository using GORM
+type GormOrderRepository struct {
+	db *gorm.DB
+}
+
+func NewGormOrderRepository(db *gorm.DB) OrderRepository {
+	return &GormOrderRepository{db: db}
+}
+
+func (r *GormOrderRepository) conn(ctx context.Context) *gorm.DB {
+	return repository.Conn(ctx, r.db)
+}
+
+// CreateWithReservation locks the events of the order in ID order, so two
+// orders sharing events wait for each other instead of deadlocking. The
+// bookings of order.Bookings carry the quantity or items of their event; the
+// order items are built from them once they are priced.
+func (r *GormOrderRepository) CreateWithReservation(ctx context.Context, order *domain.Order, payment *domain.Payment) error {
+	return r.conn(ctx).Transaction(func(tx *gorm.DB) error {
+		sort.Slice(order.Bookings, func(i, j int) bool { return order.Bookings[i].EventID < order.Bookings[j].EventID })
+		now := time.Now()
+		order.TotalPrice = 0
+		ticketPrices := make([]float64, len(order.Bookings))
+		for i := range order.Bookings {
+			event, err := reserve(tx, &order.Bookings[i], now)
+			if err != nil {
+				return err
+			}
+			ticketPrices[i] = event.TicketPrice
+			order.TotalPrice += order.Bookings[i].TotalPrice
+		}
+
+		order.Status = domain.BookingStatusPending
+		if err := tx.Omit(clause.Associations).Create(order).Error; err != nil {
+			return err
+		}
+		order.Items = nil
+		for i := range order.Bookings {
+			booking := &order.Bookings[i]
+			booking.OrderID = &order.ID
+			booking.UserID = order.UserID
+			booking.Status = domain.BookingStatusPending
+			if err := tx.Omit(clause.Associations).Create(booking).Error; err != nil {
+				return err
+			}
+			for j := range booking.Items {
+				booking.Items[j].BookingID = booking.ID
+			}
+			if len(booking.Items) > 0 {
+				if err := tx.Omit(clause.Associations).Create(&booking.Items).Error; err != nil {
+					return err
+				}
+			}
+			order.Items = append(order.Items, orderItems(order.ID, booking, ticketPrices[i])...)
+		}
+		if err := tx.Omit(clause.Associations).Create(&order.Items).Error; err != nil {
+			return err
+		}
+
+		payment.OrderID = &order.ID
+		payment.Amount = order.TotalPrice
+		return tx.Omit(clause.Associations).Create(payment).Error
+	})
+}
+
+// orderItems trả về các dòng order của booking đã định giá: mỗi hạng vé một
+// dòng, hoặc một dòng vé ticketPrice với event không chia hạng vé
+func orderItems(orderID uint, booking *domain.Booking, ticketPrice float64) []domain.OrderItem {
+	if len(booking.Items) == 0 {
+		return []domain.OrderItem{{
+			OrderID: orderID, BookingID: booking.ID, EventID: booking.EventID,
+			Quantity: booking.Quantity, UnitPrice: ticketPrice,
+		}}
+	}
+	items := make([]domain.OrderItem, len(booking.Items))
+	for i, item := range booking.Items {
+		typeID := item.TicketTypeID
+		items[i] = domain.OrderItem{
+			OrderID: orderID, BookingID: booking.ID, EventID: booking.EventID, TicketTypeID: &typeID,
+			Quantity: item.Quantity, UnitPrice: item.UnitPrice,
+		}
+	}
+	return items
+}
+
+func (r *GormOrderRepository) FindById(ctx context.Context, id uint) (*domain.Order, error) {
+	var order domain.Order
+	err := r.conn(ctx).
+		Preload("Items", func(db *gorm.DB) *gorm.DB { return db.Order("id") }).
+		Preload("Items.Event", func(db *gorm.DB) *gorm.DB { return db.Omit("Bookings") }).
+		Preload("Items.TicketType").
+		Preload("Bookings", func(db *gorm.DB) *gorm.DB { return db.Order("id") }).
+		Preload("Payment").
+		First(&order, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, domain.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
+
+func (r *GormOrderRepository) FindByIdForUpdate(ctx context.Context, id uint) (*domain.Order, error) {
+	var order domain.Order
+	err := r.conn(ctx).Clauses(clause.Locking{Strength: "UPDATE"}).First(&order, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, domain.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := r.conn(ctx).Where("order_id = ?", order.ID).Order("id").Find(&order.Bookings).Error; err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
+
+func (r *GormOrderRepository) Update(ctx context.Context, order *domain.Order) error {
+	return r.conn(ctx).Omit(clause.Associations).Save(order).Error
+}
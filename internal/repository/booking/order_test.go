@@ -0,0 +1,106 @@
+package booking_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"ticket_app/domain"
+	"ticket_app/internal/repository/booking"
+	eventRepo "ticket_app/internal/repository/event"
+)
+
+func TestOrderCreateWithReservationAllOrNothing(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+
+	user := domain.User{Email: fmt.Sprintf("orders-%d@example.com", time.Now().UnixNano()), Password: "x"}
+	require.NoError(t, db.Create(&user).Error)
+	plain := domain.Event{Name: "Plain", StartDate: time.Now().Add(24 * time.Hour), TotalTickets: 10, TicketPrice: 20,
+		Status: domain.EventStatusActive}
+	require.NoError(t, db.Create(&plain).Error)
+	tiered := domain.Event{Name: "Tiered", StartDate: time.Now().Add(24 * time.Hour), TotalTickets: 10, TicketPrice: 0,
+		Status: domain.EventStatusActive}
+	require.NoError(t, db.Create(&tiered).Error)
+	vip := domain.TicketType{EventID: tiered.ID, Name: "VIP", Price: 100, Quota: 2, MinPerOrder: 1}
+	require.NoError(t, db.Create(&vip).Error)
+
+	repo := booking.NewGormOrderRepository(db)
+	order := func(plainQty, vipQty int) (*domain.Order, error) {
+		o := &domain.Order{UserID: user.ID, Bookings: []domain.Booking{
+			{EventID: tiered.ID, Items: []domain.BookingItem{{TicketTypeID: vip.ID, Quantity: vipQty}}},
+			{EventID: plain.ID, Quantity: plainQty},
+		}}
+		return o, repo.CreateWithReservation(ctx, o, &domain.Payment{Status: domain.PaymentStatusPending})
+	}
+	remaining := func(id uint) int {
+		var e domain.Event
+		require.NoError(t, db.First(&e, id).Error)
+		return e.TotalTickets
+	}
+
+	o, err := order(3, 2)
+	require.NoError(t, err)
+	assert.Equal(t, float64(260), o.TotalPrice)
+	require.Len(t, o.Bookings, 2)
+	require.Len(t, o.Items, 2)
+	assert.Equal(t, 7, remaining(plain.ID))
+	assert.Equal(t, 8, remaining(tiered.ID))
+
+	found, err := repo.FindById(ctx, o.ID)
+	require.NoError(t, err)
+	require.NotNil(t, found.Payment)
+	assert.Equal(t, float64(260), found.Payment.Amount)
+	assert.Nil(t, found.Payment.BookingID)
+	for _, b := range found.Bookings {
+		assert.Equal(t, o.ID, *b.OrderID)
+	}
+
+	// VIP đã hết: cả order thất bại, event thường không bị trừ vé
+	_, err = order(1, 1)
+	assert.ErrorIs(t, err, domain.ErrNotEnoughTickets)
+	assert.Equal(t, 7, remaining(plain.ID))
+}
+
+func TestOrderRevenueCountsPerEvent(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+
+	user := domain.User{Email: fmt.Sprintf("order-stats-%d@example.com", time.Now().UnixNano()), Password: "x"}
+	require.NoError(t, db.Create(&user).Error)
+	concert := domain.Event{Name: "Concert", StartDate: time.Now().Add(24 * time.Hour), TotalTickets: 10, TicketPrice: 30,
+		Status: domain.EventStatusActive}
+	require.NoError(t, db.Create(&concert).Error)
+	theatre := domain.Event{Name: "Theatre", StartDate: time.Now().Add(24 * time.Hour), TotalTickets: 10, TicketPrice: 50,
+		Status: domain.EventStatusActive}
+	require.NoError(t, db.Create(&theatre).Error)
+
+	o := &domain.Order{UserID: user.ID, Bookings: []domain.Booking{
+		{EventID: concert.ID, Quantity: 2},
+		{EventID: theatre.ID, Quantity: 1},
+	}}
+	payment := &domain.Payment{Status: domain.PaymentStatusPending}
+	require.NoError(t, booking.NewGormOrderRepository(db).CreateWithReservation(ctx, o, payment))
+	// Booking lẻ của concert vẫn được tính bằng payment của nó
+	single := domain.Booking{UserID: user.ID, EventID: concert.ID, Quantity: 1, TotalPrice: 30, Status: domain.BookingStatusConfirmed}
+	require.NoError(t, db.Create(&single).Error)
+	require.NoError(t, db.Create(&domain.Payment{BookingID: &single.ID, Amount: 30, Status: domain.PaymentStatusCompleted}).Error)
+
+	events := eventRepo.NewGormEventRepository(db)
+	revenue := func(id uint) float64 {
+		stats, err := events.GetEventStats(ctx, id)
+		require.NoError(t, err)
+		return stats.ConfirmedRevenue
+	}
+	assert.Equal(t, float64(30), revenue(concert.ID))
+	assert.Equal(t, float64(0), revenue(theatre.ID))
+
+	require.NoError(t, db.Model(&domain.Payment{}).Where("id = ?", payment.ID).Update("status", domain.PaymentStatusCompleted).Error)
+	require.NoError(t, db.Model(&domain.Booking{}).Where("order_id = ?", o.ID).Update("status", domain.BookingStatusConfirmed).Error)
+	assert.Equal(t, float64(90), revenue(concert.ID))
+	assert.Equal(t, float64(50), revenue(theatre.ID))
+}
@@ -0,0 +1,198 @@
+package booking
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"ticket_app/domain"
+	"ticket_app/internal/repository"
+)
+
+// WaitlistRepository stores waitlist entries. An offer is a hold created for
+// the entry through the same reservation code as a booking, so it lives next
+// to HoldRepository.
+type WaitlistRepository interface {
+	// Join inserts entry WAITING when the tickets it asks for cannot be booked
+	// now. It returns domain.ErrTicketsAvailable when they can, and
+	// domain.ErrAlreadyWaitlisted when the user already waits for them.
+	Join(ctx context.Context, entry *domain.WaitlistEntry) error
+	// FindById returns the entry with its offer hold, or domain.ErrNotFound
+	FindById(ctx context.Context, id uint) (*domain.WaitlistEntry, error)
+	// FindByUser returns the entries of userID with their offer holds, newest first
+	FindByUser(ctx context.Context, userID uint) ([]domain.WaitlistEntry, error)
+	// Waiting returns the WAITING entries of eventID in the order they are served
+	Waiting(ctx context.Context, eventID uint) ([]domain.WaitlistEntry, error)
+	// Offer reserves the tickets of a WAITING entry as hold (Token and
+	// ExpiresAt set by the caller) and moves the entry to OFFERED, in one
+	// transaction. It reports false, and changes nothing, when the entry is no
+	// longer waiting; reservation errors such as domain.ErrNotEnoughTickets are
+	// returned as is.
+	Offer(ctx context.Context, id uint, hold *domain.Hold) (bool, error)
+	// Cancel moves a WAITING entry to CANCELLED. It reports false when the
+	// entry is no longer waiting; an offered entry is left by releasing its hold.
+	Cancel(ctx context.Context, id uint) (bool, error)
+}
+
+// GormWaitlistRepository implements WaitlistRepository using GORM
+type GormWaitlistRepository struct {
+	db *gorm.DB
+}
+
+func NewGormWaitlistRepository(db *gorm.DB) WaitlistRepository {
+	return &GormWaitlistRepository{db: db}
+}
+
+func (r *GormWaitlistRepository) conn(ctx context.Context) *gorm.DB {
+	return repository.Conn(ctx, r.db)
+}
+
+// Join locks the event like a reservation does, so an entry is never added
+// while a concurrent cancellation is giving the tickets back
+func (r *GormWaitlistRepository) Join(ctx context.Context, entry *domain.WaitlistEntry) error {
+	return r.conn(ctx).Transaction(func(tx *gorm.DB) error {
+		var event domain.Event
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Omit("Bookings").
+			First(&event, entry.EventID).Error; err != nil {
+			return err
+		}
+		if event.Status != domain.EventStatusActive {
+			return domain.ErrEventNotActive
+		}
+		if event.VenueID != nil {
+			return domain.ErrSeatSelectionRequired
+		}
+
+		var ticketTypes int64
+		if err := tx.Model(&domain.TicketType{}).Where("event_id = ?", event.ID).Count(&ticketTypes).Error; err != nil {
+			return err
+		}
+		available := event.TotalTickets
+		switch {
+		case ticketTypes > 0 && entry.TicketTypeID == nil:
+			return domain.ErrTicketTypeRequired
+		case ticketTypes == 0 && entry.TicketTypeID != nil:
+			return domain.ErrTicketTypeNotFound
+		case entry.TicketTypeID != nil:
+			var ticketType domain.TicketType
+			err := tx.Where("event_id = ? AND id = ?", event.ID, *entry.TicketTypeID).First(&ticketType).Error
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return domain.ErrTicketTypeNotFound
+			}
+			if err != nil {
+				return err
+			}
+			if err := ticketType.CheckQuantity(entry.Quantity); err != nil {
+				return err
+			}
+			available = min(available, ticketType.Available())
+		}
+		if available >= entry.Quantity {
+			return domain.ErrTicketsAvailable
+		}
+
+		var waiting int64
+		query := tx.Model(&domain.WaitlistEntry{}).
+			Where("user_id = ? AND event_id = ? AND status IN ?", entry.UserID, entry.EventID,
+				[]domain.WaitlistStatus{domain.WaitlistStatusWaiting, domain.WaitlistStatusOffered})
+		if entry.TicketTypeID != nil {
+			query = query.Where("ticket_type_id = ?", *entry.TicketTypeID)
+		} else {
+			query = query.Where("ticket_type_id IS NULL")
+		}
+		if err := query.Count(&waiting).Error; err != nil {
+			return err
+		}
+		if waiting > 0 {
+			return domain.ErrAlreadyWaitlisted
+		}
+
+		entry.Status = domain.WaitlistStatusWaiting
+		err := tx.Omit(clause.Associations).Create(entry).Error
+		// Hai request đồng thời của cùng user vướng unique index
+		if repository.IsUniqueViolation(err) {
+			return domain.ErrAlreadyWaitlisted
+		}
+		return err
+	})
+}
+
+func (r *GormWaitlistRepository) FindById(ctx context.Context, id uint) (*domain.WaitlistEntry, error) {
+	var entry domain.WaitlistEntry
+	err := r.conn(ctx).Preload("Hold").First(&entry, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, domain.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+func (r *GormWaitlistRepository) FindByUser(ctx context.Context, userID uint) ([]domain.WaitlistEntry, error) {
+	var entries []domain.WaitlistEntry
+	err := r.conn(ctx).
+		Preload("Hold").
+		Where("user_id = ?", userID).
+		Order("id DESC").
+		Find(&entries).Error
+	return entries, err
+}
+
+func (r *GormWaitlistRepository) Waiting(ctx context.Context, eventID uint) ([]domain.WaitlistEntry, error) {
+	var entries []domain.WaitlistEntry
+	err := r.conn(ctx).
+		Where("event_id = ? AND status = ?", eventID, domain.WaitlistStatusWaiting).
+		Order("id").
+		Find(&entries).Error
+	return entries, err
+}
+
+// Offer locks the entry before the event, so two workers offering the same
+// event never give one entry two holds
+func (r *GormWaitlistRepository) Offer(ctx context.Context, id uint, hold *domain.Hold) (bool, error) {
+	offered := false
+	err := r.conn(ctx).Transaction(func(tx *gorm.DB) error {
+		var entry domain.WaitlistEntry
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&entry, id).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if entry.Status != domain.WaitlistStatusWaiting {
+			return nil
+		}
+
+		hold.UserID, hold.EventID = entry.UserID, entry.EventID
+		hold.Quantity, hold.Items = entry.Quantity, nil
+		if entry.TicketTypeID != nil {
+			hold.Quantity = 0
+			hold.Items = []domain.HoldItem{{TicketTypeID: *entry.TicketTypeID, Quantity: entry.Quantity}}
+		}
+		if err := createHold(tx, hold); err != nil {
+			return err
+		}
+		now := time.Now()
+		if err := tx.Model(&entry).Updates(map[string]interface{}{
+			"status": domain.WaitlistStatusOffered, "hold_id": hold.ID, "offered_at": now, "updated_at": now,
+		}).Error; err != nil {
+			return err
+		}
+		offered = true
+		return nil
+	})
+	return offered, err
+}
+
+func (r *GormWaitlistRepository) Cancel(ctx context.Context, id uint) (bool, error) {
+	result := r.conn(ctx).Model(&domain.WaitlistEntry{}).
+		Where("id = ? AND status = ?", id, domain.WaitlistStatusWaiting).
+		Updates(map[string]interface{}{"status": domain.WaitlistStatusCancelled, "updated_at": time.Now()})
+	return result.RowsAffected > 0, result.Error
+}
@@ -0,0 +1,121 @@
+package booking_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"ticket_app/domain"
+	"ticket_app/internal/repository/booking"
+)
+
+func TestWaitlistOfferLifecycle(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+
+	stamp := time.Now().UnixNano()
+	first := domain.User{Email: fmt.Sprintf("waitlist-a-%d@example.com", stamp), Password: "x"}
+	require.NoError(t, db.Create(&first).Error)
+	second := domain.User{Email: fmt.Sprintf("waitlist-b-%d@example.com", stamp), Password: "x"}
+	require.NoError(t, db.Create(&second).Error)
+	event := domain.Event{Name: "Popular", StartDate: time.Now().Add(24 * time.Hour), TotalTickets: 2, TicketPrice: 10,
+		Status: domain.EventStatusActive}
+	require.NoError(t, db.Create(&event).Error)
+
+	holds := booking.NewGormHoldRepository(db)
+	repo := booking.NewGormWaitlistRepository(db)
+	join := func(userID uint) (*domain.WaitlistEntry, error) {
+		entry := &domain.WaitlistEntry{UserID: userID, EventID: event.ID, Quantity: 1}
+		return entry, repo.Join(ctx, entry)
+	}
+	status := func(id uint) domain.WaitlistStatus {
+		entry, err := repo.FindById(ctx, id)
+		require.NoError(t, err)
+		return entry.Status
+	}
+
+	// Còn vé thì không vào waitlist
+	_, err := join(second.ID)
+	assert.ErrorIs(t, err, domain.ErrTicketsAvailable)
+
+	sellOut := &domain.Hold{Token: fmt.Sprintf("w-%d", stamp), UserID: first.ID, EventID: event.ID, Quantity: 2,
+		ExpiresAt: time.Now().Add(time.Minute)}
+	require.NoError(t, holds.Create(ctx, sellOut))
+	entry, err := join(second.ID)
+	require.NoError(t, err)
+	assert.Equal(t, domain.WaitlistStatusWaiting, entry.Status)
+	_, err = join(second.ID)
+	assert.ErrorIs(t, err, domain.ErrAlreadyWaitlisted)
+
+	waiting, err := repo.Waiting(ctx, event.ID)
+	require.NoError(t, err)
+	require.Len(t, waiting, 1)
+	assert.Equal(t, entry.ID, waiting[0].ID)
+
+	offer := &domain.Hold{Token: fmt.Sprintf("o-%d", stamp), ExpiresAt: time.Now().Add(time.Minute)}
+	_, err = repo.Offer(ctx, entry.ID, offer)
+	assert.ErrorIs(t, err, domain.ErrNotEnoughTickets)
+	assert.Equal(t, domain.WaitlistStatusWaiting, status(entry.ID))
+
+	// Vé được trả lại thì entry nhận offer, và chỉ một lần
+	_, err = holds.Release(ctx, sellOut.ID, domain.HoldStatusReleased)
+	require.NoError(t, err)
+	offered, err := repo.Offer(ctx, entry.ID, offer)
+	require.NoError(t, err)
+	assert.True(t, offered)
+	assert.Equal(t, second.ID, offer.UserID)
+	assert.Equal(t, float64(10), offer.TotalPrice)
+	offered, err = repo.Offer(ctx, entry.ID, &domain.Hold{Token: fmt.Sprintf("p-%d", stamp), ExpiresAt: time.Now().Add(time.Minute)})
+	require.NoError(t, err)
+	assert.False(t, offered)
+
+	found, err := repo.FindById(ctx, entry.ID)
+	require.NoError(t, err)
+	assert.Equal(t, domain.WaitlistStatusOffered, found.Status)
+	require.NotNil(t, found.Hold)
+	assert.Equal(t, offer.Token, found.Hold.Token)
+	assert.NotNil(t, found.OfferedAt)
+
+	// Đặt vé bằng hold offer thì entry ACCEPTED
+	require.NoError(t, holds.Convert(ctx, offer.Token, second.ID, &domain.Booking{}, &domain.Payment{Status: domain.PaymentStatusPending}))
+	assert.Equal(t, domain.WaitlistStatusAccepted, status(entry.ID))
+	cancelled, err := repo.Cancel(ctx, entry.ID)
+	require.NoError(t, err)
+	assert.False(t, cancelled)
+
+	// Offer hết hạn thì entry EXPIRED; rời waitlist khi đang chờ thì CANCELLED
+	lastTicket := func(token string) *domain.Hold {
+		h := &domain.Hold{Token: fmt.Sprintf("%s-%d", token, stamp), UserID: second.ID, EventID: event.ID, Quantity: 1,
+			ExpiresAt: time.Now().Add(time.Minute)}
+		require.NoError(t, holds.Create(ctx, h))
+		return h
+	}
+	held := lastTicket("r")
+	again, err := join(first.ID)
+	require.NoError(t, err)
+	_, err = holds.Release(ctx, held.ID, domain.HoldStatusReleased)
+	require.NoError(t, err)
+	_, err = holds.Release(ctx, mustOffer(t, repo, again.ID, stamp).ID, domain.HoldStatusExpired)
+	require.NoError(t, err)
+	assert.Equal(t, domain.WaitlistStatusExpired, status(again.ID))
+
+	lastTicket("s")
+	last, err := join(first.ID)
+	require.NoError(t, err)
+	cancelled, err = repo.Cancel(ctx, last.ID)
+	require.NoError(t, err)
+	assert.True(t, cancelled)
+	assert.Equal(t, domain.WaitlistStatusCancelled, status(last.ID))
+}
+
+func mustOffer(t *testing.T, repo booking.WaitlistRepository, id uint, stamp int64) *domain.Hold {
+	hold := &domain.Hold{Token: fmt.Sprintf("m-%d-%d", id, stamp), ExpiresAt: time.Now().Add(time.Minute)}
+	offered, err := repo.Offer(context.Background(), id, hold)
+	require.NoError(t, err)
+	require.True(t, offered)
+	return hold
+}
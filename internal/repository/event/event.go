@@ -1,21 +1,27 @@
 package event
 
 import (
+	"context"
 	"math"
 	"ticket_app/domain"
+	"ticket_app/internal/repository"
 	"ticket_app/internal/rest/middleware"
+	"time"
 
 	"gorm.io/gorm"
 )
 
 type EventRepository interface {
-	Create(event *domain.Event) error
-	FindAll() ([]domain.Event, error)
-	FindById(id uint) (*domain.Event, error)
-	Update(event *domain.Event) error
-	Delete(id uint) error
-	FindByBookingID(bookingID uint) (*domain.Event, error)
-	GetEventsWithRemainingTickets(pagination middleware.Pagination) (middleware.PaginatedResponse, error)
+	Create(ctx context.Context, event *domain.Event) error
+	FindAll(ctx context.Context) ([]domain.Event, error)
+	FindById(ctx context.Context, id uint) (*domain.Event, error)
+	Update(ctx context.Context, event *domain.Event) error
+	Delete(ctx context.Context, id uint) error
+	FindByBookingID(ctx context.Context, bookingID uint) (*domain.Event, error)
+	ReleaseTickets(ctx context.Context, booking *domain.Booking) error
+	GetEventsWithRemainingTickets(ctx context.Context, pagination middleware.Pagination) (middleware.PaginatedResponse, error)
+	GetEventStats(ctx context.Context, id uint) (*domain.EventStats, error)
+	GetAllEventStats(ctx context.Context, pagination middleware.Pagination) (middleware.PaginatedResponse, error)
 }
 
 type GormEventRepository struct {
@@ -26,20 +32,24 @@ func NewGormEventRepository(db *gorm.DB) EventRepository {
 	return &GormEventRepository{db: db}
 }
 
-func (r *GormEventRepository) Create(event *domain.Event) error {
-	return r.db.Create(event).Error
+func (r *GormEventRepository) conn(ctx context.Context) *gorm.DB {
+	return repository.Conn(ctx, r.db)
 }
 
-func (r *GormEventRepository) FindAll() ([]domain.Event, error) {
+func (r *GormEventRepository) Create(ctx context.Context, event *domain.Event) error {
+	return r.conn(ctx).Create(event).Error
+}
+
+func (r *GormEventRepository) FindAll(ctx context.Context) ([]domain.Event, error) {
 	var events []domain.Event
-	if err := r.db.Omit("Bookings", "EventStats").Find(&events).Error; err != nil {
+	if err := r.conn(ctx).Omit("Bookings").Find(&events).Error; err != nil {
 		return nil, err
 	}
 	return events, nil
 }
 
 // GetEventsWithRemainingTickets lấy danh sách event với số vé còn lại
-func (r *GormEventRepository) GetEventsWithRemainingTickets(pagination middleware.Pagination) (middleware.PaginatedResponse, error) {
+func (r *GormEventRepository) GetEventsWithRemainingTickets(ctx context.Context, pagination middleware.Pagination) (middleware.PaginatedResponse, error) {
     var events []domain.EventWithRemainingTickets
     var response middleware.PaginatedResponse
 
@@ -57,7 +67,7 @@ func (r *GormEventRepository) GetEventsWithRemainingTickets(pagination middlewar
     `
 
     // Thực thi query với LIMIT và OFFSET
-    err := r.db.Raw(query, pagination.Limit, pagination.Offset).Scan(&events).Error
+    err := r.conn(ctx).Raw(query, pagination.Limit, pagination.Offset).Scan(&events).Error
     if err != nil {
         return response, err
     }
@@ -71,7 +81,7 @@ func (r *GormEventRepository) GetEventsWithRemainingTickets(pagination middlewar
             ON bookings.event_id = events.id 
             AND bookings.status IN ('PENDING', 'CONFIRMED')
     `
-    err = r.db.Raw(countQuery).Scan(&totalRows).Error
+    err = r.conn(ctx).Raw(countQuery).Scan(&totalRows).Error
     if err != nil {
         return response, err
     }
@@ -90,27 +100,124 @@ func (r *GormEventRepository) GetEventsWithRemainingTickets(pagination middlewar
     return response, nil
 }
 
+// eventStatsQuery tổng hợp số vé và doanh thu theo event. Mỗi booking khớp với
+// đúng một payment, của chính booking hoặc của order chứa booking, nên LEFT
+// JOIN payments không nhân đôi số dòng. Payment của order trả cho nhiều event,
+// nên booking trong order được tính doanh thu bằng total_price của nó.
+const eventStatsQuery = `
+    SELECT events.id AS event_id,
+           events.name AS event_name,
+           COALESCE(SUM(bookings.quantity) FILTER (WHERE bookings.status = 'CONFIRMED'), 0) AS tickets_sold,
+           COALESCE(SUM(bookings.quantity) FILTER (WHERE bookings.status = 'PENDING'), 0) AS tickets_pending,
+           COALESCE(SUM(bookings.quantity) FILTER (WHERE bookings.status = 'CANCELLED'), 0) AS tickets_cancelled,
+           COALESCE(SUM(CASE WHEN payments.order_id IS NULL THEN payments.amount ELSE bookings.total_price END)
+               FILTER (WHERE bookings.status = 'CONFIRMED' AND payments.status = 'COMPLETED'), 0) AS confirmed_revenue,
+           COUNT(bookings.id) AS total_bookings,
+           COUNT(bookings.id) FILTER (WHERE bookings.status = 'CONFIRMED') AS confirmed_bookings
+    FROM events
+    LEFT JOIN bookings ON bookings.event_id = events.id
+    LEFT JOIN payments ON payments.booking_id = bookings.id OR payments.order_id = bookings.order_id
+`
+
+// GetEventStats lấy thống kê bán vé của một event
+func (r *GormEventRepository) GetEventStats(ctx context.Context, id uint) (*domain.EventStats, error) {
+    var stats []domain.EventStats
+    query := eventStatsQuery + `
+        WHERE events.id = $1
+        GROUP BY events.id
+    `
+    if err := r.conn(ctx).Raw(query, id).Scan(&stats).Error; err != nil {
+        return nil, err
+    }
+    if len(stats) == 0 {
+        return nil, gorm.ErrRecordNotFound
+    }
+    withConversionRate(&stats[0])
+    return &stats[0], nil
+}
+
+// GetAllEventStats lấy thống kê bán vé của tất cả event, có phân trang
+func (r *GormEventRepository) GetAllEventStats(ctx context.Context, pagination middleware.Pagination) (middleware.PaginatedResponse, error) {
+    var stats []domain.EventStats
+    var response middleware.PaginatedResponse
+
+    query := eventStatsQuery + `
+        GROUP BY events.id
+        ORDER BY events.id
+        LIMIT $1 OFFSET $2
+    `
+    if err := r.conn(ctx).Raw(query, pagination.Limit, pagination.Offset).Scan(&stats).Error; err != nil {
+        return response, err
+    }
+    for i := range stats {
+        withConversionRate(&stats[i])
+    }
 
-func (r *GormEventRepository) FindById(id uint) (*domain.Event, error) {
+    var totalRows int64
+    if err := r.conn(ctx).Model(&domain.Event{}).Count(&totalRows).Error; err != nil {
+        return response, err
+    }
+
+    response = middleware.PaginatedResponse{
+        Data:        &stats,
+        CurrentPage: pagination.Page,
+        TotalPages:  int(math.Ceil(float64(totalRows) / float64(pagination.Limit))),
+        TotalItems:  totalRows,
+    }
+    return response, nil
+}
+
+func withConversionRate(stats *domain.EventStats) {
+    if stats.TotalBookings > 0 {
+        stats.ConversionRate = float64(stats.ConfirmedBookings) / float64(stats.TotalBookings)
+    }
+}
+
+func (r *GormEventRepository) FindById(ctx context.Context, id uint) (*domain.Event, error) {
 	var event domain.Event
-	if err := r.db.Omit("Bookings", "EventStats").First(&event, id).Error; err != nil {
+	if err := r.conn(ctx).Omit("Bookings").First(&event, id).Error; err != nil {
 		return nil, err
 	}
 	return &event, nil
 }
 
-func (r *GormEventRepository) FindByBookingID(bookingID uint) (*domain.Event, error) {
+func (r *GormEventRepository) FindByBookingID(ctx context.Context, bookingID uint) (*domain.Event, error) {
 	var event domain.Event
-	if err := r.db.Omit("Bookings", "EventStats").First(&event, "bookings.id = ?", bookingID).Error; err != nil {
+	if err := r.conn(ctx).Omit("Bookings").
+		Joins("JOIN bookings ON bookings.event_id = events.id").
+		First(&event, "bookings.id = ?", bookingID).Error; err != nil {
 		return nil, err
 	}
 	return &event, nil
 }
 
-func (r *GormEventRepository) Update(event *domain.Event) error {
-	return r.db.Save(event).Error
+func (r *GormEventRepository) Update(ctx context.Context, event *domain.Event) error {
+	return r.conn(ctx).Omit("Bookings").Save(event).Error
+}
+
+// ReleaseTickets trả lại vé của booking cho event và cho hạng vé của từng
+// item bằng các câu UPDATE nguyên tử, không ghi đè total_tickets hay reserved
+// bằng giá trị đã đọc trước đó, và nhả các ghế của booking. Gọi trong
+// transaction đổi status của booking.
+func (r *GormEventRepository) ReleaseTickets(ctx context.Context, booking *domain.Booking) error {
+	if err := r.conn(ctx).Model(&domain.Event{}).
+		Where("id = ?", booking.EventID).
+		Update("total_tickets", gorm.Expr("total_tickets + ?", booking.Quantity)).Error; err != nil {
+		return err
+	}
+	if err := r.conn(ctx).Exec(`
+        UPDATE ticket_types
+        SET reserved = ticket_types.reserved - booking_items.quantity
+        FROM booking_items
+        WHERE booking_items.ticket_type_id = ticket_types.id AND booking_items.booking_id = ?
+    `, booking.ID).Error; err != nil {
+		return err
+	}
+	return r.conn(ctx).Model(&domain.BookingSeat{}).
+		Where("booking_id = ? AND released_at IS NULL", booking.ID).
+		Update("released_at", time.Now()).Error
 }
 
-func (r *GormEventRepository) Delete(id uint) error {
-	return r.db.Delete(&domain.Event{}, id).Error
+func (r *GormEventRepository) Delete(ctx context.Context, id uint) error {
+	return r.conn(ctx).Delete(&domain.Event{}, id).Error
 }
\ No newline at end of file
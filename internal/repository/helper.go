@@ -2,13 +2,25 @@ package repository
 
 import (
 	"encoding/base64"
+	"errors"
 	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
 )
 
 const (
 	timeFormat = "2006-01-02T15:04:05.999Z07:00" // reduce precision from RFC3339Nano as date format
+
+	// uniqueViolation is the Postgres error code of a duplicate key
+	uniqueViolation = "23505"
 )
 
+// IsUniqueViolation reports whether err is Postgres rejecting a duplicate key
+func IsUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == uniqueViolation
+}
+
 // DecodeCursor will decode cursor from user for mysql
 func DecodeCursor(encodedTime string) (time.Time, error) {
 	byt, err := base64.StdEncoding.DecodeString(encodedTime)
@@ -1,19 +1,22 @@
 package payment
 
 import (
+	"context"
 	"log"
 	"ticket_app/domain"
+	"ticket_app/internal/repository"
 
 	"gorm.io/gorm"
 )
 
 type PaymentRepository interface {
-	Create(payment *domain.Payment) error
-	FindAll() ([]domain.Payment, error)
-	FindById(id uint) (*domain.Payment, error)
-	FindByBookingID(bookingID uint) (*domain.Payment, error)
-	UpdatePayment(payment *domain.Payment) error
-	DeletePayment(id uint) error
+	Create(ctx context.Context, payment *domain.Payment) error
+	FindAll(ctx context.Context) ([]domain.Payment, error)
+	FindById(ctx context.Context, id uint) (*domain.Payment, error)
+	FindByBookingID(ctx context.Context, bookingID uint) (*domain.Payment, error)
+	FindByOrderID(ctx context.Context, orderID uint) (*domain.Payment, error)
+	UpdatePayment(ctx context.Context, payment *domain.Payment) error
+	DeletePayment(ctx context.Context, id uint) error
 
 }
 
@@ -25,40 +28,52 @@ func NewGormPaymentRepository(db *gorm.DB) PaymentRepository {
 	return &GormPaymentRepository{db: db}
 }
 
-func (r *GormPaymentRepository) Create(payment *domain.Payment) error {
-	return r.db.Create(payment).Error
+func (r *GormPaymentRepository) conn(ctx context.Context) *gorm.DB {
+	return repository.Conn(ctx, r.db)
 }
 
-func (r *GormPaymentRepository) FindAll() ([]domain.Payment, error) {
+func (r *GormPaymentRepository) Create(ctx context.Context, payment *domain.Payment) error {
+	return r.conn(ctx).Create(payment).Error
+}
+
+func (r *GormPaymentRepository) FindAll(ctx context.Context) ([]domain.Payment, error) {
 	var payments []domain.Payment
-	if err := r.db.Find(&payments).Error; err != nil {
+	if err := r.conn(ctx).Find(&payments).Error; err != nil {
 		return nil, err
 	}
 	return payments, nil
 }
 
-func (r *GormPaymentRepository) FindById(id uint) (*domain.Payment, error) {
+func (r *GormPaymentRepository) FindById(ctx context.Context, id uint) (*domain.Payment, error) {
+	var payment domain.Payment
+	if err := r.conn(ctx).First(&payment, id).Error; err != nil {
+		return nil, err
+	}
+	return &payment, nil
+}
+
+func (r *GormPaymentRepository) FindByBookingID(ctx context.Context, bookingID uint) (*domain.Payment, error) {
 	var payment domain.Payment
-	if err := r.db.First(&payment, id).Error; err != nil {
+	if err := r.conn(ctx).First(&payment, "booking_id = ?", bookingID).Error; err != nil {
 		return nil, err
 	}
 	return &payment, nil
 }
 
-func (r *GormPaymentRepository) FindByBookingID(bookingID uint) (*domain.Payment, error) {
+func (r *GormPaymentRepository) FindByOrderID(ctx context.Context, orderID uint) (*domain.Payment, error) {
 	var payment domain.Payment
-	if err := r.db.First(&payment, "booking_id = ?", bookingID).Error; err != nil {
+	if err := r.conn(ctx).First(&payment, "order_id = ?", orderID).Error; err != nil {
 		return nil, err
 	}
 	return &payment, nil
 }
 
-func (r *GormPaymentRepository) UpdatePayment(payment *domain.Payment) error {
+func (r *GormPaymentRepository) UpdatePayment(ctx context.Context, payment *domain.Payment) error {
 	log.Printf("Updating payment: %v", payment)
-	return r.db.Save(payment).Error
+	return r.conn(ctx).Omit("Booking").Save(payment).Error
 }
 
-func (r *GormPaymentRepository) DeletePayment(id uint) error {
-	return r.db.Delete(&domain.Payment{}, id).Error
+func (r *GormPaymentRepository) DeletePayment(ctx context.Context, id uint) error {
+	return r.conn(ctx).Delete(&domain.Payment{}, id).Error
 }
 
@@ -0,0 +1,56 @@
+package recoverycode
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+
+	"ticket_app/domain"
+	"ticket_app/internal/repository"
+)
+
+// RecoveryCodeRepository stores the 2FA recovery codes of users by their hash
+type RecoveryCodeRepository interface {
+	// Replace deletes every code of the user and stores hashes as the new ones
+	Replace(ctx context.Context, userID uint, hashes []string) error
+	// Use marks the unused code with hash as used; false when there is none
+	Use(ctx context.Context, userID uint, hash string, at time.Time) (bool, error)
+	// DeleteUser deletes every code of the user
+	DeleteUser(ctx context.Context, userID uint) error
+}
+
+// GormRecoveryCodeRepository implements RecoveryCodeRepository using GORM
+type GormRecoveryCodeRepository struct {
+	db *gorm.DB
+}
+
+func NewGormRecoveryCodeRepository(db *gorm.DB) RecoveryCodeRepository {
+	return &GormRecoveryCodeRepository{db: db}
+}
+
+func (r *GormRecoveryCodeRepository) conn(ctx context.Context) *gorm.DB {
+	return repository.Conn(ctx, r.db)
+}
+
+func (r *GormRecoveryCodeRepository) Replace(ctx context.Context, userID uint, hashes []string) error {
+	if err := r.DeleteUser(ctx, userID); err != nil {
+		return err
+	}
+	codes := make([]domain.RecoveryCode, len(hashes))
+	for i, hash := range hashes {
+		codes[i] = domain.RecoveryCode{UserID: userID, CodeHash: hash}
+	}
+	return r.conn(ctx).Create(&codes).Error
+}
+
+func (r *GormRecoveryCodeRepository) Use(ctx context.Context, userID uint, hash string, at time.Time) (bool, error) {
+	result := r.conn(ctx).Model(&domain.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, hash).
+		Update("used_at", at)
+	return result.RowsAffected == 1, result.Error
+}
+
+func (r *GormRecoveryCodeRepository) DeleteUser(ctx context.Context, userID uint) error {
+	return r.conn(ctx).Where("user_id = ?", userID).Delete(&domain.RecoveryCode{}).Error
+}
@@ -0,0 +1,74 @@
+package refreshtoken
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"ticket_app/domain"
+	"ticket_app/internal/repository"
+)
+
+// RefreshTokenRepository stores refresh tokens by their hash
+type RefreshTokenRepository interface {
+	Create(ctx context.Context, token *domain.RefreshToken) error
+	// FindByHashForUpdate returns domain.ErrNotFound when no token has this hash
+	FindByHashForUpdate(ctx context.Context, hash string) (*domain.RefreshToken, error)
+	// Rotate revokes the token with id and records its replacement
+	Rotate(ctx context.Context, id uint, replacedByID uint, at time.Time) error
+	// RevokeFamily revokes every live token of one session
+	RevokeFamily(ctx context.Context, familyID string, at time.Time) error
+	// RevokeUser revokes every live token of every session of a user
+	RevokeUser(ctx context.Context, userID uint, at time.Time) error
+}
+
+// GormRefreshTokenRepository implements RefreshTokenRepository using GORM
+type GormRefreshTokenRepository struct {
+	db *gorm.DB
+}
+
+func NewGormRefreshTokenRepository(db *gorm.DB) RefreshTokenRepository {
+	return &GormRefreshTokenRepository{db: db}
+}
+
+func (r *GormRefreshTokenRepository) conn(ctx context.Context) *gorm.DB {
+	return repository.Conn(ctx, r.db)
+}
+
+func (r *GormRefreshTokenRepository) Create(ctx context.Context, token *domain.RefreshToken) error {
+	return r.conn(ctx).Create(token).Error
+}
+
+// FindByHashForUpdate locks the row until the surrounding transaction ends, so
+// two concurrent refreshes with the same token cannot both rotate it
+func (r *GormRefreshTokenRepository) FindByHashForUpdate(ctx context.Context, hash string) (*domain.RefreshToken, error) {
+	var token domain.RefreshToken
+	err := r.conn(ctx).Clauses(clause.Locking{Strength: "UPDATE"}).Where("token_hash = ?", hash).First(&token).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, domain.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+func (r *GormRefreshTokenRepository) Rotate(ctx context.Context, id uint, replacedByID uint, at time.Time) error {
+	return r.conn(ctx).Model(&domain.RefreshToken{}).Where("id = ?", id).
+		Updates(map[string]interface{}{"revoked_at": at, "replaced_by_id": replacedByID}).Error
+}
+
+func (r *GormRefreshTokenRepository) RevokeFamily(ctx context.Context, familyID string, at time.Time) error {
+	return r.conn(ctx).Model(&domain.RefreshToken{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Update("revoked_at", at).Error
+}
+
+func (r *GormRefreshTokenRepository) RevokeUser(ctx context.Context, userID uint, at time.Time) error {
+	return r.conn(ctx).Model(&domain.RefreshToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", at).Error
+}
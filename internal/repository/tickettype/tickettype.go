@@ -0,0 +1,144 @@
+package tickettype
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"ticket_app/domain"
+	"ticket_app/internal/repository"
+)
+
+// TicketTypeRepository stores the ticket types of events. Reserving and
+// releasing tickets of a type is done by the booking and event repositories,
+// inside the transaction that changes the booking.
+type TicketTypeRepository interface {
+	// Create returns domain.ErrConflict when the event already has a type with
+	// that name or for that section. A section must belong to the venue of the event.
+	Create(ctx context.Context, ticketType *domain.TicketType) error
+	FindByEvent(ctx context.Context, eventID uint) ([]domain.TicketType, error)
+	// FindById returns domain.ErrNotFound when the type does not exist or belongs to another event
+	FindById(ctx context.Context, eventID uint, id uint) (*domain.TicketType, error)
+	// Update saves everything but Reserved. It returns domain.ErrQuotaBelowReserved
+	// when the new quota is lower than the tickets already reserved.
+	Update(ctx context.Context, ticketType *domain.TicketType) error
+	// Delete returns domain.ErrTicketTypeInUse when a booking or an active hold has the type
+	Delete(ctx context.Context, eventID uint, id uint) error
+}
+
+// GormTicketTypeRepository implements TicketTypeRepository using GORM
+type GormTicketTypeRepository struct {
+	db *gorm.DB
+}
+
+func NewGormTicketTypeRepository(db *gorm.DB) TicketTypeRepository {
+	return &GormTicketTypeRepository{db: db}
+}
+
+func (r *GormTicketTypeRepository) conn(ctx context.Context) *gorm.DB {
+	return repository.Conn(ctx, r.db)
+}
+
+func (r *GormTicketTypeRepository) Create(ctx context.Context, ticketType *domain.TicketType) error {
+	ticketType.Reserved = 0
+	if err := checkSection(r.conn(ctx), ticketType); err != nil {
+		return err
+	}
+	return translate(r.conn(ctx).Create(ticketType).Error)
+}
+
+func (r *GormTicketTypeRepository) FindByEvent(ctx context.Context, eventID uint) ([]domain.TicketType, error) {
+	var ticketTypes []domain.TicketType
+	err := r.conn(ctx).Where("event_id = ?", eventID).Order("price, id").Find(&ticketTypes).Error
+	return ticketTypes, err
+}
+
+func (r *GormTicketTypeRepository) FindById(ctx context.Context, eventID uint, id uint) (*domain.TicketType, error) {
+	var ticketType domain.TicketType
+	err := r.conn(ctx).Where("event_id = ?", eventID).First(&ticketType, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, domain.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &ticketType, nil
+}
+
+// Update locks the row, so a booking committing at the same time cannot push
+// Reserved above the new quota
+func (r *GormTicketTypeRepository) Update(ctx context.Context, ticketType *domain.TicketType) error {
+	return r.conn(ctx).Transaction(func(tx *gorm.DB) error {
+		var current domain.TicketType
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("event_id = ?", ticketType.EventID).
+			First(&current, ticketType.ID).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return domain.ErrNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if ticketType.Quota < current.Reserved {
+			return domain.ErrQuotaBelowReserved
+		}
+		if err := checkSection(tx, ticketType); err != nil {
+			return err
+		}
+		ticketType.Reserved = current.Reserved
+		ticketType.CreatedAt = current.CreatedAt
+		return translate(tx.Model(ticketType).
+			Select("Name", "Price", "Quota", "MinPerOrder", "MaxPerOrder", "SalesStart", "SalesEnd", "SectionID", "UpdatedAt").
+			Updates(ticketType).Error)
+	})
+}
+
+func (r *GormTicketTypeRepository) Delete(ctx context.Context, eventID uint, id uint) error {
+	return r.conn(ctx).Transaction(func(tx *gorm.DB) error {
+		var booked int64
+		if err := tx.Model(&domain.BookingItem{}).Where("ticket_type_id = ?", id).Count(&booked).Error; err != nil {
+			return err
+		}
+		if booked > 0 {
+			return domain.ErrTicketTypeInUse
+		}
+		result := tx.Where("event_id = ?", eventID).Delete(&domain.TicketType{}, id)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return domain.ErrNotFound
+		}
+		return nil
+	})
+}
+
+// checkSection rejects a section that is not in the venue of the event
+func checkSection(db *gorm.DB, ticketType *domain.TicketType) error {
+	if ticketType.SectionID == nil {
+		return nil
+	}
+	var found int64
+	err := db.Model(&domain.Section{}).
+		Joins("JOIN events ON events.venue_id = sections.venue_id").
+		Where("sections.id = ? AND events.id = ?", *ticketType.SectionID, ticketType.EventID).
+		Count(&found).Error
+	if err != nil {
+		return err
+	}
+	if found == 0 {
+		return fmt.Errorf("%w: section is not in the venue of the event", domain.ErrBadParamInput)
+	}
+	return nil
+}
+
+// translate turns a duplicate (event_id, name) or (event_id, section_id) into domain.ErrConflict
+func translate(err error) error {
+	if repository.IsUniqueViolation(err) {
+		return domain.ErrConflict
+	}
+	return err
+}
@@ -0,0 +1,47 @@
+package repository
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+)
+
+type txKey struct{}
+
+// TxManager runs a unit of work inside a single database transaction.
+//
+// The transaction is carried in the context passed to fn, and every Gorm
+// repository resolves its connection with Conn, so any repository call made
+// with that context joins the transaction without knowing about it.
+type TxManager interface {
+	WithinTx(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+// GormTxManager implements TxManager on top of *gorm.DB
+type GormTxManager struct {
+	db *gorm.DB
+}
+
+func NewGormTxManager(db *gorm.DB) TxManager {
+	return &GormTxManager{db: db}
+}
+
+// WithinTx commits when fn returns nil and rolls back otherwise. If ctx already
+// carries a transaction, fn simply joins it and the outermost caller decides
+// whether to commit.
+func (m *GormTxManager) WithinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if _, ok := ctx.Value(txKey{}).(*gorm.DB); ok {
+		return fn(ctx)
+	}
+	return m.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(context.WithValue(ctx, txKey{}, tx))
+	})
+}
+
+// Conn returns the transaction stored in ctx, or db bound to ctx when there is none.
+func Conn(ctx context.Context, db *gorm.DB) *gorm.DB {
+	if tx, ok := ctx.Value(txKey{}).(*gorm.DB); ok {
+		return tx
+	}
+	return db.WithContext(ctx)
+}
@@ -0,0 +1,85 @@
+package repository_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	sqlmock "gopkg.in/DATA-DOG/go-sqlmock.v1"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"ticket_app/internal/repository"
+)
+
+func newMockDB(t *testing.T) (*gorm.DB, sqlmock.Sqlmock) {
+	sqlDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { sqlDB.Close() })
+
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: sqlDB}), &gorm.Config{
+		Logger:                 logger.Default.LogMode(logger.Silent),
+		SkipDefaultTransaction: true,
+	})
+	require.NoError(t, err)
+	return db, mock
+}
+
+func TestWithinTxCommits(t *testing.T) {
+	db, mock := newMockDB(t)
+	tm := repository.NewGormTxManager(db)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE events").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE bookings").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	err := tm.WithinTx(context.Background(), func(ctx context.Context) error {
+		if err := repository.Conn(ctx, db).Exec("UPDATE events SET total_tickets = 1").Error; err != nil {
+			return err
+		}
+		return repository.Conn(ctx, db).Exec("UPDATE bookings SET status = 'CANCELLED'").Error
+	})
+	require.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestWithinTxRollsBackOnError(t *testing.T) {
+	db, mock := newMockDB(t)
+	tm := repository.NewGormTxManager(db)
+	boom := errors.New("boom")
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE events").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectRollback()
+
+	err := tm.WithinTx(context.Background(), func(ctx context.Context) error {
+		if err := repository.Conn(ctx, db).Exec("UPDATE events SET total_tickets = 1").Error; err != nil {
+			return err
+		}
+		return boom
+	})
+	assert.ErrorIs(t, err, boom)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestWithinTxJoinsOuterTransaction(t *testing.T) {
+	db, mock := newMockDB(t)
+	tm := repository.NewGormTxManager(db)
+
+	// Only one BEGIN/COMMIT pair: the inner call reuses the outer transaction.
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE payments").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	err := tm.WithinTx(context.Background(), func(ctx context.Context) error {
+		return tm.WithinTx(ctx, func(ctx context.Context) error {
+			return repository.Conn(ctx, db).Exec("UPDATE payments SET status = 'FAILED'").Error
+		})
+	})
+	require.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -1,16 +1,33 @@
 package user
 
 import (
+	"context"
 	"ticket_app/domain"
+	"ticket_app/internal/repository"
+	"time"
 
 	"gorm.io/gorm"
 )
 
 // UserRepository defines the interface for user operations
 type UserRepository interface {
-	Create(user *domain.User) error
-	FindByEmail(email string) (*domain.User, error)
-	FindById(id uint) (*domain.User, error)
+	Create(ctx context.Context, user *domain.User) error
+	FindByEmail(ctx context.Context, email string) (*domain.User, error)
+	FindById(ctx context.Context, id uint) (*domain.User, error)
+	// UpdateRole đổi role của user, domain.ErrNotFound nếu không có user id
+	UpdateRole(ctx context.Context, id uint, role domain.Role) error
+	// MarkEmailVerified ghi nhận user đã xác nhận email lúc at
+	MarkEmailVerified(ctx context.Context, id uint, at time.Time) error
+	// UpdatePassword thay password đã hash của user
+	UpdatePassword(ctx context.Context, id uint, hashedPassword string) error
+	// SetTOTPSecret lưu secret của lần đăng ký 2FA đang chờ xác nhận
+	SetTOTPSecret(ctx context.Context, id uint, secret string) error
+	// EnableTOTP bật 2FA; step là time step của mã vừa xác nhận
+	EnableTOTP(ctx context.Context, id uint, at time.Time, step int64) error
+	// DisableTOTP tắt 2FA và xoá secret
+	DisableTOTP(ctx context.Context, id uint) error
+	// UseTOTPStep ghi nhận mã của step đã được dùng; false nếu đã có mã cùng hoặc sau step được dùng
+	UseTOTPStep(ctx context.Context, id uint, step int64) (bool, error)
 }
 
 // GormUserRepository implements UserRepository using GORM
@@ -22,13 +39,17 @@ func NewGormUserRepository(db *gorm.DB) UserRepository {
 	return &GormUserRepository{db: db}
 }
 
-func (r *GormUserRepository) Create(user *domain.User) error {
-	return r.db.Create(user).Error
+func (r *GormUserRepository) conn(ctx context.Context) *gorm.DB {
+	return repository.Conn(ctx, r.db)
 }
 
-func (r *GormUserRepository) FindByEmail(email string) (*domain.User, error) {
+func (r *GormUserRepository) Create(ctx context.Context, user *domain.User) error {
+	return r.conn(ctx).Create(user).Error
+}
+
+func (r *GormUserRepository) FindByEmail(ctx context.Context, email string) (*domain.User, error) {
 	var user domain.User
-	if err := r.db.Where("email = ?", email).First(&user).Error; err != nil {
+	if err := r.conn(ctx).Where("email = ?", email).First(&user).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return nil, nil
 		}
@@ -37,10 +58,58 @@ func (r *GormUserRepository) FindByEmail(email string) (*domain.User, error) {
 	return &user, nil
 }
 
-func (r *GormUserRepository) FindById(id uint) (*domain.User, error) {
+func (r *GormUserRepository) FindById(ctx context.Context, id uint) (*domain.User, error) {
 	var user domain.User
-	if err := r.db.First(&user, id).Error; err != nil {
+	if err := r.conn(ctx).First(&user, id).Error; err != nil {
 		return nil, err
 	}
 	return &user, nil
-}
\ No newline at end of file
+}
+func (r *GormUserRepository) UpdateRole(ctx context.Context, id uint, role domain.Role) error {
+	result := r.conn(ctx).Model(&domain.User{}).Where("id = ?", id).Update("role", role)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return domain.ErrNotFound
+	}
+	return nil
+}
+
+func (r *GormUserRepository) MarkEmailVerified(ctx context.Context, id uint, at time.Time) error {
+	return r.conn(ctx).Model(&domain.User{}).Where("id = ? AND email_verified_at IS NULL", id).
+		Update("email_verified_at", at).Error
+}
+
+func (r *GormUserRepository) UpdatePassword(ctx context.Context, id uint, hashedPassword string) error {
+	result := r.conn(ctx).Model(&domain.User{}).Where("id = ?", id).Update("password", hashedPassword)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return domain.ErrNotFound
+	}
+	return nil
+}
+
+func (r *GormUserRepository) SetTOTPSecret(ctx context.Context, id uint, secret string) error {
+	return r.conn(ctx).Model(&domain.User{}).Where("id = ? AND totp_enabled_at IS NULL", id).
+		Update("totp_secret", secret).Error
+}
+
+func (r *GormUserRepository) EnableTOTP(ctx context.Context, id uint, at time.Time, step int64) error {
+	return r.conn(ctx).Model(&domain.User{}).Where("id = ?", id).
+		Updates(map[string]interface{}{"totp_enabled_at": at, "totp_last_step": step}).Error
+}
+
+func (r *GormUserRepository) DisableTOTP(ctx context.Context, id uint) error {
+	return r.conn(ctx).Model(&domain.User{}).Where("id = ?", id).
+		Updates(map[string]interface{}{"totp_secret": nil, "totp_enabled_at": nil, "totp_last_step": 0}).Error
+}
+
+// UseTOTPStep chỉ tăng totp_last_step, nên hai request cùng một mã không thể cùng thành công
+func (r *GormUserRepository) UseTOTPStep(ctx context.Context, id uint, step int64) (bool, error) {
+	result := r.conn(ctx).Model(&domain.User{}).Where("id = ? AND totp_last_step < ?", id, step).
+		Update("totp_last_step", step)
+	return result.RowsAffected == 1, result.Error
+}
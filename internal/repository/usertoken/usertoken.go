@@ -0,0 +1,72 @@
+package usertoken
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"ticket_app/domain"
+	"ticket_app/internal/repository"
+)
+
+// UserTokenRepository stores email verification and password reset tokens by their hash
+type UserTokenRepository interface {
+	Create(ctx context.Context, token *domain.UserToken) error
+	// FindByHashForUpdate returns domain.ErrNotFound when no token of purpose has this hash
+	FindByHashForUpdate(ctx context.Context, hash string, purpose domain.TokenPurpose) (*domain.UserToken, error)
+	// MarkUsed marks the token with id as used
+	MarkUsed(ctx context.Context, id uint, at time.Time) error
+	// InvalidateUser marks every unused token of purpose of a user as used
+	InvalidateUser(ctx context.Context, userID uint, purpose domain.TokenPurpose, at time.Time) error
+}
+
+// GormUserTokenRepository implements UserTokenRepository using GORM
+type GormUserTokenRepository struct {
+	db *gorm.DB
+}
+
+func NewGormUserTokenRepository(db *gorm.DB) UserTokenRepository {
+	return &GormUserTokenRepository{db: db}
+}
+
+func (r *GormUserTokenRepository) conn(ctx context.Context) *gorm.DB {
+	return repository.Conn(ctx, r.db)
+}
+
+func (r *GormUserTokenRepository) Create(ctx context.Context, token *domain.UserToken) error {
+	return r.conn(ctx).Create(token).Error
+}
+
+// FindByHashForUpdate locks the row of a single-use password reset or email
+// verification token. The caller reads the token, checks used_at and then
+// calls MarkUsed. Without the lock, two requests with the same link could both
+// see used_at unset, for example setting two different passwords from one
+// reset email; with it the second request waits for the first to commit and
+// finds the token used. The caller must do the lookup, the check and MarkUsed
+// inside one txManager.WithinTx: outside a transaction the lock ends with the
+// SELECT and prevents nothing.
+func (r *GormUserTokenRepository) FindByHashForUpdate(ctx context.Context, hash string, purpose domain.TokenPurpose) (*domain.UserToken, error) {
+	var token domain.UserToken
+	err := r.conn(ctx).Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("token_hash = ? AND purpose = ?", hash, purpose).First(&token).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, domain.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+func (r *GormUserTokenRepository) MarkUsed(ctx context.Context, id uint, at time.Time) error {
+	return r.conn(ctx).Model(&domain.UserToken{}).Where("id = ?", id).Update("used_at", at).Error
+}
+
+func (r *GormUserTokenRepository) InvalidateUser(ctx context.Context, userID uint, purpose domain.TokenPurpose, at time.Time) error {
+	return r.conn(ctx).Model(&domain.UserToken{}).
+		Where("user_id = ? AND purpose = ? AND used_at IS NULL", userID, purpose).
+		Update("used_at", at).Error
+}
@@ -0,0 +1,133 @@
+package venue
+
+import (
+	"context"
+	"errors"
+
+	"gorm.io/gorm"
+
+	"ticket_app/domain"
+	"ticket_app/internal/repository"
+)
+
+// VenueRepository stores venues with their seat map
+type VenueRepository interface {
+	// Create inserts the venue with its sections, rows and seats. It returns
+	// domain.ErrConflict for a duplicate section name or row label.
+	Create(ctx context.Context, venue *domain.Venue) error
+	// FindAll returns the venues without their seat map
+	FindAll(ctx context.Context) ([]domain.Venue, error)
+	// FindById returns the venue with its seat map, or domain.ErrNotFound
+	FindById(ctx context.Context, id uint) (*domain.Venue, error)
+	// SeatMap returns the seat map of the venue of event with the status of every seat for it
+	SeatMap(ctx context.Context, event *domain.Event) (*domain.SeatMap, error)
+}
+
+// GormVenueRepository implements VenueRepository using GORM
+type GormVenueRepository struct {
+	db *gorm.DB
+}
+
+func NewGormVenueRepository(db *gorm.DB) VenueRepository {
+	return &GormVenueRepository{db: db}
+}
+
+func (r *GormVenueRepository) conn(ctx context.Context) *gorm.DB {
+	return repository.Conn(ctx, r.db)
+}
+
+func (r *GormVenueRepository) Create(ctx context.Context, venue *domain.Venue) error {
+	err := r.conn(ctx).Create(venue).Error
+	if repository.IsUniqueViolation(err) {
+		return domain.ErrConflict
+	}
+	return err
+}
+
+func (r *GormVenueRepository) FindAll(ctx context.Context) ([]domain.Venue, error) {
+	var venues []domain.Venue
+	err := r.conn(ctx).Order("id").Find(&venues).Error
+	return venues, err
+}
+
+func (r *GormVenueRepository) FindById(ctx context.Context, id uint) (*domain.Venue, error) {
+	var venue domain.Venue
+	err := r.conn(ctx).
+		Preload("Sections", func(db *gorm.DB) *gorm.DB { return db.Order("id") }).
+		Preload("Sections.Rows", func(db *gorm.DB) *gorm.DB { return db.Order("position, id") }).
+		Preload("Sections.Rows.Seats", func(db *gorm.DB) *gorm.DB { return db.Order("number") }).
+		First(&venue, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, domain.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &venue, nil
+}
+
+// SeatMap đánh dấu ghế của hold hoặc booking PENDING là HELD, của booking
+// CONFIRMED là SOLD. Giá của section là giá hạng vé gắn với section khi event chia hạng vé,
+// còn không thì là ticket_price của event.
+func (r *GormVenueRepository) SeatMap(ctx context.Context, event *domain.Event) (*domain.SeatMap, error) {
+	if event.VenueID == nil {
+		return nil, domain.ErrNoSeatMap
+	}
+	venue, err := r.FindById(ctx, *event.VenueID)
+	if err != nil {
+		return nil, err
+	}
+
+	var taken []struct {
+		SeatID uint
+		Status domain.BookingStatus
+	}
+	if err := r.conn(ctx).Table("booking_seats").
+		Select("booking_seats.seat_id, COALESCE(bookings.status, '') AS status").
+		Joins("LEFT JOIN bookings ON bookings.id = booking_seats.booking_id").
+		Where("booking_seats.event_id = ? AND booking_seats.released_at IS NULL", event.ID).
+		Scan(&taken).Error; err != nil {
+		return nil, err
+	}
+	status := make(map[uint]domain.SeatStatus, len(taken))
+	for _, t := range taken {
+		status[t.SeatID] = domain.SeatHeld
+		if t.Status == domain.BookingStatusConfirmed {
+			status[t.SeatID] = domain.SeatSold
+		}
+	}
+
+	var ticketTypes []domain.TicketType
+	if err := r.conn(ctx).Where("event_id = ?", event.ID).Find(&ticketTypes).Error; err != nil {
+		return nil, err
+	}
+	bySection := make(map[uint]domain.TicketType, len(ticketTypes))
+	for _, t := range ticketTypes {
+		if t.SectionID != nil {
+			bySection[*t.SectionID] = t
+		}
+	}
+
+	seatMap := &domain.SeatMap{EventID: event.ID, VenueID: venue.ID, Venue: venue.Name, Sections: []domain.SeatMapSection{}}
+	for _, section := range venue.Sections {
+		s := domain.SeatMapSection{ID: section.ID, Name: section.Name, Rows: []domain.SeatMapRow{}}
+		if t, ok := bySection[section.ID]; ok {
+			s.TicketTypeID, s.Price = &t.ID, &t.Price
+		} else if len(ticketTypes) == 0 {
+			s.Price = &event.TicketPrice
+		}
+		for _, row := range section.Rows {
+			mr := domain.SeatMapRow{ID: row.ID, Label: row.Label, Seats: make([]domain.SeatMapSeat, 0, len(row.Seats))}
+			for _, seat := range row.Seats {
+				st, ok := status[seat.ID]
+				if !ok {
+					st = domain.SeatAvailable
+				}
+				mr.Seats = append(mr.Seats, domain.SeatMapSeat{ID: seat.ID, Number: seat.Number, Status: st})
+			}
+			s.Rows = append(s.Rows, mr)
+		}
+		seatMap.Sections = append(seatMap.Sections, s)
+	}
+	return seatMap, nil
+}
@@ -2,14 +2,19 @@ package rest
 
 import (
 	"encoding/json"
+	"errors"
 	"log"
+	"math"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/gofiber/fiber/v2"
 
 	auth "ticket_app/auth"
+	"ticket_app/domain"
+	"ticket_app/internal/rest/middleware"
 )
 
 type AuthHandler struct {
@@ -17,7 +22,8 @@ type AuthHandler struct {
 	validate    *validator.Validate
 }
 
-func NewAuthHandlerFiber(app *fiber.App, authService auth.AuthService) {
+// NewAuthHandlerFiber đăng ký các route xác thực; requireAuth bảo vệ các route cần access token
+func NewAuthHandlerFiber(app *fiber.App, authService auth.AuthService, requireAuth fiber.Handler) {
 	validate := validator.New()
 	handler := &AuthHandler{
 		authService: authService,
@@ -39,7 +45,20 @@ func NewAuthHandlerFiber(app *fiber.App, authService auth.AuthService) {
 		return c.Next()
 	}, handler.Register)
 	app.Post("/login", handler.Login)
-	app.Get("/auth/profile", handler.Profile)
+	app.Post("/auth/refresh", handler.Refresh)
+	app.Get("/auth/profile", requireAuth, handler.Profile)
+	app.Post("/auth/logout", requireAuth, handler.Logout)
+	app.Post("/auth/logout-all", requireAuth, handler.LogoutAll)
+	app.Post("/auth/verify-email", handler.VerifyEmail)
+	app.Post("/auth/resend-verification", handler.ResendVerification)
+	app.Post("/auth/forgot-password", handler.ForgotPassword)
+	app.Post("/auth/reset-password", handler.ResetPassword)
+	app.Post("/auth/unlock-account", handler.UnlockAccount)
+	app.Post("/auth/mfa/verify", handler.VerifyMFA)
+	app.Post("/auth/mfa/enroll", requireAuth, handler.EnrollMFA)
+	app.Post("/auth/mfa/confirm", requireAuth, handler.ConfirmMFA)
+	app.Post("/auth/mfa/disable", requireAuth, handler.DisableMFA)
+	app.Post("/auth/mfa/recovery-codes", requireAuth, handler.RegenerateRecoveryCodes)
 }
 
 type RegisterRequest struct {
@@ -52,11 +71,46 @@ type LoginRequest struct {
 	Password string `json:"password" validate:"required"`
 }
 
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" validate:"required"`
+}
+
+// VerifyEmailRequest là body của verify-email và unlock-account
+type VerifyEmailRequest struct {
+	Token string `json:"token" validate:"required"`
+}
+
+// EmailRequest là body của resend-verification và forgot-password
+type EmailRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+type ResetPasswordRequest struct {
+	Token    string `json:"token" validate:"required"`
+	Password string `json:"password" validate:"required,min=6"`
+}
+
+type VerifyMFARequest struct {
+	MFAToken string `json:"mfa_token" validate:"required"`
+	Code     string `json:"code" validate:"required"`
+}
+
+// MFACodeRequest là body của các route 2FA cần mã TOTP hoặc recovery code
+type MFACodeRequest struct {
+	Code string `json:"code" validate:"required"`
+}
+
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
 type UserResponse struct {
-	ID        uint      `json:"id"`
-	Email     string    `json:"email"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID              uint       `json:"id"`
+	Email           string     `json:"email"`
+	Role            string     `json:"role,omitempty"`
+	EmailVerifiedAt *time.Time `json:"email_verified_at,omitempty"` // nil: email chưa được xác nhận
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
 }
 
 func (h *AuthHandler) Register(c *fiber.Ctx) error {
@@ -77,7 +131,7 @@ func (h *AuthHandler) Register(c *fiber.Ctx) error {
 		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
 	}
 
-	user, err := h.authService.Register(c.Context(), req.Email, req.Password)
+	user, err := h.authService.Register(c.UserContext(), req.Email, req.Password)
 	log.Println("AuthService.Register returned user:", user, "error:", err)
 	if err != nil {
 		log.Println("Register error:", err)
@@ -90,10 +144,12 @@ func (h *AuthHandler) Register(c *fiber.Ctx) error {
 	}
 
 	response := UserResponse{
-		ID:        user.ID,
-		Email:     user.Email,
-		CreatedAt: user.CreatedAt,
-		UpdatedAt: user.UpdatedAt,
+		ID:              user.ID,
+		Email:           user.Email,
+		Role:            string(user.Role),
+		EmailVerifiedAt: user.EmailVerifiedAt,
+		CreatedAt:       user.CreatedAt,
+		UpdatedAt:       user.UpdatedAt,
 	}
 	log.Println("Register success, response:", response)
 	return c.Status(http.StatusCreated).JSON(response)
@@ -109,22 +165,274 @@ func (h *AuthHandler) Login(c *fiber.Ctx) error {
 		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
 	}
 
-	accessToken, err := h.authService.Login(c.Context(), req.Email, req.Password)
+	result, err := h.authService.Login(c.UserContext(), req.Email, req.Password, clientOf(c))
+	if throttled, ok := tooManyAttempts(c, err); ok {
+		return throttled
+	}
+	if errors.Is(err, domain.ErrInvalidCredentials) {
+		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": err.Error()})
+	}
 	if err != nil {
+		log.Println("Login error:", err)
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Login failed"})
+	}
+
+	return c.JSON(result)
+}
+
+// clientOf là thông tin người gọi để ghi audit log
+func clientOf(c *fiber.Ctx) auth.Client {
+	return auth.Client{IP: c.IP(), UserAgent: c.Get(fiber.HeaderUserAgent)}
+}
+
+// tooManyAttempts trả 429 kèm Retry-After khi err là *auth.ThrottledError
+func tooManyAttempts(c *fiber.Ctx, err error) (error, bool) {
+	var throttled *auth.ThrottledError
+	if !errors.As(err, &throttled) {
+		return nil, false
+	}
+	// Làm tròn lên để client không thử lại quá sớm
+	c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(math.Ceil(throttled.RetryAfter.Seconds()))))
+	return c.Status(http.StatusTooManyRequests).JSON(fiber.Map{"error": err.Error()}), true
+}
+
+// VerifyMFA là bước thứ hai của đăng nhập khi user đã bật 2FA
+func (h *AuthHandler) VerifyMFA(c *fiber.Ctx) error {
+	var req VerifyMFARequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request"})
+	}
+	if err := h.validate.Struct(req); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	tokens, err := h.authService.VerifyMFA(c.UserContext(), req.MFAToken, req.Code, clientOf(c))
+	if throttled, ok := tooManyAttempts(c, err); ok {
+		return throttled
+	}
+	if errors.Is(err, domain.ErrInvalidMFACode) || errors.Is(err, domain.ErrInvalidUserToken) {
 		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": err.Error()})
 	}
+	if err != nil {
+		log.Println("MFA verify error:", err)
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Login failed"})
+	}
+	return c.JSON(tokens)
+}
 
-	return c.JSON(fiber.Map{"access_token": accessToken})
+func (h *AuthHandler) EnrollMFA(c *fiber.Ctx) error {
+	user, ok := middleware.CurrentUser(c)
+	if !ok || user.UserID == 0 {
+		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid token claims"})
+	}
+	enrollment, err := h.authService.EnrollMFA(c.UserContext(), user.UserID)
+	if errors.Is(err, domain.ErrMFAAlreadyEnabled) {
+		return c.Status(http.StatusConflict).JSON(fiber.Map{"error": err.Error()})
+	}
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(enrollment)
+}
+
+func (h *AuthHandler) ConfirmMFA(c *fiber.Ctx) error {
+	return h.withMFACode(c, func(userID uint, code string) error {
+		codes, err := h.authService.ConfirmMFA(c.UserContext(), userID, code)
+		if err != nil {
+			return err
+		}
+		return c.JSON(RecoveryCodesResponse{RecoveryCodes: codes})
+	})
+}
+
+func (h *AuthHandler) DisableMFA(c *fiber.Ctx) error {
+	return h.withMFACode(c, func(userID uint, code string) error {
+		if err := h.authService.DisableMFA(c.UserContext(), userID, code, clientOf(c)); err != nil {
+			return err
+		}
+		return c.SendStatus(http.StatusNoContent)
+	})
+}
+
+func (h *AuthHandler) RegenerateRecoveryCodes(c *fiber.Ctx) error {
+	return h.withMFACode(c, func(userID uint, code string) error {
+		codes, err := h.authService.RegenerateRecoveryCodes(c.UserContext(), userID, code, clientOf(c))
+		if err != nil {
+			return err
+		}
+		return c.JSON(RecoveryCodesResponse{RecoveryCodes: codes})
+	})
+}
+
+// withMFACode đọc user và mã trong body rồi gọi fn; lỗi fn trả về được đổi thành response
+func (h *AuthHandler) withMFACode(c *fiber.Ctx, fn func(userID uint, code string) error) error {
+	user, ok := middleware.CurrentUser(c)
+	if !ok || user.UserID == 0 {
+		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid token claims"})
+	}
+	var req MFACodeRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request"})
+	}
+	if err := h.validate.Struct(req); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	err := fn(user.UserID, req.Code)
+	if throttled, ok := tooManyAttempts(c, err); ok {
+		return throttled
+	}
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, domain.ErrInvalidMFACode):
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, domain.ErrMFAAlreadyEnabled), errors.Is(err, domain.ErrMFANotEnabled):
+		return c.Status(http.StatusConflict).JSON(fiber.Map{"error": err.Error()})
+	default:
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+}
+
+func (h *AuthHandler) Refresh(c *fiber.Ctx) error {
+	var req RefreshRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request"})
+	}
+	if err := h.validate.Struct(req); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	tokens, err := h.authService.Refresh(c.UserContext(), req.RefreshToken)
+	if errors.Is(err, domain.ErrInvalidRefreshToken) || errors.Is(err, domain.ErrRefreshTokenReused) {
+		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": err.Error()})
+	}
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(tokens)
+}
+
+func (h *AuthHandler) Logout(c *fiber.Ctx) error {
+	claims, ok := middleware.Claims(c)
+	if !ok {
+		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "No user in context"})
+	}
+	if err := h.authService.Logout(c.UserContext(), claims); err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.SendStatus(http.StatusNoContent)
+}
+
+func (h *AuthHandler) LogoutAll(c *fiber.Ctx) error {
+	claims, ok := middleware.Claims(c)
+	if !ok {
+		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "No user in context"})
+	}
+	if err := h.authService.LogoutAll(c.UserContext(), claims); err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.SendStatus(http.StatusNoContent)
+}
+
+func (h *AuthHandler) VerifyEmail(c *fiber.Ctx) error {
+	var req VerifyEmailRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request"})
+	}
+	if err := h.validate.Struct(req); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	err := h.authService.VerifyEmail(c.UserContext(), req.Token)
+	if errors.Is(err, domain.ErrInvalidUserToken) {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.SendStatus(http.StatusNoContent)
+}
+
+// ResendVerification luôn trả 202 để không lộ email nào đã đăng ký
+func (h *AuthHandler) ResendVerification(c *fiber.Ctx) error {
+	var req EmailRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request"})
+	}
+	if err := h.validate.Struct(req); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if err := h.authService.ResendVerification(c.UserContext(), req.Email); err != nil {
+		log.Println("Resend verification error:", err)
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to send verification email"})
+	}
+	return c.Status(http.StatusAccepted).JSON(fiber.Map{"message": "If the account exists and is not verified yet, a verification email has been sent"})
+}
+
+// ForgotPassword luôn trả 202 để không lộ email nào đã đăng ký
+func (h *AuthHandler) ForgotPassword(c *fiber.Ctx) error {
+	var req EmailRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request"})
+	}
+	if err := h.validate.Struct(req); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if err := h.authService.RequestPasswordReset(c.UserContext(), req.Email); err != nil {
+		log.Println("Password reset request error:", err)
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to send password reset email"})
+	}
+	return c.Status(http.StatusAccepted).JSON(fiber.Map{"message": "If the account exists, a password reset email has been sent"})
+}
+
+func (h *AuthHandler) ResetPassword(c *fiber.Ctx) error {
+	var req ResetPasswordRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request"})
+	}
+	if err := h.validate.Struct(req); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	err := h.authService.ResetPassword(c.UserContext(), req.Token, req.Password)
+	if errors.Is(err, domain.ErrInvalidUserToken) {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.SendStatus(http.StatusNoContent)
+}
+
+func (h *AuthHandler) UnlockAccount(c *fiber.Ctx) error {
+	var req VerifyEmailRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request"})
+	}
+	if err := h.validate.Struct(req); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	err := h.authService.UnlockAccount(c.UserContext(), req.Token, clientOf(c))
+	if errors.Is(err, domain.ErrInvalidUserToken) {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.SendStatus(http.StatusNoContent)
 }
 
 func (h *AuthHandler) Profile(c *fiber.Ctx) error {
 
-	userInterface := c.Locals("user")
-	if userInterface == nil {
+	if c.Locals("user") == nil {
 		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "No user in context"})
 	}
 
-	claims, ok := userInterface.(map[string]interface{})
+	claims, ok := middleware.Claims(c)
 	if !ok {
 		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid token type"})
 	}
@@ -134,16 +442,18 @@ func (h *AuthHandler) Profile(c *fiber.Ctx) error {
 		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "Email not found in token claims"})
 	}
 
-	user, err := h.authService.FindByEmail(email)
+	user, err := h.authService.FindByEmail(c.UserContext(), email)
 	if err != nil {
 		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
 	}
 
 	response := UserResponse{
-		ID:        user.ID,
-		Email:     user.Email,
-		CreatedAt: user.CreatedAt,
-		UpdatedAt: user.UpdatedAt,
+		ID:              user.ID,
+		Email:           user.Email,
+		Role:            string(user.Role),
+		EmailVerifiedAt: user.EmailVerifiedAt,
+		CreatedAt:       user.CreatedAt,
+		UpdatedAt:       user.UpdatedAt,
 	}
 	return c.JSON(response)
-}
\ No newline at end of file
+}
@@ -12,9 +12,11 @@ import (
 
 	"github.com/go-playground/validator/v10"
 	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 
+	authsvc "ticket_app/auth"
 	"ticket_app/domain"
 	auth "ticket_app/domain"
 )
@@ -28,12 +30,102 @@ func (m *MockAuthService) Register(ctx context.Context, email, password string)
 	return args.Get(0).(*auth.User), args.Error(1)
 }
 
-func (m *MockAuthService) Login(ctx context.Context, email, password string) (string, error) {
+func (m *MockAuthService) Login(ctx context.Context, email, password string, client authsvc.Client) (*authsvc.LoginResult, error) {
 	args := m.Called(ctx, email, password)
-	return args.String(0), args.Error(1)
+	switch result := args.Get(0).(type) {
+	case *authsvc.LoginResult:
+		return result, args.Error(1)
+	case *authsvc.TokenPair:
+		return &authsvc.LoginResult{TokenPair: result}, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockAuthService) VerifyMFA(ctx context.Context, mfaToken, code string, client authsvc.Client) (*authsvc.TokenPair, error) {
+	args := m.Called(mfaToken, code)
+	if pair, ok := args.Get(0).(*authsvc.TokenPair); ok {
+		return pair, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockAuthService) EnrollMFA(ctx context.Context, userID uint) (*authsvc.MFAEnrollment, error) {
+	args := m.Called(userID)
+	if enrollment, ok := args.Get(0).(*authsvc.MFAEnrollment); ok {
+		return enrollment, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockAuthService) ConfirmMFA(ctx context.Context, userID uint, code string) ([]string, error) {
+	args := m.Called(userID, code)
+	codes, _ := args.Get(0).([]string)
+	return codes, args.Error(1)
+}
+
+func (m *MockAuthService) DisableMFA(ctx context.Context, userID uint, code string, client authsvc.Client) error {
+	args := m.Called(userID, code)
+	return args.Error(0)
+}
+
+func (m *MockAuthService) RegenerateRecoveryCodes(ctx context.Context, userID uint, code string, client authsvc.Client) ([]string, error) {
+	args := m.Called(userID, code)
+	codes, _ := args.Get(0).([]string)
+	return codes, args.Error(1)
+}
+
+func (m *MockAuthService) Refresh(ctx context.Context, refreshToken string) (*authsvc.TokenPair, error) {
+	args := m.Called(refreshToken)
+	if pair, ok := args.Get(0).(*authsvc.TokenPair); ok {
+		return pair, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockAuthService) Logout(ctx context.Context, claims jwt.MapClaims) error {
+	args := m.Called(claims)
+	return args.Error(0)
+}
+
+func (m *MockAuthService) LogoutAll(ctx context.Context, claims jwt.MapClaims) error {
+	args := m.Called(claims)
+	return args.Error(0)
+}
+
+func (m *MockAuthService) ChangeRole(ctx context.Context, userID uint, role auth.Role) (*auth.User, error) {
+	args := m.Called(userID, role)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*auth.User), args.Error(1)
+}
+
+func (m *MockAuthService) VerifyEmail(ctx context.Context, token string) error {
+	args := m.Called(token)
+	return args.Error(0)
+}
+
+func (m *MockAuthService) ResendVerification(ctx context.Context, email string) error {
+	args := m.Called(email)
+	return args.Error(0)
 }
 
-func (m *MockAuthService) FindByEmail(email string) (*auth.User, error) {
+func (m *MockAuthService) RequestPasswordReset(ctx context.Context, email string) error {
+	args := m.Called(email)
+	return args.Error(0)
+}
+
+func (m *MockAuthService) ResetPassword(ctx context.Context, token, password string) error {
+	args := m.Called(token, password)
+	return args.Error(0)
+}
+
+func (m *MockAuthService) UnlockAccount(ctx context.Context, token string, client authsvc.Client) error {
+	args := m.Called(token)
+	return args.Error(0)
+}
+
+func (m *MockAuthService) FindByEmail(ctx context.Context, email string) (*auth.User, error) {
 	args := m.Called(email)
 	if user, ok := args.Get(0).(*domain.User); ok {
 		return user, args.Error(1)
@@ -48,9 +140,15 @@ func setupTestApp(handler *AuthHandler) *fiber.App {
 
 	app.Post("/register", handler.Register)
 	app.Post("/login", handler.Login)
-
-	// Add middleware to inject user context for testing /auth/profile
-	app.Use("/auth/profile", func(c *fiber.Ctx) error {
+	app.Post("/auth/refresh", handler.Refresh)
+	app.Post("/auth/verify-email", handler.VerifyEmail)
+	app.Post("/auth/resend-verification", handler.ResendVerification)
+	app.Post("/auth/forgot-password", handler.ForgotPassword)
+	app.Post("/auth/reset-password", handler.ResetPassword)
+	app.Post("/auth/unlock-account", handler.UnlockAccount)
+
+	// Add middleware to inject user context for testing /auth/profile and /auth/logout
+	app.Use([]string{"/auth/profile", "/auth/logout"}, func(c *fiber.Ctx) error {
 		if c.Get("X-Mock-User") != "" {
 			c.Locals("user", map[string]interface{}{
 				"email": c.Get("X-Mock-User"),
@@ -58,7 +156,20 @@ func setupTestApp(handler *AuthHandler) *fiber.App {
 		}
 		return c.Next()
 	})
+	app.Use("/auth/mfa", func(c *fiber.Ctx) error {
+		if sub := c.Get("X-Mock-Sub"); sub != "" {
+			c.Locals("user", map[string]interface{}{"sub": sub, "email": "test@example.com"})
+		}
+		return c.Next()
+	})
+	app.Post("/auth/mfa/verify", handler.VerifyMFA)
+	app.Post("/auth/mfa/enroll", handler.EnrollMFA)
+	app.Post("/auth/mfa/confirm", handler.ConfirmMFA)
+	app.Post("/auth/mfa/disable", handler.DisableMFA)
+	app.Post("/auth/mfa/recovery-codes", handler.RegenerateRecoveryCodes)
 	app.Get("/auth/profile", handler.Profile)
+	app.Post("/auth/logout", handler.Logout)
+	app.Post("/auth/logout-all", handler.LogoutAll)
 
 	return app
 }
@@ -150,6 +261,8 @@ func testRegisterServiceError(t *testing.T) {
 func TestAuthHandler_Login(t *testing.T) {
 	t.Run("Success", testLoginSuccess)
 	t.Run("InvalidCredentials", testLoginInvalidCredentials)
+	t.Run("Throttled", testLoginThrottled)
+	t.Run("ServiceError", testLoginServiceError)
 	t.Run("InvalidRequestBody", testLoginInvalidRequestBody)
 }
 
@@ -157,15 +270,16 @@ func testLoginSuccess(t *testing.T) {
 	mockAuth := new(MockAuthService)
 	app := setupTestApp(&AuthHandler{authService: mockAuth, validate: validator.New()})
 	reqBody := LoginRequest{Email: "test@example.com", Password: "password123"}
-	mockAuth.On("Login", mock.Anything, reqBody.Email, reqBody.Password).Return("access-token", nil)
+	mockAuth.On("Login", mock.Anything, reqBody.Email, reqBody.Password).Return(&authsvc.TokenPair{AccessToken: "access-token", RefreshToken: "refresh-token", TokenType: "Bearer", ExpiresIn: 3600}, nil)
 	body, _ := json.Marshal(reqBody)
 	req := httptest.NewRequest(http.MethodPost, "/login", bytes.NewBuffer(body))
 	req.Header.Set("Content-Type", "application/json")
 	resp, _ := app.Test(req)
 	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
-	var response map[string]string
+	var response authsvc.TokenPair
 	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&response))
-	assert.Equal(t, "access-token", response["access_token"])
+	assert.Equal(t, "access-token", response.AccessToken)
+	assert.Equal(t, "refresh-token", response.RefreshToken)
 	mockAuth.AssertExpectations(t)
 }
 
@@ -173,7 +287,7 @@ func testLoginInvalidCredentials(t *testing.T) {
 	mockAuth := new(MockAuthService)
 	app := setupTestApp(&AuthHandler{authService: mockAuth, validate: validator.New()})
 	reqBody := LoginRequest{Email: "test@example.com", Password: "wrong-password"}
-	mockAuth.On("Login", mock.Anything, reqBody.Email, reqBody.Password).Return("", errors.New("invalid credentials"))
+	mockAuth.On("Login", mock.Anything, reqBody.Email, reqBody.Password).Return(nil, domain.ErrInvalidCredentials)
 	body, _ := json.Marshal(reqBody)
 	req := httptest.NewRequest(http.MethodPost, "/login", bytes.NewBuffer(body))
 	req.Header.Set("Content-Type", "application/json")
@@ -181,10 +295,33 @@ func testLoginInvalidCredentials(t *testing.T) {
 	assert.Equal(t, fiber.StatusUnauthorized, resp.StatusCode)
 	var response map[string]string
 	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&response))
-	assert.Equal(t, "invalid credentials", response["error"])
+	assert.Equal(t, "invalid email or password", response["error"])
 	mockAuth.AssertExpectations(t)
 }
 
+func testLoginThrottled(t *testing.T) {
+	mockAuth := new(MockAuthService)
+	app := setupTestApp(&AuthHandler{authService: mockAuth, validate: validator.New()})
+	mockAuth.On("Login", mock.Anything, "test@example.com", "guess").Return(nil, &authsvc.ThrottledError{RetryAfter: 1500 * time.Millisecond})
+	resp := postJSON(app, "/login", `{"email":"test@example.com","password":"guess"}`)
+	assert.Equal(t, fiber.StatusTooManyRequests, resp.StatusCode)
+	assert.Equal(t, "2", resp.Header.Get("Retry-After"))
+	var response map[string]string
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&response))
+	assert.Equal(t, "too many login attempts, please try again later", response["error"])
+}
+
+func testLoginServiceError(t *testing.T) {
+	mockAuth := new(MockAuthService)
+	app := setupTestApp(&AuthHandler{authService: mockAuth, validate: validator.New()})
+	mockAuth.On("Login", mock.Anything, "test@example.com", "password123").Return(nil, errors.New("redis: connection refused"))
+	resp := postJSON(app, "/login", `{"email":"test@example.com","password":"password123"}`)
+	assert.Equal(t, fiber.StatusInternalServerError, resp.StatusCode)
+	var response map[string]string
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&response))
+	assert.Equal(t, "Login failed", response["error"])
+}
+
 func testLoginInvalidRequestBody(t *testing.T) {
 	mockAuth := new(MockAuthService)
 	app := setupTestApp(&AuthHandler{authService: mockAuth, validate: validator.New()})
@@ -249,3 +386,334 @@ func testProfileInvalidToken(t *testing.T) {
 	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&response))
 	assert.Equal(t, "No user in context", response["error"])
 }
+
+func TestAuthHandler_Refresh(t *testing.T) {
+	t.Run("Success", testRefreshSuccess)
+	t.Run("Reused", testRefreshReused)
+	t.Run("MissingToken", testRefreshMissingToken)
+}
+
+func testRefreshSuccess(t *testing.T) {
+	mockAuth := new(MockAuthService)
+	app := setupTestApp(&AuthHandler{authService: mockAuth, validate: validator.New()})
+	mockAuth.On("Refresh", "old-refresh").Return(&authsvc.TokenPair{AccessToken: "new-access", RefreshToken: "new-refresh"}, nil)
+	req := httptest.NewRequest(http.MethodPost, "/auth/refresh", bytes.NewBufferString(`{"refresh_token":"old-refresh"}`))
+	req.Header.Set("Content-Type", "application/json")
+	resp, _ := app.Test(req)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	var response authsvc.TokenPair
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&response))
+	assert.Equal(t, "new-refresh", response.RefreshToken)
+	mockAuth.AssertExpectations(t)
+}
+
+func testRefreshReused(t *testing.T) {
+	mockAuth := new(MockAuthService)
+	app := setupTestApp(&AuthHandler{authService: mockAuth, validate: validator.New()})
+	mockAuth.On("Refresh", "rotated").Return(nil, domain.ErrRefreshTokenReused)
+	req := httptest.NewRequest(http.MethodPost, "/auth/refresh", bytes.NewBufferString(`{"refresh_token":"rotated"}`))
+	req.Header.Set("Content-Type", "application/json")
+	resp, _ := app.Test(req)
+	assert.Equal(t, fiber.StatusUnauthorized, resp.StatusCode)
+}
+
+func testRefreshMissingToken(t *testing.T) {
+	mockAuth := new(MockAuthService)
+	app := setupTestApp(&AuthHandler{authService: mockAuth, validate: validator.New()})
+	req := httptest.NewRequest(http.MethodPost, "/auth/refresh", bytes.NewBufferString(`{}`))
+	req.Header.Set("Content-Type", "application/json")
+	resp, _ := app.Test(req)
+	assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+	mockAuth.AssertNotCalled(t, "Refresh", mock.Anything)
+}
+
+func TestAuthHandler_Logout(t *testing.T) {
+	t.Run("Logout", testLogout)
+	t.Run("LogoutAll", testLogoutAll)
+	t.Run("NoUserInContext", testLogoutNoUser)
+}
+
+func testLogout(t *testing.T) {
+	mockAuth := new(MockAuthService)
+	app := setupTestApp(&AuthHandler{authService: mockAuth, validate: validator.New()})
+	mockAuth.On("Logout", jwt.MapClaims{"email": "test@example.com"}).Return(nil)
+	req := httptest.NewRequest(http.MethodPost, "/auth/logout", nil)
+	req.Header.Set("X-Mock-User", "test@example.com")
+	resp, _ := app.Test(req)
+	assert.Equal(t, fiber.StatusNoContent, resp.StatusCode)
+	mockAuth.AssertExpectations(t)
+}
+
+func testLogoutAll(t *testing.T) {
+	mockAuth := new(MockAuthService)
+	app := setupTestApp(&AuthHandler{authService: mockAuth, validate: validator.New()})
+	mockAuth.On("LogoutAll", jwt.MapClaims{"email": "test@example.com"}).Return(nil)
+	req := httptest.NewRequest(http.MethodPost, "/auth/logout-all", nil)
+	req.Header.Set("X-Mock-User", "test@example.com")
+	resp, _ := app.Test(req)
+	assert.Equal(t, fiber.StatusNoContent, resp.StatusCode)
+	mockAuth.AssertExpectations(t)
+}
+
+func testLogoutNoUser(t *testing.T) {
+	mockAuth := new(MockAuthService)
+	app := setupTestApp(&AuthHandler{authService: mockAuth, validate: validator.New()})
+	req := httptest.NewRequest(http.MethodPost, "/auth/logout", nil)
+	resp, _ := app.Test(req)
+	assert.Equal(t, fiber.StatusUnauthorized, resp.StatusCode)
+}
+
+func TestAuthHandler_EmailFlows(t *testing.T) {
+	t.Run("VerifyEmail", testVerifyEmail)
+	t.Run("VerifyEmailInvalidToken", testVerifyEmailInvalidToken)
+	t.Run("ResendVerification", testResendVerification)
+	t.Run("ForgotPassword", testForgotPassword)
+	t.Run("ForgotPasswordInvalidEmail", testForgotPasswordInvalidEmail)
+	t.Run("ResetPassword", testResetPassword)
+	t.Run("ResetPasswordInvalidToken", testResetPasswordInvalidToken)
+	t.Run("ResetPasswordTooShort", testResetPasswordTooShort)
+	t.Run("UnlockAccount", testUnlockAccount)
+	t.Run("UnlockAccountInvalidToken", testUnlockAccountInvalidToken)
+}
+
+// jsonRequest gửi body JSON tới app; body rỗng cho các route không cần body
+func jsonRequest(app *fiber.App, method, path, body string) *http.Response {
+	req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp, _ := app.Test(req)
+	return resp
+}
+
+func postJSON(app *fiber.App, path, body string) *http.Response {
+	return jsonRequest(app, http.MethodPost, path, body)
+}
+
+func testVerifyEmail(t *testing.T) {
+	mockAuth := new(MockAuthService)
+	app := setupTestApp(&AuthHandler{authService: mockAuth, validate: validator.New()})
+	mockAuth.On("VerifyEmail", "verify-token").Return(nil)
+	resp := postJSON(app, "/auth/verify-email", `{"token":"verify-token"}`)
+	assert.Equal(t, fiber.StatusNoContent, resp.StatusCode)
+	mockAuth.AssertExpectations(t)
+}
+
+func testVerifyEmailInvalidToken(t *testing.T) {
+	mockAuth := new(MockAuthService)
+	app := setupTestApp(&AuthHandler{authService: mockAuth, validate: validator.New()})
+	mockAuth.On("VerifyEmail", "used").Return(domain.ErrInvalidUserToken)
+	resp := postJSON(app, "/auth/verify-email", `{"token":"used"}`)
+	assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+}
+
+func testResendVerification(t *testing.T) {
+	mockAuth := new(MockAuthService)
+	app := setupTestApp(&AuthHandler{authService: mockAuth, validate: validator.New()})
+	mockAuth.On("ResendVerification", "test@example.com").Return(nil)
+	resp := postJSON(app, "/auth/resend-verification", `{"email":"test@example.com"}`)
+	assert.Equal(t, fiber.StatusAccepted, resp.StatusCode)
+	mockAuth.AssertExpectations(t)
+}
+
+func testForgotPassword(t *testing.T) {
+	mockAuth := new(MockAuthService)
+	app := setupTestApp(&AuthHandler{authService: mockAuth, validate: validator.New()})
+	// Email không tồn tại cũng trả 202: service không báo lỗi
+	mockAuth.On("RequestPasswordReset", "nobody@example.com").Return(nil)
+	resp := postJSON(app, "/auth/forgot-password", `{"email":"nobody@example.com"}`)
+	assert.Equal(t, fiber.StatusAccepted, resp.StatusCode)
+	mockAuth.AssertExpectations(t)
+}
+
+func testForgotPasswordInvalidEmail(t *testing.T) {
+	mockAuth := new(MockAuthService)
+	app := setupTestApp(&AuthHandler{authService: mockAuth, validate: validator.New()})
+	resp := postJSON(app, "/auth/forgot-password", `{"email":"not-an-email"}`)
+	assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+	mockAuth.AssertNotCalled(t, "RequestPasswordReset", mock.Anything)
+}
+
+func testResetPassword(t *testing.T) {
+	mockAuth := new(MockAuthService)
+	app := setupTestApp(&AuthHandler{authService: mockAuth, validate: validator.New()})
+	mockAuth.On("ResetPassword", "reset-token", "new-secret").Return(nil)
+	resp := postJSON(app, "/auth/reset-password", `{"token":"reset-token","password":"new-secret"}`)
+	assert.Equal(t, fiber.StatusNoContent, resp.StatusCode)
+	mockAuth.AssertExpectations(t)
+}
+
+func testResetPasswordInvalidToken(t *testing.T) {
+	mockAuth := new(MockAuthService)
+	app := setupTestApp(&AuthHandler{authService: mockAuth, validate: validator.New()})
+	mockAuth.On("ResetPassword", "expired", "new-secret").Return(domain.ErrInvalidUserToken)
+	resp := postJSON(app, "/auth/reset-password", `{"token":"expired","password":"new-secret"}`)
+	assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+}
+
+func testResetPasswordTooShort(t *testing.T) {
+	mockAuth := new(MockAuthService)
+	app := setupTestApp(&AuthHandler{authService: mockAuth, validate: validator.New()})
+	resp := postJSON(app, "/auth/reset-password", `{"token":"reset-token","password":"123"}`)
+	assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+	mockAuth.AssertNotCalled(t, "ResetPassword", mock.Anything, mock.Anything)
+}
+
+func testUnlockAccount(t *testing.T) {
+	mockAuth := new(MockAuthService)
+	app := setupTestApp(&AuthHandler{authService: mockAuth, validate: validator.New()})
+	mockAuth.On("UnlockAccount", "unlock-token").Return(nil)
+	resp := postJSON(app, "/auth/unlock-account", `{"token":"unlock-token"}`)
+	assert.Equal(t, fiber.StatusNoContent, resp.StatusCode)
+	mockAuth.AssertExpectations(t)
+}
+
+func testUnlockAccountInvalidToken(t *testing.T) {
+	mockAuth := new(MockAuthService)
+	app := setupTestApp(&AuthHandler{authService: mockAuth, validate: validator.New()})
+	mockAuth.On("UnlockAccount", "used").Return(domain.ErrInvalidUserToken)
+	resp := postJSON(app, "/auth/unlock-account", `{"token":"used"}`)
+	assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+}
+
+func TestAuthHandler_MFA(t *testing.T) {
+	t.Run("LoginRequiresMFA", testLoginRequiresMFA)
+	t.Run("Verify", testVerifyMFA)
+	t.Run("VerifyInvalidCode", testVerifyMFAInvalidCode)
+	t.Run("VerifyThrottled", testVerifyMFAThrottled)
+	t.Run("Enroll", testEnrollMFA)
+	t.Run("EnrollAlreadyEnabled", testEnrollMFAAlreadyEnabled)
+	t.Run("EnrollWithoutToken", testEnrollMFAWithoutToken)
+	t.Run("Confirm", testConfirmMFA)
+	t.Run("ConfirmInvalidCode", testConfirmMFAInvalidCode)
+	t.Run("Disable", testDisableMFA)
+	t.Run("DisableNotEnabled", testDisableMFANotEnabled)
+	t.Run("RegenerateRecoveryCodes", testRegenerateRecoveryCodes)
+}
+
+func mfaRequest(app *fiber.App, path, body string) *http.Response {
+	req := httptest.NewRequest(http.MethodPost, path, bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Mock-Sub", "7")
+	resp, _ := app.Test(req)
+	return resp
+}
+
+func testLoginRequiresMFA(t *testing.T) {
+	mockAuth := new(MockAuthService)
+	app := setupTestApp(&AuthHandler{authService: mockAuth, validate: validator.New()})
+	mockAuth.On("Login", mock.Anything, "org@example.com", "password123").
+		Return(&authsvc.LoginResult{MFARequired: true, MFAToken: "challenge", MFAExpiresIn: 300}, nil)
+	resp := postJSON(app, "/login", `{"email":"org@example.com","password":"password123"}`)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	var response map[string]interface{}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&response))
+	assert.Equal(t, true, response["mfa_required"])
+	assert.Equal(t, "challenge", response["mfa_token"])
+	assert.NotContains(t, response, "access_token")
+}
+
+func testVerifyMFA(t *testing.T) {
+	mockAuth := new(MockAuthService)
+	app := setupTestApp(&AuthHandler{authService: mockAuth, validate: validator.New()})
+	mockAuth.On("VerifyMFA", "challenge", "123456").Return(&authsvc.TokenPair{AccessToken: "access", RefreshToken: "refresh"}, nil)
+	resp := postJSON(app, "/auth/mfa/verify", `{"mfa_token":"challenge","code":"123456"}`)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	var response authsvc.TokenPair
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&response))
+	assert.Equal(t, "access", response.AccessToken)
+	mockAuth.AssertExpectations(t)
+}
+
+func testVerifyMFAInvalidCode(t *testing.T) {
+	mockAuth := new(MockAuthService)
+	app := setupTestApp(&AuthHandler{authService: mockAuth, validate: validator.New()})
+	mockAuth.On("VerifyMFA", "challenge", "000000").Return(nil, domain.ErrInvalidMFACode)
+	mockAuth.On("VerifyMFA", "expired", "123456").Return(nil, domain.ErrInvalidUserToken)
+	resp := postJSON(app, "/auth/mfa/verify", `{"mfa_token":"challenge","code":"000000"}`)
+	assert.Equal(t, fiber.StatusUnauthorized, resp.StatusCode)
+	resp = postJSON(app, "/auth/mfa/verify", `{"mfa_token":"expired","code":"123456"}`)
+	assert.Equal(t, fiber.StatusUnauthorized, resp.StatusCode)
+}
+
+func testVerifyMFAThrottled(t *testing.T) {
+	mockAuth := new(MockAuthService)
+	app := setupTestApp(&AuthHandler{authService: mockAuth, validate: validator.New()})
+	mockAuth.On("VerifyMFA", "challenge", "000000").Return(nil, &authsvc.ThrottledError{RetryAfter: 10 * time.Minute})
+	resp := postJSON(app, "/auth/mfa/verify", `{"mfa_token":"challenge","code":"000000"}`)
+	assert.Equal(t, fiber.StatusTooManyRequests, resp.StatusCode)
+	assert.Equal(t, "600", resp.Header.Get("Retry-After"))
+}
+
+func testEnrollMFA(t *testing.T) {
+	mockAuth := new(MockAuthService)
+	app := setupTestApp(&AuthHandler{authService: mockAuth, validate: validator.New()})
+	mockAuth.On("EnrollMFA", uint(7)).Return(&authsvc.MFAEnrollment{Secret: "JBSWY3DPEHPK3PXP", URI: "otpauth://totp/x"}, nil)
+	resp := mfaRequest(app, "/auth/mfa/enroll", "")
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	var response map[string]string
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&response))
+	assert.Equal(t, "JBSWY3DPEHPK3PXP", response["secret"])
+	assert.Equal(t, "otpauth://totp/x", response["otpauth_uri"])
+}
+
+func testEnrollMFAAlreadyEnabled(t *testing.T) {
+	mockAuth := new(MockAuthService)
+	app := setupTestApp(&AuthHandler{authService: mockAuth, validate: validator.New()})
+	mockAuth.On("EnrollMFA", uint(7)).Return(nil, domain.ErrMFAAlreadyEnabled)
+	resp := mfaRequest(app, "/auth/mfa/enroll", "")
+	assert.Equal(t, fiber.StatusConflict, resp.StatusCode)
+}
+
+func testEnrollMFAWithoutToken(t *testing.T) {
+	mockAuth := new(MockAuthService)
+	app := setupTestApp(&AuthHandler{authService: mockAuth, validate: validator.New()})
+	resp := postJSON(app, "/auth/mfa/enroll", "")
+	assert.Equal(t, fiber.StatusUnauthorized, resp.StatusCode)
+	mockAuth.AssertNotCalled(t, "EnrollMFA", mock.Anything)
+}
+
+func testConfirmMFA(t *testing.T) {
+	mockAuth := new(MockAuthService)
+	app := setupTestApp(&AuthHandler{authService: mockAuth, validate: validator.New()})
+	mockAuth.On("ConfirmMFA", uint(7), "123456").Return([]string{"abcde-fghij", "klmno-pqrst"}, nil)
+	resp := mfaRequest(app, "/auth/mfa/confirm", `{"code":"123456"}`)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	var response RecoveryCodesResponse
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&response))
+	assert.Equal(t, []string{"abcde-fghij", "klmno-pqrst"}, response.RecoveryCodes)
+}
+
+func testConfirmMFAInvalidCode(t *testing.T) {
+	mockAuth := new(MockAuthService)
+	app := setupTestApp(&AuthHandler{authService: mockAuth, validate: validator.New()})
+	mockAuth.On("ConfirmMFA", uint(7), "000000").Return(nil, domain.ErrInvalidMFACode)
+	resp := mfaRequest(app, "/auth/mfa/confirm", `{"code":"000000"}`)
+	assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+}
+
+func testDisableMFA(t *testing.T) {
+	mockAuth := new(MockAuthService)
+	app := setupTestApp(&AuthHandler{authService: mockAuth, validate: validator.New()})
+	mockAuth.On("DisableMFA", uint(7), "abcde-fghij").Return(nil)
+	resp := mfaRequest(app, "/auth/mfa/disable", `{"code":"abcde-fghij"}`)
+	assert.Equal(t, fiber.StatusNoContent, resp.StatusCode)
+	mockAuth.AssertExpectations(t)
+}
+
+func testDisableMFANotEnabled(t *testing.T) {
+	mockAuth := new(MockAuthService)
+	app := setupTestApp(&AuthHandler{authService: mockAuth, validate: validator.New()})
+	mockAuth.On("DisableMFA", uint(7), "123456").Return(domain.ErrMFANotEnabled)
+	resp := mfaRequest(app, "/auth/mfa/disable", `{"code":"123456"}`)
+	assert.Equal(t, fiber.StatusConflict, resp.StatusCode)
+}
+
+func testRegenerateRecoveryCodes(t *testing.T) {
+	mockAuth := new(MockAuthService)
+	app := setupTestApp(&AuthHandler{authService: mockAuth, validate: validator.New()})
+	mockAuth.On("RegenerateRecoveryCodes", uint(7), "123456").Return([]string{"uvwxy-z2345"}, nil)
+	resp := mfaRequest(app, "/auth/mfa/recovery-codes", `{"code":"123456"}`)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	var response RecoveryCodesResponse
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&response))
+	assert.Equal(t, []string{"uvwxy-z2345"}, response.RecoveryCodes)
+}
@@ -1,10 +1,11 @@
 package rest
 
 import (
+	"errors"
 	"log"
 	"math"
 	"strconv"
-	auth "ticket_app/auth"
+	"strings"
 	"ticket_app/booking"
 	"ticket_app/domain"
 	"ticket_app/event"
@@ -13,7 +14,6 @@ import (
 
 	validator "github.com/go-playground/validator/v10"
 	"github.com/gofiber/fiber/v2"
-	"github.com/golang-jwt/jwt/v5"
 )
 
 type Pagination struct {
@@ -33,13 +33,24 @@ type PaginatedResponse struct {
 type BookingHandler struct {
 	bookingService booking.BookingService
 	eventService   event.EventService
-	authService auth.AuthService
 	validate       *validator.Validate
 }
 
+// CreateBookingRequest đặt vé theo SeatIDs với event có sơ đồ ghế, theo Items
+// với event chia hạng vé, hoặc Quantity vé giá thường; hoặc đặt vé đã giữ bằng
+// HoldToken (không cần EventID). Chỉ gửi một trong bốn. POST /holds nhận cùng
+// body, trừ HoldToken.
 type CreateBookingRequest struct {
-	EventID  uint `json:"event_id" validate:"required"`
-	Quantity int  `json:"quantity" validate:"required,min=1"`
+	EventID   uint                 `json:"event_id" validate:"required_without=HoldToken"`
+	Quantity  int                  `json:"quantity" validate:"omitempty,min=1"`
+	Items     []BookingItemRequest `json:"items" validate:"omitempty,max=20,dive"`
+	SeatIDs   []uint               `json:"seat_ids" validate:"omitempty,max=20,dive,required"`
+	HoldToken string               `json:"hold_token" validate:"omitempty,max=64"`
+}
+
+type BookingItemRequest struct {
+	TicketTypeID uint `json:"ticket_type_id" validate:"required"`
+	Quantity     int  `json:"quantity" validate:"required,min=1"`
 }
 
 type UpdateBookingRequest struct {
@@ -57,51 +68,108 @@ type BookingResponse struct {
 	UpdatedAt  time.Time     `json:"updated_at"`
 	User       UserResponse  `json:"user"`
 	Event      EventResponse `json:"event"`
+	Items      []BookingItemResponse `json:"items,omitempty"`
+	Seats      []BookingSeatResponse `json:"seats,omitempty"`
+}
+
+// BookingItemResponse là số vé và đơn giá của một hạng vé trong booking
+type BookingItemResponse struct {
+	TicketTypeID uint    `json:"ticket_type_id"`
+	Name         string  `json:"name,omitempty"`
+	Quantity     int     `json:"quantity"`
+	UnitPrice    float64 `json:"unit_price"`
+}
+
+func newBookingItemResponses(items []domain.BookingItem) []BookingItemResponse {
+	if len(items) == 0 {
+		return nil
+	}
+	responses := make([]BookingItemResponse, len(items))
+	for i, item := range items {
+		responses[i] = BookingItemResponse{TicketTypeID: item.TicketTypeID, Quantity: item.Quantity, UnitPrice: item.UnitPrice}
+		if item.TicketType != nil {
+			responses[i].Name = item.TicketType.Name
+		}
+	}
+	return responses
+}
+
+// BookingSeatResponse là một ghế được giữ cho booking
+type BookingSeatResponse struct {
+	SeatID    uint    `json:"seat_id"`
+	Section   string  `json:"section,omitempty"`
+	Row       string  `json:"row,omitempty"`
+	Number    int     `json:"number,omitempty"`
+	UnitPrice float64 `json:"unit_price"`
+}
+
+func newBookingSeatResponses(seats []domain.BookingSeat) []BookingSeatResponse {
+	if len(seats) == 0 {
+		return nil
+	}
+	responses := make([]BookingSeatResponse, len(seats))
+	for i, seat := range seats {
+		responses[i] = BookingSeatResponse{SeatID: seat.SeatID, UnitPrice: seat.UnitPrice}
+		if seat.Seat == nil {
+			continue
+		}
+		responses[i].Number = seat.Seat.Number
+		if row := seat.Seat.Row; row != nil {
+			responses[i].Row = row.Label
+			if row.Section != nil {
+				responses[i].Section = row.Section.Name
+			}
+		}
+	}
+	return responses
 }
 
-func NewBookingHandler(app *fiber.App, bookingService booking.BookingService, authService auth.AuthService) *BookingHandler {
+// NewBookingHandler đăng ký các route booking. Các route này cần access token
+// và middleware.ResolveUser đứng trước.
+func NewBookingHandler(app *fiber.App, bookingService booking.BookingService) *BookingHandler {
 	handler := &BookingHandler{
 		bookingService: bookingService,
-		authService:    authService,
 		validate:      validator.New(),	}
 
+	// Xem, huỷ một booking: chủ booking hoặc admin (kiểm tra trong handler).
+	// Liệt kê mọi booking, đổi status tuỳ ý và xác nhận booking chưa qua
+	// payment: chỉ admin, như các route payment.
+	admins := middleware.RequireRole(domain.RoleAdmin)
 	app.Post("/bookings", handler.CreateBooking)
-	app.Get("/bookings", handler.GetAllBookings)
+	app.Get("/bookings", admins, middleware.PaginationMiddleware(), handler.GetAllBookings)
 	app.Get("/bookings/:id", handler.GetBookingById)
-	app.Put("/bookings/:id", handler.UpdateBooking)
+	app.Put("/bookings/:id", admins, handler.UpdateBooking)
 	app.Put("/bookings/:id/cancel", handler.CancelBooking)
-	app.Put("/bookings/:id/confirm", handler.ConfirmBooking)
+	app.Put("/bookings/:id/confirm", admins, handler.ConfirmBooking)
+
+	// Giữ vé trước khi đặt; chỉ user tạo hold mới xem hoặc bỏ được
+	app.Post("/holds", handler.CreateHold)
+	app.Get("/holds/:token", handler.GetHold)
+	app.Delete("/holds/:token", handler.ReleaseHold)
+
+	// Lịch sử booking của chính user đang đăng nhập
+	app.Get("/me/bookings", middleware.PaginationMiddleware(), handler.ListMyBookings)
+	app.Get("/me/bookings/:id", handler.GetMyBooking)
 
 	return handler
 }
 
 func (h *BookingHandler) CreateBooking(c *fiber.Ctx) error {
-	var req CreateBookingRequest
-	if err := c.BodyParser(&req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
-	}
-
-	if err := h.validate.Struct(&req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Validation failed", "details": err.Error()})
+	req, resp, ok := h.parseBookingRequest(c)
+	if !ok {
+		return resp
 	}
 
 	// Get userID from token
-	user := c.Locals("user").(*jwt.Token)
-	claims := user.Claims.(jwt.MapClaims)
-	email, ok := claims["email"].(string)
-	if !ok {
+	user, ok := middleware.CurrentUser(c)
+	if !ok || user.UserID == 0 {
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid token claims"})
 	}
-	log.Println("email", email)
-	userData, err := h.authService.FindByEmail(email)
-	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
-	}
 
 	// Call service to create booking and handle payment queue
-	booking, err := h.bookingService.CreateBooking(c.Context(), userData.ID, req.EventID, req.Quantity)
+	booking, err := h.bookingService.CreateBooking(c.UserContext(), user.UserID, req)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to create booking"})
+		return reservationError(c, err, "Failed to create booking")
 	}
 
 	return c.Status(fiber.StatusCreated).JSON(
@@ -116,22 +184,96 @@ func (h *BookingHandler) CreateBooking(c *fiber.Ctx) error {
 			UpdatedAt:  booking.UpdatedAt,
 			User:       UserResponse{ID: booking.User.ID, Email: booking.User.Email},
 			Event:      EventResponse{ID: booking.Event.ID, Name: booking.Event.Name, TicketPrice: booking.Event.TicketPrice},
+			Items:      newBookingItemResponses(booking.Items),
+			Seats:      newBookingSeatResponses(booking.Seats),
 		},
 	)
 }
 
+// parseBookingRequest đọc body của POST /bookings và POST /holds; ok là false
+// khi resp đã ghi lỗi 400
+func (h *BookingHandler) parseBookingRequest(c *fiber.Ctx) (req domain.BookingRequest, resp error, ok bool) {
+	var body CreateBookingRequest
+	if err := c.BodyParser(&body); err != nil {
+		return domain.BookingRequest{}, c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"}), false
+	}
+
+	if err := h.validate.Struct(&body); err != nil {
+		return domain.BookingRequest{}, c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Validation failed", "details": err.Error()}), false
+	}
+
+	modes := 0
+	for _, set := range []bool{body.Quantity > 0, len(body.Items) > 0, len(body.SeatIDs) > 0, body.HoldToken != ""} {
+		if set {
+			modes++
+		}
+	}
+	if modes != 1 {
+		return domain.BookingRequest{}, c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Send exactly one of quantity, items, seat_ids or hold_token"}), false
+	}
+	items := make([]domain.BookingItem, len(body.Items))
+	seen := make(map[uint]bool, len(body.Items))
+	for i, item := range body.Items {
+		if seen[item.TicketTypeID] {
+			return domain.BookingRequest{}, c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Each ticket type may appear only once in items"}), false
+		}
+		seen[item.TicketTypeID] = true
+		items[i] = domain.BookingItem{TicketTypeID: item.TicketTypeID, Quantity: item.Quantity}
+	}
+	seenSeat := make(map[uint]bool, len(body.SeatIDs))
+	for _, id := range body.SeatIDs {
+		if seenSeat[id] {
+			return domain.BookingRequest{}, c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Each seat may appear only once in seat_ids"}), false
+		}
+		seenSeat[id] = true
+	}
+	return domain.BookingRequest{
+		EventID:   body.EventID,
+		Quantity:  body.Quantity,
+		Items:     items,
+		SeatIDs:   body.SeatIDs,
+		HoldToken: body.HoldToken,
+	}, nil, true
+}
+
+// reservationError ghi response cho lỗi khi đặt, giữ vé hoặc đổi trạng thái booking
+func reservationError(c *fiber.Ctx, err error, message string) error {
+	if errors.Is(err, domain.ErrNotEnoughTickets) || errors.Is(err, domain.ErrEventNotActive) ||
+		errors.Is(err, domain.ErrTicketTypeNotOnSale) || errors.Is(err, domain.ErrSeatTaken) ||
+		errors.Is(err, domain.ErrSeatNotOnSale) || errors.Is(err, domain.ErrHoldNotActive) ||
+		errors.Is(err, domain.ErrBookingNotPending) || errors.Is(err, domain.ErrBookingInOrder) {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": err.Error()})
+	}
+	if errors.Is(err, domain.ErrTicketTypeNotFound) || errors.Is(err, domain.ErrTicketTypeRequired) ||
+		errors.Is(err, domain.ErrTicketQuantityOutOfRange) || errors.Is(err, domain.ErrBadParamInput) ||
+		errors.Is(err, domain.ErrSeatNotFound) || errors.Is(err, domain.ErrSeatSelectionRequired) ||
+		errors.Is(err, domain.ErrNoSeatMap) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	if errors.Is(err, domain.ErrHoldNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Hold not found"})
+	}
+	if errors.Is(err, domain.ErrHoldExpired) {
+		return c.Status(fiber.StatusGone).JSON(fiber.Map{"error": err.Error()})
+	}
+	if errors.Is(err, domain.ErrEmailNotVerified) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Please verify your email address before booking"})
+	}
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": message})
+}
+
 func (h *BookingHandler) GetAllBookings(c *fiber.Ctx) error {
 	pagination, ok := c.Locals("pagination").(middleware.Pagination)
 	if !ok {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Pagination info not found"})
 	}
 
-	totalItems, err := h.bookingService.CountBookings()
+	totalItems, err := h.bookingService.CountBookings(c.UserContext())
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
 	}
 
-	bookings, err := h.bookingService.GetAllBookingsWithPagination(pagination.Offset, pagination.Limit)
+	bookings, err := h.bookingService.GetAllBookingsWithPagination(c.UserContext(), pagination.Offset, pagination.Limit)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
 	}
@@ -155,9 +297,9 @@ func (h *BookingHandler) GetBookingById(c *fiber.Ctx) error {
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid booking ID"})
 	}
-	booking, err := h.bookingService.GetBookingById(uint(id))
-	if err != nil {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Booking not found"})
+	booking, resp, ok := h.ownBooking(c, uint(id))
+	if !ok {
+		return resp
 	}
 	return c.JSON(BookingResponse{
 		ID:         booking.ID,
@@ -170,9 +312,14 @@ func (h *BookingHandler) GetBookingById(c *fiber.Ctx) error {
 		UpdatedAt:  booking.UpdatedAt,
 		User:       UserResponse{ID: booking.User.ID, Email: booking.User.Email},
 		Event:      EventResponse{ID: booking.Event.ID, Name: booking.Event.Name, TicketPrice: booking.Event.TicketPrice},
+		Items:      newBookingItemResponses(booking.Items),
+		Seats:      newBookingSeatResponses(booking.Seats),
 	})
 }
 
+// UpdateBooking đổi status của booking đang PENDING. CANCELLED và CONFIRMED đi
+// qua CancelBooking và ConfirmBooking, nên vé, ghế, payment và waitlist được
+// cập nhật như khi huỷ hoặc xác nhận; booking không quay lại PENDING được.
 func (h *BookingHandler) UpdateBooking(c *fiber.Ctx) error {
 	idParam := c.Params("id")
 
@@ -183,25 +330,34 @@ func (h *BookingHandler) UpdateBooking(c *fiber.Ctx) error {
 
 	var req UpdateBookingRequest
 	if err := c.BodyParser(&req); err != nil {
-		log.Println("err1", err)
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
 	}
 	if err := h.validate.Struct(&req); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Validation failed", "details": err.Error()})
 	}
-	booking, err := h.bookingService.GetBookingById(uint(id))
+	if !req.Status.Validate() {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid booking status"})
+	}
+	booking, err := h.bookingService.GetBookingById(c.UserContext(), uint(id))
 	if err != nil {
-		log.Println("err3", err)
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Booking not found"})
 	}
 
-	booking.Status = req.Status
-
-
-	err = h.bookingService.UpdateBooking(booking)
-	if err != nil {
-		log.Println("err5", err)
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update booking"})
+	if req.Status != booking.Status {
+		var updated *domain.Booking
+		switch req.Status {
+		case domain.BookingStatusCancelled:
+			updated, err = h.bookingService.CancelBooking(c.UserContext(), booking.ID)
+		case domain.BookingStatusConfirmed:
+			updated, err = h.bookingService.ConfirmBooking(c.UserContext(), booking.ID)
+		default:
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Booking cannot go back to PENDING"})
+		}
+		if err != nil {
+			return reservationError(c, err, "Failed to update booking")
+		}
+		booking.Status = updated.Status
+		booking.UpdatedAt = updated.UpdatedAt
 	}
 
 	return c.JSON(BookingResponse{
@@ -215,6 +371,8 @@ func (h *BookingHandler) UpdateBooking(c *fiber.Ctx) error {
 		UpdatedAt:  booking.UpdatedAt,
 		User:       UserResponse{ID: booking.User.ID, Email: booking.User.Email},
 		Event:      EventResponse{ID: booking.Event.ID, Name: booking.Event.Name, TicketPrice: booking.Event.TicketPrice},
+		Items:      newBookingItemResponses(booking.Items),
+		Seats:      newBookingSeatResponses(booking.Seats),
 	})
 }
 
@@ -224,9 +382,13 @@ func (h *BookingHandler) ConfirmBooking(c *fiber.Ctx) error {
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid booking ID"})
 	}
-	booking, err := h.bookingService.ConfirmBooking(uint(id))
+	// Route chỉ dành cho admin (RequireRole), nên chỉ cần kiểm tra booking tồn tại
+	if _, err := h.bookingService.GetBookingById(c.UserContext(), uint(id)); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Booking not found"})
+	}
+	booking, err := h.bookingService.ConfirmBooking(c.UserContext(), uint(id))
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Failed to confirm booking"})
+		return reservationError(c, err, "Failed to confirm booking")
 	}
 	return c.JSON(booking)
 }
@@ -239,11 +401,134 @@ func (h *BookingHandler) CancelBooking(c *fiber.Ctx) error {
 		log.Println("err1", err)
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid booking ID"})
 	}
-	booking, err := h.bookingService.CancelBooking(uint(id))
+	if _, resp, ok := h.ownBooking(c, uint(id)); !ok {
+		return resp
+	}
+	booking, err := h.bookingService.CancelBooking(c.UserContext(), uint(id))
 	if err != nil {
 		log.Println("err2", err)
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Failed to cancel booking"})
+		return reservationError(c, err, "Failed to cancel booking")
 	}
 	return c.JSON(booking)
 }
 
+// ownBooking tải booking id và kiểm tra user được thao tác trên nó: admin với
+// mọi booking, các role khác chỉ với booking của mình. Khi không được, response
+// lỗi đã được ghi và ok là false.
+func (h *BookingHandler) ownBooking(c *fiber.Ctx, id uint) (booking *domain.Booking, resp error, ok bool) {
+	booking, err := h.bookingService.GetBookingById(c.UserContext(), id)
+	if err != nil || booking == nil {
+		return nil, c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Booking not found"}), false
+	}
+	user, ok := middleware.CurrentUser(c)
+	if !ok || user.UserID == 0 {
+		return nil, c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid token claims"}), false
+	}
+	if user.IsAdmin() {
+		return booking, nil, true
+	}
+	if booking.UserID != user.UserID {
+		return nil, c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "You can only access your own bookings"}), false
+	}
+	return booking, nil, true
+}
+
+// MyBookingResponse là một booking trong lịch sử của user đang đăng nhập
+type MyBookingResponse struct {
+	ID         uint          `json:"id"`
+	EventID    uint          `json:"event_id"`
+	Quantity   int           `json:"quantity"`
+	TotalPrice float64       `json:"total_price"`
+	Status     string        `json:"status"`
+	CreatedAt  time.Time     `json:"created_at"`
+	UpdatedAt  time.Time     `json:"updated_at"`
+	Event      EventResponse `json:"event"`
+	Items      []BookingItemResponse `json:"items,omitempty"`
+	Seats      []BookingSeatResponse `json:"seats,omitempty"`
+}
+
+func newMyBookingResponse(b *domain.Booking) MyBookingResponse {
+	return MyBookingResponse{
+		ID:         b.ID,
+		EventID:    b.EventID,
+		Quantity:   b.Quantity,
+		TotalPrice: b.TotalPrice,
+		Status:     string(b.Status),
+		CreatedAt:  b.CreatedAt,
+		UpdatedAt:  b.UpdatedAt,
+		Event: EventResponse{
+			ID:          b.Event.ID,
+			Name:        b.Event.Name,
+			Description: b.Event.Description,
+			StartDate:   b.Event.StartDate,
+			EndDate:     b.Event.EndDate,
+			TicketPrice: b.Event.TicketPrice,
+		},
+		Items: newBookingItemResponses(b.Items),
+		Seats: newBookingSeatResponses(b.Seats),
+	}
+}
+
+// ListMyBookings trả về booking của user đang đăng nhập, mới nhất trước.
+// Query: status (PENDING, CONFIRMED, CANCELLED), event_id, page, limit.
+func (h *BookingHandler) ListMyBookings(c *fiber.Ctx) error {
+	user, ok := middleware.CurrentUser(c)
+	if !ok || user.UserID == 0 {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid token claims"})
+	}
+	pagination, ok := c.Locals("pagination").(middleware.Pagination)
+	if !ok {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Pagination info not found"})
+	}
+
+	var filter domain.BookingFilter
+	if status := c.Query("status"); status != "" {
+		filter.Status = domain.BookingStatus(strings.ToUpper(status))
+		if !filter.Status.Validate() {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid booking status"})
+		}
+	}
+	if eventID := c.Query("event_id"); eventID != "" {
+		id, err := strconv.ParseUint(eventID, 10, 32)
+		if err != nil || id == 0 {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid event ID"})
+		}
+		filter.EventID = uint(id)
+	}
+
+	bookings, total, err := h.bookingService.ListUserBookings(c.UserContext(), user.UserID, filter, pagination.Offset, pagination.Limit)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to list bookings"})
+	}
+	items := make([]MyBookingResponse, 0, len(bookings))
+	for i := range bookings {
+		items = append(items, newMyBookingResponse(&bookings[i]))
+	}
+	return c.JSON(middleware.PaginatedResponse{
+		Data:        items,
+		CurrentPage: pagination.Page,
+		TotalPages:  int(math.Ceil(float64(total) / float64(pagination.Limit))),
+		TotalItems:  total,
+	})
+}
+
+// GetMyBooking trả về một booking của user đang đăng nhập; booking của user khác là 404
+func (h *BookingHandler) GetMyBooking(c *fiber.Ctx) error {
+	user, ok := middleware.CurrentUser(c)
+	if !ok || user.UserID == 0 {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid token claims"})
+	}
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid booking ID"})
+	}
+	booking, err := h.bookingService.GetUserBooking(c.UserContext(), user.UserID, uint(id))
+	if errors.Is(err, domain.ErrNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Booking not found"})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to get booking"})
+	}
+	return c.JSON(newMyBookingResponse(booking))
+}
+
@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"errors"
 	"log"
+	"net/http"
 	"net/http/httptest"
 	"testing"
 	"ticket_app/domain"
@@ -22,15 +23,15 @@ import (
 // Define mock services
 type MockBookingService struct{ mock.Mock }
 
-func (m *MockBookingService) CreateBooking(ctx context.Context, userID, eventID uint, quantity int) (*domain.Booking, error) {
-	args := m.Called(ctx, userID, eventID, quantity)
+func (m *MockBookingService) CreateBooking(ctx context.Context, userID uint, req domain.BookingRequest) (*domain.Booking, error) {
+	args := m.Called(ctx, userID, req)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
 	return args.Get(0).(*domain.Booking), args.Error(1)
 }
 
-func (m *MockBookingService) GetBookingById(id uint) (*domain.Booking, error) {
+func (m *MockBookingService) GetBookingById(ctx context.Context, id uint) (*domain.Booking, error) {
 	args := m.Called(id)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
@@ -38,11 +39,11 @@ func (m *MockBookingService) GetBookingById(id uint) (*domain.Booking, error) {
 	return args.Get(0).(*domain.Booking), args.Error(1)
 }
 
-func (m *MockBookingService) UpdateBooking(b *domain.Booking) error {
+func (m *MockBookingService) UpdateBooking(ctx context.Context, b *domain.Booking) error {
 	return m.Called(b).Error(0)
 }
 
-func (m *MockBookingService) CancelBooking(id uint) (*domain.Booking, error) {
+func (m *MockBookingService) CancelBooking(ctx context.Context, id uint) (*domain.Booking, error) {
 	log.Println("CancelBooking")
 	args := m.Called(id)
 	if args.Get(0) == nil {
@@ -51,15 +52,28 @@ func (m *MockBookingService) CancelBooking(id uint) (*domain.Booking, error) {
 	return args.Get(0).(*domain.Booking), args.Error(1)
 }
 
-func (m *MockBookingService) CountBookings() (int64, error) {
+func (m *MockBookingService) CountBookings(ctx context.Context) (int64, error) {
 	return 0, nil
 }
 
-func (m *MockBookingService) GetAllBookingsWithPagination(offset, limit int) ([]domain.Booking, error) {
+func (m *MockBookingService) GetAllBookingsWithPagination(ctx context.Context, offset, limit int) ([]domain.Booking, error) {
 	return []domain.Booking{}, nil
 }
 
-func (m *MockBookingService) ConfirmBooking(id uint) (*domain.Booking, error) {
+func (m *MockBookingService) ListUserBookings(ctx context.Context, userID uint, filter domain.BookingFilter, offset, limit int) ([]domain.Booking, int64, error) {
+	args := m.Called(userID, filter, offset, limit)
+	return args.Get(0).([]domain.Booking), args.Get(1).(int64), args.Error(2)
+}
+
+func (m *MockBookingService) GetUserBooking(ctx context.Context, userID, id uint) (*domain.Booking, error) {
+	args := m.Called(userID, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Booking), args.Error(1)
+}
+
+func (m *MockBookingService) ConfirmBooking(ctx context.Context, id uint) (*domain.Booking, error) {
 	args := m.Called(id)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
@@ -67,6 +81,26 @@ func (m *MockBookingService) ConfirmBooking(id uint) (*domain.Booking, error) {
 	return args.Get(0).(*domain.Booking), args.Error(1)
 }
 
+func (m *MockBookingService) CreateHold(ctx context.Context, userID uint, req domain.BookingRequest) (*domain.Hold, error) {
+	args := m.Called(userID, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Hold), args.Error(1)
+}
+
+func (m *MockBookingService) GetHold(ctx context.Context, userID uint, token string) (*domain.Hold, error) {
+	args := m.Called(userID, token)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Hold), args.Error(1)
+}
+
+func (m *MockBookingService) ReleaseHold(ctx context.Context, userID uint, token string) error {
+	return m.Called(userID, token).Error(0)
+}
+
 
 
 
@@ -77,12 +111,11 @@ func setupBookingApp(bs *MockBookingService, as *MockAuthService, es event.Event
 	h := &BookingHandler{
 		bookingService: bs,
 		eventService:   es,
-		authService:    as,
 		validate:       validate,
 	}
 
 	app.Use(func(c *fiber.Ctx) error {
-		claims := jwt.MapClaims{"email": "test@example.com"}
+		claims := jwt.MapClaims{"sub": "1", "email": "test@example.com"}
 		token := &jwt.Token{Claims: claims}
 		c.Locals("user", token)
 		c.Locals("authService", as)
@@ -94,6 +127,9 @@ func setupBookingApp(bs *MockBookingService, as *MockAuthService, es event.Event
 	app.Put("/bookings/:id", h.UpdateBooking)
 	app.Put("/bookings/:id/cancel", h.CancelBooking)
 	app.Put("/bookings/:id/confirm", h.ConfirmBooking)
+	app.Post("/holds", h.CreateHold)
+	app.Get("/holds/:token", h.GetHold)
+	app.Delete("/holds/:token", h.ReleaseHold)
 	return app
 }
 
@@ -102,13 +138,143 @@ func TestCreateBooking(t *testing.T) {
 	t.Run("Success", TestCreateBookingSuccess)
 	t.Run("InvalidBody", TestCreateBookingInvalidBody)
 	t.Run("ServiceError", TestCreateBookingServiceError)
+	t.Run("SoldOut", TestCreateBookingSoldOut)
+	t.Run("EmailNotVerified", TestCreateBookingEmailNotVerified)
+	t.Run("WithItems", testCreateBookingWithItems)
+	t.Run("QuantityOrItems", testCreateBookingQuantityOrItems)
+	t.Run("DuplicateItems", testCreateBookingDuplicateItems)
+	t.Run("TicketTypeErrors", testCreateBookingTicketTypeErrors)
+	t.Run("WithSeats", testCreateBookingWithSeats)
+	t.Run("DuplicateSeats", testCreateBookingDuplicateSeats)
+	t.Run("SeatErrors", testCreateBookingSeatErrors)
+}
+
+func testCreateBookingWithItems(t *testing.T) {
+	bookingSvc := new(MockBookingService)
+	items := []domain.BookingItem{{TicketTypeID: 3, Quantity: 2}, {TicketTypeID: 4, Quantity: 1}}
+	bookingSvc.On("CreateBooking", mock.Anything, uint(1), domain.BookingRequest{EventID: 1, Items: items}).
+		Return(&domain.Booking{
+			ID: 1, UserID: 1, EventID: 1, Quantity: 3, TotalPrice: 3500000, Status: domain.BookingStatusPending,
+			Items: []domain.BookingItem{
+				{TicketTypeID: 3, Quantity: 2, UnitPrice: 1000000, TicketType: &domain.TicketType{Name: "VIP"}},
+				{TicketTypeID: 4, Quantity: 1, UnitPrice: 1500000},
+			},
+		}, nil)
+
+	app := setupBookingApp(bookingSvc, &MockAuthService{}, nil)
+	resp := postJSON(app, "/bookings", `{"event_id":1,"items":[{"ticket_type_id":3,"quantity":2},{"ticket_type_id":4,"quantity":1}]}`)
+	assert.Equal(t, fiber.StatusCreated, resp.StatusCode)
+	var result BookingResponse
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+	assert.Equal(t, 3, result.Quantity)
+	assert.Equal(t, []BookingItemResponse{
+		{TicketTypeID: 3, Name: "VIP", Quantity: 2, UnitPrice: 1000000},
+		{TicketTypeID: 4, Quantity: 1, UnitPrice: 1500000},
+	}, result.Items)
+	bookingSvc.AssertExpectations(t)
+}
+
+func testCreateBookingQuantityOrItems(t *testing.T) {
+	bookingSvc := new(MockBookingService)
+	app := setupBookingApp(bookingSvc, &MockAuthService{}, nil)
+	resp := postJSON(app, "/bookings", `{"event_id":1}`)
+	assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+	resp = postJSON(app, "/bookings", `{"event_id":1,"quantity":2,"items":[{"ticket_type_id":3,"quantity":2}]}`)
+	assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+	resp = postJSON(app, "/bookings", `{"event_id":1,"items":[{"ticket_type_id":3,"quantity":0}]}`)
+	assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+	resp = postJSON(app, "/bookings", `{"event_id":1,"quantity":2,"seat_ids":[7,8]}`)
+	assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+	resp = postJSON(app, "/bookings", `{"event_id":1,"seat_ids":[0]}`)
+	assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+	bookingSvc.AssertNotCalled(t, "CreateBooking", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func testCreateBookingDuplicateItems(t *testing.T) {
+	bookingSvc := new(MockBookingService)
+	app := setupBookingApp(bookingSvc, &MockAuthService{}, nil)
+	resp := postJSON(app, "/bookings", `{"event_id":1,"items":[{"ticket_type_id":3,"quantity":1},{"ticket_type_id":3,"quantity":1}]}`)
+	assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+	bookingSvc.AssertNotCalled(t, "CreateBooking", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func testCreateBookingTicketTypeErrors(t *testing.T) {
+	cases := []struct {
+		err    error
+		status int
+	}{
+		{domain.ErrTicketTypeRequired, fiber.StatusBadRequest},
+		{domain.ErrTicketTypeNotFound, fiber.StatusBadRequest},
+		{domain.ErrTicketQuantityOutOfRange, fiber.StatusBadRequest},
+		{domain.ErrTicketTypeNotOnSale, fiber.StatusConflict},
+		{domain.ErrNotEnoughTickets, fiber.StatusConflict},
+	}
+	for _, tc := range cases {
+		bookingSvc := new(MockBookingService)
+		bookingSvc.On("CreateBooking", mock.Anything, uint(1), mock.Anything).Return(nil, tc.err)
+		app := setupBookingApp(bookingSvc, &MockAuthService{}, nil)
+		resp := postJSON(app, "/bookings", `{"event_id":1,"items":[{"ticket_type_id":3,"quantity":2}]}`)
+		assert.Equal(t, tc.status, resp.StatusCode, tc.err.Error())
+	}
+}
+
+func testCreateBookingWithSeats(t *testing.T) {
+	bookingSvc := new(MockBookingService)
+	seat := &domain.Seat{Number: 12, Row: &domain.Row{Label: "C", Section: &domain.Section{Name: "Orchestra"}}}
+	bookingSvc.On("CreateBooking", mock.Anything, uint(1), domain.BookingRequest{EventID: 1, Items: []domain.BookingItem{}, SeatIDs: []uint{7, 8}}).
+		Return(&domain.Booking{
+			ID: 1, UserID: 1, EventID: 1, Quantity: 2, TotalPrice: 1000000, Status: domain.BookingStatusPending,
+			Seats: []domain.BookingSeat{
+				{SeatID: 7, UnitPrice: 500000, Seat: seat},
+				{SeatID: 8, UnitPrice: 500000},
+			},
+		}, nil)
+
+	app := setupBookingApp(bookingSvc, &MockAuthService{}, nil)
+	resp := postJSON(app, "/bookings", `{"event_id":1,"seat_ids":[7,8]}`)
+	assert.Equal(t, fiber.StatusCreated, resp.StatusCode)
+	var result BookingResponse
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+	assert.Equal(t, []BookingSeatResponse{
+		{SeatID: 7, Section: "Orchestra", Row: "C", Number: 12, UnitPrice: 500000},
+		{SeatID: 8, UnitPrice: 500000},
+	}, result.Seats)
+	bookingSvc.AssertExpectations(t)
+}
+
+func testCreateBookingDuplicateSeats(t *testing.T) {
+	bookingSvc := new(MockBookingService)
+	app := setupBookingApp(bookingSvc, &MockAuthService{}, nil)
+	resp := postJSON(app, "/bookings", `{"event_id":1,"seat_ids":[7,7]}`)
+	assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+	bookingSvc.AssertNotCalled(t, "CreateBooking", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func testCreateBookingSeatErrors(t *testing.T) {
+	cases := []struct {
+		err    error
+		status int
+	}{
+		{domain.ErrSeatNotFound, fiber.StatusBadRequest},
+		{domain.ErrSeatSelectionRequired, fiber.StatusBadRequest},
+		{domain.ErrNoSeatMap, fiber.StatusBadRequest},
+		{domain.ErrSeatTaken, fiber.StatusConflict},
+		{domain.ErrSeatNotOnSale, fiber.StatusConflict},
+	}
+	for _, tc := range cases {
+		bookingSvc := new(MockBookingService)
+		bookingSvc.On("CreateBooking", mock.Anything, uint(1), mock.Anything).Return(nil, tc.err)
+		app := setupBookingApp(bookingSvc, &MockAuthService{}, nil)
+		resp := postJSON(app, "/bookings", `{"event_id":1,"seat_ids":[7]}`)
+		assert.Equal(t, tc.status, resp.StatusCode, tc.err.Error())
+	}
 }
 
 func TestCreateBookingSuccess(t *testing.T) {
 	bookingSvc := new(MockBookingService)
 	authSvc := new(MockAuthService)
 	authSvc.On("FindByEmail", "test@example.com").Return(&domain.User{ID: 1, Email: "test@example.com"}, nil)
-	bookingSvc.On("CreateBooking", mock.Anything, uint(1), uint(1), 2).
+	bookingSvc.On("CreateBooking", mock.Anything, uint(1), domain.BookingRequest{EventID: 1, Quantity: 2, Items: []domain.BookingItem{}}).
 		Return(&domain.Booking{
 			ID:         1,
 			UserID:     1,
@@ -142,7 +308,7 @@ func TestCreateBookingServiceError(t *testing.T) {
 	bookingSvc := new(MockBookingService)
 	authSvc := new(MockAuthService)
 	authSvc.On("FindByEmail", "test@example.com").Return(&domain.User{ID: 1, Email: "test@example.com"}, nil)
-	bookingSvc.On("CreateBooking", mock.Anything, uint(1), uint(1), 2).
+	bookingSvc.On("CreateBooking", mock.Anything, uint(1), domain.BookingRequest{EventID: 1, Quantity: 2, Items: []domain.BookingItem{}}).
 		Return(nil, errors.New("internal error"))
 
 	app := setupBookingApp(bookingSvc, authSvc, nil)
@@ -153,6 +319,33 @@ func TestCreateBookingServiceError(t *testing.T) {
 	assert.Equal(t, 500, resp.StatusCode)
 }
 
+func TestCreateBookingSoldOut(t *testing.T) {
+	bookingSvc := new(MockBookingService)
+	authSvc := new(MockAuthService)
+	authSvc.On("FindByEmail", "test@example.com").Return(&domain.User{ID: 1, Email: "test@example.com"}, nil)
+	bookingSvc.On("CreateBooking", mock.Anything, uint(1), domain.BookingRequest{EventID: 1, Quantity: 2, Items: []domain.BookingItem{}}).
+		Return(nil, domain.ErrNotEnoughTickets)
+
+	app := setupBookingApp(bookingSvc, authSvc, nil)
+	body, _ := json.Marshal(map[string]interface{}{"event_id": 1, "quantity": 2})
+	req := httptest.NewRequest("POST", "/bookings", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp, _ := app.Test(req)
+	assert.Equal(t, 409, resp.StatusCode)
+}
+
+func TestCreateBookingEmailNotVerified(t *testing.T) {
+	bookingSvc := new(MockBookingService)
+	bookingSvc.On("CreateBooking", mock.Anything, uint(1), domain.BookingRequest{EventID: 1, Quantity: 2, Items: []domain.BookingItem{}}).
+		Return(nil, domain.ErrEmailNotVerified)
+
+	app := setupBookingApp(bookingSvc, &MockAuthService{}, nil)
+	body, _ := json.Marshal(map[string]interface{}{"event_id": 1, "quantity": 2})
+	req := httptest.NewRequest("POST", "/bookings", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp, _ := app.Test(req)
+	assert.Equal(t, 403, resp.StatusCode)
+}
 
 func TestGetBookingById(t *testing.T) {
 	t.Run("Success", TestGetBookingByIdSuccess)
@@ -196,6 +389,11 @@ func TestUpdateBooking(t *testing.T) {
 	t.Run("Success", TestUpdateBookingSuccess)
 	t.Run("InvalidBody", TestUpdateBookingInvalidBody)
 	t.Run("NotFound", TestUpdateBookingNotFound)
+	t.Run("InvalidStatus", testUpdateBookingInvalidStatus)
+	t.Run("CancelReleases", testUpdateBookingCancelReleases)
+	t.Run("BackToPending", testUpdateBookingBackToPending)
+	t.Run("NotPending", testUpdateBookingNotPending)
+	t.Run("Failure", testUpdateBookingFailure)
 }
 func TestUpdateBookingSuccess(t *testing.T) {
 	bookingSvc := new(MockBookingService)
@@ -212,7 +410,7 @@ func TestUpdateBookingSuccess(t *testing.T) {
 		Event:  domain.Event{ID: 1, Name: "Event", TicketPrice: 50},
 	}
 	bookingSvc.On("GetBookingById", uint(1)).Return(booking, nil)
-	bookingSvc.On("UpdateBooking", booking).Return(nil)
+	bookingSvc.On("ConfirmBooking", uint(1)).Return(&domain.Booking{ID: 1, UserID: 1, Status: domain.BookingStatusConfirmed}, nil)
 
 	app := setupBookingApp(bookingSvc, authSvc, nil)
 
@@ -249,10 +447,72 @@ func TestUpdateBookingNotFound(t *testing.T) {
 	assert.Equal(t, 404, resp.StatusCode)
 }
 
+func putBookingStatus(app *fiber.App, status string) *http.Response {
+	body, _ := json.Marshal(map[string]string{"status": status})
+	return jsonRequest(app, "PUT", "/bookings/1", string(body))
+}
+
+func testUpdateBookingInvalidStatus(t *testing.T) {
+	svc := new(MockBookingService)
+	resp := putBookingStatus(setupBookingApp(svc, nil, nil), "PAID")
+	assert.Equal(t, 400, resp.StatusCode)
+	svc.AssertNotCalled(t, "GetBookingById", uint(1))
+}
+
+// Huỷ qua PUT /bookings/:id đi qua CancelBooking để trả vé, ghế và payment
+func testUpdateBookingCancelReleases(t *testing.T) {
+	svc := new(MockBookingService)
+	svc.On("GetBookingById", uint(1)).Return(&domain.Booking{ID: 1, UserID: 1, Status: domain.BookingStatusPending}, nil)
+	svc.On("CancelBooking", uint(1)).Return(&domain.Booking{ID: 1, UserID: 1, Status: domain.BookingStatusCancelled}, nil)
+	resp := putBookingStatus(setupBookingApp(svc, nil, nil), string(domain.BookingStatusCancelled))
+	assert.Equal(t, 200, resp.StatusCode)
+	var result BookingResponse
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+	assert.Equal(t, string(domain.BookingStatusCancelled), result.Status)
+	svc.AssertExpectations(t)
+	svc.AssertNotCalled(t, "UpdateBooking", mock.Anything)
+}
+
+func testUpdateBookingBackToPending(t *testing.T) {
+	svc := new(MockBookingService)
+	svc.On("GetBookingById", uint(1)).Return(&domain.Booking{ID: 1, UserID: 1, Status: domain.BookingStatusCancelled}, nil)
+	resp := putBookingStatus(setupBookingApp(svc, nil, nil), string(domain.BookingStatusPending))
+	assert.Equal(t, 409, resp.StatusCode)
+	svc.AssertNotCalled(t, "UpdateBooking", mock.Anything)
+}
+
+// Booking đã đổi trạng thái trong lúc request chạy là conflict, không phải request sai
+func testUpdateBookingNotPending(t *testing.T) {
+	svc := new(MockBookingService)
+	svc.On("GetBookingById", uint(1)).Return(&domain.Booking{ID: 1, UserID: 1, Status: domain.BookingStatusPending}, nil)
+	svc.On("ConfirmBooking", uint(1)).Return(nil, domain.ErrBookingNotPending)
+	resp := putBookingStatus(setupBookingApp(svc, nil, nil), string(domain.BookingStatusConfirmed))
+	assert.Equal(t, 409, resp.StatusCode)
+}
+
+func testUpdateBookingFailure(t *testing.T) {
+	svc := new(MockBookingService)
+	svc.On("GetBookingById", uint(1)).Return(&domain.Booking{ID: 1, UserID: 1, Status: domain.BookingStatusPending}, nil)
+	svc.On("CancelBooking", uint(1)).Return(nil, errors.New("connection reset"))
+	resp := putBookingStatus(setupBookingApp(svc, nil, nil), string(domain.BookingStatusCancelled))
+	assert.Equal(t, 500, resp.StatusCode)
+}
+
 func TestCancelBooking(t *testing.T) {
 	t.Run("Success", TestCancelBookingSuccess)
 	t.Run("NotFound", TestCancelBookingNotFound)
 	t.Run("InvalidID", TestCancelBookingInvalidID)
+	t.Run("InOrder", testCancelBookingInOrder)
+}
+
+func testCancelBookingInOrder(t *testing.T) {
+	bookingSvc := new(MockBookingService)
+	orderID := uint(7)
+	bookingSvc.On("GetBookingById", uint(1)).Return(&domain.Booking{ID: 1, UserID: 1, OrderID: &orderID}, nil)
+	bookingSvc.On("CancelBooking", uint(1)).Return(nil, domain.ErrBookingInOrder)
+	app := setupBookingApp(bookingSvc, &MockAuthService{}, nil)
+	resp, _ := app.Test(httptest.NewRequest("PUT", "/bookings/1/cancel", nil))
+	assert.Equal(t, fiber.StatusConflict, resp.StatusCode)
 }
 
 func TestCancelBookingSuccess(t *testing.T) {
@@ -282,12 +542,13 @@ func TestCancelBookingSuccess(t *testing.T) {
 
 func TestCancelBookingNotFound(t *testing.T) {
 	svc := new(MockBookingService)
-	svc.On("CancelBooking", uint(1)).Return(nil, errors.New("booking not found"))
+	svc.On("GetBookingById", uint(1)).Return(nil, errors.New("booking not found"))
 	app := setupBookingApp(svc, nil, nil)
 	req := httptest.NewRequest("PUT", "/bookings/1/cancel", nil)
 	resp, _ := app.Test(req)
 	log.Println("resp", resp)
-	assert.Equal(t, 400, resp.StatusCode)
+	assert.Equal(t, 404, resp.StatusCode)
+	svc.AssertNotCalled(t, "CancelBooking", uint(1))
 }
 
 func TestCancelBookingInvalidID(t *testing.T) {
@@ -319,11 +580,12 @@ func TestConfirmBookingSuccess(t *testing.T) {
 
 func TestConfirmBookingNotFound(t *testing.T) {
 	svc := new(MockBookingService)
-	svc.On("ConfirmBooking", uint(1)).Return(nil, errors.New("booking not found"))
+	svc.On("GetBookingById", uint(1)).Return(nil, errors.New("booking not found"))
 	app := setupBookingApp(svc, nil, nil)
 	req := httptest.NewRequest("PUT", "/bookings/1/confirm", nil)
 	resp, _ := app.Test(req)
-	assert.Equal(t, 400, resp.StatusCode)
+	assert.Equal(t, 404, resp.StatusCode)
+	svc.AssertNotCalled(t, "ConfirmBooking", uint(1))
 }
 
 func TestConfirmBookingInvalidID(t *testing.T) {	
@@ -331,4 +593,160 @@ func TestConfirmBookingInvalidID(t *testing.T) {
 	req := httptest.NewRequest("PUT", "/bookings/abc/confirm", nil)
 	resp, _ := app.Test(req)
 	assert.Equal(t, 400, resp.StatusCode)
-}
\ No newline at end of file
+}
+
+// appWithClaims tạo app gán sẵn claims thay cho JWTMiddleware; handler được
+// đăng ký sau đó như trong serve
+func appWithClaims(claims jwt.MapClaims) *fiber.App {
+	app := fiber.New()
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals("user", &jwt.Token{Claims: claims})
+		return c.Next()
+	})
+	return app
+}
+
+// setupBookingRoutesAs đăng ký routes qua NewBookingHandler với claims cho trước
+func setupBookingRoutesAs(bs *MockBookingService, claims jwt.MapClaims) *fiber.App {
+	app := appWithClaims(claims)
+	NewBookingHandler(app, bs)
+	return app
+}
+
+func TestBookingOwnership(t *testing.T) {
+	t.Run("OwnerReads", testBookingOwnerReads)
+	t.Run("OtherCustomerForbidden", testBookingOtherCustomerForbidden)
+	t.Run("OtherCustomerCannotCancel", testBookingOtherCustomerCannotCancel)
+	t.Run("AdminCancelsAny", testBookingAdminCancelsAny)
+	t.Run("CustomerCannotSetStatus", testBookingCustomerCannotSetStatus)
+	t.Run("CustomerCannotConfirmOwn", testBookingCustomerCannotConfirmOwn)
+	t.Run("CustomerCannotListAll", testBookingCustomerCannotListAll)
+}
+
+func customerClaims(id string) jwt.MapClaims {
+	return jwt.MapClaims{"sub": id, "email": "c" + id + "@example.com", "role": "customer"}
+}
+
+func testBookingOwnerReads(t *testing.T) {
+	svc := new(MockBookingService)
+	svc.On("GetBookingById", uint(1)).Return(&domain.Booking{ID: 1, UserID: 5}, nil)
+	app := setupBookingRoutesAs(svc, customerClaims("5"))
+	resp, _ := app.Test(httptest.NewRequest("GET", "/bookings/1", nil))
+	assert.Equal(t, 200, resp.StatusCode)
+}
+
+func testBookingOtherCustomerForbidden(t *testing.T) {
+	svc := new(MockBookingService)
+	svc.On("GetBookingById", uint(1)).Return(&domain.Booking{ID: 1, UserID: 5}, nil)
+	app := setupBookingRoutesAs(svc, customerClaims("6"))
+	resp, _ := app.Test(httptest.NewRequest("GET", "/bookings/1", nil))
+	assert.Equal(t, 403, resp.StatusCode)
+}
+
+func testBookingOtherCustomerCannotCancel(t *testing.T) {
+	svc := new(MockBookingService)
+	svc.On("GetBookingById", uint(1)).Return(&domain.Booking{ID: 1, UserID: 5}, nil)
+	app := setupBookingRoutesAs(svc, customerClaims("6"))
+	resp, _ := app.Test(httptest.NewRequest("PUT", "/bookings/1/cancel", nil))
+	assert.Equal(t, 403, resp.StatusCode)
+	svc.AssertNotCalled(t, "CancelBooking", uint(1))
+}
+
+func testBookingAdminCancelsAny(t *testing.T) {
+	svc := new(MockBookingService)
+	booking := &domain.Booking{ID: 1, UserID: 5, Status: domain.BookingStatusCancelled}
+	svc.On("GetBookingById", uint(1)).Return(booking, nil)
+	svc.On("CancelBooking", uint(1)).Return(booking, nil)
+	app := setupBookingRoutesAs(svc, jwt.MapClaims{"sub": "1", "role": "admin"})
+	resp, _ := app.Test(httptest.NewRequest("PUT", "/bookings/1/cancel", nil))
+	assert.Equal(t, 200, resp.StatusCode)
+}
+
+func testBookingCustomerCannotSetStatus(t *testing.T) {
+	app := setupBookingRoutesAs(new(MockBookingService), customerClaims("5"))
+	body, _ := json.Marshal(map[string]string{"status": "CONFIRMED"})
+	req := httptest.NewRequest("PUT", "/bookings/1", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp, _ := app.Test(req)
+	assert.Equal(t, 403, resp.StatusCode)
+}
+
+// Xác nhận booking bỏ qua payment, nên chủ booking cũng không được tự làm
+func testBookingCustomerCannotConfirmOwn(t *testing.T) {
+	svc := new(MockBookingService)
+	svc.On("GetBookingById", uint(1)).Return(&domain.Booking{ID: 1, UserID: 5}, nil)
+	app := setupBookingRoutesAs(svc, customerClaims("5"))
+	resp, _ := app.Test(httptest.NewRequest("PUT", "/bookings/1/confirm", nil))
+	assert.Equal(t, 403, resp.StatusCode)
+	svc.AssertNotCalled(t, "ConfirmBooking", uint(1))
+}
+
+func testBookingCustomerCannotListAll(t *testing.T) {
+	app := setupBookingRoutesAs(new(MockBookingService), customerClaims("5"))
+	resp, _ := app.Test(httptest.NewRequest("GET", "/bookings", nil))
+	assert.Equal(t, 403, resp.StatusCode)
+}
+
+func TestMyBookings(t *testing.T) {
+	t.Run("List", testListMyBookings)
+	t.Run("ListFiltered", testListMyBookingsFiltered)
+	t.Run("ListInvalidStatus", testListMyBookingsInvalidStatus)
+	t.Run("Get", testGetMyBooking)
+	t.Run("GetOthersBooking", testGetMyBookingOthers)
+}
+
+func testListMyBookings(t *testing.T) {
+	svc := new(MockBookingService)
+	svc.On("ListUserBookings", uint(5), domain.BookingFilter{}, 0, 10).Return([]domain.Booking{
+		{ID: 2, UserID: 5, EventID: 1, Quantity: 1, Status: domain.BookingStatusPending, Event: domain.Event{ID: 1, Name: "Concert"}},
+		{ID: 1, UserID: 5, EventID: 1, Quantity: 2, Status: domain.BookingStatusConfirmed, Event: domain.Event{ID: 1, Name: "Concert"}},
+	}, int64(2), nil)
+	app := setupBookingRoutesAs(svc, customerClaims("5"))
+	resp, _ := app.Test(httptest.NewRequest("GET", "/me/bookings", nil))
+	assert.Equal(t, 200, resp.StatusCode)
+
+	var body struct {
+		Data       []MyBookingResponse `json:"data"`
+		TotalItems int64               `json:"total_items"`
+		TotalPages int                 `json:"total_pages"`
+	}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	assert.Len(t, body.Data, 2)
+	assert.Equal(t, "Concert", body.Data[0].Event.Name)
+	assert.Equal(t, int64(2), body.TotalItems)
+	assert.Equal(t, 1, body.TotalPages)
+	svc.AssertExpectations(t)
+}
+
+func testListMyBookingsFiltered(t *testing.T) {
+	svc := new(MockBookingService)
+	filter := domain.BookingFilter{Status: domain.BookingStatusConfirmed, EventID: 3}
+	svc.On("ListUserBookings", uint(5), filter, 5, 5).Return([]domain.Booking{}, int64(0), nil)
+	app := setupBookingRoutesAs(svc, customerClaims("5"))
+	resp, _ := app.Test(httptest.NewRequest("GET", "/me/bookings?status=confirmed&event_id=3&page=2&limit=5", nil))
+	assert.Equal(t, 200, resp.StatusCode)
+	svc.AssertExpectations(t)
+}
+
+func testListMyBookingsInvalidStatus(t *testing.T) {
+	app := setupBookingRoutesAs(new(MockBookingService), customerClaims("5"))
+	resp, _ := app.Test(httptest.NewRequest("GET", "/me/bookings?status=PAID", nil))
+	assert.Equal(t, 400, resp.StatusCode)
+}
+
+func testGetMyBooking(t *testing.T) {
+	svc := new(MockBookingService)
+	svc.On("GetUserBooking", uint(5), uint(1)).Return(&domain.Booking{ID: 1, UserID: 5}, nil)
+	app := setupBookingRoutesAs(svc, customerClaims("5"))
+	resp, _ := app.Test(httptest.NewRequest("GET", "/me/bookings/1", nil))
+	assert.Equal(t, 200, resp.StatusCode)
+}
+
+func testGetMyBookingOthers(t *testing.T) {
+	svc := new(MockBookingService)
+	svc.On("GetUserBooking", uint(6), uint(1)).Return(nil, domain.ErrNotFound)
+	app := setupBookingRoutesAs(svc, customerClaims("6"))
+	resp, _ := app.Test(httptest.NewRequest("GET", "/me/bookings/1", nil))
+	assert.Equal(t, 404, resp.StatusCode)
+}
+
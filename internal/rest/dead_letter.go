@@ -0,0 +1,76 @@
+package rest
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+
+	"ticket_app/domain"
+	"ticket_app/internal/queue"
+)
+
+// DeadLetterHandler exposes the payment jobs that exhausted their retries to operators
+type DeadLetterHandler struct {
+	deadLetters queue.DeadLetterQueue
+}
+
+func NewDeadLetterHandler(app *fiber.App, deadLetters queue.DeadLetterQueue) *DeadLetterHandler {
+	handler := &DeadLetterHandler{deadLetters: deadLetters}
+
+	app.Get("/admin/payment-jobs/dead", handler.ListDeadLetters)
+	app.Delete("/admin/payment-jobs/dead", handler.PurgeDeadLetters)
+	app.Get("/admin/payment-jobs/dead/:id", handler.GetDeadLetter)
+	app.Post("/admin/payment-jobs/dead/:id/requeue", handler.RequeueDeadLetter)
+	app.Delete("/admin/payment-jobs/dead/:id", handler.DeleteDeadLetter)
+
+	return handler
+}
+
+func (h *DeadLetterHandler) ListDeadLetters(c *fiber.Ctx) error {
+	letters, err := h.deadLetters.ListDeadLetters(c.UserContext())
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to list dead-lettered jobs"})
+	}
+	return c.JSON(fiber.Map{"data": letters, "total": len(letters)})
+}
+
+func (h *DeadLetterHandler) GetDeadLetter(c *fiber.Ctx) error {
+	letter, err := h.deadLetters.GetDeadLetter(c.UserContext(), c.Params("id"))
+	if errors.Is(err, domain.ErrNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Dead-lettered job not found"})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to get dead-lettered job"})
+	}
+	return c.JSON(letter)
+}
+
+func (h *DeadLetterHandler) RequeueDeadLetter(c *fiber.Ctx) error {
+	err := h.deadLetters.RequeueDeadLetter(c.UserContext(), c.Params("id"))
+	if errors.Is(err, domain.ErrNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Dead-lettered job not found"})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to requeue job"})
+	}
+	return c.Status(fiber.StatusAccepted).JSON(fiber.Map{"message": "Job requeued"})
+}
+
+func (h *DeadLetterHandler) DeleteDeadLetter(c *fiber.Ctx) error {
+	err := h.deadLetters.DeleteDeadLetter(c.UserContext(), c.Params("id"))
+	if errors.Is(err, domain.ErrNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Dead-lettered job not found"})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to delete job"})
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+func (h *DeadLetterHandler) PurgeDeadLetters(c *fiber.Ctx) error {
+	purged, err := h.deadLetters.PurgeDeadLetters(c.UserContext())
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to purge dead-lettered jobs"})
+	}
+	return c.JSON(fiber.Map{"purged": purged})
+}
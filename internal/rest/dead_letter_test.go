@@ -0,0 +1,155 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"ticket_app/domain"
+	"ticket_app/internal/queue"
+)
+
+type MockDeadLetterQueue struct {
+	mock.Mock
+}
+
+func (m *MockDeadLetterQueue) ListDeadLetters(ctx context.Context) ([]queue.DeadLetter, error) {
+	args := m.Called()
+	return args.Get(0).([]queue.DeadLetter), args.Error(1)
+}
+
+func (m *MockDeadLetterQueue) GetDeadLetter(ctx context.Context, id string) (*queue.DeadLetter, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*queue.DeadLetter), args.Error(1)
+}
+
+func (m *MockDeadLetterQueue) RequeueDeadLetter(ctx context.Context, id string) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func (m *MockDeadLetterQueue) DeleteDeadLetter(ctx context.Context, id string) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func (m *MockDeadLetterQueue) PurgeDeadLetters(ctx context.Context) (int64, error) {
+	args := m.Called()
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func setupDeadLetterApp(q *MockDeadLetterQueue) *fiber.App {
+	app := fiber.New()
+	NewDeadLetterHandler(app, q)
+	return app
+}
+
+func TestDeadLetters(t *testing.T) {
+	t.Run("List", testListDeadLetters)
+	t.Run("Get", testGetDeadLetter)
+	t.Run("GetNotFound", testGetDeadLetterNotFound)
+	t.Run("Requeue", testRequeueDeadLetter)
+	t.Run("RequeueNotFound", testRequeueDeadLetterNotFound)
+	t.Run("Delete", testDeleteDeadLetter)
+	t.Run("Purge", testPurgeDeadLetters)
+	t.Run("ServiceError", testListDeadLettersServiceError)
+}
+
+func testListDeadLetters(t *testing.T) {
+	q := new(MockDeadLetterQueue)
+	app := setupDeadLetterApp(q)
+	q.On("ListDeadLetters").Return([]queue.DeadLetter{
+		{Job: queue.PaymentJob{ID: "job-1", BookingID: 1, Attempts: 5, LastError: "payment not found or error"}, FailedAt: time.Now()},
+	}, nil)
+	req := httptest.NewRequest("GET", "/admin/payment-jobs/dead", nil)
+	resp, _ := app.Test(req)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+	var body struct {
+		Data  []queue.DeadLetter `json:"data"`
+		Total int                `json:"total"`
+	}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	assert.Equal(t, 1, body.Total)
+	assert.Equal(t, "job-1", body.Data[0].Job.ID)
+	assert.Equal(t, 5, body.Data[0].Job.Attempts)
+}
+
+func testGetDeadLetter(t *testing.T) {
+	q := new(MockDeadLetterQueue)
+	app := setupDeadLetterApp(q)
+	q.On("GetDeadLetter", "job-1").Return(&queue.DeadLetter{Job: queue.PaymentJob{ID: "job-1", BookingID: 1}}, nil)
+	req := httptest.NewRequest("GET", "/admin/payment-jobs/dead/job-1", nil)
+	resp, _ := app.Test(req)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+}
+
+func testGetDeadLetterNotFound(t *testing.T) {
+	q := new(MockDeadLetterQueue)
+	app := setupDeadLetterApp(q)
+	q.On("GetDeadLetter", "missing").Return(nil, domain.ErrNotFound)
+	req := httptest.NewRequest("GET", "/admin/payment-jobs/dead/missing", nil)
+	resp, _ := app.Test(req)
+	assert.Equal(t, fiber.StatusNotFound, resp.StatusCode)
+}
+
+func testRequeueDeadLetter(t *testing.T) {
+	q := new(MockDeadLetterQueue)
+	app := setupDeadLetterApp(q)
+	q.On("RequeueDeadLetter", "job-1").Return(nil)
+	req := httptest.NewRequest("POST", "/admin/payment-jobs/dead/job-1/requeue", nil)
+	resp, _ := app.Test(req)
+	assert.Equal(t, fiber.StatusAccepted, resp.StatusCode)
+	q.AssertExpectations(t)
+}
+
+func testRequeueDeadLetterNotFound(t *testing.T) {
+	q := new(MockDeadLetterQueue)
+	app := setupDeadLetterApp(q)
+	q.On("RequeueDeadLetter", "missing").Return(domain.ErrNotFound)
+	req := httptest.NewRequest("POST", "/admin/payment-jobs/dead/missing/requeue", nil)
+	resp, _ := app.Test(req)
+	assert.Equal(t, fiber.StatusNotFound, resp.StatusCode)
+}
+
+func testDeleteDeadLetter(t *testing.T) {
+	q := new(MockDeadLetterQueue)
+	app := setupDeadLetterApp(q)
+	q.On("DeleteDeadLetter", "job-1").Return(nil)
+	req := httptest.NewRequest("DELETE", "/admin/payment-jobs/dead/job-1", nil)
+	resp, _ := app.Test(req)
+	assert.Equal(t, fiber.StatusNoContent, resp.StatusCode)
+	q.AssertExpectations(t)
+}
+
+func testPurgeDeadLetters(t *testing.T) {
+	q := new(MockDeadLetterQueue)
+	app := setupDeadLetterApp(q)
+	q.On("PurgeDeadLetters").Return(int64(3), nil)
+	req := httptest.NewRequest("DELETE", "/admin/payment-jobs/dead", nil)
+	resp, _ := app.Test(req)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+	var body map[string]int64
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	assert.Equal(t, int64(3), body["purged"])
+}
+
+func testListDeadLettersServiceError(t *testing.T) {
+	q := new(MockDeadLetterQueue)
+	app := setupDeadLetterApp(q)
+	q.On("ListDeadLetters").Return([]queue.DeadLetter(nil), errors.New("redis down"))
+	req := httptest.NewRequest("GET", "/admin/payment-jobs/dead", nil)
+	resp, _ := app.Test(req)
+	assert.Equal(t, fiber.StatusInternalServerError, resp.StatusCode)
+}
@@ -1,11 +1,13 @@
 package rest
 
 import (
+	"errors"
 	"strconv"
 	"time"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
 
 	"ticket_app/domain"
 	event "ticket_app/event"
@@ -17,20 +19,33 @@ type EventHandler struct {
 	validate     *validator.Validate
 }
 
-func NewEventHandler(app *fiber.App, eventService event.EventService) *EventHandler {
+// NewEventHandler đăng ký các route event và hạng vé. Xem event và hạng vé là
+// public; tạo, sửa, xoá và xem thống kê cần requireAuth và role organizer hoặc admin.
+func NewEventHandler(app *fiber.App, eventService event.EventService, requireAuth fiber.Handler) *EventHandler {
 	// Khởi tạo handler với eventService và validate
 	handler := &EventHandler{
 		eventService: eventService,
 		validate:     validator.New(),
 	}
 
+	managers := middleware.RequireRole(domain.RoleOrganizer, domain.RoleAdmin)
+	admins := middleware.RequireRole(domain.RoleAdmin)
+
 	// Đăng ký routes
-	app.Post("/events", handler.CreateEvent)
+	app.Post("/events", requireAuth, managers, handler.CreateEvent)
 	app.Get("/events", handler.GetAllEvents)
 	app.Get("/events/remaining-tickets", handler.GetEventsWithRemainingTickets)
+	app.Get("/events/stats", requireAuth, admins, handler.GetAllEventStats)
+	app.Get("/events/:id/stats", requireAuth, managers, handler.GetEventStats)
 	app.Get("/events/:id", handler.GetEventById)
-	app.Put("/events/:id", handler.UpdateEvent)
-	app.Delete("/events/:id", handler.DeleteEvent)
+	app.Put("/events/:id", requireAuth, managers, handler.UpdateEvent)
+	app.Delete("/events/:id", requireAuth, managers, handler.DeleteEvent)
+
+	app.Get("/events/:id/ticket-types", handler.ListTicketTypes)
+	app.Post("/events/:id/ticket-types", requireAuth, managers, handler.CreateTicketType)
+	app.Get("/events/:id/ticket-types/:typeId", handler.GetTicketType)
+	app.Put("/events/:id/ticket-types/:typeId", requireAuth, managers, handler.UpdateTicketType)
+	app.Delete("/events/:id/ticket-types/:typeId", requireAuth, managers, handler.DeleteTicketType)
 
 	return handler
 }
@@ -42,6 +57,8 @@ type CreateEventRequest struct {
 	EndDate     string  `json:"end_date"`
 	TotalTickets int    `json:"total_tickets" validate:"required"`
 	TicketPrice float64 `json:"ticket_price" validate:"required"`
+	VenueID     *uint   `json:"venue_id"`
+	HoldMinutes int     `json:"hold_minutes" validate:"omitempty,min=1,max=60"`
 }
 
 type EventResponse struct {
@@ -54,6 +71,18 @@ type EventResponse struct {
 	TicketPrice float64   `json:"ticket_price"`
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
+	OrganizerID *uint     `json:"organizer_id,omitempty"`
+	VenueID     *uint     `json:"venue_id,omitempty"`
+	HoldMinutes int       `json:"hold_minutes,omitempty"`
+}
+
+// canManage cho biết user có được sửa, xoá và xem thống kê của event không:
+// admin quản lý mọi event, organizer chỉ event do mình tạo
+func canManage(user middleware.Identity, event *domain.Event) bool {
+	if user.IsAdmin() {
+		return true
+	}
+	return user.UserID != 0 && event.OrganizerID != nil && *event.OrganizerID == user.UserID
 }
 
 func (h *EventHandler) CreateEvent(c *fiber.Ctx) error {
@@ -70,9 +99,14 @@ func (h *EventHandler) CreateEvent(c *fiber.Ctx) error {
 		EndDate:     time.Time{}, // Sẽ được xử lý trong service
 		TotalTickets: req.TotalTickets,
 		TicketPrice: req.TicketPrice,
+		VenueID:     req.VenueID,
+		HoldMinutes: req.HoldMinutes,
+	}
+	if user, ok := middleware.CurrentUser(c); ok && user.UserID != 0 {
+		event.OrganizerID = &user.UserID
 	}
 
-	err := h.eventService.CreateEvent(&event)
+	err := h.eventService.CreateEvent(c.UserContext(), &event)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to create event", "details": err.Error()})
 	}
@@ -87,11 +121,14 @@ func (h *EventHandler) CreateEvent(c *fiber.Ctx) error {
 		TicketPrice: event.TicketPrice,
 		CreatedAt:   event.CreatedAt,
 		UpdatedAt:   event.UpdatedAt,
+		OrganizerID: event.OrganizerID,
+		VenueID:     event.VenueID,
+		HoldMinutes: event.HoldMinutes,
 	})
 }
 
 func (h *EventHandler) GetAllEvents(c *fiber.Ctx) error {
-	events, err := h.eventService.GetAllEvents()
+	events, err := h.eventService.GetAllEvents(c.UserContext())
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to get events"})
 	}
@@ -108,6 +145,9 @@ func (h *EventHandler) GetAllEvents(c *fiber.Ctx) error {
 			TicketPrice: e.TicketPrice,
 			CreatedAt:   e.CreatedAt,
 			UpdatedAt:   e.UpdatedAt,
+			OrganizerID: e.OrganizerID,
+			VenueID:     e.VenueID,
+			HoldMinutes: e.HoldMinutes,
 		})
 	}
 	return c.JSON(eventResponses)
@@ -118,7 +158,7 @@ func (h *EventHandler) GetEventsWithRemainingTickets(c *fiber.Ctx) error {
 		Page: c.QueryInt("page", 1),
 		Limit: c.QueryInt("limit", 10),
 	}
-	events, err := h.eventService.GetEventsWithRemainingTickets(pagination)
+	events, err := h.eventService.GetEventsWithRemainingTickets(c.UserContext(), pagination)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to get events with remaining tickets"})
 	}
@@ -132,7 +172,7 @@ func (h *EventHandler) GetEventById(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid event ID"})
 	}
 
-	event, err := h.eventService.GetEventById(uint(id))
+	event, err := h.eventService.GetEventById(c.UserContext(), uint(id))
 	if err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Event not found"})
 	}
@@ -150,6 +190,9 @@ func (h *EventHandler) GetEventById(c *fiber.Ctx) error {
 		TicketPrice: event.TicketPrice,
 		CreatedAt:   event.CreatedAt,
 		UpdatedAt:   event.UpdatedAt,
+		OrganizerID: event.OrganizerID,
+		VenueID:     event.VenueID,
+		HoldMinutes: event.HoldMinutes,
 	})
 }
 
@@ -165,10 +208,19 @@ func (h *EventHandler) UpdateEvent(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
 	}
 
-	// Set the ID from the URL parameter
+	existing, err := h.eventService.GetEventById(c.UserContext(), uint(id))
+	if err != nil || existing == nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Event not found"})
+	}
+	if user, _ := middleware.CurrentUser(c); !canManage(user, existing) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "You can only manage your own events"})
+	}
+
+	// Set the ID from the URL parameter; organizer không đổi được qua body
 	event.ID = uint(id)
+	event.OrganizerID = existing.OrganizerID
 
-	err = h.eventService.UpdateEvent(&event)
+	err = h.eventService.UpdateEvent(c.UserContext(), &event)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update event", "details": err.Error()})
 	}
@@ -183,6 +235,9 @@ func (h *EventHandler) UpdateEvent(c *fiber.Ctx) error {
 		TicketPrice: event.TicketPrice,
 		CreatedAt:   event.CreatedAt,
 		UpdatedAt:   event.UpdatedAt,
+		OrganizerID: event.OrganizerID,
+		VenueID:     event.VenueID,
+		HoldMinutes: event.HoldMinutes,
 	})
 }
 
@@ -193,10 +248,241 @@ func (h *EventHandler) DeleteEvent(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid event ID"})
 	}
 
-	err = h.eventService.DeleteEvent(uint(id))
+	if resp, ok := h.requireOwnEvent(c, uint(id)); !ok {
+		return resp
+	}
+
+	err = h.eventService.DeleteEvent(c.UserContext(), uint(id))
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to delete event", "details": err.Error()})
 	}
 
 	return c.Status(fiber.StatusNoContent).JSON(fiber.Map{"message": "Event deleted successfully"})
-}
\ No newline at end of file
+}
+
+func (h *EventHandler) GetEventStats(c *fiber.Ctx) error {
+	idParam := c.Params("id")
+	id, err := strconv.ParseUint(idParam, 10, 32)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid event ID"})
+	}
+	if resp, ok := h.requireOwnEvent(c, uint(id)); !ok {
+		return resp
+	}
+	stats, err := h.eventService.GetEventStats(c.UserContext(), uint(id))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Event not found"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to get event stats"})
+	}
+	return c.JSON(stats)
+}
+
+func (h *EventHandler) GetAllEventStats(c *fiber.Ctx) error {
+	page := c.QueryInt("page", 1)
+	if page < 1 {
+		page = 1
+	}
+	limit := c.QueryInt("limit", 10)
+	if limit < 1 || limit > 100 {
+		limit = 10
+	}
+	pagination := middleware.Pagination{
+		Page:   page,
+		Limit:  limit,
+		Offset: (page - 1) * limit,
+	}
+	stats, err := h.eventService.GetAllEventStats(c.UserContext(), pagination)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to get event stats"})
+	}
+	return c.JSON(stats)
+}
+
+// requireOwnEvent cho admin đi tiếp ngay; organizer phải là người tạo event.
+// Khi không được đi tiếp, response lỗi đã được ghi và ok là false.
+func (h *EventHandler) requireOwnEvent(c *fiber.Ctx, id uint) (resp error, ok bool) {
+	user, _ := middleware.CurrentUser(c)
+	if user.IsAdmin() {
+		return nil, true
+	}
+	event, err := h.eventService.GetEventById(c.UserContext(), id)
+	if err != nil || event == nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Event not found"}), false
+	}
+	if !canManage(user, event) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "You can only manage your own events"}), false
+	}
+	return nil, true
+}
+
+// TicketTypeRequest tạo hoặc thay toàn bộ một hạng vé. Price và Quota bắt
+// buộc nhưng có thể là 0; MinPerOrder mặc định 1, MaxPerOrder 0 là không giới hạn.
+// SectionID gắn hạng vé với một khu ghế của venue khi event có sơ đồ ghế.
+type TicketTypeRequest struct {
+	Name        string     `json:"name" validate:"required,max=100"`
+	Price       *float64   `json:"price" validate:"required,min=0"`
+	Quota       *int       `json:"quota" validate:"required,min=0"`
+	MinPerOrder int        `json:"min_per_order" validate:"omitempty,min=1"`
+	MaxPerOrder int        `json:"max_per_order" validate:"omitempty,min=1"`
+	SalesStart  *time.Time `json:"sales_start"`
+	SalesEnd    *time.Time `json:"sales_end"`
+	SectionID   *uint      `json:"section_id"`
+}
+
+func (r *TicketTypeRequest) toDomain(eventID uint) domain.TicketType {
+	return domain.TicketType{
+		EventID:     eventID,
+		Name:        r.Name,
+		Price:       *r.Price,
+		Quota:       *r.Quota,
+		MinPerOrder: r.MinPerOrder,
+		MaxPerOrder: r.MaxPerOrder,
+		SalesStart:  r.SalesStart,
+		SalesEnd:    r.SalesEnd,
+		SectionID:   r.SectionID,
+	}
+}
+
+// TicketTypeResponse là hạng vé kèm số vé còn có thể đặt
+type TicketTypeResponse struct {
+	domain.TicketType
+	Available int `json:"available"`
+}
+
+func newTicketTypeResponse(t *domain.TicketType) TicketTypeResponse {
+	return TicketTypeResponse{TicketType: *t, Available: t.Available()}
+}
+
+func (h *EventHandler) ListTicketTypes(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid event ID"})
+	}
+	if event, err := h.eventService.GetEventById(c.UserContext(), uint(id)); err != nil || event == nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Event not found"})
+	}
+	ticketTypes, err := h.eventService.ListTicketTypes(c.UserContext(), uint(id))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to get ticket types"})
+	}
+	responses := make([]TicketTypeResponse, len(ticketTypes))
+	for i := range ticketTypes {
+		responses[i] = newTicketTypeResponse(&ticketTypes[i])
+	}
+	return c.JSON(responses)
+}
+
+func (h *EventHandler) GetTicketType(c *fiber.Ctx) error {
+	eventID, id, resp, ok := ticketTypeParams(c)
+	if !ok {
+		return resp
+	}
+	ticketType, err := h.eventService.GetTicketType(c.UserContext(), eventID, id)
+	if err != nil {
+		return ticketTypeError(c, err, "Failed to get ticket type")
+	}
+	return c.JSON(newTicketTypeResponse(ticketType))
+}
+
+func (h *EventHandler) CreateTicketType(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid event ID"})
+	}
+	req, resp, ok := h.parseTicketType(c)
+	if !ok {
+		return resp
+	}
+	// Kể cả admin cũng cần event tồn tại trước khi thêm hạng vé
+	event, err := h.eventService.GetEventById(c.UserContext(), uint(id))
+	if err != nil || event == nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Event not found"})
+	}
+	if user, _ := middleware.CurrentUser(c); !canManage(user, event) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "You can only manage your own events"})
+	}
+
+	ticketType := req.toDomain(uint(id))
+	if err := h.eventService.CreateTicketType(c.UserContext(), &ticketType); err != nil {
+		return ticketTypeError(c, err, "Failed to create ticket type")
+	}
+	return c.Status(fiber.StatusCreated).JSON(newTicketTypeResponse(&ticketType))
+}
+
+func (h *EventHandler) UpdateTicketType(c *fiber.Ctx) error {
+	eventID, id, resp, ok := ticketTypeParams(c)
+	if !ok {
+		return resp
+	}
+	req, resp, ok := h.parseTicketType(c)
+	if !ok {
+		return resp
+	}
+	if resp, ok := h.requireOwnEvent(c, eventID); !ok {
+		return resp
+	}
+
+	ticketType := req.toDomain(eventID)
+	ticketType.ID = id
+	if err := h.eventService.UpdateTicketType(c.UserContext(), &ticketType); err != nil {
+		return ticketTypeError(c, err, "Failed to update ticket type")
+	}
+	return c.JSON(newTicketTypeResponse(&ticketType))
+}
+
+func (h *EventHandler) DeleteTicketType(c *fiber.Ctx) error {
+	eventID, id, resp, ok := ticketTypeParams(c)
+	if !ok {
+		return resp
+	}
+	if resp, ok := h.requireOwnEvent(c, eventID); !ok {
+		return resp
+	}
+	if err := h.eventService.DeleteTicketType(c.UserContext(), eventID, id); err != nil {
+		return ticketTypeError(c, err, "Failed to delete ticket type")
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// parseTicketType đọc và kiểm tra body; khi không hợp lệ, response lỗi đã được ghi và ok là false
+func (h *EventHandler) parseTicketType(c *fiber.Ctx) (req *TicketTypeRequest, resp error, ok bool) {
+	req = new(TicketTypeRequest)
+	if err := c.BodyParser(req); err != nil {
+		return nil, c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"}), false
+	}
+	if err := h.validate.Struct(req); err != nil {
+		return nil, c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Validation failed", "details": err.Error()}), false
+	}
+	return req, nil, true
+}
+
+// ticketTypeParams đọc :id và :typeId của route hạng vé. Khi không hợp lệ,
+// response lỗi đã được ghi và ok là false.
+func ticketTypeParams(c *fiber.Ctx) (eventID uint, id uint, resp error, ok bool) {
+	event, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return 0, 0, c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid event ID"}), false
+	}
+	ticketType, err := strconv.ParseUint(c.Params("typeId"), 10, 32)
+	if err != nil {
+		return 0, 0, c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid ticket type ID"}), false
+	}
+	return uint(event), uint(ticketType), nil, true
+}
+
+// ticketTypeError ghi response cho lỗi từ các method hạng vé của EventService
+func ticketTypeError(c *fiber.Ctx, err error, message string) error {
+	switch {
+	case errors.Is(err, domain.ErrBadParamInput):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, domain.ErrNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Ticket type not found"})
+	case errors.Is(err, domain.ErrConflict):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "The event already has a ticket type with this name or section"})
+	case errors.Is(err, domain.ErrQuotaBelowReserved), errors.Is(err, domain.ErrTicketTypeInUse):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": message})
+}
@@ -2,8 +2,10 @@ package rest
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"log"
 	"net/http/httptest"
 	"testing"
@@ -13,49 +15,100 @@ import (
 	middleware "ticket_app/internal/rest/middleware"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
 )
 
 type MockEventService struct {
 	mock.Mock
 }
 
-func (m *MockEventService) CreateEvent(event *domain.Event) error {
+func (m *MockEventService) CreateEvent(ctx context.Context, event *domain.Event) error {
 	args := m.Called(event)
 	return args.Error(0)
 }
 
-func (m *MockEventService) GetAllEvents() ([]domain.Event, error) {
+func (m *MockEventService) GetAllEvents(ctx context.Context) ([]domain.Event, error) {
 	args := m.Called()
 	return args.Get(0).([]domain.Event), args.Error(1)
 }
 
-func (m *MockEventService) GetEventById(id uint) (*domain.Event, error) {
+func (m *MockEventService) GetEventById(ctx context.Context, id uint) (*domain.Event, error) {
 	args := m.Called(id)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
 	return args.Get(0).(*domain.Event), args.Error(1)
 }
-func (m *MockEventService) UpdateEvent(event *domain.Event) error {
+func (m *MockEventService) UpdateEvent(ctx context.Context, event *domain.Event) error {
 	args := m.Called(event)
 	return args.Error(0)
 }
 
-func (m *MockEventService) DeleteEvent(id uint) error {
+func (m *MockEventService) DeleteEvent(ctx context.Context, id uint) error {
 	args := m.Called(id)
 	return args.Error(0)
 }
 
-func (m *MockEventService) GetEventsWithRemainingTickets(pagination middleware.Pagination) (middleware.PaginatedResponse, error) {
+func (m *MockEventService) GetEventsWithRemainingTickets(ctx context.Context, pagination middleware.Pagination) (middleware.PaginatedResponse, error) {
 	args := m.Called(pagination)
 	return args.Get(0).(middleware.PaginatedResponse), args.Error(1)
 }
 
+func (m *MockEventService) GetEventStats(ctx context.Context, id uint) (*domain.EventStats, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.EventStats), args.Error(1)
+}
+
+func (m *MockEventService) GetAllEventStats(ctx context.Context, pagination middleware.Pagination) (middleware.PaginatedResponse, error) {
+	args := m.Called(pagination)
+	return args.Get(0).(middleware.PaginatedResponse), args.Error(1)
+}
+
+func (m *MockEventService) ListTicketTypes(ctx context.Context, eventID uint) ([]domain.TicketType, error) {
+	args := m.Called(eventID)
+	return args.Get(0).([]domain.TicketType), args.Error(1)
+}
+
+func (m *MockEventService) GetTicketType(ctx context.Context, eventID, id uint) (*domain.TicketType, error) {
+	args := m.Called(eventID, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.TicketType), args.Error(1)
+}
+
+func (m *MockEventService) CreateTicketType(ctx context.Context, ticketType *domain.TicketType) error {
+	return m.Called(ticketType).Error(0)
+}
+
+func (m *MockEventService) UpdateTicketType(ctx context.Context, ticketType *domain.TicketType) error {
+	return m.Called(ticketType).Error(0)
+}
+
+func (m *MockEventService) DeleteTicketType(ctx context.Context, eventID, id uint) error {
+	return m.Called(eventID, id).Error(0)
+}
+
 func setupEventApp(svc *MockEventService) *fiber.App {
+	return setupEventAppAs(svc, jwt.MapClaims{"sub": "1", "email": "admin@example.com", "role": "admin"})
+}
+
+// setupEventAppAs thay JWTMiddleware bằng một middleware gán sẵn claims; claims nil là chưa đăng nhập
+func setupEventAppAs(svc *MockEventService, claims jwt.MapClaims) *fiber.App {
 	app := fiber.New()
-	NewEventHandler(app, svc)
+	NewEventHandler(app, svc, func(c *fiber.Ctx) error {
+		if claims == nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Missing authorization header"})
+		}
+		c.Locals("user", &jwt.Token{Claims: claims})
+		return c.Next()
+	})
 	return app
 }
 
@@ -205,6 +258,7 @@ func TestUpdateEvent(t *testing.T) {
 func testUpdateEventSuccess(t *testing.T) {
 	mock_event := new(MockEventService)
 	app := setupEventApp(mock_event)
+	mock_event.On("GetEventById", uint(1)).Return(&domain.Event{ID: 1}, nil)
 	mock_event.On("UpdateEvent", mock.Anything).Return(nil)
 	body, _ := json.Marshal(domain.Event{Name: "Updated"})
 	req := httptest.NewRequest("PUT", "/events/1", bytes.NewReader(body))
@@ -225,6 +279,7 @@ func testUpdateEventInvalidBody(t *testing.T) {
 func testUpdateEventServiceError(t *testing.T) {
 	mock_event := new(MockEventService)
 	app := setupEventApp(mock_event)
+	mock_event.On("GetEventById", uint(1)).Return(&domain.Event{ID: 1}, nil)
 	mock_event.On("UpdateEvent", mock.Anything).Return(errors.New("fail"))
 	body, _ := json.Marshal(domain.Event{Name: "Updated"})
 	req := httptest.NewRequest("PUT", "/events/1", bytes.NewReader(body))
@@ -291,4 +346,307 @@ func testGetEventsWithRemainingTicketsServiceError(t *testing.T) {
 	req := httptest.NewRequest("GET", "/events/remaining-tickets", nil)
 	resp, _ := app.Test(req)
 	assert.Equal(t, fiber.StatusInternalServerError, resp.StatusCode)
-}
\ No newline at end of file
+}
+
+func TestGetEventStats(t *testing.T) {
+	t.Run("Success", testGetEventStatsSuccess)
+	t.Run("NotFound", testGetEventStatsNotFound)
+	t.Run("InvalidID", testGetEventStatsInvalidID)
+	t.Run("All", testGetAllEventStatsSuccess)
+	t.Run("AllServiceError", testGetAllEventStatsServiceError)
+}
+
+func testGetEventStatsSuccess(t *testing.T) {
+	mock_event := new(MockEventService)
+	app := setupEventApp(mock_event)
+	mock_event.On("GetEventStats", uint(1)).Return(&domain.EventStats{
+		EventID: 1, EventName: "Concert", TicketsSold: 3, ConfirmedRevenue: 150,
+		TotalBookings: 2, ConfirmedBookings: 1, ConversionRate: 0.5,
+	}, nil)
+	req := httptest.NewRequest("GET", "/events/1/stats", nil)
+	resp, _ := app.Test(req)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+	var stats domain.EventStats
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&stats))
+	assert.Equal(t, int64(3), stats.TicketsSold)
+	assert.Equal(t, 150.0, stats.ConfirmedRevenue)
+	assert.Equal(t, 0.5, stats.ConversionRate)
+}
+
+func testGetEventStatsNotFound(t *testing.T) {
+	mock_event := new(MockEventService)
+	app := setupEventApp(mock_event)
+	mock_event.On("GetEventStats", uint(99)).Return(nil, gorm.ErrRecordNotFound)
+	req := httptest.NewRequest("GET", "/events/99/stats", nil)
+	resp, _ := app.Test(req)
+	assert.Equal(t, fiber.StatusNotFound, resp.StatusCode)
+}
+
+func testGetEventStatsInvalidID(t *testing.T) {
+	app := setupEventApp(new(MockEventService))
+	req := httptest.NewRequest("GET", "/events/abc/stats", nil)
+	resp, _ := app.Test(req)
+	assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+}
+
+func testGetAllEventStatsSuccess(t *testing.T) {
+	mock_event := new(MockEventService)
+	app := setupEventApp(mock_event)
+	mock_event.On("GetAllEventStats", middleware.Pagination{Page: 2, Limit: 5, Offset: 5}).Return(middleware.PaginatedResponse{
+		Data:        []domain.EventStats{{EventID: 6, EventName: "Concert"}},
+		CurrentPage: 2,
+		TotalPages:  2,
+		TotalItems:  6,
+	}, nil)
+	req := httptest.NewRequest("GET", "/events/stats?page=2&limit=5", nil)
+	resp, _ := app.Test(req)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	mock_event.AssertExpectations(t)
+}
+
+func testGetAllEventStatsServiceError(t *testing.T) {
+	mock_event := new(MockEventService)
+	app := setupEventApp(mock_event)
+	mock_event.On("GetAllEventStats", mock.Anything).Return(middleware.PaginatedResponse{}, errors.New("error"))
+	req := httptest.NewRequest("GET", "/events/stats", nil)
+	resp, _ := app.Test(req)
+	assert.Equal(t, fiber.StatusInternalServerError, resp.StatusCode)
+}
+
+func TestEventAccessControl(t *testing.T) {
+	t.Run("PublicRead", testEventPublicRead)
+	t.Run("CreateNeedsLogin", testCreateEventNeedsLogin)
+	t.Run("CustomerCannotCreate", testCustomerCannotCreateEvent)
+	t.Run("OrganizerOwnsCreatedEvent", testOrganizerOwnsCreatedEvent)
+	t.Run("OrganizerCannotUpdateOthers", testOrganizerCannotUpdateOthersEvent)
+	t.Run("OrganizerDeletesOwn", testOrganizerDeletesOwnEvent)
+	t.Run("OrganizerCannotListAllStats", testOrganizerCannotListAllStats)
+}
+
+func organizerClaims(id string) jwt.MapClaims {
+	return jwt.MapClaims{"sub": id, "email": "org@example.com", "role": "organizer"}
+}
+
+func testEventPublicRead(t *testing.T) {
+	mock_event := new(MockEventService)
+	mock_event.On("GetEventById", uint(1)).Return(&domain.Event{ID: 1, Name: "Concert"}, nil)
+	app := setupEventAppAs(mock_event, nil)
+	resp, _ := app.Test(httptest.NewRequest("GET", "/events/1", nil))
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+}
+
+func testCreateEventNeedsLogin(t *testing.T) {
+	app := setupEventAppAs(new(MockEventService), nil)
+	req := httptest.NewRequest("POST", "/events", bytes.NewReader([]byte(`{}`)))
+	req.Header.Set("Content-Type", "application/json")
+	resp, _ := app.Test(req)
+	assert.Equal(t, fiber.StatusUnauthorized, resp.StatusCode)
+}
+
+func testCustomerCannotCreateEvent(t *testing.T) {
+	mock_event := new(MockEventService)
+	app := setupEventAppAs(mock_event, jwt.MapClaims{"sub": "3", "role": "customer"})
+	body, _ := json.Marshal(CreateEventRequest{Name: "Concert", Description: "Live", TotalTickets: 100, TicketPrice: 50.0})
+	req := httptest.NewRequest("POST", "/events", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp, _ := app.Test(req)
+	assert.Equal(t, fiber.StatusForbidden, resp.StatusCode)
+	mock_event.AssertNotCalled(t, "CreateEvent", mock.Anything)
+}
+
+func testOrganizerOwnsCreatedEvent(t *testing.T) {
+	mock_event := new(MockEventService)
+	mock_event.On("CreateEvent", mock.MatchedBy(func(e *domain.Event) bool {
+		return e.OrganizerID != nil && *e.OrganizerID == 7
+	})).Return(nil)
+	app := setupEventAppAs(mock_event, organizerClaims("7"))
+	body, _ := json.Marshal(CreateEventRequest{Name: "Concert", Description: "Live", TotalTickets: 100, TicketPrice: 50.0})
+	req := httptest.NewRequest("POST", "/events", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp, _ := app.Test(req)
+	assert.Equal(t, fiber.StatusCreated, resp.StatusCode)
+	mock_event.AssertExpectations(t)
+}
+
+func testOrganizerCannotUpdateOthersEvent(t *testing.T) {
+	mock_event := new(MockEventService)
+	owner := uint(8)
+	mock_event.On("GetEventById", uint(1)).Return(&domain.Event{ID: 1, OrganizerID: &owner}, nil)
+	app := setupEventAppAs(mock_event, organizerClaims("7"))
+	body, _ := json.Marshal(domain.Event{Name: "Updated"})
+	req := httptest.NewRequest("PUT", "/events/1", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp, _ := app.Test(req)
+	assert.Equal(t, fiber.StatusForbidden, resp.StatusCode)
+	mock_event.AssertNotCalled(t, "UpdateEvent", mock.Anything)
+}
+
+func testOrganizerDeletesOwnEvent(t *testing.T) {
+	mock_event := new(MockEventService)
+	owner := uint(7)
+	mock_event.On("GetEventById", uint(1)).Return(&domain.Event{ID: 1, OrganizerID: &owner}, nil)
+	mock_event.On("DeleteEvent", uint(1)).Return(nil)
+	app := setupEventAppAs(mock_event, organizerClaims("7"))
+	resp, _ := app.Test(httptest.NewRequest("DELETE", "/events/1", nil))
+	assert.Equal(t, fiber.StatusNoContent, resp.StatusCode)
+}
+
+func testOrganizerCannotListAllStats(t *testing.T) {
+	app := setupEventAppAs(new(MockEventService), organizerClaims("7"))
+	resp, _ := app.Test(httptest.NewRequest("GET", "/events/stats", nil))
+	assert.Equal(t, fiber.StatusForbidden, resp.StatusCode)
+}
+
+func TestTicketTypes(t *testing.T) {
+	t.Run("List", testListTicketTypes)
+	t.Run("ListUnknownEvent", testListTicketTypesUnknownEvent)
+	t.Run("Get", testGetTicketType)
+	t.Run("GetNotFound", testGetTicketTypeNotFound)
+	t.Run("OrganizerCreates", testOrganizerCreatesTicketType)
+	t.Run("CreateValidation", testCreateTicketTypeValidation)
+	t.Run("CreateDuplicateName", testCreateTicketTypeDuplicateName)
+	t.Run("OrganizerCannotCreateForOthers", testOrganizerCannotCreateTicketTypeForOthers)
+	t.Run("CustomerCannotCreate", testCustomerCannotCreateTicketType)
+	t.Run("UpdateQuotaBelowReserved", testUpdateTicketTypeQuotaBelowReserved)
+	t.Run("Delete", testDeleteTicketType)
+	t.Run("DeleteInUse", testDeleteTicketTypeInUse)
+}
+
+const vipTicketType = `{"name":"VIP","price":1500000,"quota":50,"max_per_order":4}`
+
+func testListTicketTypes(t *testing.T) {
+	mock_event := new(MockEventService)
+	mock_event.On("GetEventById", uint(1)).Return(&domain.Event{ID: 1}, nil)
+	mock_event.On("ListTicketTypes", uint(1)).Return([]domain.TicketType{
+		{ID: 1, EventID: 1, Name: "Standard", Price: 500000, Quota: 200, Reserved: 150, MinPerOrder: 1},
+		{ID: 2, EventID: 1, Name: "VIP", Price: 1500000, Quota: 50, Reserved: 50, MinPerOrder: 1},
+	}, nil)
+	app := setupEventAppAs(mock_event, nil)
+	resp := jsonRequest(app, "GET", "/events/1/ticket-types", "")
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	var result []TicketTypeResponse
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+	assert.Len(t, result, 2)
+	assert.Equal(t, "Standard", result[0].Name)
+	assert.Equal(t, 50, result[0].Available)
+	assert.Equal(t, 0, result[1].Available)
+}
+
+func testListTicketTypesUnknownEvent(t *testing.T) {
+	mock_event := new(MockEventService)
+	mock_event.On("GetEventById", uint(9)).Return(nil, gorm.ErrRecordNotFound)
+	app := setupEventAppAs(mock_event, nil)
+	resp := jsonRequest(app, "GET", "/events/9/ticket-types", "")
+	assert.Equal(t, fiber.StatusNotFound, resp.StatusCode)
+}
+
+func testGetTicketType(t *testing.T) {
+	mock_event := new(MockEventService)
+	mock_event.On("GetTicketType", uint(1), uint(2)).Return(&domain.TicketType{ID: 2, EventID: 1, Name: "VIP", Quota: 50, Reserved: 10}, nil)
+	app := setupEventAppAs(mock_event, nil)
+	resp := jsonRequest(app, "GET", "/events/1/ticket-types/2", "")
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	var result TicketTypeResponse
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+	assert.Equal(t, 40, result.Available)
+}
+
+func testGetTicketTypeNotFound(t *testing.T) {
+	mock_event := new(MockEventService)
+	mock_event.On("GetTicketType", uint(1), uint(3)).Return(nil, domain.ErrNotFound)
+	app := setupEventAppAs(mock_event, nil)
+	resp := jsonRequest(app, "GET", "/events/1/ticket-types/3", "")
+	assert.Equal(t, fiber.StatusNotFound, resp.StatusCode)
+}
+
+func testOrganizerCreatesTicketType(t *testing.T) {
+	mock_event := new(MockEventService)
+	owner := uint(7)
+	mock_event.On("GetEventById", uint(1)).Return(&domain.Event{ID: 1, OrganizerID: &owner}, nil)
+	mock_event.On("CreateTicketType", mock.MatchedBy(func(tt *domain.TicketType) bool {
+		return tt.EventID == 1 && tt.Name == "VIP" && tt.Price == 1500000 && tt.Quota == 50 && tt.MaxPerOrder == 4
+	})).Run(func(args mock.Arguments) {
+		args.Get(0).(*domain.TicketType).ID = 5
+	}).Return(nil)
+	app := setupEventAppAs(mock_event, organizerClaims("7"))
+	resp := jsonRequest(app, "POST", "/events/1/ticket-types", vipTicketType)
+	assert.Equal(t, fiber.StatusCreated, resp.StatusCode)
+	var result TicketTypeResponse
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+	assert.Equal(t, uint(5), result.ID)
+	assert.Equal(t, 50, result.Available)
+	mock_event.AssertExpectations(t)
+}
+
+func testCreateTicketTypeValidation(t *testing.T) {
+	mock_event := new(MockEventService)
+	mock_event.On("GetEventById", uint(1)).Return(&domain.Event{ID: 1}, nil)
+	mock_event.On("CreateTicketType", mock.Anything).Return(fmt.Errorf("%w: max_per_order must be 0 or at least min_per_order", domain.ErrBadParamInput))
+	app := setupEventApp(mock_event)
+
+	// Thiếu price
+	resp := jsonRequest(app, "POST", "/events/1/ticket-types", `{"name":"VIP","quota":50}`)
+	assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+	mock_event.AssertNotCalled(t, "CreateTicketType", mock.Anything)
+
+	resp = jsonRequest(app, "POST", "/events/1/ticket-types", `{"name":"VIP","price":0,"quota":50,"min_per_order":5,"max_per_order":2}`)
+	assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+}
+
+func testCreateTicketTypeDuplicateName(t *testing.T) {
+	mock_event := new(MockEventService)
+	mock_event.On("GetEventById", uint(1)).Return(&domain.Event{ID: 1}, nil)
+	mock_event.On("CreateTicketType", mock.Anything).Return(domain.ErrConflict)
+	app := setupEventApp(mock_event)
+	resp := jsonRequest(app, "POST", "/events/1/ticket-types", vipTicketType)
+	assert.Equal(t, fiber.StatusConflict, resp.StatusCode)
+}
+
+func testOrganizerCannotCreateTicketTypeForOthers(t *testing.T) {
+	mock_event := new(MockEventService)
+	owner := uint(8)
+	mock_event.On("GetEventById", uint(1)).Return(&domain.Event{ID: 1, OrganizerID: &owner}, nil)
+	app := setupEventAppAs(mock_event, organizerClaims("7"))
+	resp := jsonRequest(app, "POST", "/events/1/ticket-types", vipTicketType)
+	assert.Equal(t, fiber.StatusForbidden, resp.StatusCode)
+	mock_event.AssertNotCalled(t, "CreateTicketType", mock.Anything)
+}
+
+func testCustomerCannotCreateTicketType(t *testing.T) {
+	mock_event := new(MockEventService)
+	app := setupEventAppAs(mock_event, customerClaims("3"))
+	resp := jsonRequest(app, "POST", "/events/1/ticket-types", vipTicketType)
+	assert.Equal(t, fiber.StatusForbidden, resp.StatusCode)
+	mock_event.AssertNotCalled(t, "CreateTicketType", mock.Anything)
+}
+
+func testUpdateTicketTypeQuotaBelowReserved(t *testing.T) {
+	mock_event := new(MockEventService)
+	mock_event.On("UpdateTicketType", mock.MatchedBy(func(tt *domain.TicketType) bool {
+		return tt.ID == 2 && tt.EventID == 1
+	})).Return(domain.ErrQuotaBelowReserved)
+	app := setupEventApp(mock_event)
+	resp := jsonRequest(app, "PUT", "/events/1/ticket-types/2", vipTicketType)
+	assert.Equal(t, fiber.StatusConflict, resp.StatusCode)
+	mock_event.AssertExpectations(t)
+}
+
+func testDeleteTicketType(t *testing.T) {
+	mock_event := new(MockEventService)
+	owner := uint(7)
+	mock_event.On("GetEventById", uint(1)).Return(&domain.Event{ID: 1, OrganizerID: &owner}, nil)
+	mock_event.On("DeleteTicketType", uint(1), uint(2)).Return(nil)
+	app := setupEventAppAs(mock_event, organizerClaims("7"))
+	resp := jsonRequest(app, "DELETE", "/events/1/ticket-types/2", "")
+	assert.Equal(t, fiber.StatusNoContent, resp.StatusCode)
+	mock_event.AssertExpectations(t)
+}
+
+func testDeleteTicketTypeInUse(t *testing.T) {
+	mock_event := new(MockEventService)
+	mock_event.On("DeleteTicketType", uint(1), uint(2)).Return(domain.ErrTicketTypeInUse)
+	app := setupEventApp(mock_event)
+	resp := jsonRequest(app, "DELETE", "/events/1/ticket-types/2", "")
+	assert.Equal(t, fiber.StatusConflict, resp.StatusCode)
+}
@@ -8,7 +8,7 @@ import (
 
 func NewHealthHandlerFiber(app *fiber.App, healthService health.HealthService) {
 	app.Get("/health", func(c *fiber.Ctx) error {
-		health, err := healthService.CheckHealth(c.Context())
+		health, err := healthService.CheckHealth(c.UserContext())
 		if err != nil {
 			return c.Status(fiber.StatusServiceUnavailable).JSON(health)
 		}
@@ -0,0 +1,100 @@
+package rest
+
+import (
+	"errors"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"ticket_app/domain"
+	middleware "ticket_app/internal/rest/middleware"
+)
+
+// HoldResponse là vé đang được giữ cho user; gửi Token trong hold_token của
+// POST /bookings trước ExpiresAt để đặt
+type HoldResponse struct {
+	Token      string                `json:"token"`
+	EventID    uint                  `json:"event_id"`
+	Quantity   int                   `json:"quantity"`
+	TotalPrice float64               `json:"total_price"`
+	Status     domain.HoldStatus     `json:"status"`
+	ExpiresAt  time.Time             `json:"expires_at"`
+	BookingID  *uint                 `json:"booking_id,omitempty"`
+	Items      []BookingItemResponse `json:"items,omitempty"`
+	Seats      []BookingSeatResponse `json:"seats,omitempty"`
+}
+
+func newHoldResponse(hold *domain.Hold) HoldResponse {
+	resp := HoldResponse{
+		Token:      hold.Token,
+		EventID:    hold.EventID,
+		Quantity:   hold.Quantity,
+		TotalPrice: hold.TotalPrice,
+		Status:     hold.Status,
+		ExpiresAt:  hold.ExpiresAt,
+		BookingID:  hold.BookingID,
+		Seats:      newBookingSeatResponses(hold.Seats),
+	}
+	for _, item := range hold.Items {
+		r := BookingItemResponse{TicketTypeID: item.TicketTypeID, Quantity: item.Quantity, UnitPrice: item.UnitPrice}
+		if item.TicketType != nil {
+			r.Name = item.TicketType.Name
+		}
+		resp.Items = append(resp.Items, r)
+	}
+	return resp
+}
+
+// CreateHold giữ vé với cùng body như POST /bookings (trừ hold_token)
+func (h *BookingHandler) CreateHold(c *fiber.Ctx) error {
+	req, resp, ok := h.parseBookingRequest(c)
+	if !ok {
+		return resp
+	}
+
+	user, ok := middleware.CurrentUser(c)
+	if !ok || user.UserID == 0 {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid token claims"})
+	}
+
+	hold, err := h.bookingService.CreateHold(c.UserContext(), user.UserID, req)
+	if err != nil {
+		return reservationError(c, err, "Failed to hold tickets")
+	}
+	return c.Status(fiber.StatusCreated).JSON(newHoldResponse(hold))
+}
+
+func (h *BookingHandler) GetHold(c *fiber.Ctx) error {
+	user, ok := middleware.CurrentUser(c)
+	if !ok || user.UserID == 0 {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid token claims"})
+	}
+
+	hold, err := h.bookingService.GetHold(c.UserContext(), user.UserID, c.Params("token"))
+	if errors.Is(err, domain.ErrHoldNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Hold not found"})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to get hold"})
+	}
+	return c.JSON(newHoldResponse(hold))
+}
+
+// ReleaseHold trả vé đang giữ ngay, không chờ hết hạn
+func (h *BookingHandler) ReleaseHold(c *fiber.Ctx) error {
+	user, ok := middleware.CurrentUser(c)
+	if !ok || user.UserID == 0 {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid token claims"})
+	}
+
+	err := h.bookingService.ReleaseHold(c.UserContext(), user.UserID, c.Params("token"))
+	switch {
+	case errors.Is(err, domain.ErrHoldNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Hold not found"})
+	case errors.Is(err, domain.ErrHoldNotActive):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": err.Error()})
+	case err != nil:
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to release hold"})
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
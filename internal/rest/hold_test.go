@@ -0,0 +1,132 @@
+package rest
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"ticket_app/domain"
+)
+
+func TestHolds(t *testing.T) {
+	t.Run("Create", testCreateHold)
+	t.Run("CreateValidation", testCreateHoldValidation)
+	t.Run("CreateSoldOut", testCreateHoldSoldOut)
+	t.Run("GetNotFound", testGetHoldNotFound)
+	t.Run("Release", testReleaseHold)
+	t.Run("ReleaseUsed", testReleaseUsedHold)
+	t.Run("Book", testCreateBookingFromHold)
+	t.Run("BookHoldErrors", testCreateBookingHoldErrors)
+	t.Run("BookHoldAndQuantity", testCreateBookingHoldAndQuantity)
+}
+
+func testCreateHold(t *testing.T) {
+	bookingSvc := new(MockBookingService)
+	expires := time.Now().Add(10 * time.Minute).UTC().Truncate(time.Second)
+	bookingSvc.On("CreateHold", uint(1), domain.BookingRequest{EventID: 1, Items: []domain.BookingItem{}, SeatIDs: []uint{7}}).
+		Return(&domain.Hold{
+			Token: "tok", EventID: 1, Quantity: 1, TotalPrice: 500000, Status: domain.HoldStatusActive, ExpiresAt: expires,
+			Seats: []domain.BookingSeat{{SeatID: 7, UnitPrice: 500000}},
+		}, nil)
+
+	app := setupBookingApp(bookingSvc, &MockAuthService{}, nil)
+	resp := jsonRequest(app, "POST", "/holds", `{"event_id":1,"seat_ids":[7]}`)
+	assert.Equal(t, fiber.StatusCreated, resp.StatusCode)
+	var result HoldResponse
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+	assert.Equal(t, "tok", result.Token)
+	assert.Equal(t, domain.HoldStatusActive, result.Status)
+	assert.True(t, expires.Equal(result.ExpiresAt))
+	assert.Equal(t, []BookingSeatResponse{{SeatID: 7, UnitPrice: 500000}}, result.Seats)
+	bookingSvc.AssertExpectations(t)
+}
+
+func testCreateHoldValidation(t *testing.T) {
+	bookingSvc := new(MockBookingService)
+	app := setupBookingApp(bookingSvc, &MockAuthService{}, nil)
+	for _, body := range []string{
+		`{}`,
+		`{"event_id":1}`,
+		`{"quantity":2}`,
+		`{"event_id":1,"quantity":2,"seat_ids":[7]}`,
+	} {
+		resp := jsonRequest(app, "POST", "/holds", body)
+		assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode, body)
+	}
+	bookingSvc.AssertNotCalled(t, "CreateHold", mock.Anything, mock.Anything)
+}
+
+func testCreateHoldSoldOut(t *testing.T) {
+	bookingSvc := new(MockBookingService)
+	bookingSvc.On("CreateHold", uint(1), mock.Anything).Return(nil, domain.ErrNotEnoughTickets)
+	app := setupBookingApp(bookingSvc, &MockAuthService{}, nil)
+	resp := jsonRequest(app, "POST", "/holds", `{"event_id":1,"quantity":2}`)
+	assert.Equal(t, fiber.StatusConflict, resp.StatusCode)
+}
+
+func testGetHoldNotFound(t *testing.T) {
+	bookingSvc := new(MockBookingService)
+	bookingSvc.On("GetHold", uint(1), "tok").Return(nil, domain.ErrHoldNotFound)
+	app := setupBookingApp(bookingSvc, &MockAuthService{}, nil)
+	resp := jsonRequest(app, "GET", "/holds/tok", "")
+	assert.Equal(t, fiber.StatusNotFound, resp.StatusCode)
+}
+
+func testReleaseHold(t *testing.T) {
+	bookingSvc := new(MockBookingService)
+	bookingSvc.On("ReleaseHold", uint(1), "tok").Return(nil)
+	app := setupBookingApp(bookingSvc, &MockAuthService{}, nil)
+	resp := jsonRequest(app, "DELETE", "/holds/tok", "")
+	assert.Equal(t, fiber.StatusNoContent, resp.StatusCode)
+	bookingSvc.AssertExpectations(t)
+}
+
+func testReleaseUsedHold(t *testing.T) {
+	bookingSvc := new(MockBookingService)
+	bookingSvc.On("ReleaseHold", uint(1), "tok").Return(domain.ErrHoldNotActive)
+	app := setupBookingApp(bookingSvc, &MockAuthService{}, nil)
+	resp := jsonRequest(app, "DELETE", "/holds/tok", "")
+	assert.Equal(t, fiber.StatusConflict, resp.StatusCode)
+}
+
+func testCreateBookingFromHold(t *testing.T) {
+	bookingSvc := new(MockBookingService)
+	bookingSvc.On("CreateBooking", mock.Anything, uint(1), domain.BookingRequest{Items: []domain.BookingItem{}, HoldToken: "tok"}).
+		Return(&domain.Booking{ID: 5, UserID: 1, EventID: 1, Quantity: 2, TotalPrice: 200, Status: domain.BookingStatusPending}, nil)
+
+	app := setupBookingApp(bookingSvc, &MockAuthService{}, nil)
+	resp := postJSON(app, "/bookings", `{"hold_token":"tok"}`)
+	assert.Equal(t, fiber.StatusCreated, resp.StatusCode)
+	bookingSvc.AssertExpectations(t)
+}
+
+func testCreateBookingHoldErrors(t *testing.T) {
+	cases := []struct {
+		err    error
+		status int
+	}{
+		{domain.ErrHoldNotFound, fiber.StatusNotFound},
+		{domain.ErrHoldExpired, fiber.StatusGone},
+		{domain.ErrHoldNotActive, fiber.StatusConflict},
+		{domain.ErrEventNotActive, fiber.StatusConflict},
+	}
+	for _, tc := range cases {
+		bookingSvc := new(MockBookingService)
+		bookingSvc.On("CreateBooking", mock.Anything, uint(1), mock.Anything).Return(nil, tc.err)
+		app := setupBookingApp(bookingSvc, &MockAuthService{}, nil)
+		resp := postJSON(app, "/bookings", `{"hold_token":"tok"}`)
+		assert.Equal(t, tc.status, resp.StatusCode, tc.err.Error())
+	}
+}
+
+func testCreateBookingHoldAndQuantity(t *testing.T) {
+	bookingSvc := new(MockBookingService)
+	app := setupBookingApp(bookingSvc, &MockAuthService{}, nil)
+	resp := postJSON(app, "/bookings", `{"event_id":1,"quantity":2,"hold_token":"tok"}`)
+	assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+	bookingSvc.AssertNotCalled(t, "CreateBooking", mock.Anything, mock.Anything, mock.Anything)
+}
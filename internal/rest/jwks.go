@@ -0,0 +1,24 @@
+package rest
+
+import (
+	"github.com/gofiber/fiber/v2"
+
+	"ticket_app/internal/token"
+)
+
+// JWKSHandler publishes the public keys used to sign access tokens, so other
+// services can verify them. HMAC secrets are never included.
+type JWKSHandler struct {
+	tokens *token.Manager
+}
+
+func NewJWKSHandler(app *fiber.App, tokens *token.Manager) *JWKSHandler {
+	handler := &JWKSHandler{tokens: tokens}
+	app.Get("/.well-known/jwks.json", handler.JWKS)
+	return handler
+}
+
+func (h *JWKSHandler) JWKS(c *fiber.Ctx) error {
+	c.Set(fiber.HeaderCacheControl, "public, max-age=300")
+	return c.JSON(h.tokens.JWKS())
+}
@@ -0,0 +1,41 @@
+package rest
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"ticket_app/internal/token"
+)
+
+func TestJWKS(t *testing.T) {
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKCS8PrivateKey(priv)
+	require.NoError(t, err)
+	signing, err := token.ParsePrivateKey("ed-1", pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+	require.NoError(t, err)
+	tokens, err := token.NewManager("ed-1", signing, token.NewHMACKey("hs-old", []byte("secret")))
+	require.NoError(t, err)
+
+	app := fiber.New()
+	NewJWKSHandler(app, tokens)
+	resp, err := app.Test(httptest.NewRequest("GET", "/.well-known/jwks.json", nil))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	assert.Contains(t, resp.Header.Get("Cache-Control"), "max-age")
+
+	var body token.JWKS
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	require.Len(t, body.Keys, 1)
+	assert.Equal(t, "ed-1", body.Keys[0].Kid)
+	assert.Equal(t, "OKP", body.Keys[0].Kty)
+}
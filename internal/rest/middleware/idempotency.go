@@ -0,0 +1,116 @@
+package middleware
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"ticket_app/internal/idempotency"
+)
+
+const (
+	// IdempotencyHeader is the request header clients use to make a POST safe to retry
+	IdempotencyHeader = "Idempotency-Key"
+	// IdempotencyReplayedHeader is set on responses served from the idempotency store
+	IdempotencyReplayedHeader = "Idempotency-Replayed"
+
+	// idempotencyLockTTL bounds how long an in-flight request keeps its key
+	// reserved, so a crashed instance does not block retries for the full TTL.
+	idempotencyLockTTL      = time.Minute
+	maxIdempotencyKeyLength = 255
+)
+
+// Idempotency makes the wrapped route honour the Idempotency-Key header.
+//
+// The first request with a key runs normally and its response is stored for
+// ttl. Retries with the same key and the same body get the stored response
+// back; retries with a different body get 422, and retries that arrive while
+// the first request is still running get 409. Keys are scoped per user ID and
+// route, so the route must run after ResolveUser; a key sent without a known
+// user gets 401. Requests without the header are passed through untouched, and
+// 5xx responses are not stored so the client can retry them.
+func Idempotency(store idempotency.Store, ttl time.Duration) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		key := c.Get(IdempotencyHeader)
+		if key == "" {
+			return c.Next()
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Idempotency-Key is too long"})
+		}
+
+		// Keys are scoped per user; without one, callers would share stored responses
+		user, ok := CurrentUser(c)
+		if !ok || user.UserID == 0 {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid token claims"})
+		}
+
+		ctx := c.UserContext()
+		storeKey := fmt.Sprintf("idempotency:%d:%s:%s:%s", user.UserID, c.Method(), c.Path(), key)
+		fingerprint := requestFingerprint(c)
+
+		existing, reserved, err := store.Reserve(ctx, storeKey, idempotency.Record{Fingerprint: fingerprint}, idempotencyLockTTL)
+		if err != nil {
+			log.Printf("Idempotency store error: %v", err)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to check Idempotency-Key"})
+		}
+		if !reserved {
+			if existing.Fingerprint != fingerprint {
+				return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{"error": "Idempotency-Key was already used with a different request"})
+			}
+			if !existing.Completed {
+				return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "A request with this Idempotency-Key is still being processed"})
+			}
+			c.Set(IdempotencyReplayedHeader, "true")
+			if existing.ContentType != "" {
+				c.Set(fiber.HeaderContentType, existing.ContentType)
+			}
+			return c.Status(existing.StatusCode).Send(existing.Body)
+		}
+
+		if err := c.Next(); err != nil {
+			releaseIdempotencyKey(store, storeKey)
+			return err
+		}
+
+		status := c.Response().StatusCode()
+		if status >= fiber.StatusInternalServerError {
+			releaseIdempotencyKey(store, storeKey)
+			return nil
+		}
+		rec := idempotency.Record{
+			Fingerprint: fingerprint,
+			Completed:   true,
+			StatusCode:  status,
+			ContentType: string(c.Response().Header.ContentType()),
+			Body:        append([]byte(nil), c.Response().Body()...),
+		}
+		if err := store.Save(context.Background(), storeKey, rec, ttl); err != nil {
+			log.Printf("Failed to save idempotent response for %s: %v", storeKey, err)
+		}
+		return nil
+	}
+}
+
+// releaseIdempotencyKey uses a fresh context: the request context may already
+// be cancelled, and leaving the key reserved would block retries.
+func releaseIdempotencyKey(store idempotency.Store, key string) {
+	if err := store.Delete(context.Background(), key); err != nil {
+		log.Printf("Failed to release Idempotency-Key %s: %v", key, err)
+	}
+}
+
+func requestFingerprint(c *fiber.Ctx) string {
+	h := sha256.New()
+	h.Write([]byte(c.Method()))
+	h.Write([]byte{0})
+	h.Write([]byte(c.Path()))
+	h.Write([]byte{0})
+	h.Write(c.Body())
+	return hex.EncodeToString(h.Sum(nil))
+}
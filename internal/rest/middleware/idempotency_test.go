@@ -0,0 +1,172 @@
+package middleware_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"ticket_app/internal/idempotency"
+	"ticket_app/internal/rest/middleware"
+)
+
+type memoryIdempotencyStore struct {
+	mu      sync.Mutex
+	records map[string]idempotency.Record
+}
+
+func newMemoryIdempotencyStore() *memoryIdempotencyStore {
+	return &memoryIdempotencyStore{records: map[string]idempotency.Record{}}
+}
+
+func (s *memoryIdempotencyStore) Reserve(ctx context.Context, key string, rec idempotency.Record, ttl time.Duration) (*idempotency.Record, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if existing, ok := s.records[key]; ok {
+		return &existing, false, nil
+	}
+	s.records[key] = rec
+	return nil, true, nil
+}
+
+func (s *memoryIdempotencyStore) Save(ctx context.Context, key string, rec idempotency.Record, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records[key] = rec
+	return nil
+}
+
+func (s *memoryIdempotencyStore) Delete(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.records, key)
+	return nil
+}
+
+// testUserHeader sets the caller in place of JWTMiddleware and ResolveUser
+const testUserHeader = "X-Test-User"
+
+func setupIdempotencyApp(store idempotency.Store, status int, calls *int) *fiber.App {
+	app := fiber.New()
+	app.Use(func(c *fiber.Ctx) error {
+		if sub := c.Get(testUserHeader); sub != "" {
+			c.Locals("user", &jwt.Token{Claims: jwt.MapClaims{"sub": sub}})
+		}
+		return c.Next()
+	})
+	app.Post("/bookings", middleware.Idempotency(store, time.Hour), func(c *fiber.Ctx) error {
+		*calls++
+		return c.Status(status).JSON(fiber.Map{"call": *calls})
+	})
+	return app
+}
+
+func postWithKey(t *testing.T, app *fiber.App, key, body string) (int, string, string) {
+	return postAs(t, app, "1", key, body)
+}
+
+func postAs(t *testing.T, app *fiber.App, user, key, body string) (int, string, string) {
+	req := httptest.NewRequest("POST", "/bookings", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	if user != "" {
+		req.Header.Set(testUserHeader, user)
+	}
+	if key != "" {
+		req.Header.Set(middleware.IdempotencyHeader, key)
+	}
+	resp, err := app.Test(req)
+	require.NoError(t, err)
+	b, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, string(b), resp.Header.Get(middleware.IdempotencyReplayedHeader)
+}
+
+func TestIdempotencyReplaysResponse(t *testing.T) {
+	calls := 0
+	app := setupIdempotencyApp(newMemoryIdempotencyStore(), fiber.StatusCreated, &calls)
+
+	status, body, replayed := postWithKey(t, app, "abc", `{"event_id":1}`)
+	assert.Equal(t, fiber.StatusCreated, status)
+	assert.Empty(t, replayed)
+
+	status2, body2, replayed2 := postWithKey(t, app, "abc", `{"event_id":1}`)
+	assert.Equal(t, fiber.StatusCreated, status2)
+	assert.Equal(t, body, body2)
+	assert.Equal(t, "true", replayed2)
+	assert.Equal(t, 1, calls)
+}
+
+func TestIdempotencyKeyReusedWithDifferentBody(t *testing.T) {
+	calls := 0
+	app := setupIdempotencyApp(newMemoryIdempotencyStore(), fiber.StatusCreated, &calls)
+
+	postWithKey(t, app, "abc", `{"event_id":1}`)
+	status, _, _ := postWithKey(t, app, "abc", `{"event_id":2}`)
+	assert.Equal(t, fiber.StatusUnprocessableEntity, status)
+	assert.Equal(t, 1, calls)
+}
+
+func TestIdempotencyInFlight(t *testing.T) {
+	store := newMemoryIdempotencyStore()
+	calls := 0
+	app := setupIdempotencyApp(store, fiber.StatusCreated, &calls)
+
+	// Simulate a first request that reserved the key and has not finished yet.
+	postWithKey(t, app, "abc", `{"event_id":1}`)
+	for k, rec := range store.records {
+		rec.Completed = false
+		store.records[k] = rec
+	}
+
+	status, _, _ := postWithKey(t, app, "abc", `{"event_id":1}`)
+	assert.Equal(t, fiber.StatusConflict, status)
+	assert.Equal(t, 1, calls)
+}
+
+func TestIdempotencyServerErrorIsNotStored(t *testing.T) {
+	calls := 0
+	app := setupIdempotencyApp(newMemoryIdempotencyStore(), fiber.StatusInternalServerError, &calls)
+
+	postWithKey(t, app, "abc", `{"event_id":1}`)
+	postWithKey(t, app, "abc", `{"event_id":1}`)
+	assert.Equal(t, 2, calls)
+}
+
+func TestIdempotencyWithoutKey(t *testing.T) {
+	calls := 0
+	app := setupIdempotencyApp(newMemoryIdempotencyStore(), fiber.StatusCreated, &calls)
+
+	postWithKey(t, app, "", `{"event_id":1}`)
+	postWithKey(t, app, "", `{"event_id":1}`)
+	assert.Equal(t, 2, calls)
+}
+
+func TestIdempotencyKeysAreScopedPerUser(t *testing.T) {
+	calls := 0
+	app := setupIdempotencyApp(newMemoryIdempotencyStore(), fiber.StatusCreated, &calls)
+
+	_, body, _ := postAs(t, app, "1", "abc", `{"event_id":1}`)
+	status, body2, replayed := postAs(t, app, "2", "abc", `{"event_id":1}`)
+	assert.Equal(t, fiber.StatusCreated, status)
+	assert.Empty(t, replayed)
+	assert.NotEqual(t, body, body2)
+	assert.Equal(t, 2, calls)
+}
+
+func TestIdempotencyKeyWithoutUser(t *testing.T) {
+	calls := 0
+	app := setupIdempotencyApp(newMemoryIdempotencyStore(), fiber.StatusCreated, &calls)
+
+	status, _, _ := postAs(t, app, "", "abc", `{"event_id":1}`)
+	assert.Equal(t, fiber.StatusUnauthorized, status)
+	status, _, _ = postAs(t, app, "", "", `{"event_id":1}`)
+	assert.Equal(t, fiber.StatusCreated, status)
+	assert.Equal(t, 1, calls)
+}
@@ -0,0 +1,100 @@
+package middleware
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+
+	"ticket_app/domain"
+)
+
+// identityKey là key của c.Locals nơi ResolveUser lưu Identity
+const identityKey = "identity"
+
+// Identity là user đang gọi API, đọc từ claims của access token
+type Identity struct {
+	// UserID là 0 với token cũ chưa có "sub"; khi đó chỉ có Email
+	UserID uint
+	Email  string
+	Role   domain.Role
+	// MFARequired: role thật của user cần 2FA nhưng phiên này chưa qua 2FA, nên Role chỉ là customer
+	MFARequired bool
+}
+
+// IsAdmin tells whether the caller may act on resources of other users
+func (i Identity) IsAdmin() bool {
+	return i.Role == domain.RoleAdmin
+}
+
+// Claims đọc claims của access token mà JWTMiddleware đã lưu vào c.Locals("user")
+func Claims(c *fiber.Ctx) (jwt.MapClaims, bool) {
+	switch user := c.Locals("user").(type) {
+	case *jwt.Token:
+		claims, ok := user.Claims.(jwt.MapClaims)
+		return claims, ok
+	case map[string]interface{}:
+		return jwt.MapClaims(user), true
+	}
+	return nil, false
+}
+
+// CurrentUser trả về Identity của request: bản ResolveUser đã lưu, nếu có,
+// không thì đọc từ claims. Token phát hành trước khi có role không có claim
+// "role" và được coi là customer.
+func CurrentUser(c *fiber.Ctx) (Identity, bool) {
+	if id, ok := c.Locals(identityKey).(Identity); ok {
+		return id, true
+	}
+	claims, ok := Claims(c)
+	if !ok {
+		return Identity{}, false
+	}
+	id := Identity{Role: domain.RoleCustomer}
+	id.Email, _ = claims["email"].(string)
+	if sub, _ := claims["sub"].(string); sub != "" {
+		n, err := strconv.ParseUint(sub, 10, 64)
+		if err != nil {
+			return Identity{}, false
+		}
+		id.UserID = uint(n)
+	}
+	if role, _ := claims["role"].(string); role != "" {
+		id.Role = domain.Role(role)
+	}
+	id.MFARequired, _ = claims["mfa_required"].(bool)
+	return id, true
+}
+
+// UserLookup tìm user theo email, cho token cũ chưa có claim "sub"
+type UserLookup interface {
+	FindByEmail(ctx context.Context, email string) (*domain.User, error)
+}
+
+// ResolveUser xác định user của request một lần, ngay sau JWTMiddleware, để
+// handler chỉ cần gọi CurrentUser. Access token có "sub" không cần truy vấn
+// DB; chỉ token cũ chưa có "sub" mới phải tìm user theo email.
+func ResolveUser(users UserLookup) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		id, ok := CurrentUser(c)
+		if !ok {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid token claims"})
+		}
+		if id.UserID == 0 {
+			if id.Email == "" {
+				return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid token claims"})
+			}
+			user, err := users.FindByEmail(c.UserContext(), id.Email)
+			if err != nil {
+				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to load user"})
+			}
+			if user == nil {
+				return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "User no longer exists"})
+			}
+			id.UserID = user.ID
+		}
+		c.Locals(identityKey, id)
+		return c.Next()
+	}
+}
@@ -1,15 +1,18 @@
 package middleware
 
 import (
-	"fmt"
 	"strings"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/golang-jwt/jwt/v5"
+
+	"ticket_app/internal/token"
 )
 
-// JWTMiddleware là middleware xác thực JWT
-func JWTMiddleware() fiber.Handler {
+// JWTMiddleware là middleware xác thực JWT bằng verifier. Nếu có revocations,
+// token đã bị thu hồi (logout, logout-all) cũng bị từ chối.
+func JWTMiddleware(verifier token.Verifier, revocations token.RevocationStore) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		// Lấy token từ header Authorization
 		authHeader := c.Get("Authorization")
@@ -23,21 +26,32 @@ func JWTMiddleware() fiber.Handler {
 			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid authorization header format"})
 		}
 
-		// Phân tích và xác thực token
-		token, err := jwt.Parse(tokenStr, func(token *jwt.Token) (interface{}, error) {
-			// Kiểm tra phương thức ký
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-			}
-			return []byte("your-secret-key"), nil // Phải khớp với jwtKey trong auth_service.go
-		})
-
-		if err != nil || !token.Valid {
+		// Phân tích và xác thực token (chữ ký theo kid, thuật toán, hạn dùng)
+		parsed, err := verifier.Verify(tokenStr)
+		if err != nil || !parsed.Valid {
 			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid or expired token"})
 		}
 
+		// Kiểm tra token đã bị thu hồi chưa, theo jti và theo user (sub)
+		if revocations != nil {
+			claims, _ := parsed.Claims.(jwt.MapClaims)
+			jti, _ := claims["jti"].(string)
+			sub, _ := claims["sub"].(string)
+			var issuedAt time.Time
+			if iat, err := claims.GetIssuedAt(); err == nil && iat != nil {
+				issuedAt = iat.Time
+			}
+			revoked, err := revocations.IsRevoked(c.UserContext(), jti, sub, issuedAt)
+			if err != nil {
+				return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "Could not check token revocation"})
+			}
+			if revoked {
+				return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Token has been revoked"})
+			}
+		}
+
 		// Lưu token vào c.Locals để handler sử dụng
-		c.Locals("user", token)
+		c.Locals("user", parsed)
 		return c.Next()
 	}
 }
\ No newline at end of file
@@ -0,0 +1,109 @@
+package middleware_test
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"ticket_app/internal/rest/middleware"
+	"ticket_app/internal/token"
+)
+
+func TestJWTMiddlewareAcceptsRotatedKeys(t *testing.T) {
+	oldKeys, err := token.NewManager("v1", token.NewHMACKey("v1", []byte("old-secret")))
+	require.NoError(t, err)
+	oldToken, err := oldKeys.Sign(jwt.MapClaims{"email": "a@example.com", "exp": time.Now().Add(time.Hour).Unix()})
+	require.NoError(t, err)
+
+	keys, err := token.NewManager("v2", token.NewHMACKey("v2", []byte("new-secret")), token.NewHMACKey("v1", []byte("old-secret")))
+	require.NoError(t, err)
+	app := fiber.New()
+	app.Use(middleware.JWTMiddleware(keys, nil))
+	app.Get("/", func(c *fiber.Ctx) error {
+		claims := c.Locals("user").(*jwt.Token).Claims.(jwt.MapClaims)
+		return c.SendString(claims["email"].(string))
+	})
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Authorization", "Bearer "+oldToken)
+	resp, err := app.Test(req)
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+	unknown, err := token.NewManager("v3", token.NewHMACKey("v3", []byte("other")))
+	require.NoError(t, err)
+	badToken, err := unknown.Sign(jwt.MapClaims{"email": "a@example.com", "exp": time.Now().Add(time.Hour).Unix()})
+	require.NoError(t, err)
+	req = httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Authorization", "Bearer "+badToken)
+	resp, err = app.Test(req)
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusUnauthorized, resp.StatusCode)
+}
+
+type fakeRevocations struct {
+	jtis     map[string]bool
+	cutoffs  map[string]time.Time
+	checkErr error
+}
+
+func (f *fakeRevocations) RevokeToken(ctx context.Context, jti string, expiresAt time.Time) error {
+	f.jtis[jti] = true
+	return nil
+}
+
+func (f *fakeRevocations) RevokeSubject(ctx context.Context, subject string, at time.Time, ttl time.Duration) error {
+	f.cutoffs[subject] = at
+	return nil
+}
+
+func (f *fakeRevocations) IsRevoked(ctx context.Context, jti, subject string, issuedAt time.Time) (bool, error) {
+	if f.checkErr != nil {
+		return false, f.checkErr
+	}
+	cutoff, ok := f.cutoffs[subject]
+	return f.jtis[jti] || (ok && !issuedAt.After(cutoff)), nil
+}
+
+func TestJWTMiddlewareRejectsRevokedTokens(t *testing.T) {
+	keys, err := token.NewManager("v1", token.NewHMACKey("v1", []byte("secret")))
+	require.NoError(t, err)
+	revocations := &fakeRevocations{jtis: map[string]bool{}, cutoffs: map[string]time.Time{}}
+	app := fiber.New()
+	app.Use(middleware.JWTMiddleware(keys, revocations))
+	app.Get("/", func(c *fiber.Ctx) error { return c.SendStatus(fiber.StatusOK) })
+
+	issued := time.Now().Add(-time.Minute)
+	sign := func(jti string) string {
+		s, err := keys.Sign(jwt.MapClaims{"sub": "7", "jti": jti, "iat": issued.Unix(), "exp": time.Now().Add(time.Hour).Unix()})
+		require.NoError(t, err)
+		return s
+	}
+	status := func(tok string) int {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("Authorization", "Bearer "+tok)
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		return resp.StatusCode
+	}
+
+	assert.Equal(t, fiber.StatusOK, status(sign("a")))
+
+	revocations.jtis["a"] = true
+	assert.Equal(t, fiber.StatusUnauthorized, status(sign("a")))
+	assert.Equal(t, fiber.StatusOK, status(sign("b")))
+
+	// logout-all: every token of the user issued before now
+	revocations.cutoffs["7"] = time.Now()
+	assert.Equal(t, fiber.StatusUnauthorized, status(sign("b")))
+
+	revocations.checkErr = errors.New("redis down")
+	assert.Equal(t, fiber.StatusServiceUnavailable, status(sign("c")))
+}
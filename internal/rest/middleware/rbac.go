@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	"github.com/gofiber/fiber/v2"
+
+	"ticket_app/domain"
+)
+
+// RequireRole chỉ cho request đi tiếp khi user có một trong các role. Phải đặt
+// sau JWTMiddleware.
+func RequireRole(roles ...domain.Role) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		id, ok := CurrentUser(c)
+		if !ok {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Missing or invalid access token"})
+		}
+		for _, role := range roles {
+			if id.Role == role {
+				return c.Next()
+			}
+		}
+		if id.MFARequired {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error":        "Two-factor authentication is required for your role, log in with it to continue",
+				"mfa_required": true,
+			})
+		}
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Insufficient permissions"})
+	}
+}
@@ -0,0 +1,110 @@
+package middleware_test
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"ticket_app/domain"
+	"ticket_app/internal/rest/middleware"
+)
+
+func roleApp(claims jwt.MapClaims, roles ...domain.Role) *fiber.App {
+	app := fiber.New()
+	app.Use(func(c *fiber.Ctx) error {
+		if claims != nil {
+			c.Locals("user", &jwt.Token{Claims: claims})
+		}
+		return c.Next()
+	})
+	app.Get("/", middleware.RequireRole(roles...), func(c *fiber.Ctx) error {
+		user, _ := middleware.CurrentUser(c)
+		return c.SendString(string(user.Role))
+	})
+	return app
+}
+
+func TestRequireRole(t *testing.T) {
+	cases := []struct {
+		name   string
+		claims jwt.MapClaims
+		roles  []domain.Role
+		status int
+	}{
+		{"NoToken", nil, []domain.Role{domain.RoleAdmin}, fiber.StatusUnauthorized},
+		{"Allowed", jwt.MapClaims{"sub": "1", "role": "admin"}, []domain.Role{domain.RoleAdmin}, fiber.StatusOK},
+		{"OneOfMany", jwt.MapClaims{"sub": "1", "role": "organizer"}, []domain.Role{domain.RoleOrganizer, domain.RoleAdmin}, fiber.StatusOK},
+		{"Forbidden", jwt.MapClaims{"sub": "1", "role": "customer"}, []domain.Role{domain.RoleAdmin}, fiber.StatusForbidden},
+		// Token phát hành trước khi có role được coi là customer
+		{"LegacyTokenIsCustomer", jwt.MapClaims{"email": "a@example.com"}, []domain.Role{domain.RoleCustomer}, fiber.StatusOK},
+		{"LegacyTokenNotAdmin", jwt.MapClaims{"email": "a@example.com"}, []domain.Role{domain.RoleAdmin}, fiber.StatusForbidden},
+		{"BadSubject", jwt.MapClaims{"sub": "abc", "role": "admin"}, []domain.Role{domain.RoleAdmin}, fiber.StatusUnauthorized},
+		// Organizer đăng nhập không qua 2FA chỉ có quyền customer
+		{"MFARequired", jwt.MapClaims{"sub": "1", "role": "customer", "mfa_required": true}, []domain.Role{domain.RoleOrganizer}, fiber.StatusForbidden},
+		{"MFARequiredStillCustomer", jwt.MapClaims{"sub": "1", "role": "customer", "mfa_required": true}, []domain.Role{domain.RoleCustomer}, fiber.StatusOK},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			resp, err := roleApp(tc.claims, tc.roles...).Test(httptest.NewRequest("GET", "/", nil))
+			assert.NoError(t, err)
+			assert.Equal(t, tc.status, resp.StatusCode)
+		})
+	}
+}
+
+func TestCurrentUser(t *testing.T) {
+	app := fiber.New()
+	var got middleware.Identity
+	app.Get("/", func(c *fiber.Ctx) error {
+		c.Locals("user", &jwt.Token{Claims: jwt.MapClaims{"sub": "42", "email": "o@example.com", "role": "organizer"}})
+		got, _ = middleware.CurrentUser(c)
+		return nil
+	})
+	_, err := app.Test(httptest.NewRequest("GET", "/", nil))
+	assert.NoError(t, err)
+	assert.Equal(t, middleware.Identity{UserID: 42, Email: "o@example.com", Role: domain.RoleOrganizer}, got)
+	assert.False(t, got.IsAdmin())
+}
+
+type fakeUsers map[string]*domain.User
+
+func (f fakeUsers) FindByEmail(ctx context.Context, email string) (*domain.User, error) {
+	return f[email], nil
+}
+
+func TestResolveUser(t *testing.T) {
+	users := fakeUsers{"legacy@example.com": {ID: 9, Email: "legacy@example.com"}}
+	resolve := func(claims jwt.MapClaims) (int, middleware.Identity) {
+		app := fiber.New()
+		var got middleware.Identity
+		app.Use(func(c *fiber.Ctx) error {
+			c.Locals("user", &jwt.Token{Claims: claims})
+			return c.Next()
+		}, middleware.ResolveUser(users))
+		app.Get("/", func(c *fiber.Ctx) error {
+			got, _ = middleware.CurrentUser(c)
+			return nil
+		})
+		resp, err := app.Test(httptest.NewRequest("GET", "/", nil))
+		require.NoError(t, err)
+		return resp.StatusCode, got
+	}
+
+	status, got := resolve(jwt.MapClaims{"sub": "4", "email": "new@example.com", "role": "admin"})
+	assert.Equal(t, fiber.StatusOK, status)
+	assert.Equal(t, middleware.Identity{UserID: 4, Email: "new@example.com", Role: domain.RoleAdmin}, got)
+
+	// Token cũ chưa có "sub": tìm một lần theo email
+	status, got = resolve(jwt.MapClaims{"email": "legacy@example.com"})
+	assert.Equal(t, fiber.StatusOK, status)
+	assert.Equal(t, uint(9), got.UserID)
+	assert.Equal(t, domain.RoleCustomer, got.Role)
+
+	status, _ = resolve(jwt.MapClaims{"email": "deleted@example.com"})
+	assert.Equal(t, fiber.StatusUnauthorized, status)
+}
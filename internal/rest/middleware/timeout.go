@@ -2,21 +2,26 @@ package middleware
 
 import (
 	"context"
+	"errors"
 	"time"
 
-	echo "github.com/labstack/echo/v4"
+	"github.com/gofiber/fiber/v2"
 )
 
-// SetRequestContextWithTimeout will set the request context with timeout for every incoming HTTP Request
-func SetRequestContextWithTimeout(d time.Duration) echo.MiddlewareFunc {
-	return func(next echo.HandlerFunc) echo.HandlerFunc {
-		return func(c echo.Context) error {
-			ctx, cancel := context.WithTimeout(c.Request().Context(), d)
-			defer cancel()
+// Timeout attaches a context with deadline d to every incoming request via
+// c.SetUserContext. Handlers pass c.UserContext() down to services, so database
+// and Redis calls are cancelled once the deadline passes. If it has passed when
+// the handler returns, the response is replaced with 504 Gateway Timeout.
+func Timeout(d time.Duration) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx, cancel := context.WithTimeout(c.UserContext(), d)
+		defer cancel()
+		c.SetUserContext(ctx)
 
-			newRequest := c.Request().WithContext(ctx)
-			c.SetRequest(newRequest)
-			return next(c)
+		err := c.Next()
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return c.Status(fiber.StatusGatewayTimeout).JSON(fiber.Map{"error": "Request timed out"})
 		}
+		return err
 	}
 }
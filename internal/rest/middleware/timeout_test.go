@@ -0,0 +1,40 @@
+package middleware_test
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"ticket_app/internal/rest/middleware"
+)
+
+func TestTimeoutSetsDeadline(t *testing.T) {
+	app := fiber.New()
+	app.Use(middleware.Timeout(time.Second))
+	app.Get("/", func(c *fiber.Ctx) error {
+		_, ok := c.UserContext().Deadline()
+		assert.True(t, ok)
+		return c.SendStatus(fiber.StatusOK)
+	})
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/", nil))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+}
+
+func TestTimeoutExpired(t *testing.T) {
+	app := fiber.New()
+	app.Use(middleware.Timeout(10 * time.Millisecond))
+	app.Get("/", func(c *fiber.Ctx) error {
+		<-c.UserContext().Done()
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": c.UserContext().Err().Error()})
+	})
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/", nil))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusGatewayTimeout, resp.StatusCode)
+}
@@ -0,0 +1,203 @@
+package rest
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+
+	"ticket_app/booking"
+	"ticket_app/domain"
+	middleware "ticket_app/internal/rest/middleware"
+)
+
+type OrderHandler struct {
+	orderService booking.OrderService
+	validate     *validator.Validate
+}
+
+// NewOrderHandler đăng ký các route order; mọi route cần đăng nhập, và chỉ
+// chủ order hoặc admin xem hoặc huỷ được order
+func NewOrderHandler(app *fiber.App, orderService booking.OrderService) *OrderHandler {
+	handler := &OrderHandler{
+		orderService: orderService,
+		validate:     validator.New(),
+	}
+
+	app.Post("/orders", handler.CreateOrder)
+	app.Get("/orders/:id", handler.GetOrder)
+	app.Put("/orders/:id/cancel", handler.CancelOrder)
+
+	return handler
+}
+
+// CreateOrderRequest đặt vé của nhiều event trong một lần thanh toán. Mỗi item
+// là vé giá thường của một event, hoặc vé của một hạng vé khi có TicketTypeID.
+type CreateOrderRequest struct {
+	Items []OrderItemRequest `json:"items" validate:"required,min=1,max=20,dive"`
+}
+
+type OrderItemRequest struct {
+	EventID      uint  `json:"event_id" validate:"required"`
+	TicketTypeID *uint `json:"ticket_type_id" validate:"omitempty,min=1"`
+	Quantity     int   `json:"quantity" validate:"required,min=1"`
+}
+
+// OrderResponse là order với các dòng vé, booking của từng event và payment chung
+type OrderResponse struct {
+	ID         uint                   `json:"id"`
+	UserID     uint                   `json:"user_id"`
+	TotalPrice float64                `json:"total_price"`
+	Status     domain.BookingStatus   `json:"status"`
+	CreatedAt  time.Time              `json:"created_at"`
+	UpdatedAt  time.Time              `json:"updated_at"`
+	Items      []OrderItemResponse    `json:"items"`
+	Bookings   []OrderBookingResponse `json:"bookings"`
+	Payment    *OrderPaymentResponse  `json:"payment,omitempty"`
+}
+
+type OrderItemResponse struct {
+	EventID      uint    `json:"event_id"`
+	EventName    string  `json:"event_name,omitempty"`
+	TicketTypeID *uint   `json:"ticket_type_id,omitempty"`
+	Name         string  `json:"name,omitempty"`
+	Quantity     int     `json:"quantity"`
+	UnitPrice    float64 `json:"unit_price"`
+	BookingID    uint    `json:"booking_id"`
+}
+
+// OrderBookingResponse là booking của một event trong order
+type OrderBookingResponse struct {
+	ID         uint                 `json:"id"`
+	EventID    uint                 `json:"event_id"`
+	Quantity   int                  `json:"quantity"`
+	TotalPrice float64              `json:"total_price"`
+	Status     domain.BookingStatus `json:"status"`
+}
+
+type OrderPaymentResponse struct {
+	ID     uint                 `json:"id"`
+	Amount float64              `json:"amount"`
+	Status domain.PaymentStatus `json:"status"`
+}
+
+func newOrderResponse(order *domain.Order) OrderResponse {
+	resp := OrderResponse{
+		ID:         order.ID,
+		UserID:     order.UserID,
+		TotalPrice: order.TotalPrice,
+		Status:     order.Status,
+		CreatedAt:  order.CreatedAt,
+		UpdatedAt:  order.UpdatedAt,
+		Items:      make([]OrderItemResponse, len(order.Items)),
+		Bookings:   make([]OrderBookingResponse, len(order.Bookings)),
+	}
+	for i, item := range order.Items {
+		resp.Items[i] = OrderItemResponse{
+			EventID:      item.EventID,
+			TicketTypeID: item.TicketTypeID,
+			Quantity:     item.Quantity,
+			UnitPrice:    item.UnitPrice,
+			BookingID:    item.BookingID,
+		}
+		if item.Event != nil {
+			resp.Items[i].EventName = item.Event.Name
+		}
+		if item.TicketType != nil {
+			resp.Items[i].Name = item.TicketType.Name
+		}
+	}
+	for i, b := range order.Bookings {
+		resp.Bookings[i] = OrderBookingResponse{ID: b.ID, EventID: b.EventID, Quantity: b.Quantity, TotalPrice: b.TotalPrice, Status: b.Status}
+	}
+	if order.Payment != nil {
+		resp.Payment = &OrderPaymentResponse{ID: order.Payment.ID, Amount: order.Payment.Amount, Status: order.Payment.Status}
+	}
+	return resp
+}
+
+func (h *OrderHandler) CreateOrder(c *fiber.Ctx) error {
+	var req CreateOrderRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	if err := h.validate.Struct(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Validation failed", "details": err.Error()})
+	}
+
+	type line struct{ eventID, ticketTypeID uint }
+	seen := make(map[line]bool, len(req.Items))
+	items := make([]domain.OrderItem, len(req.Items))
+	for i, item := range req.Items {
+		key := line{eventID: item.EventID}
+		if item.TicketTypeID != nil {
+			key.ticketTypeID = *item.TicketTypeID
+		}
+		if seen[key] {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Each event or ticket type may appear only once in items"})
+		}
+		seen[key] = true
+		items[i] = domain.OrderItem{EventID: item.EventID, TicketTypeID: item.TicketTypeID, Quantity: item.Quantity}
+	}
+
+	user, ok := middleware.CurrentUser(c)
+	if !ok || user.UserID == 0 {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid token claims"})
+	}
+
+	order, err := h.orderService.CreateOrder(c.UserContext(), user.UserID, items)
+	if err != nil {
+		return reservationError(c, err, "Failed to create order")
+	}
+	return c.Status(fiber.StatusCreated).JSON(newOrderResponse(order))
+}
+
+func (h *OrderHandler) GetOrder(c *fiber.Ctx) error {
+	order, resp, ok := h.ownOrder(c)
+	if !ok {
+		return resp
+	}
+	return c.JSON(newOrderResponse(order))
+}
+
+// CancelOrder huỷ order đang PENDING cùng mọi booking của nó và trả lại vé
+func (h *OrderHandler) CancelOrder(c *fiber.Ctx) error {
+	order, resp, ok := h.ownOrder(c)
+	if !ok {
+		return resp
+	}
+	order, err := h.orderService.CancelOrder(c.UserContext(), order.ID)
+	if errors.Is(err, domain.ErrOrderNotPending) {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": err.Error()})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to cancel order"})
+	}
+	return c.JSON(newOrderResponse(order))
+}
+
+// ownOrder tải order trong :id và kiểm tra user được thao tác trên nó, như
+// ownBooking: admin với mọi order, các role khác chỉ với order của mình
+func (h *OrderHandler) ownOrder(c *fiber.Ctx) (order *domain.Order, resp error, ok bool) {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return nil, c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid order ID"}), false
+	}
+	order, err = h.orderService.GetOrder(c.UserContext(), uint(id))
+	if errors.Is(err, domain.ErrNotFound) {
+		return nil, c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Order not found"}), false
+	}
+	if err != nil {
+		return nil, c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to get order"}), false
+	}
+	user, ok := middleware.CurrentUser(c)
+	if !ok || user.UserID == 0 {
+		return nil, c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid token claims"}), false
+	}
+	if !user.IsAdmin() && order.UserID != user.UserID {
+		return nil, c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "You can only access your own orders"}), false
+	}
+	return order, nil, true
+}
@@ -0,0 +1,176 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"ticket_app/domain"
+)
+
+type MockOrderService struct {
+	mock.Mock
+}
+
+func (m *MockOrderService) CreateOrder(ctx context.Context, userID uint, items []domain.OrderItem) (*domain.Order, error) {
+	args := m.Called(userID, items)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Order), args.Error(1)
+}
+
+func (m *MockOrderService) GetOrder(ctx context.Context, id uint) (*domain.Order, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Order), args.Error(1)
+}
+
+func (m *MockOrderService) CancelOrder(ctx context.Context, id uint) (*domain.Order, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Order), args.Error(1)
+}
+
+func setupOrderAppAs(svc *MockOrderService, claims jwt.MapClaims) *fiber.App {
+	app := appWithClaims(claims)
+	NewOrderHandler(app, svc)
+	return app
+}
+
+func TestOrders(t *testing.T) {
+	t.Run("Create", testCreateOrder)
+	t.Run("CreateValidation", testCreateOrderValidation)
+	t.Run("CreateSoldOut", testCreateOrderSoldOut)
+	t.Run("GetOwn", testGetOwnOrder)
+	t.Run("GetOthers", testGetOrderOfOtherUser)
+	t.Run("AdminGets", testAdminGetsOrder)
+	t.Run("Cancel", testCancelOrder)
+	t.Run("CancelNotPending", testCancelOrderNotPending)
+}
+
+func testCreateOrder(t *testing.T) {
+	svc := new(MockOrderService)
+	typeID := uint(3)
+	svc.On("CreateOrder", uint(1), []domain.OrderItem{
+		{EventID: 1, Quantity: 2},
+		{EventID: 2, TicketTypeID: &typeID, Quantity: 1},
+	}).Return(&domain.Order{
+		ID: 7, UserID: 1, TotalPrice: 250, Status: domain.BookingStatusPending,
+		Items: []domain.OrderItem{
+			{EventID: 1, Quantity: 2, UnitPrice: 50, BookingID: 11},
+			{EventID: 2, TicketTypeID: &typeID, Quantity: 1, UnitPrice: 150, BookingID: 12},
+		},
+		Bookings: []domain.Booking{
+			{ID: 11, EventID: 1, Quantity: 2, TotalPrice: 100, Status: domain.BookingStatusPending},
+			{ID: 12, EventID: 2, Quantity: 1, TotalPrice: 150, Status: domain.BookingStatusPending},
+		},
+		Payment: &domain.Payment{ID: 5, Amount: 250, Status: domain.PaymentStatusPending},
+	}, nil)
+
+	resp := jsonRequest(setupOrderAppAs(svc, customerClaims("1")), "POST", "/orders",
+		`{"items":[{"event_id":1,"quantity":2},{"event_id":2,"ticket_type_id":3,"quantity":1}]}`)
+	assert.Equal(t, fiber.StatusCreated, resp.StatusCode)
+	var result OrderResponse
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+	assert.Equal(t, float64(250), result.TotalPrice)
+	assert.Len(t, result.Items, 2)
+	assert.Len(t, result.Bookings, 2)
+	assert.Equal(t, float64(250), result.Payment.Amount)
+	svc.AssertExpectations(t)
+}
+
+func testCreateOrderValidation(t *testing.T) {
+	svc := new(MockOrderService)
+	app := setupOrderAppAs(svc, customerClaims("1"))
+	for _, body := range []string{
+		`{}`,
+		`{"items":[]}`,
+		`{"items":[{"event_id":1}]}`,
+		`{"items":[{"quantity":1}]}`,
+		`{"items":[{"event_id":1,"quantity":1},{"event_id":1,"quantity":2}]}`,
+		`{"items":[{"event_id":1,"ticket_type_id":3,"quantity":1},{"event_id":1,"ticket_type_id":3,"quantity":2}]}`,
+	} {
+		resp := jsonRequest(app, "POST", "/orders", body)
+		assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode, body)
+	}
+	svc.AssertNotCalled(t, "CreateOrder", mock.Anything, mock.Anything)
+}
+
+func testCreateOrderSoldOut(t *testing.T) {
+	cases := []struct {
+		err    error
+		status int
+	}{
+		{domain.ErrNotEnoughTickets, fiber.StatusConflict},
+		{domain.ErrTicketTypeRequired, fiber.StatusBadRequest},
+		{domain.ErrSeatSelectionRequired, fiber.StatusBadRequest},
+		{domain.ErrEmailNotVerified, fiber.StatusForbidden},
+	}
+	for _, tc := range cases {
+		svc := new(MockOrderService)
+		svc.On("CreateOrder", uint(1), mock.Anything).Return(nil, tc.err)
+		resp := jsonRequest(setupOrderAppAs(svc, customerClaims("1")), "POST", "/orders",
+			`{"items":[{"event_id":1,"quantity":2},{"event_id":2,"quantity":1}]}`)
+		assert.Equal(t, tc.status, resp.StatusCode, tc.err.Error())
+	}
+}
+
+func testGetOwnOrder(t *testing.T) {
+	svc := new(MockOrderService)
+	svc.On("GetOrder", uint(7)).Return(&domain.Order{ID: 7, UserID: 1, Status: domain.BookingStatusPending}, nil)
+	resp := jsonRequest(setupOrderAppAs(svc, customerClaims("1")), "GET", "/orders/7", "")
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+	svc.On("GetOrder", uint(8)).Return(nil, domain.ErrNotFound)
+	resp = jsonRequest(setupOrderAppAs(svc, customerClaims("1")), "GET", "/orders/8", "")
+	assert.Equal(t, fiber.StatusNotFound, resp.StatusCode)
+}
+
+func testGetOrderOfOtherUser(t *testing.T) {
+	svc := new(MockOrderService)
+	svc.On("GetOrder", uint(7)).Return(&domain.Order{ID: 7, UserID: 2}, nil)
+	app := setupOrderAppAs(svc, customerClaims("1"))
+	resp := jsonRequest(app, "GET", "/orders/7", "")
+	assert.Equal(t, fiber.StatusForbidden, resp.StatusCode)
+	resp = jsonRequest(app, "PUT", "/orders/7/cancel", "")
+	assert.Equal(t, fiber.StatusForbidden, resp.StatusCode)
+	svc.AssertNotCalled(t, "CancelOrder", mock.Anything)
+}
+
+func testAdminGetsOrder(t *testing.T) {
+	svc := new(MockOrderService)
+	svc.On("GetOrder", uint(7)).Return(&domain.Order{ID: 7, UserID: 2}, nil)
+	claims := jwt.MapClaims{"sub": "9", "email": "admin@example.com", "role": "admin"}
+	resp := jsonRequest(setupOrderAppAs(svc, claims), "GET", "/orders/7", "")
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+}
+
+func testCancelOrder(t *testing.T) {
+	svc := new(MockOrderService)
+	svc.On("GetOrder", uint(7)).Return(&domain.Order{ID: 7, UserID: 1, Status: domain.BookingStatusPending}, nil)
+	svc.On("CancelOrder", uint(7)).Return(&domain.Order{ID: 7, UserID: 1, Status: domain.BookingStatusCancelled}, nil)
+	resp := jsonRequest(setupOrderAppAs(svc, customerClaims("1")), "PUT", "/orders/7/cancel", "")
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	var result OrderResponse
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+	assert.Equal(t, domain.BookingStatusCancelled, result.Status)
+	svc.AssertExpectations(t)
+}
+
+func testCancelOrderNotPending(t *testing.T) {
+	svc := new(MockOrderService)
+	svc.On("GetOrder", uint(7)).Return(&domain.Order{ID: 7, UserID: 1, Status: domain.BookingStatusConfirmed}, nil)
+	svc.On("CancelOrder", uint(7)).Return(nil, domain.ErrOrderNotPending)
+	resp := jsonRequest(setupOrderAppAs(svc, customerClaims("1")), "PUT", "/orders/7/cancel", "")
+	assert.Equal(t, fiber.StatusConflict, resp.StatusCode)
+}
@@ -6,6 +6,7 @@ import (
 
 	"strconv"
 	"ticket_app/domain"
+	middleware "ticket_app/internal/rest/middleware"
 
 	validator "github.com/go-playground/validator/v10"
 	"github.com/gofiber/fiber/v2"
@@ -27,9 +28,11 @@ func NewPaymentHandler(app *fiber.App, paymentService payment.PaymentService) *P
 		validate:     validator.New(),
 	}
 
-	app.Post("/payments", handler.CreatePayment)
-	app.Put("/payments/:id/confirm", handler.ConfirmPayment)
-	app.Put("/payments/:id/cancel", handler.CancelPayment)
+	// Payment được tạo cùng booking và xử lý bởi worker; các route này để admin xử lý tay
+	admins := middleware.RequireRole(domain.RoleAdmin)
+	app.Post("/payments", admins, handler.CreatePayment)
+	app.Put("/payments/:id/confirm", admins, handler.ConfirmPayment)
+	app.Put("/payments/:id/cancel", admins, handler.CancelPayment)
 
 	return handler
 }
@@ -42,7 +45,7 @@ func (h *PaymentHandler) CreatePayment(c *fiber.Ctx) error {
 	}
 
 	payment := domain.Payment{
-		BookingID: req.BookingID,
+		BookingID: &req.BookingID,
 		Amount: float64(req.Amount),
 		Status: domain.PaymentStatusPending,
 	}
@@ -51,7 +54,7 @@ func (h *PaymentHandler) CreatePayment(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Validation failed", "details": err.Error()})
 	}
 
-	err := h.paymentService.CreatePayment(&payment)
+	err := h.paymentService.CreatePayment(c.UserContext(), &payment)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to create payment"})
 	}
@@ -67,7 +70,7 @@ func (h *PaymentHandler) ConfirmPayment(c *fiber.Ctx) error {
 
 	payment := domain.Payment{ID: uint(id)}
 
-	err = h.paymentService.ConfirmPayment(&payment)
+	err = h.paymentService.ConfirmPayment(c.UserContext(), &payment)
 	if err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Payment not found"})
 	}
@@ -83,7 +86,7 @@ func (h *PaymentHandler) CancelPayment(c *fiber.Ctx) error {
 
 	payment := domain.Payment{ID: uint(id)}
 	payment.Status = domain.PaymentStatusFailed
-	err = h.paymentService.CancelPayment(&payment)
+	err = h.paymentService.CancelPayment(c.UserContext(), &payment)
 	if err != nil {
 		log.Printf("Error canceling payment: %v", err)
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Payment not found"})
@@ -2,6 +2,7 @@ package rest
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"net/http/httptest"
@@ -20,22 +21,30 @@ type MockPaymentService struct {
 	mock.Mock
 }
 
-func (m *MockPaymentService) CreatePayment(payment *domain.Payment) error {
+func (m *MockPaymentService) CreatePayment(ctx context.Context, payment *domain.Payment) error {
 	return m.Called(payment).Error(0)
 }
 
-func (m *MockPaymentService) ConfirmPayment(payment *domain.Payment) error {
+func (m *MockPaymentService) ConfirmPayment(ctx context.Context, payment *domain.Payment) error {
 	return m.Called(payment).Error(0)
 }
 
-func (m *MockPaymentService) CancelPayment(payment *domain.Payment) error {
+func (m *MockPaymentService) CancelPayment(ctx context.Context, payment *domain.Payment) error {
 	return m.Called(payment).Error(0)
 }
 
-func (m *MockPaymentService) FindByBookingID(bookingID uint) (*domain.Payment, error) {
+func (m *MockPaymentService) FindByBookingID(ctx context.Context, bookingID uint) (*domain.Payment, error) {
 	return m.Called(bookingID).Get(0).(*domain.Payment), m.Called(bookingID).Error(1)
 }
 
+func (m *MockPaymentService) FindByOrderID(ctx context.Context, orderID uint) (*domain.Payment, error) {
+	args := m.Called(orderID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Payment), args.Error(1)
+}
+
 
 func setupPaymentApp(ps *MockPaymentService, as *MockAuthService) *fiber.App {
 	app := fiber.New()
@@ -0,0 +1,61 @@
+package rest
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+
+	auth "ticket_app/auth"
+	"ticket_app/domain"
+)
+
+// UserAdminHandler lets admins manage the roles of users
+type UserAdminHandler struct {
+	authService auth.AuthService
+	validate    *validator.Validate
+}
+
+type ChangeRoleRequest struct {
+	Role domain.Role `json:"role" validate:"required,oneof=customer organizer admin"`
+}
+
+func NewUserAdminHandler(app *fiber.App, authService auth.AuthService) *UserAdminHandler {
+	handler := &UserAdminHandler{authService: authService, validate: validator.New()}
+
+	app.Put("/admin/users/:id/role", handler.ChangeRole)
+
+	return handler
+}
+
+func (h *UserAdminHandler) ChangeRole(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid user ID"})
+	}
+	var req ChangeRoleRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	if err := h.validate.Struct(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Validation failed", "details": err.Error()})
+	}
+
+	user, err := h.authService.ChangeRole(c.UserContext(), uint(id), req.Role)
+	if errors.Is(err, domain.ErrNotFound) || errors.Is(err, gorm.ErrRecordNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "User not found"})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to change role"})
+	}
+	return c.JSON(UserResponse{
+		ID:              user.ID,
+		Email:           user.Email,
+		Role:            string(user.Role),
+		EmailVerifiedAt: user.EmailVerifiedAt,
+		CreatedAt:       user.CreatedAt,
+		UpdatedAt:       user.UpdatedAt,
+	})
+}
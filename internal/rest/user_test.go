@@ -0,0 +1,54 @@
+package rest
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+
+	"ticket_app/domain"
+)
+
+func TestChangeRole(t *testing.T) {
+	t.Run("Success", testChangeRoleSuccess)
+	t.Run("UnknownRole", testChangeRoleUnknownRole)
+	t.Run("NotFound", testChangeRoleNotFound)
+}
+
+func changeRoleRequest(app *fiber.App, id, body string) int {
+	req := httptest.NewRequest("PUT", "/admin/users/"+id+"/role", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp, _ := app.Test(req)
+	return resp.StatusCode
+}
+
+func testChangeRoleSuccess(t *testing.T) {
+	mockAuth := new(MockAuthService)
+	mockAuth.On("ChangeRole", uint(2), domain.RoleOrganizer).
+		Return(&domain.User{ID: 2, Email: "bob@example.com", Role: domain.RoleOrganizer}, nil)
+	app := fiber.New()
+	NewUserAdminHandler(app, mockAuth)
+
+	assert.Equal(t, fiber.StatusOK, changeRoleRequest(app, "2", `{"role":"organizer"}`))
+	mockAuth.AssertExpectations(t)
+}
+
+func testChangeRoleUnknownRole(t *testing.T) {
+	mockAuth := new(MockAuthService)
+	app := fiber.New()
+	NewUserAdminHandler(app, mockAuth)
+
+	assert.Equal(t, fiber.StatusBadRequest, changeRoleRequest(app, "2", `{"role":"superuser"}`))
+	mockAuth.AssertNotCalled(t, "ChangeRole")
+}
+
+func testChangeRoleNotFound(t *testing.T) {
+	mockAuth := new(MockAuthService)
+	mockAuth.On("ChangeRole", uint(99), domain.RoleAdmin).Return(nil, domain.ErrNotFound)
+	app := fiber.New()
+	NewUserAdminHandler(app, mockAuth)
+
+	assert.Equal(t, fiber.StatusNotFound, changeRoleRequest(app, "99", `{"role":"admin"}`))
+}
@@ -0,0 +1,137 @@
+package rest
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+
+	"ticket_app/domain"
+	middleware "ticket_app/internal/rest/middleware"
+	"ticket_app/venue"
+)
+
+type VenueHandler struct {
+	venueService venue.VenueService
+	validate     *validator.Validate
+}
+
+// NewVenueHandler đăng ký các route venue và sơ đồ ghế. Xem venue và sơ đồ ghế
+// là public; tạo venue cần requireAuth và role organizer hoặc admin.
+func NewVenueHandler(app *fiber.App, venueService venue.VenueService, requireAuth fiber.Handler) *VenueHandler {
+	handler := &VenueHandler{
+		venueService: venueService,
+		validate:     validator.New(),
+	}
+
+	managers := middleware.RequireRole(domain.RoleOrganizer, domain.RoleAdmin)
+
+	app.Post("/venues", requireAuth, managers, handler.CreateVenue)
+	app.Get("/venues", handler.ListVenues)
+	app.Get("/venues/:id", handler.GetVenue)
+	app.Get("/events/:id/seat-map", handler.GetSeatMap)
+
+	return handler
+}
+
+// CreateVenueRequest mô tả sơ đồ ghế theo section và hàng; ghế của mỗi hàng
+// được đánh số từ 1 đến Seats
+type CreateVenueRequest struct {
+	Name     string           `json:"name" validate:"required,max=255"`
+	Address  string           `json:"address"`
+	Sections []SectionRequest `json:"sections" validate:"required,min=1,max=50,dive"`
+}
+
+type SectionRequest struct {
+	Name string       `json:"name" validate:"required,max=100"`
+	Rows []RowRequest `json:"rows" validate:"required,min=1,max=100,dive"`
+}
+
+type RowRequest struct {
+	Label string `json:"label" validate:"required,max=10"`
+	Seats int    `json:"seats" validate:"required,min=1,max=200"`
+}
+
+func (r *CreateVenueRequest) toDomain() domain.Venue {
+	venue := domain.Venue{Name: r.Name, Address: r.Address, Sections: make([]domain.Section, len(r.Sections))}
+	for i, section := range r.Sections {
+		rows := make([]domain.Row, len(section.Rows))
+		for j, row := range section.Rows {
+			seats := make([]domain.Seat, row.Seats)
+			for n := range seats {
+				seats[n].Number = n + 1
+			}
+			rows[j] = domain.Row{Label: row.Label, Position: j + 1, Seats: seats}
+		}
+		venue.Sections[i] = domain.Section{Name: section.Name, Rows: rows}
+	}
+	return venue
+}
+
+func (h *VenueHandler) CreateVenue(c *fiber.Ctx) error {
+	var req CreateVenueRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	if err := h.validate.Struct(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Validation failed", "details": err.Error()})
+	}
+
+	venue := req.toDomain()
+	if err := h.venueService.CreateVenue(c.UserContext(), &venue); err != nil {
+		switch {
+		case errors.Is(err, domain.ErrBadParamInput):
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		case errors.Is(err, domain.ErrConflict):
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Section names and row labels must be unique"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to create venue"})
+	}
+	return c.Status(fiber.StatusCreated).JSON(venue)
+}
+
+func (h *VenueHandler) ListVenues(c *fiber.Ctx) error {
+	venues, err := h.venueService.ListVenues(c.UserContext())
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to get venues"})
+	}
+	if venues == nil {
+		venues = []domain.Venue{}
+	}
+	return c.JSON(venues)
+}
+
+func (h *VenueHandler) GetVenue(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid venue ID"})
+	}
+	venue, err := h.venueService.GetVenue(c.UserContext(), uint(id))
+	if errors.Is(err, domain.ErrNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Venue not found"})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to get venue"})
+	}
+	return c.JSON(venue)
+}
+
+// GetSeatMap trả về sơ đồ ghế của event với trạng thái AVAILABLE, HELD hoặc
+// SOLD của từng ghế và giá của từng section
+func (h *VenueHandler) GetSeatMap(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid event ID"})
+	}
+	seatMap, err := h.venueService.GetSeatMap(c.UserContext(), uint(id))
+	switch {
+	case errors.Is(err, domain.ErrNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Event not found"})
+	case errors.Is(err, domain.ErrNoSeatMap):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	case err != nil:
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to get seat map"})
+	}
+	return c.JSON(seatMap)
+}
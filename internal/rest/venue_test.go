@@ -0,0 +1,166 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"ticket_app/domain"
+)
+
+type MockVenueService struct {
+	mock.Mock
+}
+
+func (m *MockVenueService) CreateVenue(ctx context.Context, venue *domain.Venue) error {
+	args := m.Called(venue)
+	return args.Error(0)
+}
+
+func (m *MockVenueService) ListVenues(ctx context.Context) ([]domain.Venue, error) {
+	args := m.Called()
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]domain.Venue), args.Error(1)
+}
+
+func (m *MockVenueService) GetVenue(ctx context.Context, id uint) (*domain.Venue, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Venue), args.Error(1)
+}
+
+func (m *MockVenueService) GetSeatMap(ctx context.Context, eventID uint) (*domain.SeatMap, error) {
+	args := m.Called(eventID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.SeatMap), args.Error(1)
+}
+
+// setupVenueAppAs thay JWTMiddleware bằng một middleware gán sẵn claims; claims nil là chưa đăng nhập
+func setupVenueAppAs(svc *MockVenueService, claims jwt.MapClaims) *fiber.App {
+	app := fiber.New()
+	NewVenueHandler(app, svc, func(c *fiber.Ctx) error {
+		if claims == nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Missing authorization header"})
+		}
+		c.Locals("user", &jwt.Token{Claims: claims})
+		return c.Next()
+	})
+	return app
+}
+
+func TestVenues(t *testing.T) {
+	t.Run("OrganizerCreates", testOrganizerCreatesVenue)
+	t.Run("CreateValidation", testCreateVenueValidation)
+	t.Run("CreateDuplicateRow", testCreateVenueDuplicateRow)
+	t.Run("CustomerCannotCreate", testCustomerCannotCreateVenue)
+	t.Run("GetNotFound", testGetVenueNotFound)
+	t.Run("SeatMap", testGetSeatMap)
+	t.Run("SeatMapWithoutVenue", testGetSeatMapWithoutVenue)
+	t.Run("SeatMapUnknownEvent", testGetSeatMapUnknownEvent)
+}
+
+const hallVenue = `{"name":"Hall","address":"1 Main St","sections":[{"name":"Orchestra","rows":[{"label":"A","seats":3},{"label":"B","seats":2}]}]}`
+
+func testOrganizerCreatesVenue(t *testing.T) {
+	svc := new(MockVenueService)
+	svc.On("CreateVenue", mock.MatchedBy(func(v *domain.Venue) bool {
+		rows := v.Sections[0].Rows
+		return v.Name == "Hall" && len(v.Sections) == 1 && len(rows) == 2 &&
+			rows[0].Position == 1 && len(rows[0].Seats) == 3 && rows[0].Seats[2].Number == 3 &&
+			rows[1].Label == "B" && rows[1].Position == 2 && len(rows[1].Seats) == 2
+	})).Return(nil)
+
+	resp := jsonRequest(setupVenueAppAs(svc, organizerClaims("2")), "POST", "/venues", hallVenue)
+	assert.Equal(t, fiber.StatusCreated, resp.StatusCode)
+	svc.AssertExpectations(t)
+}
+
+func testCreateVenueValidation(t *testing.T) {
+	svc := new(MockVenueService)
+	app := setupVenueAppAs(svc, organizerClaims("2"))
+	for _, body := range []string{
+		`{"name":"Hall"}`,
+		`{"name":"Hall","sections":[{"name":"Orchestra","rows":[]}]}`,
+		`{"name":"Hall","sections":[{"name":"Orchestra","rows":[{"label":"A","seats":0}]}]}`,
+		`{"sections":[{"name":"Orchestra","rows":[{"label":"A","seats":1}]}]}`,
+	} {
+		resp := jsonRequest(app, "POST", "/venues", body)
+		assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode, body)
+	}
+	svc.AssertNotCalled(t, "CreateVenue", mock.Anything)
+}
+
+func testCreateVenueDuplicateRow(t *testing.T) {
+	svc := new(MockVenueService)
+	svc.On("CreateVenue", mock.Anything).Return(domain.ErrConflict)
+	resp := jsonRequest(setupVenueAppAs(svc, organizerClaims("2")), "POST", "/venues", hallVenue)
+	assert.Equal(t, fiber.StatusConflict, resp.StatusCode)
+}
+
+func testCustomerCannotCreateVenue(t *testing.T) {
+	svc := new(MockVenueService)
+	claims := jwt.MapClaims{"sub": "3", "email": "customer@example.com", "role": "customer"}
+	resp := jsonRequest(setupVenueAppAs(svc, claims), "POST", "/venues", hallVenue)
+	assert.Equal(t, fiber.StatusForbidden, resp.StatusCode)
+	resp = jsonRequest(setupVenueAppAs(svc, nil), "POST", "/venues", hallVenue)
+	assert.Equal(t, fiber.StatusUnauthorized, resp.StatusCode)
+	svc.AssertNotCalled(t, "CreateVenue", mock.Anything)
+}
+
+func testGetVenueNotFound(t *testing.T) {
+	svc := new(MockVenueService)
+	svc.On("GetVenue", uint(9)).Return(nil, domain.ErrNotFound)
+	resp := jsonRequest(setupVenueAppAs(svc, nil), "GET", "/venues/9", "")
+	assert.Equal(t, fiber.StatusNotFound, resp.StatusCode)
+}
+
+func testGetSeatMap(t *testing.T) {
+	svc := new(MockVenueService)
+	price := 500000.0
+	svc.On("GetSeatMap", uint(1)).Return(&domain.SeatMap{
+		EventID: 1, VenueID: 2, Venue: "Hall",
+		Sections: []domain.SeatMapSection{{
+			ID: 3, Name: "Orchestra", Price: &price,
+			Rows: []domain.SeatMapRow{{ID: 4, Label: "A", Seats: []domain.SeatMapSeat{
+				{ID: 5, Number: 1, Status: domain.SeatAvailable},
+				{ID: 6, Number: 2, Status: domain.SeatHeld},
+				{ID: 7, Number: 3, Status: domain.SeatSold},
+			}}},
+		}},
+	}, nil)
+
+	// Sơ đồ ghế là public
+	resp := jsonRequest(setupVenueAppAs(svc, nil), "GET", "/events/1/seat-map", "")
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	var result domain.SeatMap
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+	seats := result.Sections[0].Rows[0].Seats
+	assert.Equal(t, []domain.SeatStatus{domain.SeatAvailable, domain.SeatHeld, domain.SeatSold},
+		[]domain.SeatStatus{seats[0].Status, seats[1].Status, seats[2].Status})
+	assert.Equal(t, price, *result.Sections[0].Price)
+}
+
+func testGetSeatMapWithoutVenue(t *testing.T) {
+	svc := new(MockVenueService)
+	svc.On("GetSeatMap", uint(1)).Return(nil, domain.ErrNoSeatMap)
+	resp := jsonRequest(setupVenueAppAs(svc, nil), "GET", "/events/1/seat-map", "")
+	assert.Equal(t, fiber.StatusNotFound, resp.StatusCode)
+}
+
+func testGetSeatMapUnknownEvent(t *testing.T) {
+	svc := new(MockVenueService)
+	svc.On("GetSeatMap", uint(9)).Return(nil, domain.ErrNotFound)
+	resp := jsonRequest(setupVenueAppAs(svc, nil), "GET", "/events/9/seat-map", "")
+	assert.Equal(t, fiber.StatusNotFound, resp.StatusCode)
+}
@@ -0,0 +1,146 @@
+package rest
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+
+	"ticket_app/booking"
+	"ticket_app/domain"
+	middleware "ticket_app/internal/rest/middleware"
+)
+
+type WaitlistHandler struct {
+	waitlistService booking.WaitlistService
+	validate        *validator.Validate
+}
+
+// NewWaitlistHandler đăng ký các route waitlist; mọi route cần đăng nhập và
+// chỉ thao tác trên entry của chính user
+func NewWaitlistHandler(app *fiber.App, waitlistService booking.WaitlistService) *WaitlistHandler {
+	handler := &WaitlistHandler{
+		waitlistService: waitlistService,
+		validate:        validator.New(),
+	}
+
+	app.Post("/events/:id/waitlist", handler.JoinWaitlist)
+	app.Get("/me/waitlist", handler.ListMyWaitlist)
+	app.Delete("/me/waitlist/:id", handler.LeaveWaitlist)
+
+	return handler
+}
+
+// JoinWaitlistRequest chờ Quantity vé giá thường của event, hoặc vé của một
+// hạng vé khi có TicketTypeID
+type JoinWaitlistRequest struct {
+	TicketTypeID *uint `json:"ticket_type_id" validate:"omitempty,min=1"`
+	Quantity     int   `json:"quantity" validate:"required,min=1"`
+}
+
+// WaitlistEntryResponse là một chỗ trong waitlist. Khi Status là OFFERED,
+// Offer là hold đang giữ vé cho user: gửi Offer.Token trong hold_token của
+// POST /bookings trước Offer.ExpiresAt để đặt.
+type WaitlistEntryResponse struct {
+	ID           uint                   `json:"id"`
+	EventID      uint                   `json:"event_id"`
+	TicketTypeID *uint                  `json:"ticket_type_id,omitempty"`
+	Quantity     int                    `json:"quantity"`
+	Status       domain.WaitlistStatus  `json:"status"`
+	CreatedAt    time.Time              `json:"created_at"`
+	OfferedAt    *time.Time             `json:"offered_at,omitempty"`
+	Offer        *WaitlistOfferResponse `json:"offer,omitempty"`
+}
+
+type WaitlistOfferResponse struct {
+	Token      string    `json:"token"`
+	TotalPrice float64   `json:"total_price"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+func newWaitlistEntryResponse(entry *domain.WaitlistEntry) WaitlistEntryResponse {
+	resp := WaitlistEntryResponse{
+		ID:           entry.ID,
+		EventID:      entry.EventID,
+		TicketTypeID: entry.TicketTypeID,
+		Quantity:     entry.Quantity,
+		Status:       entry.Status,
+		CreatedAt:    entry.CreatedAt,
+		OfferedAt:    entry.OfferedAt,
+	}
+	if entry.Status == domain.WaitlistStatusOffered && entry.Hold != nil {
+		resp.Offer = &WaitlistOfferResponse{Token: entry.Hold.Token, TotalPrice: entry.Hold.TotalPrice, ExpiresAt: entry.Hold.ExpiresAt}
+	}
+	return resp
+}
+
+func (h *WaitlistHandler) JoinWaitlist(c *fiber.Ctx) error {
+	eventID, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid event ID"})
+	}
+	var req JoinWaitlistRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	if err := h.validate.Struct(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Validation failed", "details": err.Error()})
+	}
+
+	user, ok := middleware.CurrentUser(c)
+	if !ok || user.UserID == 0 {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid token claims"})
+	}
+
+	entry, err := h.waitlistService.JoinWaitlist(c.UserContext(), user.UserID, uint(eventID), req.TicketTypeID, req.Quantity)
+	switch {
+	case errors.Is(err, domain.ErrNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Event not found"})
+	case errors.Is(err, domain.ErrAlreadyWaitlisted), errors.Is(err, domain.ErrTicketsAvailable):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": err.Error()})
+	case err != nil:
+		return reservationError(c, err, "Failed to join waitlist")
+	}
+	return c.Status(fiber.StatusCreated).JSON(newWaitlistEntryResponse(entry))
+}
+
+func (h *WaitlistHandler) ListMyWaitlist(c *fiber.Ctx) error {
+	user, ok := middleware.CurrentUser(c)
+	if !ok || user.UserID == 0 {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid token claims"})
+	}
+	entries, err := h.waitlistService.ListUserWaitlist(c.UserContext(), user.UserID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to list waitlist"})
+	}
+	resp := make([]WaitlistEntryResponse, len(entries))
+	for i := range entries {
+		resp[i] = newWaitlistEntryResponse(&entries[i])
+	}
+	return c.JSON(resp)
+}
+
+// LeaveWaitlist bỏ chỗ trong waitlist; entry đang có offer thì offer bị từ chối
+func (h *WaitlistHandler) LeaveWaitlist(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid waitlist entry ID"})
+	}
+	user, ok := middleware.CurrentUser(c)
+	if !ok || user.UserID == 0 {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid token claims"})
+	}
+
+	entry, err := h.waitlistService.LeaveWaitlist(c.UserContext(), user.UserID, uint(id))
+	switch {
+	case errors.Is(err, domain.ErrNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Waitlist entry not found"})
+	case errors.Is(err, domain.ErrWaitlistEntryNotActive):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": err.Error()})
+	case err != nil:
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to leave waitlist"})
+	}
+	return c.JSON(newWaitlistEntryResponse(entry))
+}
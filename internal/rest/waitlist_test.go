@@ -0,0 +1,157 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"ticket_app/domain"
+)
+
+type MockWaitlistService struct {
+	mock.Mock
+}
+
+func (m *MockWaitlistService) JoinWaitlist(ctx context.Context, userID uint, eventID uint, ticketTypeID *uint, quantity int) (*domain.WaitlistEntry, error) {
+	args := m.Called(userID, eventID, ticketTypeID, quantity)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.WaitlistEntry), args.Error(1)
+}
+
+func (m *MockWaitlistService) ListUserWaitlist(ctx context.Context, userID uint) ([]domain.WaitlistEntry, error) {
+	args := m.Called(userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]domain.WaitlistEntry), args.Error(1)
+}
+
+func (m *MockWaitlistService) LeaveWaitlist(ctx context.Context, userID uint, id uint) (*domain.WaitlistEntry, error) {
+	args := m.Called(userID, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.WaitlistEntry), args.Error(1)
+}
+
+func setupWaitlistAppAs(svc *MockWaitlistService, claims jwt.MapClaims) *fiber.App {
+	app := appWithClaims(claims)
+	NewWaitlistHandler(app, svc)
+	return app
+}
+
+func TestWaitlist(t *testing.T) {
+	t.Run("Join", testJoinWaitlist)
+	t.Run("JoinValidation", testJoinWaitlistValidation)
+	t.Run("JoinErrors", testJoinWaitlistErrors)
+	t.Run("ListShowsOffer", testListWaitlistShowsOffer)
+	t.Run("Leave", testLeaveWaitlist)
+	t.Run("LeaveErrors", testLeaveWaitlistErrors)
+}
+
+func testJoinWaitlist(t *testing.T) {
+	svc := new(MockWaitlistService)
+	vip := uint(3)
+	svc.On("JoinWaitlist", uint(1), uint(5), &vip, 2).
+		Return(&domain.WaitlistEntry{ID: 9, UserID: 1, EventID: 5, TicketTypeID: &vip, Quantity: 2, Status: domain.WaitlistStatusWaiting}, nil)
+
+	resp := jsonRequest(setupWaitlistAppAs(svc, customerClaims("1")), "POST", "/events/5/waitlist", `{"ticket_type_id":3,"quantity":2}`)
+	assert.Equal(t, fiber.StatusCreated, resp.StatusCode)
+	var result WaitlistEntryResponse
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+	assert.Equal(t, uint(9), result.ID)
+	assert.Equal(t, domain.WaitlistStatusWaiting, result.Status)
+	assert.Nil(t, result.Offer)
+	svc.AssertExpectations(t)
+}
+
+func testJoinWaitlistValidation(t *testing.T) {
+	svc := new(MockWaitlistService)
+	app := setupWaitlistAppAs(svc, customerClaims("1"))
+	for path, body := range map[string]string{
+		"/events/5/waitlist":   `{}`,
+		"/events/abc/waitlist": `{"quantity":1}`,
+	} {
+		resp := jsonRequest(app, "POST", path, body)
+		assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode, path+" "+body)
+	}
+	resp := jsonRequest(app, "POST", "/events/5/waitlist", `{"ticket_type_id":0,"quantity":1}`)
+	assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+	svc.AssertNotCalled(t, "JoinWaitlist", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func testJoinWaitlistErrors(t *testing.T) {
+	cases := []struct {
+		err    error
+		status int
+	}{
+		{domain.ErrTicketsAvailable, fiber.StatusConflict},
+		{domain.ErrAlreadyWaitlisted, fiber.StatusConflict},
+		{domain.ErrEventNotActive, fiber.StatusConflict},
+		{domain.ErrNotFound, fiber.StatusNotFound},
+		{domain.ErrTicketTypeRequired, fiber.StatusBadRequest},
+		{domain.ErrSeatSelectionRequired, fiber.StatusBadRequest},
+		{domain.ErrEmailNotVerified, fiber.StatusForbidden},
+	}
+	for _, tc := range cases {
+		svc := new(MockWaitlistService)
+		svc.On("JoinWaitlist", uint(1), uint(5), (*uint)(nil), 1).Return(nil, tc.err)
+		resp := jsonRequest(setupWaitlistAppAs(svc, customerClaims("1")), "POST", "/events/5/waitlist", `{"quantity":1}`)
+		assert.Equal(t, tc.status, resp.StatusCode, tc.err.Error())
+	}
+}
+
+func testListWaitlistShowsOffer(t *testing.T) {
+	svc := new(MockWaitlistService)
+	expires := time.Now().Add(30 * time.Minute).UTC().Truncate(time.Second)
+	offered := time.Now().UTC().Truncate(time.Second)
+	holdID := uint(4)
+	svc.On("ListUserWaitlist", uint(1)).Return([]domain.WaitlistEntry{
+		{ID: 2, EventID: 5, Quantity: 1, Status: domain.WaitlistStatusOffered, OfferedAt: &offered, HoldID: &holdID,
+			Hold: &domain.Hold{ID: holdID, Token: "offer-tok", TotalPrice: 100, ExpiresAt: expires}},
+		{ID: 1, EventID: 6, Quantity: 2, Status: domain.WaitlistStatusExpired,
+			Hold: &domain.Hold{ID: 3, Token: "old-tok", Status: domain.HoldStatusExpired}},
+	}, nil)
+
+	resp := jsonRequest(setupWaitlistAppAs(svc, customerClaims("1")), "GET", "/me/waitlist", "")
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	var result []WaitlistEntryResponse
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+	if assert.Len(t, result, 2) && assert.NotNil(t, result[0].Offer) {
+		assert.Equal(t, "offer-tok", result[0].Offer.Token)
+		assert.True(t, expires.Equal(result[0].Offer.ExpiresAt))
+	}
+	// Offer đã hết hạn không lộ token
+	assert.Nil(t, result[1].Offer)
+}
+
+func testLeaveWaitlist(t *testing.T) {
+	svc := new(MockWaitlistService)
+	svc.On("LeaveWaitlist", uint(1), uint(2)).
+		Return(&domain.WaitlistEntry{ID: 2, UserID: 1, EventID: 5, Quantity: 1, Status: domain.WaitlistStatusCancelled}, nil)
+	resp := jsonRequest(setupWaitlistAppAs(svc, customerClaims("1")), "DELETE", "/me/waitlist/2", "")
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	var result WaitlistEntryResponse
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+	assert.Equal(t, domain.WaitlistStatusCancelled, result.Status)
+	svc.AssertExpectations(t)
+}
+
+func testLeaveWaitlistErrors(t *testing.T) {
+	svc := new(MockWaitlistService)
+	svc.On("LeaveWaitlist", uint(1), uint(2)).Return(nil, domain.ErrNotFound)
+	svc.On("LeaveWaitlist", uint(1), uint(3)).Return(nil, domain.ErrWaitlistEntryNotActive)
+	app := setupWaitlistAppAs(svc, customerClaims("1"))
+
+	assert.Equal(t, fiber.StatusNotFound, jsonRequest(app, "DELETE", "/me/waitlist/2", "").StatusCode)
+	assert.Equal(t, fiber.StatusConflict, jsonRequest(app, "DELETE", "/me/waitlist/3", "").StatusCode)
+	assert.Equal(t, fiber.StatusBadRequest, jsonRequest(app, "DELETE", "/me/waitlist/x", "").StatusCode)
+}
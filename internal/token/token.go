@@ -0,0 +1,310 @@
+package token
+
+import (
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"gopkg.in/yaml.v3"
+
+	"ticket_app/internal/config"
+)
+
+// Signing algorithms, as written in the JWT "alg" header
+const (
+	AlgHS256 = "HS256"
+	AlgRS256 = "RS256"
+	AlgEdDSA = "EdDSA"
+)
+
+// Issuer signs access tokens
+type Issuer interface {
+	Sign(claims jwt.MapClaims) (string, error)
+}
+
+// Verifier checks the signature and expiry of access tokens
+type Verifier interface {
+	Verify(tokenString string) (*jwt.Token, error)
+}
+
+// Key is one signing or verification key, identified by the "kid" header
+type Key struct {
+	ID        string
+	Algorithm string
+
+	secret  []byte           // HS256
+	private crypto.Signer    // RS256 / EdDSA, nil for verify-only keys
+	public  crypto.PublicKey // RS256 / EdDSA
+}
+
+// NewHMACKey creates an HS256 key from a shared secret
+func NewHMACKey(kid string, secret []byte) *Key {
+	return &Key{ID: kid, Algorithm: AlgHS256, secret: secret}
+}
+
+// ParsePrivateKey reads a PEM encoded RSA (PKCS#1 or PKCS#8) or Ed25519
+// (PKCS#8) private key. The algorithm follows from the key type.
+func ParsePrivateKey(kid string, data []byte) (*Key, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("key %s: no PEM data found", kid)
+	}
+	var parsed interface{}
+	var err error
+	if block.Type == "RSA PRIVATE KEY" {
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	} else {
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("key %s: %w", kid, err)
+	}
+	switch k := parsed.(type) {
+	case *rsa.PrivateKey:
+		return &Key{ID: kid, Algorithm: AlgRS256, private: k, public: &k.PublicKey}, nil
+	case ed25519.PrivateKey:
+		return &Key{ID: kid, Algorithm: AlgEdDSA, private: k, public: k.Public()}, nil
+	default:
+		return nil, fmt.Errorf("key %s: unsupported private key type %T", kid, parsed)
+	}
+}
+
+// ParsePublicKey reads a PEM encoded RSA or Ed25519 public key (PKIX), for a
+// key that may only verify tokens
+func ParsePublicKey(kid string, data []byte) (*Key, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("key %s: no PEM data found", kid)
+	}
+	parsed, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("key %s: %w", kid, err)
+	}
+	switch k := parsed.(type) {
+	case *rsa.PublicKey:
+		return &Key{ID: kid, Algorithm: AlgRS256, public: k}, nil
+	case ed25519.PublicKey:
+		return &Key{ID: kid, Algorithm: AlgEdDSA, public: k}, nil
+	default:
+		return nil, fmt.Errorf("key %s: unsupported public key type %T", kid, parsed)
+	}
+}
+
+func (k *Key) method() jwt.SigningMethod {
+	return jwt.GetSigningMethod(k.Algorithm)
+}
+
+func (k *Key) signingKey() interface{} {
+	if k.Algorithm == AlgHS256 {
+		return k.secret
+	}
+	return k.private
+}
+
+func (k *Key) verificationKey() interface{} {
+	if k.Algorithm == AlgHS256 {
+		return k.secret
+	}
+	return k.public
+}
+
+// Manager signs tokens with the current key and verifies tokens signed with
+// any configured key. Rotating a secret means adding a new signing key while
+// keeping the old one for verification until the tokens it signed expire.
+type Manager struct {
+	signing *Key
+	keys    map[string]*Key
+}
+
+// NewManager signs with the key whose ID is signingKID; the other keys only verify
+func NewManager(signingKID string, keys ...*Key) (*Manager, error) {
+	m := &Manager{keys: map[string]*Key{}}
+	for _, k := range keys {
+		if k.ID == "" {
+			return nil, errors.New("every key needs a kid")
+		}
+		if _, dup := m.keys[k.ID]; dup {
+			return nil, fmt.Errorf("duplicate key id %q", k.ID)
+		}
+		if k.Algorithm == AlgHS256 && len(k.secret) == 0 {
+			return nil, fmt.Errorf("key %s: empty secret", k.ID)
+		}
+		m.keys[k.ID] = k
+	}
+	signing, ok := m.keys[signingKID]
+	if !ok {
+		return nil, fmt.Errorf("signing key %q is not configured", signingKID)
+	}
+	if signing.signingKey() == nil {
+		return nil, fmt.Errorf("signing key %q has no private key", signingKID)
+	}
+	m.signing = signing
+	return m, nil
+}
+
+// Sign signs claims with the current key and sets the "kid" header
+func (m *Manager) Sign(claims jwt.MapClaims) (string, error) {
+	t := jwt.NewWithClaims(m.signing.method(), claims)
+	t.Header["kid"] = m.signing.ID
+	return t.SignedString(m.signing.signingKey())
+}
+
+// Verify parses tokenString and checks it against the key named by its "kid".
+// Tokens without a kid were issued before key IDs existed and are checked
+// against the current signing key. The algorithm must be the one of the key,
+// so an RSA public key can never be used as an HMAC secret.
+func (m *Manager) Verify(tokenString string) (*jwt.Token, error) {
+	return jwt.Parse(tokenString, func(t *jwt.Token) (interface{}, error) {
+		key := m.signing
+		if kid, ok := t.Header["kid"].(string); ok {
+			if key, ok = m.keys[kid]; !ok {
+				return nil, fmt.Errorf("unknown key id %q", kid)
+			}
+		}
+		if t.Method.Alg() != key.Algorithm {
+			return nil, fmt.Errorf("unexpected signing method: %v", t.Header["alg"])
+		}
+		return key.verificationKey(), nil
+	}, jwt.WithExpirationRequired())
+}
+
+// JWK is a public key in JSON Web Key format (RFC 7517)
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+// JWKS is the document served at /.well-known/jwks.json
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS returns the public keys, sorted by kid. HMAC secrets are never published.
+func (m *Manager) JWKS() JWKS {
+	set := JWKS{Keys: []JWK{}}
+	for _, k := range m.keys {
+		b64 := base64.RawURLEncoding.EncodeToString
+		switch pub := k.public.(type) {
+		case *rsa.PublicKey:
+			set.Keys = append(set.Keys, JWK{Kty: "RSA", Kid: k.ID, Alg: k.Algorithm, Use: "sig",
+				N: b64(pub.N.Bytes()), E: b64(big.NewInt(int64(pub.E)).Bytes())})
+		case ed25519.PublicKey:
+			set.Keys = append(set.Keys, JWK{Kty: "OKP", Kid: k.ID, Alg: k.Algorithm, Use: "sig",
+				Crv: "Ed25519", X: b64(pub)})
+		}
+	}
+	sort.Slice(set.Keys, func(i, j int) bool { return set.Keys[i].Kid < set.Keys[j].Kid })
+	return set
+}
+
+// keyFile is the format of JWT_KEYS_FILE (YAML or JSON)
+type keyFile struct {
+	SigningKey string `yaml:"signing_key"`
+	Keys       []struct {
+		Kid            string `yaml:"kid"`
+		Secret         string `yaml:"secret"`
+		PrivateKey     string `yaml:"private_key"`
+		PrivateKeyFile string `yaml:"private_key_file"`
+		PublicKey      string `yaml:"public_key"`
+		PublicKeyFile  string `yaml:"public_key_file"`
+	} `yaml:"keys"`
+}
+
+// LoadKeyFile builds a Manager from a key file. Each key has a kid and exactly
+// one of secret (HS256), private_key[_file] or public_key[_file] (PEM, RS256 or
+// EdDSA by key type). Relative file paths are resolved from the key file.
+func LoadKeyFile(path string) (*Manager, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read JWT key file: %w", err)
+	}
+	var f keyFile
+	// JSON is a subset of YAML, so one decoder reads both
+	if err := yaml.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("parse JWT key file %s: %w", path, err)
+	}
+
+	readPEM := func(inline, file string) ([]byte, error) {
+		if inline != "" {
+			return []byte(inline), nil
+		}
+		if !filepath.IsAbs(file) {
+			file = filepath.Join(filepath.Dir(path), file)
+		}
+		return os.ReadFile(file)
+	}
+	var keys []*Key
+	for _, entry := range f.Keys {
+		var key *Key
+		switch {
+		case entry.Secret != "":
+			key = NewHMACKey(entry.Kid, []byte(entry.Secret))
+		case entry.PrivateKey != "" || entry.PrivateKeyFile != "":
+			data, err := readPEM(entry.PrivateKey, entry.PrivateKeyFile)
+			if err != nil {
+				return nil, fmt.Errorf("key %s: %w", entry.Kid, err)
+			}
+			if key, err = ParsePrivateKey(entry.Kid, data); err != nil {
+				return nil, err
+			}
+		case entry.PublicKey != "" || entry.PublicKeyFile != "":
+			data, err := readPEM(entry.PublicKey, entry.PublicKeyFile)
+			if err != nil {
+				return nil, fmt.Errorf("key %s: %w", entry.Kid, err)
+			}
+			if key, err = ParsePublicKey(entry.Kid, data); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, fmt.Errorf("key %q needs a secret, a private key or a public key", entry.Kid)
+		}
+		keys = append(keys, key)
+	}
+	return NewManager(f.SigningKey, keys...)
+}
+
+// FromConfig builds the Manager from JWT_KEYS_FILE when it is set, otherwise
+// from JWT_SECRET (signing, kid JWT_KEY_ID) and JWT_PREVIOUS_KEYS (verify only)
+func FromConfig(cfg config.JWTConfig) (*Manager, error) {
+	if cfg.KeysFile != "" {
+		return LoadKeyFile(cfg.KeysFile)
+	}
+	keys := []*Key{NewHMACKey(cfg.KeyID, []byte(cfg.Secret))}
+	kids := make([]string, 0, len(cfg.PreviousKeys))
+	for kid := range cfg.PreviousKeys {
+		kids = append(kids, kid)
+	}
+	sort.Strings(kids)
+	for _, kid := range kids {
+		keys = append(keys, NewHMACKey(kid, []byte(cfg.PreviousKeys[kid])))
+	}
+	return NewManager(cfg.KeyID, keys...)
+}
+
+// RevocationStore remembers revoked access tokens until they would have expired
+type RevocationStore interface {
+	// RevokeToken rejects the token with this jti until expiresAt
+	RevokeToken(ctx context.Context, jti string, expiresAt time.Time) error
+	// RevokeSubject rejects, for ttl, every token of subject issued at or before at
+	RevokeSubject(ctx context.Context, subject string, at time.Time, ttl time.Duration) error
+	// IsRevoked tells whether a token is revoked by its jti or by the cutoff of its subject
+	IsRevoked(ctx context.Context, jti, subject string, issuedAt time.Time) (bool, error)
+}
@@ -0,0 +1,170 @@
+package token_test
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"ticket_app/internal/config"
+	"ticket_app/internal/token"
+)
+
+func claims() jwt.MapClaims {
+	return jwt.MapClaims{"email": "test@example.com", "exp": time.Now().Add(time.Hour).Unix()}
+}
+
+func pemBytes(t *testing.T, typ string, der []byte) []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: typ, Bytes: der})
+}
+
+func TestRotationKeepsOldTokensValid(t *testing.T) {
+	before, err := token.NewManager("2025-01", token.NewHMACKey("2025-01", []byte("old-secret")))
+	require.NoError(t, err)
+	oldToken, err := before.Sign(claims())
+	require.NoError(t, err)
+
+	after, err := token.NewManager("2025-02",
+		token.NewHMACKey("2025-02", []byte("new-secret")),
+		token.NewHMACKey("2025-01", []byte("old-secret")))
+	require.NoError(t, err)
+	newToken, err := after.Sign(claims())
+	require.NoError(t, err)
+
+	parsed, err := after.Verify(oldToken)
+	require.NoError(t, err)
+	assert.Equal(t, "2025-01", parsed.Header["kid"])
+	parsed, err = after.Verify(newToken)
+	require.NoError(t, err)
+	assert.Equal(t, "2025-02", parsed.Header["kid"])
+
+	// Once the old key is dropped, its tokens are rejected
+	_, err = before.Verify(newToken)
+	assert.ErrorContains(t, err, "unknown key id")
+}
+
+func TestVerifyRejectsBadTokens(t *testing.T) {
+	m, err := token.NewManager("k1", token.NewHMACKey("k1", []byte("secret")))
+	require.NoError(t, err)
+
+	expired := jwt.MapClaims{"email": "a@b.c", "exp": time.Now().Add(-time.Minute).Unix()}
+	s, err := m.Sign(expired)
+	require.NoError(t, err)
+	_, err = m.Verify(s)
+	assert.ErrorIs(t, err, jwt.ErrTokenExpired)
+
+	noExp, err := m.Sign(jwt.MapClaims{"email": "a@b.c"})
+	require.NoError(t, err)
+	_, err = m.Verify(noExp)
+	assert.Error(t, err)
+
+	forged := jwt.NewWithClaims(jwt.SigningMethodHS256, claims())
+	forged.Header["kid"] = "k1"
+	s, err = forged.SignedString([]byte("guessed"))
+	require.NoError(t, err)
+	_, err = m.Verify(s)
+	assert.ErrorIs(t, err, jwt.ErrSignatureInvalid)
+}
+
+func TestVerifyAcceptsLegacyTokensWithoutKid(t *testing.T) {
+	m, err := token.NewManager("primary", token.NewHMACKey("primary", []byte("secret")))
+	require.NoError(t, err)
+	legacy, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims()).SignedString([]byte("secret"))
+	require.NoError(t, err)
+
+	_, err = m.Verify(legacy)
+	assert.NoError(t, err)
+}
+
+func TestPublicKeyCannotBeUsedAsHMACSecret(t *testing.T) {
+	priv, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKCS8PrivateKey(priv)
+	require.NoError(t, err)
+	key, err := token.ParsePrivateKey("rsa", pemBytes(t, "PRIVATE KEY", der))
+	require.NoError(t, err)
+	m, err := token.NewManager("rsa", key)
+	require.NoError(t, err)
+
+	pubDER, err := x509.MarshalPKIXPublicKey(&priv.PublicKey)
+	require.NoError(t, err)
+	forged := jwt.NewWithClaims(jwt.SigningMethodHS256, claims())
+	forged.Header["kid"] = "rsa"
+	s, err := forged.SignedString(pemBytes(t, "PUBLIC KEY", pubDER))
+	require.NoError(t, err)
+
+	_, err = m.Verify(s)
+	assert.ErrorContains(t, err, "unexpected signing method")
+}
+
+func TestKeyFileWithEdDSAAndJWKS(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKCS8PrivateKey(priv)
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "ed.pem"), pemBytes(t, "PRIVATE KEY", der), 0o600))
+	keyFile := filepath.Join(dir, "keys.yaml")
+	require.NoError(t, os.WriteFile(keyFile, []byte(`
+signing_key: ed-2025
+keys:
+  - kid: ed-2025
+    private_key_file: ed.pem
+  - kid: hs-old
+    secret: old-secret
+`), 0o600))
+
+	m, err := token.FromConfig(config.JWTConfig{KeysFile: keyFile})
+	require.NoError(t, err)
+	s, err := m.Sign(claims())
+	require.NoError(t, err)
+	parsed, err := m.Verify(s)
+	require.NoError(t, err)
+	assert.Equal(t, "EdDSA", parsed.Method.Alg())
+
+	jwks := m.JWKS()
+	require.Len(t, jwks.Keys, 1, "HMAC secrets must not be published")
+	assert.Equal(t, token.JWK{Kty: "OKP", Kid: "ed-2025", Alg: "EdDSA", Use: "sig", Crv: "Ed25519",
+		X: base64.RawURLEncoding.EncodeToString(pub)}, jwks.Keys[0])
+}
+
+func TestFromConfigWithPreviousKeys(t *testing.T) {
+	old, err := token.NewManager("v1", token.NewHMACKey("v1", []byte("one")))
+	require.NoError(t, err)
+	s, err := old.Sign(claims())
+	require.NoError(t, err)
+
+	m, err := token.FromConfig(config.JWTConfig{Secret: "two", KeyID: "v2", PreviousKeys: map[string]string{"v1": "one"}})
+	require.NoError(t, err)
+	_, err = m.Verify(s)
+	assert.NoError(t, err)
+	assert.Empty(t, m.JWKS().Keys)
+}
+
+func TestNewManagerValidatesKeys(t *testing.T) {
+	_, err := token.NewManager("missing", token.NewHMACKey("k1", []byte("s")))
+	assert.ErrorContains(t, err, "not configured")
+
+	_, err = token.NewManager("k1", token.NewHMACKey("k1", []byte("s")), token.NewHMACKey("k1", []byte("t")))
+	assert.ErrorContains(t, err, "duplicate")
+
+	pub, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	require.NoError(t, err)
+	verifyOnly, err := token.ParsePublicKey("partner", pemBytes(t, "PUBLIC KEY", der))
+	require.NoError(t, err)
+	_, err = token.NewManager("partner", verifyOnly)
+	assert.ErrorContains(t, err, "no private key")
+}
@@ -0,0 +1,73 @@
+package totp_test
+
+import (
+	"encoding/base32"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"ticket_app/internal/totp"
+)
+
+// rfcSecret is the SHA-1 key of the RFC 6238 test vectors
+var rfcSecret = base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+
+func TestCodeMatchesRFC6238(t *testing.T) {
+	// The RFC lists 8 digit codes; 6 digit codes are their last 6 digits
+	vectors := map[int64]string{
+		59:          "287082",
+		1111111109:  "081804",
+		1111111111:  "050471",
+		1234567890:  "005924",
+		2000000000:  "279037",
+		20000000000: "353130",
+	}
+	for unix, want := range vectors {
+		code, err := totp.Code(rfcSecret, totp.Step(time.Unix(unix, 0)))
+		require.NoError(t, err)
+		assert.Equal(t, want, code, "time %d", unix)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	secret, err := totp.GenerateSecret()
+	require.NoError(t, err)
+	assert.Len(t, secret, 32)
+
+	now := time.Unix(1700000000, 0)
+	code, err := totp.Code(secret, totp.Step(now))
+	require.NoError(t, err)
+
+	step, ok := totp.Validate(secret, code, now)
+	assert.True(t, ok)
+	assert.Equal(t, totp.Step(now), step)
+
+	// One step of clock skew either way is accepted, two are not
+	_, ok = totp.Validate(secret, code, now.Add(totp.Period))
+	assert.True(t, ok)
+	_, ok = totp.Validate(secret, code, now.Add(-totp.Period))
+	assert.True(t, ok)
+	_, ok = totp.Validate(secret, code, now.Add(2*totp.Period))
+	assert.False(t, ok)
+
+	_, ok = totp.Validate(secret, "12345", now)
+	assert.False(t, ok)
+	_, ok = totp.Validate("not base32!", code, now)
+	assert.False(t, ok)
+}
+
+func TestURI(t *testing.T) {
+	uri := totp.URI("Ticket App", "alice@example.com", "JBSWY3DPEHPK3PXP")
+	assert.True(t, strings.HasPrefix(uri, "otpauth://totp/Ticket%20App:alice@example.com?"))
+	u, err := url.Parse(uri)
+	require.NoError(t, err)
+	q := u.Query()
+	assert.Equal(t, "JBSWY3DPEHPK3PXP", q.Get("secret"))
+	assert.Equal(t, "Ticket App", q.Get("issuer"))
+	assert.Equal(t, "6", q.Get("digits"))
+	assert.Equal(t, "30", q.Get("period"))
+}
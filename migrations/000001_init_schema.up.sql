@@ -0,0 +1,91 @@
+-- Initial schema: users, events, bookings and payments.
+-- Tables and indexes use IF NOT EXISTS and the names GORM AutoMigrate used, so
+-- this migration can also be applied to a database created by AutoMigrate.
+
+CREATE TABLE IF NOT EXISTS users (
+    id         BIGSERIAL PRIMARY KEY,
+    email      VARCHAR(255) NOT NULL CONSTRAINT uni_users_email UNIQUE,
+    password   VARCHAR(255) NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS events (
+    id            BIGSERIAL PRIMARY KEY,
+    name          VARCHAR(255) NOT NULL,
+    description   TEXT,
+    start_date    TIMESTAMPTZ,
+    end_date      TIMESTAMPTZ,
+    total_tickets BIGINT NOT NULL,
+    ticket_price  DECIMAL(10,2) NOT NULL,
+    created_at    TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at    TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    status        VARCHAR(255) NOT NULL DEFAULT 'ACTIVE'
+);
+
+CREATE TABLE IF NOT EXISTS bookings (
+    id          BIGSERIAL PRIMARY KEY,
+    user_id     BIGINT NOT NULL,
+    event_id    BIGINT NOT NULL,
+    quantity    BIGINT NOT NULL,
+    total_price NUMERIC NOT NULL,
+    status      VARCHAR(20) NOT NULL DEFAULT 'PENDING',
+    created_at  TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at  TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS payments (
+    id         BIGSERIAL PRIMARY KEY,
+    booking_id BIGINT NOT NULL,
+    amount     DECIMAL(10,2) NOT NULL,
+    status     VARCHAR(20) NOT NULL DEFAULT 'PENDING',
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Foreign keys and CHECK constraints, skipped when an equivalent one exists
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conrelid = 'bookings'::regclass AND contype = 'f' AND confrelid = 'users'::regclass) THEN
+        ALTER TABLE bookings ADD CONSTRAINT fk_bookings_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE RESTRICT;
+    END IF;
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conrelid = 'bookings'::regclass AND contype = 'f' AND confrelid = 'events'::regclass) THEN
+        ALTER TABLE bookings ADD CONSTRAINT fk_bookings_event FOREIGN KEY (event_id) REFERENCES events (id) ON DELETE RESTRICT;
+    END IF;
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conrelid = 'payments'::regclass AND contype = 'f' AND confrelid = 'bookings'::regclass) THEN
+        ALTER TABLE payments ADD CONSTRAINT fk_payments_booking FOREIGN KEY (booking_id) REFERENCES bookings (id) ON DELETE CASCADE;
+    END IF;
+
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'chk_events_total_tickets') THEN
+        ALTER TABLE events ADD CONSTRAINT chk_events_total_tickets CHECK (total_tickets >= 0);
+    END IF;
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'chk_events_ticket_price') THEN
+        ALTER TABLE events ADD CONSTRAINT chk_events_ticket_price CHECK (ticket_price >= 0);
+    END IF;
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'chk_events_status') THEN
+        ALTER TABLE events ADD CONSTRAINT chk_events_status CHECK (status IN ('ACTIVE', 'INACTIVE'));
+    END IF;
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'chk_bookings_quantity') THEN
+        ALTER TABLE bookings ADD CONSTRAINT chk_bookings_quantity CHECK (quantity > 0);
+    END IF;
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'chk_bookings_status') THEN
+        ALTER TABLE bookings ADD CONSTRAINT chk_bookings_status CHECK (status IN ('PENDING', 'CONFIRMED', 'CANCELLED'));
+    END IF;
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'chk_payments_amount') THEN
+        ALTER TABLE payments ADD CONSTRAINT chk_payments_amount CHECK (amount >= 0);
+    END IF;
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'chk_payments_status') THEN
+        ALTER TABLE payments ADD CONSTRAINT chk_payments_status CHECK (status IN ('PENDING', 'COMPLETED', 'FAILED'));
+    END IF;
+END
+$$;
+
+CREATE INDEX IF NOT EXISTS idx_bookings_user_id ON bookings (user_id);
+CREATE INDEX IF NOT EXISTS idx_bookings_event_id ON bookings (event_id);
+CREATE INDEX IF NOT EXISTS idx_payments_booking_id ON payments (booking_id);
+-- Event statistics aggregate bookings per event and status
+CREATE INDEX IF NOT EXISTS idx_bookings_event_id_status ON bookings (event_id, status);
+-- Listing a user's bookings, newest first
+CREATE INDEX IF NOT EXISTS idx_bookings_user_id_created_at ON bookings (user_id, created_at DESC);
+-- Listing bookable events
+CREATE INDEX IF NOT EXISTS idx_events_status_start_date ON events (status, start_date);
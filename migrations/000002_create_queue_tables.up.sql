@@ -0,0 +1,22 @@
+-- Tables of the Postgres queue backend (QUEUE_BACKEND=postgres)
+
+CREATE TABLE IF NOT EXISTS queue_jobs (
+    id           BIGSERIAL PRIMARY KEY,
+    queue        VARCHAR(100) NOT NULL,
+    payload      TEXT NOT NULL,
+    run_at       TIMESTAMPTZ NOT NULL,
+    locked_until TIMESTAMPTZ,
+    created_at   TIMESTAMPTZ
+);
+
+-- Workers look for due jobs of one queue
+CREATE INDEX IF NOT EXISTS idx_queue_jobs_due ON queue_jobs (queue, run_at);
+
+CREATE TABLE IF NOT EXISTS queue_dead_letters (
+    id        VARCHAR(64) PRIMARY KEY,
+    queue     VARCHAR(100) NOT NULL,
+    job       TEXT NOT NULL,
+    failed_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_queue_dead_letters_queue ON queue_dead_letters (queue);
@@ -0,0 +1,16 @@
+-- Refresh tokens, stored as SHA-256 hashes. A family is one login session.
+
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+    id             BIGSERIAL PRIMARY KEY,
+    user_id        BIGINT NOT NULL CONSTRAINT fk_refresh_tokens_user REFERENCES users (id) ON DELETE CASCADE,
+    family_id      VARCHAR(36) NOT NULL,
+    token_hash     CHAR(64) NOT NULL CONSTRAINT uni_refresh_tokens_token_hash UNIQUE,
+    expires_at     TIMESTAMPTZ NOT NULL,
+    revoked_at     TIMESTAMPTZ,
+    replaced_by_id BIGINT CONSTRAINT fk_refresh_tokens_replaced_by REFERENCES refresh_tokens (id) ON DELETE SET NULL,
+    created_at     TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Reuse detection revokes a whole family, logout-all every family of a user
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_family_id ON refresh_tokens (family_id);
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_user_id ON refresh_tokens (user_id);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_events_organizer_id;
+ALTER TABLE events DROP COLUMN IF EXISTS organizer_id;
+ALTER TABLE users DROP COLUMN IF EXISTS role;
@@ -0,0 +1,11 @@
+-- Roles for access control, and the organizer who owns each event.
+-- Existing users become customers; existing events have no organizer, so only
+-- admins can change them.
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS role VARCHAR(20) NOT NULL DEFAULT 'customer'
+    CONSTRAINT chk_users_role CHECK (role IN ('customer', 'organizer', 'admin'));
+
+ALTER TABLE events ADD COLUMN IF NOT EXISTS organizer_id BIGINT
+    CONSTRAINT fk_events_organizer REFERENCES users (id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_events_organizer_id ON events (organizer_id);
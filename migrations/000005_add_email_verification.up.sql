@@ -0,0 +1,21 @@
+-- Email verification and password reset.
+-- Existing users registered before verification existed, so they are treated
+-- as verified; new users have to confirm their email before booking.
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS email_verified_at TIMESTAMPTZ;
+UPDATE users SET email_verified_at = COALESCE(created_at, CURRENT_TIMESTAMP) WHERE email_verified_at IS NULL;
+
+-- Single-use tokens sent by email, stored as SHA-256 hashes
+CREATE TABLE IF NOT EXISTS user_tokens (
+    id         BIGSERIAL PRIMARY KEY,
+    user_id    BIGINT NOT NULL CONSTRAINT fk_user_tokens_user REFERENCES users (id) ON DELETE CASCADE,
+    purpose    VARCHAR(32) NOT NULL
+        CONSTRAINT chk_user_tokens_purpose CHECK (purpose IN ('email_verification', 'password_reset')),
+    token_hash CHAR(64) NOT NULL CONSTRAINT uni_user_tokens_token_hash UNIQUE,
+    expires_at TIMESTAMPTZ NOT NULL,
+    used_at    TIMESTAMPTZ,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+-- A new token invalidates the unused ones of the same user and purpose
+CREATE INDEX IF NOT EXISTS idx_user_tokens_user_id ON user_tokens (user_id, purpose);
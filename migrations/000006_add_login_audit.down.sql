@@ -0,0 +1,6 @@
+DELETE FROM user_tokens WHERE purpose = 'account_unlock';
+ALTER TABLE user_tokens DROP CONSTRAINT IF EXISTS chk_user_tokens_purpose;
+ALTER TABLE user_tokens ADD CONSTRAINT chk_user_tokens_purpose
+    CHECK (purpose IN ('email_verification', 'password_reset'));
+
+DROP TABLE IF EXISTS audit_logs;
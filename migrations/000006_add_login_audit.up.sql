@@ -0,0 +1,18 @@
+-- Audit log of logins, and account unlock tokens for locked out users
+
+CREATE TABLE IF NOT EXISTS audit_logs (
+    id         BIGSERIAL PRIMARY KEY,
+    user_id    BIGINT CONSTRAINT fk_audit_logs_user REFERENCES users (id) ON DELETE SET NULL,
+    action     VARCHAR(32) NOT NULL,
+    email      VARCHAR(255),
+    ip         VARCHAR(45),
+    user_agent VARCHAR(255),
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_logs_user_id ON audit_logs (user_id);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_created_at ON audit_logs (created_at);
+
+ALTER TABLE user_tokens DROP CONSTRAINT IF EXISTS chk_user_tokens_purpose;
+ALTER TABLE user_tokens ADD CONSTRAINT chk_user_tokens_purpose
+    CHECK (purpose IN ('email_verification', 'password_reset', 'account_unlock'));
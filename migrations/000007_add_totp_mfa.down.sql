@@ -0,0 +1,11 @@
+DELETE FROM user_tokens WHERE purpose = 'mfa_challenge';
+ALTER TABLE user_tokens DROP CONSTRAINT IF EXISTS chk_user_tokens_purpose;
+ALTER TABLE user_tokens ADD CONSTRAINT chk_user_tokens_purpose
+    CHECK (purpose IN ('email_verification', 'password_reset', 'account_unlock'));
+
+ALTER TABLE refresh_tokens DROP COLUMN IF EXISTS mfa;
+DROP TABLE IF EXISTS recovery_codes;
+
+ALTER TABLE users DROP COLUMN IF EXISTS totp_last_step;
+ALTER TABLE users DROP COLUMN IF EXISTS totp_enabled_at;
+ALTER TABLE users DROP COLUMN IF EXISTS totp_secret;
@@ -0,0 +1,22 @@
+-- TOTP two-factor authentication: the secret of each user, hashed recovery
+-- codes, and whether a refresh token session passed the second factor
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS totp_secret VARCHAR(64);
+ALTER TABLE users ADD COLUMN IF NOT EXISTS totp_enabled_at TIMESTAMPTZ;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS totp_last_step BIGINT NOT NULL DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS recovery_codes (
+    id         BIGSERIAL PRIMARY KEY,
+    user_id    BIGINT NOT NULL CONSTRAINT fk_recovery_codes_user REFERENCES users (id) ON DELETE CASCADE,
+    code_hash  CHAR(64) NOT NULL,
+    used_at    TIMESTAMPTZ,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_recovery_codes_user_id ON recovery_codes (user_id);
+
+ALTER TABLE refresh_tokens ADD COLUMN IF NOT EXISTS mfa BOOLEAN NOT NULL DEFAULT FALSE;
+
+ALTER TABLE user_tokens DROP CONSTRAINT IF EXISTS chk_user_tokens_purpose;
+ALTER TABLE user_tokens ADD CONSTRAINT chk_user_tokens_purpose
+    CHECK (purpose IN ('email_verification', 'password_reset', 'account_unlock', 'mfa_challenge'));
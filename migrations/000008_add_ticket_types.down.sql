@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS booking_items;
+DROP TABLE IF EXISTS ticket_types;
@@ -0,0 +1,35 @@
+-- Ticket types (price tiers) per event, and the line items of a booking.
+-- Bookings of events without ticket types keep using events.ticket_price and
+-- have no items.
+
+CREATE TABLE IF NOT EXISTS ticket_types (
+    id            BIGSERIAL PRIMARY KEY,
+    event_id      BIGINT NOT NULL CONSTRAINT fk_ticket_types_event REFERENCES events (id) ON DELETE CASCADE,
+    name          VARCHAR(100) NOT NULL,
+    price         DECIMAL(10,2) NOT NULL CONSTRAINT chk_ticket_types_price CHECK (price >= 0),
+    quota         BIGINT NOT NULL CONSTRAINT chk_ticket_types_quota CHECK (quota >= 0),
+    reserved      BIGINT NOT NULL DEFAULT 0,
+    min_per_order BIGINT NOT NULL DEFAULT 1,
+    max_per_order BIGINT NOT NULL DEFAULT 0,
+    sales_start   TIMESTAMPTZ,
+    sales_end     TIMESTAMPTZ,
+    created_at    TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at    TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT uni_ticket_types_event_id_name UNIQUE (event_id, name),
+    -- The last line of defence against overselling a tier
+    CONSTRAINT chk_ticket_types_reserved CHECK (reserved >= 0 AND reserved <= quota),
+    CONSTRAINT chk_ticket_types_per_order CHECK (min_per_order >= 1 AND (max_per_order = 0 OR max_per_order >= min_per_order)),
+    CONSTRAINT chk_ticket_types_sales_window CHECK (sales_start IS NULL OR sales_end IS NULL OR sales_start < sales_end)
+);
+
+CREATE TABLE IF NOT EXISTS booking_items (
+    id             BIGSERIAL PRIMARY KEY,
+    booking_id     BIGINT NOT NULL CONSTRAINT fk_booking_items_booking REFERENCES bookings (id) ON DELETE CASCADE,
+    ticket_type_id BIGINT NOT NULL CONSTRAINT fk_booking_items_ticket_type REFERENCES ticket_types (id) ON DELETE RESTRICT,
+    quantity       BIGINT NOT NULL CONSTRAINT chk_booking_items_quantity CHECK (quantity > 0),
+    unit_price     DECIMAL(10,2) NOT NULL CONSTRAINT chk_booking_items_unit_price CHECK (unit_price >= 0),
+    CONSTRAINT uni_booking_items_booking_id_ticket_type_id UNIQUE (booking_id, ticket_type_id)
+);
+
+-- Deleting a ticket type checks whether it was booked
+CREATE INDEX IF NOT EXISTS idx_booking_items_ticket_type_id ON booking_items (ticket_type_id);
@@ -0,0 +1,10 @@
+DROP TABLE IF EXISTS booking_seats;
+
+DROP INDEX IF EXISTS uni_ticket_types_event_id_section_id;
+ALTER TABLE ticket_types DROP COLUMN IF EXISTS section_id;
+ALTER TABLE events DROP COLUMN IF EXISTS venue_id;
+
+DROP TABLE IF EXISTS seats;
+DROP TABLE IF EXISTS seat_rows;
+DROP TABLE IF EXISTS sections;
+DROP TABLE IF EXISTS venues;
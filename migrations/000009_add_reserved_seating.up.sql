@@ -0,0 +1,53 @@
+-- Reserved seating: venues with a seat map (sections > rows > seats), events
+-- held at a venue, and the seats held by each booking
+
+CREATE TABLE IF NOT EXISTS venues (
+    id         BIGSERIAL PRIMARY KEY,
+    name       VARCHAR(255) NOT NULL,
+    address    TEXT,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS sections (
+    id       BIGSERIAL PRIMARY KEY,
+    venue_id BIGINT NOT NULL CONSTRAINT fk_sections_venue REFERENCES venues (id) ON DELETE CASCADE,
+    name     VARCHAR(100) NOT NULL,
+    CONSTRAINT uni_sections_venue_id_name UNIQUE (venue_id, name)
+);
+
+-- "rows" is an SQL keyword
+CREATE TABLE IF NOT EXISTS seat_rows (
+    id         BIGSERIAL PRIMARY KEY,
+    section_id BIGINT NOT NULL CONSTRAINT fk_seat_rows_section REFERENCES sections (id) ON DELETE CASCADE,
+    label      VARCHAR(10) NOT NULL,
+    position   BIGINT NOT NULL,
+    CONSTRAINT uni_seat_rows_section_id_label UNIQUE (section_id, label)
+);
+
+CREATE TABLE IF NOT EXISTS seats (
+    id     BIGSERIAL PRIMARY KEY,
+    row_id BIGINT NOT NULL CONSTRAINT fk_seats_row REFERENCES seat_rows (id) ON DELETE CASCADE,
+    number BIGINT NOT NULL CONSTRAINT chk_seats_number CHECK (number > 0),
+    CONSTRAINT uni_seats_row_id_number UNIQUE (row_id, number)
+);
+
+ALTER TABLE events ADD COLUMN IF NOT EXISTS venue_id BIGINT CONSTRAINT fk_events_venue REFERENCES venues (id) ON DELETE RESTRICT;
+
+-- The seats of a section are sold as the event's ticket type for that section
+ALTER TABLE ticket_types ADD COLUMN IF NOT EXISTS section_id BIGINT CONSTRAINT fk_ticket_types_section REFERENCES sections (id) ON DELETE SET NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS uni_ticket_types_event_id_section_id ON ticket_types (event_id, section_id) WHERE section_id IS NOT NULL;
+
+CREATE TABLE IF NOT EXISTS booking_seats (
+    id          BIGSERIAL PRIMARY KEY,
+    booking_id  BIGINT NOT NULL CONSTRAINT fk_booking_seats_booking REFERENCES bookings (id) ON DELETE CASCADE,
+    event_id    BIGINT NOT NULL CONSTRAINT fk_booking_seats_event REFERENCES events (id) ON DELETE RESTRICT,
+    seat_id     BIGINT NOT NULL CONSTRAINT fk_booking_seats_seat REFERENCES seats (id) ON DELETE RESTRICT,
+    unit_price  DECIMAL(10,2) NOT NULL CONSTRAINT chk_booking_seats_unit_price CHECK (unit_price >= 0),
+    released_at TIMESTAMPTZ,
+    created_at  TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+-- A seat is held by at most one live booking per event: two users can never get the same seat
+CREATE UNIQUE INDEX IF NOT EXISTS uni_booking_seats_event_id_seat_id ON booking_seats (event_id, seat_id) WHERE released_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_booking_seats_booking_id ON booking_seats (booking_id);
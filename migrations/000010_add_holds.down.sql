@@ -0,0 +1,10 @@
+DELETE FROM booking_seats WHERE booking_id IS NULL;
+DROP INDEX IF EXISTS idx_booking_seats_hold_id;
+ALTER TABLE booking_seats DROP CONSTRAINT IF EXISTS chk_booking_seats_owner;
+ALTER TABLE booking_seats DROP COLUMN IF EXISTS hold_id;
+ALTER TABLE booking_seats ALTER COLUMN booking_id SET NOT NULL;
+
+DROP TABLE IF EXISTS hold_items;
+DROP TABLE IF EXISTS holds;
+
+ALTER TABLE events DROP COLUMN IF EXISTS hold_minutes;
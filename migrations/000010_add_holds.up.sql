@@ -0,0 +1,39 @@
+-- Holds: tickets (and seats) reserved for a few minutes before a booking is
+-- created. A hold takes inventory exactly like a booking; POST /bookings turns
+-- it into one, and the hold expiry queue gives the inventory back otherwise.
+
+ALTER TABLE events ADD COLUMN IF NOT EXISTS hold_minutes BIGINT NOT NULL DEFAULT 0
+    CONSTRAINT chk_events_hold_minutes CHECK (hold_minutes >= 0);
+
+CREATE TABLE IF NOT EXISTS holds (
+    id          BIGSERIAL PRIMARY KEY,
+    token       VARCHAR(64) NOT NULL,
+    user_id     BIGINT NOT NULL CONSTRAINT fk_holds_user REFERENCES users (id) ON DELETE CASCADE,
+    event_id    BIGINT NOT NULL CONSTRAINT fk_holds_event REFERENCES events (id) ON DELETE CASCADE,
+    quantity    BIGINT NOT NULL CONSTRAINT chk_holds_quantity CHECK (quantity > 0),
+    total_price DECIMAL(10,2) NOT NULL,
+    status      VARCHAR(20) NOT NULL DEFAULT 'ACTIVE'
+        CONSTRAINT chk_holds_status CHECK (status IN ('ACTIVE', 'CONVERTED', 'EXPIRED', 'RELEASED')),
+    expires_at  TIMESTAMPTZ NOT NULL,
+    booking_id  BIGINT CONSTRAINT fk_holds_booking REFERENCES bookings (id) ON DELETE SET NULL,
+    created_at  TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at  TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT uni_holds_token UNIQUE (token)
+);
+CREATE INDEX IF NOT EXISTS idx_holds_user_id ON holds (user_id);
+
+-- Items of finished holds do not keep a ticket type from being deleted
+CREATE TABLE IF NOT EXISTS hold_items (
+    id             BIGSERIAL PRIMARY KEY,
+    hold_id        BIGINT NOT NULL CONSTRAINT fk_hold_items_hold REFERENCES holds (id) ON DELETE CASCADE,
+    ticket_type_id BIGINT NOT NULL CONSTRAINT fk_hold_items_ticket_type REFERENCES ticket_types (id) ON DELETE CASCADE,
+    quantity       BIGINT NOT NULL CONSTRAINT chk_hold_items_quantity CHECK (quantity > 0),
+    unit_price     DECIMAL(10,2) NOT NULL,
+    CONSTRAINT uni_hold_items_hold_id_ticket_type_id UNIQUE (hold_id, ticket_type_id)
+);
+
+-- A held seat has no booking yet; converting the hold sets booking_id on the same row
+ALTER TABLE booking_seats ALTER COLUMN booking_id DROP NOT NULL;
+ALTER TABLE booking_seats ADD COLUMN IF NOT EXISTS hold_id BIGINT CONSTRAINT fk_booking_seats_hold REFERENCES holds (id) ON DELETE SET NULL;
+ALTER TABLE booking_seats ADD CONSTRAINT chk_booking_seats_owner CHECK (booking_id IS NOT NULL OR hold_id IS NOT NULL);
+CREATE INDEX IF NOT EXISTS idx_booking_seats_hold_id ON booking_seats (hold_id);
@@ -0,0 +1,12 @@
+DELETE FROM payments WHERE booking_id IS NULL;
+DROP INDEX IF EXISTS idx_payments_order_id;
+ALTER TABLE payments DROP CONSTRAINT IF EXISTS chk_payments_owner;
+ALTER TABLE payments DROP COLUMN IF EXISTS order_id;
+ALTER TABLE payments ALTER COLUMN booking_id SET NOT NULL;
+
+DROP TABLE IF EXISTS order_items;
+
+DROP INDEX IF EXISTS idx_bookings_order_id;
+ALTER TABLE bookings DROP COLUMN IF EXISTS order_id;
+
+DROP TABLE IF EXISTS orders;
@@ -0,0 +1,34 @@
+-- Orders: tickets of several events paid with one payment. Every event of an
+-- order still gets its own booking; the payment belongs to the order instead.
+
+CREATE TABLE IF NOT EXISTS orders (
+    id          BIGSERIAL PRIMARY KEY,
+    user_id     BIGINT NOT NULL CONSTRAINT fk_orders_user REFERENCES users (id) ON DELETE RESTRICT,
+    total_price DECIMAL(10,2) NOT NULL CONSTRAINT chk_orders_total_price CHECK (total_price >= 0),
+    status      VARCHAR(20) NOT NULL DEFAULT 'PENDING'
+        CONSTRAINT chk_orders_status CHECK (status IN ('PENDING', 'CONFIRMED', 'CANCELLED')),
+    created_at  TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at  TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_orders_user_id ON orders (user_id);
+
+ALTER TABLE bookings ADD COLUMN IF NOT EXISTS order_id BIGINT CONSTRAINT fk_bookings_order REFERENCES orders (id) ON DELETE RESTRICT;
+CREATE INDEX IF NOT EXISTS idx_bookings_order_id ON bookings (order_id);
+
+CREATE TABLE IF NOT EXISTS order_items (
+    id             BIGSERIAL PRIMARY KEY,
+    order_id       BIGINT NOT NULL CONSTRAINT fk_order_items_order REFERENCES orders (id) ON DELETE CASCADE,
+    booking_id     BIGINT NOT NULL CONSTRAINT fk_order_items_booking REFERENCES bookings (id) ON DELETE CASCADE,
+    event_id       BIGINT NOT NULL CONSTRAINT fk_order_items_event REFERENCES events (id) ON DELETE RESTRICT,
+    ticket_type_id BIGINT CONSTRAINT fk_order_items_ticket_type REFERENCES ticket_types (id) ON DELETE RESTRICT,
+    quantity       BIGINT NOT NULL CONSTRAINT chk_order_items_quantity CHECK (quantity > 0),
+    unit_price     DECIMAL(10,2) NOT NULL CONSTRAINT chk_order_items_unit_price CHECK (unit_price >= 0)
+);
+CREATE INDEX IF NOT EXISTS idx_order_items_order_id ON order_items (order_id);
+CREATE INDEX IF NOT EXISTS idx_order_items_booking_id ON order_items (booking_id);
+
+-- A payment is either for one booking or for a whole order
+ALTER TABLE payments ALTER COLUMN booking_id DROP NOT NULL;
+ALTER TABLE payments ADD COLUMN IF NOT EXISTS order_id BIGINT CONSTRAINT fk_payments_order REFERENCES orders (id) ON DELETE CASCADE;
+ALTER TABLE payments ADD CONSTRAINT chk_payments_owner CHECK ((booking_id IS NULL) <> (order_id IS NULL));
+CREATE INDEX IF NOT EXISTS idx_payments_order_id ON payments (order_id);
@@ -0,0 +1,27 @@
+-- Waitlist: users waiting for tickets of a sold-out event or ticket type. When
+-- tickets come back, the first waiting entry gets a hold (the offer); booking
+-- with its token accepts it, and an expired or dropped offer passes on.
+
+CREATE TABLE IF NOT EXISTS waitlist_entries (
+    id             BIGSERIAL PRIMARY KEY,
+    user_id        BIGINT NOT NULL CONSTRAINT fk_waitlist_entries_user REFERENCES users (id) ON DELETE CASCADE,
+    event_id       BIGINT NOT NULL CONSTRAINT fk_waitlist_entries_event REFERENCES events (id) ON DELETE CASCADE,
+    ticket_type_id BIGINT CONSTRAINT fk_waitlist_entries_ticket_type REFERENCES ticket_types (id) ON DELETE CASCADE,
+    quantity       BIGINT NOT NULL CONSTRAINT chk_waitlist_entries_quantity CHECK (quantity > 0),
+    status         VARCHAR(20) NOT NULL DEFAULT 'WAITING'
+        CONSTRAINT chk_waitlist_entries_status CHECK (status IN ('WAITING', 'OFFERED', 'ACCEPTED', 'EXPIRED', 'CANCELLED')),
+    hold_id        BIGINT CONSTRAINT fk_waitlist_entries_hold REFERENCES holds (id) ON DELETE SET NULL,
+    offered_at     TIMESTAMPTZ,
+    created_at     TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at     TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_waitlist_entries_user_id ON waitlist_entries (user_id);
+CREATE INDEX IF NOT EXISTS idx_waitlist_entries_hold_id ON waitlist_entries (hold_id);
+
+-- The queue of an event is read in ID order; only waiting entries are scanned
+CREATE INDEX IF NOT EXISTS idx_waitlist_entries_waiting
+    ON waitlist_entries (event_id, id) WHERE status = 'WAITING';
+
+-- One live place per user and event or ticket type
+CREATE UNIQUE INDEX IF NOT EXISTS uni_waitlist_entries_active
+    ON waitlist_entries (user_id, event_id, COALESCE(ticket_type_id, 0)) WHERE status IN ('WAITING', 'OFFERED');
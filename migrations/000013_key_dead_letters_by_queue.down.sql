@@ -0,0 +1,4 @@
+-- Fails if two queues hold a dead letter with the same job ID
+CREATE INDEX IF NOT EXISTS idx_queue_dead_letters_queue ON queue_dead_letters (queue);
+ALTER TABLE queue_dead_letters DROP CONSTRAINT IF EXISTS queue_dead_letters_pkey;
+ALTER TABLE queue_dead_letters ADD CONSTRAINT queue_dead_letters_pkey PRIMARY KEY (id);
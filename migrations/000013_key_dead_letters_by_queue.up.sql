@@ -0,0 +1,6 @@
+-- Job IDs are only unique within a queue, so a dead letter is identified by
+-- its queue and job ID. The primary key also serves lookups by queue.
+
+ALTER TABLE queue_dead_letters DROP CONSTRAINT IF EXISTS queue_dead_letters_pkey;
+ALTER TABLE queue_dead_letters ADD CONSTRAINT queue_dead_letters_pkey PRIMARY KEY (queue, id);
+DROP INDEX IF EXISTS idx_queue_dead_letters_queue;
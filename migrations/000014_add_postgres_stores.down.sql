@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS login_throttles;
+DROP TABLE IF EXISTS revoked_subjects;
+DROP TABLE IF EXISTS revoked_tokens;
+DROP TABLE IF EXISTS idempotency_keys;
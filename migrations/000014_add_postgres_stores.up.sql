@@ -0,0 +1,36 @@
+-- Stores that live in Redis on the redis queue backend. The postgres and
+-- memory backends keep them here, so those deployments need no Redis at all.
+
+-- Idempotency-Key records; a row past expires_at counts as free
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    key          TEXT PRIMARY KEY,
+    fingerprint  VARCHAR(64) NOT NULL,
+    completed    BOOLEAN NOT NULL DEFAULT FALSE,
+    status_code  INTEGER NOT NULL DEFAULT 0,
+    content_type VARCHAR(255) NOT NULL DEFAULT '',
+    body         BYTEA,
+    expires_at   TIMESTAMPTZ NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_idempotency_keys_expires_at ON idempotency_keys (expires_at);
+
+-- Revoked access tokens, kept until the token itself expires
+CREATE TABLE IF NOT EXISTS revoked_tokens (
+    jti        TEXT PRIMARY KEY,
+    expires_at TIMESTAMPTZ NOT NULL
+);
+
+-- Logout-all cutoffs: tokens of subject issued at or before revoked_at are revoked
+CREATE TABLE IF NOT EXISTS revoked_subjects (
+    subject    VARCHAR(64) PRIMARY KEY,
+    revoked_at TIMESTAMPTZ NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL
+);
+
+-- Failed logins per email ('email:<email>') and per IP ('ip:<ip>')
+CREATE TABLE IF NOT EXISTS login_throttles (
+    key            VARCHAR(320) PRIMARY KEY,
+    failures       INTEGER NOT NULL DEFAULT 0,
+    window_ends_at TIMESTAMPTZ,
+    delay_until    TIMESTAMPTZ,
+    locked_until   TIMESTAMPTZ
+);
@@ -0,0 +1,10 @@
+// Package migrations holds the versioned SQL migrations of the database
+// schema, embedded into the binary. Files are named
+// <version>_<name>.up.sql / <version>_<name>.down.sql; create new ones with
+// `ticket_app migrate create <name>`.
+package migrations
+
+import "embed"
+
+//go:embed *.sql
+var FS embed.FS
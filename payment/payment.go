@@ -1,6 +1,7 @@
 package payment
 
 import (
+	"context"
 	"fmt"
 	"ticket_app/domain"
 	"ticket_app/internal/repository/payment"
@@ -8,10 +9,11 @@ import (
 )
 
 type PaymentService interface {
-	CreatePayment(payment *domain.Payment) error
-	ConfirmPayment(payment *domain.Payment) error
-	CancelPayment(payment *domain.Payment) error
-	FindByBookingID(bookingID uint) (*domain.Payment, error)
+	CreatePayment(ctx context.Context, payment *domain.Payment) error
+	ConfirmPayment(ctx context.Context, payment *domain.Payment) error
+	CancelPayment(ctx context.Context, payment *domain.Payment) error
+	FindByBookingID(ctx context.Context, bookingID uint) (*domain.Payment, error)
+	FindByOrderID(ctx context.Context, orderID uint) (*domain.Payment, error)
 }
 
 type paymentService struct {
@@ -22,27 +24,39 @@ func NewPaymentService(paymentRepo payment.PaymentRepository) PaymentService {
 	return &paymentService{paymentRepo: paymentRepo}
 }
 
-func (s *paymentService) CreatePayment(payment *domain.Payment) error {
-	return s.paymentRepo.Create(payment)
+func (s *paymentService) CreatePayment(ctx context.Context, payment *domain.Payment) error {
+	return s.paymentRepo.Create(ctx, payment)
 }
 
-func (s *paymentService) ConfirmPayment(payment *domain.Payment) error {
-	payment, err := s.paymentRepo.FindById(payment.ID)
+func (s *paymentService) ConfirmPayment(ctx context.Context, payment *domain.Payment) error {
+	payment, err := s.paymentRepo.FindById(ctx, payment.ID)
 	if err != nil {
 		return fmt.Errorf("payment not found")
 	}
 	payment.Status = domain.PaymentStatusCompleted
 	payment.UpdatedAt = time.Now()
-	return s.paymentRepo.UpdatePayment(payment)
+	return s.paymentRepo.UpdatePayment(ctx, payment)
 }
 
-func (s *paymentService) CancelPayment(payment *domain.Payment) error {
-	payment, err := s.paymentRepo.FindByBookingID(payment.BookingID)
+// CancelPayment tìm payment theo BookingID nếu có, còn không thì theo ID
+func (s *paymentService) CancelPayment(ctx context.Context, payment *domain.Payment) error {
+	var err error
+	if payment.BookingID != nil {
+		payment, err = s.paymentRepo.FindByBookingID(ctx, *payment.BookingID)
+	} else {
+		payment, err = s.paymentRepo.FindById(ctx, payment.ID)
+	}
 	if err != nil {
 		return fmt.Errorf("payment not found")
 	}
-	return s.paymentRepo.UpdatePayment(payment)
+	payment.Status = domain.PaymentStatusFailed
+	payment.UpdatedAt = time.Now()
+	return s.paymentRepo.UpdatePayment(ctx, payment)
+}
+func (s *paymentService) FindByBookingID(ctx context.Context, bookingID uint) (*domain.Payment, error) {
+	return s.paymentRepo.FindByBookingID(ctx, bookingID)
+}
+
+func (s *paymentService) FindByOrderID(ctx context.Context, orderID uint) (*domain.Payment, error) {
+	return s.paymentRepo.FindByOrderID(ctx, orderID)
 }
-func (s *paymentService) FindByBookingID(bookingID uint) (*domain.Payment, error) {
-	return s.paymentRepo.FindByBookingID(bookingID)
-}
\ No newline at end of file
@@ -0,0 +1,68 @@
+package venue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"gorm.io/gorm"
+
+	"ticket_app/domain"
+	eventRepo "ticket_app/internal/repository/event"
+	venueRepo "ticket_app/internal/repository/venue"
+)
+
+// VenueService quản lý venue và sơ đồ ghế của event
+type VenueService interface {
+	// CreateVenue tạo venue cùng toàn bộ section, hàng và ghế
+	CreateVenue(ctx context.Context, venue *domain.Venue) error
+	ListVenues(ctx context.Context) ([]domain.Venue, error)
+	// GetVenue trả về domain.ErrNotFound nếu venue không có
+	GetVenue(ctx context.Context, id uint) (*domain.Venue, error)
+	// GetSeatMap trả về sơ đồ ghế của event với trạng thái từng ghế;
+	// domain.ErrNotFound nếu event không có, domain.ErrNoSeatMap nếu event không gắn venue
+	GetSeatMap(ctx context.Context, eventID uint) (*domain.SeatMap, error)
+}
+
+type venueService struct {
+	venueRepo venueRepo.VenueRepository
+	eventRepo eventRepo.EventRepository
+}
+
+func NewVenueService(venueRepo venueRepo.VenueRepository, eventRepo eventRepo.EventRepository) VenueService {
+	return &venueService{venueRepo: venueRepo, eventRepo: eventRepo}
+}
+
+func (s *venueService) CreateVenue(ctx context.Context, venue *domain.Venue) error {
+	if venue.Name == "" {
+		return fmt.Errorf("%w: name is required", domain.ErrBadParamInput)
+	}
+	if len(venue.Sections) == 0 {
+		return fmt.Errorf("%w: a venue needs at least one section", domain.ErrBadParamInput)
+	}
+	if err := s.venueRepo.Create(ctx, venue); err != nil {
+		return err
+	}
+	log.Println("Venue created successfully")
+	return nil
+}
+
+func (s *venueService) ListVenues(ctx context.Context) ([]domain.Venue, error) {
+	return s.venueRepo.FindAll(ctx)
+}
+
+func (s *venueService) GetVenue(ctx context.Context, id uint) (*domain.Venue, error) {
+	return s.venueRepo.FindById(ctx, id)
+}
+
+func (s *venueService) GetSeatMap(ctx context.Context, eventID uint) (*domain.SeatMap, error) {
+	event, err := s.eventRepo.FindById(ctx, eventID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, domain.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s.venueRepo.SeatMap(ctx, event)
+}